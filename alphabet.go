@@ -0,0 +1,777 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Alphabet is the set of characters a machine is wired for, in the order
+// of the contacts. The Enigma machines of this package are wired for the
+// 26 letters of Latin, which the rotor tables are made for, and their
+// plugboard pairs are read with it; AlphabetMachine is for the others,
+// like the ten digits of the Enigma Z. The filler is what's encoded in
+// place of the characters it hasn't got (see AlphabetMachine.EncodeText),
+// the X if it has one, its first character if not, unless a machine sets
+// another. Alphabets don't change once made, so machines share them.
+type Alphabet struct {
+	letters []rune
+	index   map[rune]int
+}
+
+// Alphabets of the contacts: the letters from A to Z, the digits of the
+// Enigma Z, and the letters followed by the digits, for a ring of 36.
+var (
+	Latin        = mustAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	Digits       = mustAlphabet("0123456789")
+	Alphanumeric = mustAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+)
+
+// NewAlphabet is the constructor for alphabets, taking the characters in
+// the order of the contacts. It takes at least two of them, none twice.
+func NewAlphabet(letters string) (*Alphabet, error) {
+	a := &Alphabet{letters: []rune(letters), index: make(map[rune]int)}
+	if len(a.letters) < 2 {
+		return nil, fmt.Errorf("an alphabet needs at least 2 characters, got %d", len(a.letters))
+	}
+	for i, letter := range a.letters {
+		if _, ok := a.index[letter]; ok {
+			return nil, fmt.Errorf(`"%c" is in the alphabet twice`, letter)
+		}
+		a.index[letter] = i
+	}
+	return a, nil
+}
+
+// mustAlphabet is NewAlphabet for the alphabets known to be right.
+func mustAlphabet(letters string) *Alphabet {
+	a, err := NewAlphabet(letters)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Size returns the number of characters, and of contacts.
+func (a *Alphabet) Size() int {
+	return len(a.letters)
+}
+
+// String returns the characters of the alphabet.
+func (a *Alphabet) String() string {
+	return string(a.letters)
+}
+
+// Index returns the contact of a character, and whether it's in the
+// alphabet at all.
+func (a *Alphabet) Index(letter rune) (int, bool) {
+	i, ok := a.index[letter]
+	return i, ok
+}
+
+// Char returns the character of a contact, taken around the alphabet
+// like the offsets of the rotors.
+func (a *Alphabet) Char(index int) rune {
+	return a.letters[a.mod(index)]
+}
+
+// Filler returns the character encoded in place of the ones the
+// alphabet hasn't got, unless the machine sets another.
+func (a *Alphabet) Filler() rune {
+	if _, ok := a.index['X']; ok {
+		return 'X'
+	}
+	return a.letters[0]
+}
+
+// mod returns the index wrapped around the alphabet.
+func (a *Alphabet) mod(index int) int {
+	n := len(a.letters)
+	return (index%n + n) % n
+}
+
+// wiring turns a mapping (the characters the contacts are wired to, in
+// order) into contacts, checking that it's wired for the alphabet.
+func (a *Alphabet) wiring(mapping string) ([]int, error) {
+	letters := []rune(mapping)
+	if len(letters) != a.Size() {
+		return nil, fmt.Errorf(`wiring "%s" should have %d characters, got %d`, mapping, a.Size(), len(letters))
+	}
+	wiring := make([]int, len(letters))
+	used := make([]bool, len(letters))
+	for i, letter := range letters {
+		index, ok := a.Index(letter)
+		if !ok {
+			return nil, fmt.Errorf(`wiring "%s" has "%c", which isn't in the alphabet "%s"`, mapping, letter, a)
+		}
+		if used[index] {
+			return nil, fmt.Errorf(`wiring "%s" has "%c" twice`, mapping, letter)
+		}
+		used[index] = true
+		wiring[i] = index
+	}
+	return wiring, nil
+}
+
+// plugboard turns plugboard pairs of characters into the mapping of the
+// contacts, checking that no character is plugged twice or into itself.
+// The Latin machines read their pairs with it too.
+func (a *Alphabet) plugboard(pairs []string) ([]int, error) {
+	mapping := make([]int, a.Size())
+	for i := range mapping {
+		mapping[i] = i
+	}
+	for _, pair := range pairs {
+		letters := []rune(pair)
+		if len(letters) != 2 {
+			return nil, fmt.Errorf(`plugboard should be grouped by letter pairs ("AB CD"), got "%s"`, pair)
+		}
+		i, ok := a.Index(letters[0])
+		j, ok2 := a.Index(letters[1])
+		if !ok || !ok2 {
+			return nil, fmt.Errorf(`plugboard should be grouped by letter pairs ("AB CD"), got "%s"`, pair)
+		}
+		repeated := rune(0)
+		switch {
+		case mapping[i] != i:
+			repeated = letters[0]
+		case mapping[j] != j, i == j:
+			repeated = letters[1]
+		}
+		if repeated != 0 {
+			err := settingError(ErrPlugConflict, `letters cannot repeat across the plugboard, check "%s"`, pair)
+			err.ID = "plugboard"
+			if repeated < utf8.RuneSelf {
+				err.Letter = byte(repeated)
+			}
+			return nil, err
+		}
+		mapping[i], mapping[j] = j, i
+	}
+	return mapping, nil
+}
+
+// pairs returns the plugboard pairs of a mapping of the contacts, the
+// way Plugboard.Pairs has them.
+func (a *Alphabet) pairs(mapping []int) []string {
+	var pairs []string
+	for i, j := range mapping {
+		if i < j {
+			pairs = append(pairs, string([]rune{a.Char(i), a.Char(j)}))
+		}
+	}
+	return pairs
+}
+
+// spell returns the characters a mapping of the contacts takes them
+// to, the way wiring reads them.
+func (a *Alphabet) spell(mapping []int) string {
+	letters := make([]rune, len(mapping))
+	for i, j := range mapping {
+		letters[i] = a.Char(j)
+	}
+	return string(letters)
+}
+
+// AlphabetRotor is a rotor wired for an alphabet (see Rotor, which it
+// works like, Fixed and DrivenBy included). Offset and Ring count from
+// 0, the first character.
+type AlphabetRotor struct {
+	ID       string
+	Alphabet *Alphabet
+	Offset   int
+	Ring     int
+	Fixed    bool
+	DrivenBy int
+
+	straight, reverse []int
+	turnover          []int
+}
+
+// NewAlphabetRotor is the constructor for rotors wired for an alphabet,
+// taking the mapping and the turnover positions, in the characters of
+// the alphabet, like NewRotor.
+func NewAlphabetRotor(alphabet *Alphabet, mapping string, id string, turnovers string) (*AlphabetRotor, error) {
+	straight, err := alphabet.wiring(mapping)
+	if err != nil {
+		return nil, fmt.Errorf(`rotor "%s": %w`, id, err)
+	}
+	r := &AlphabetRotor{ID: id, Alphabet: alphabet, straight: straight, reverse: make([]int, len(straight))}
+	invert(r.reverse, straight)
+	for _, letter := range turnovers {
+		index, ok := alphabet.Index(letter)
+		if !ok {
+			return nil, fmt.Errorf(`rotor "%s": turnover "%c" isn't in the alphabet "%s"`, id, letter, alphabet)
+		}
+		r.turnover = append(r.turnover, index)
+	}
+	return r, nil
+}
+
+// step sends the signal through the rotor, like Rotor.Step.
+func (r *AlphabetRotor) step(letter int, invert bool) int {
+	letter = r.Alphabet.mod(letter - r.Ring + r.Offset)
+	if invert {
+		letter = r.reverse[letter]
+	} else {
+		letter = r.straight[letter]
+	}
+	return r.Alphabet.mod(letter + r.Ring - r.Offset)
+}
+
+// atNotch tells if the rotor is at one of its turnover positions.
+func (r *AlphabetRotor) atNotch() bool {
+	for _, turnover := range r.turnover {
+		if r.Alphabet.mod(r.Offset) == turnover {
+			return true
+		}
+	}
+	return false
+}
+
+// wiring returns the wiring and the notches of the rotor, the way
+// NewAlphabetRotor takes them.
+func (r *AlphabetRotor) wiring() (mapping string, turnovers string) {
+	notches := make([]rune, len(r.turnover))
+	for i, turnover := range r.turnover {
+		notches[i] = r.Alphabet.Char(turnover)
+	}
+	return r.Alphabet.spell(r.straight), string(notches)
+}
+
+// AlphabetEntryWheel is an entry wheel wired for an alphabet (see
+// EntryWheel). A machine without one is wired straight through.
+type AlphabetEntryWheel struct {
+	ID       string
+	Alphabet *Alphabet
+
+	straight, reverse []int
+}
+
+// NewAlphabetEntryWheel is the constructor for entry wheels wired for an
+// alphabet, taking the characters in the order they are wired to its
+// contacts, like NewEntryWheel.
+func NewAlphabetEntryWheel(alphabet *Alphabet, mapping string, id string) (*AlphabetEntryWheel, error) {
+	reverse, err := alphabet.wiring(mapping)
+	if err != nil {
+		return nil, fmt.Errorf(`entry wheel "%s": %w`, id, err)
+	}
+	w := &AlphabetEntryWheel{ID: id, Alphabet: alphabet, straight: make([]int, len(reverse)), reverse: reverse}
+	invert(w.straight, reverse)
+	return w, nil
+}
+
+// step sends the signal through the entry wheel, like EntryWheel.Step.
+func (w *AlphabetEntryWheel) step(letter int, invert bool) int {
+	if invert {
+		return w.reverse[letter]
+	}
+	return w.straight[letter]
+}
+
+// AlphabetReflector is a reflector wired for an alphabet, set to a
+// Position like the settable ones.
+type AlphabetReflector struct {
+	ID       string
+	Alphabet *Alphabet
+	Position int
+
+	sequence []int
+}
+
+// NewAlphabetReflector is the constructor for reflectors wired for an
+// alphabet. The wiring has to pair up the contacts, none with itself,
+// so the alphabet has to have an even number of characters.
+func NewAlphabetReflector(alphabet *Alphabet, mapping string, id string) (*AlphabetReflector, error) {
+	sequence, err := alphabet.wiring(mapping)
+	if err != nil {
+		return nil, fmt.Errorf(`reflector "%s": %w`, id, err)
+	}
+	if i := unpaired(sequence); i >= 0 {
+		return nil, fmt.Errorf(`reflector "%s": "%c" should be wired in pairs, not to "%c"`, id, alphabet.Char(i), alphabet.Char(sequence[i]))
+	}
+	return &AlphabetReflector{ID: id, Alphabet: alphabet, sequence: sequence}, nil
+}
+
+// reflect sends the signal back, like Reflector.Reflect.
+func (r *AlphabetReflector) reflect(letter int) int {
+	return r.Alphabet.mod(r.sequence[r.Alphabet.mod(letter+r.Position)] - r.Position)
+}
+
+// AlphabetMachine is a machine over an alphabet other than the Latin
+// one: the rotors from the left and the reflector, with the plugboard
+// pairs (nil for no cables) and the entry wheel (nil for one wired
+// straight through). The rotors step the way those of Enigma do, with
+// the same mechanisms: the lever one unless Stepping is set, and its
+// double step unless NoDoubleStep is. Only the mechanisms of this
+// package can step them. The filler is the one of the alphabet unless
+// Filler is set.
+type AlphabetMachine struct {
+	Alphabet     *Alphabet
+	Rotors       []*AlphabetRotor
+	Reflector    *AlphabetReflector
+	EntryWheel   *AlphabetEntryWheel
+	Plugboard    []int
+	Stepping     SteppingMechanism
+	NoDoubleStep bool
+	Filler       rune
+
+	start          []int
+	reflectorStart int
+	moves, doubles []bool
+}
+
+// NewAlphabetMachine is the constructor for machines over an alphabet,
+// taking the rotors from the left and the reflector, all wired for the
+// same one. The positions they're at are the ones Reset sets them back
+// to.
+func NewAlphabetMachine(rotors []*AlphabetRotor, reflector *AlphabetReflector) (*AlphabetMachine, error) {
+	if len(rotors) == 0 {
+		return nil, fmt.Errorf("at least one rotor is required")
+	}
+	if reflector == nil {
+		return nil, fmt.Errorf("a reflector is required")
+	}
+	m := &AlphabetMachine{Alphabet: reflector.Alphabet, Rotors: rotors, Reflector: reflector, reflectorStart: reflector.Position}
+	for _, rotor := range rotors {
+		if rotor.Alphabet != reflector.Alphabet {
+			return nil, fmt.Errorf(`rotor "%s" is wired for "%s", and reflector "%s" for "%s"`, rotor.ID, rotor.Alphabet, reflector.ID, reflector.Alphabet)
+		}
+		m.start = append(m.start, rotor.Offset)
+	}
+	return m, nil
+}
+
+// SetPlugboard plugs the cables in, replacing the ones there were, from
+// pairs of characters of the alphabet like those of Enigma ("AB CD").
+func (m *AlphabetMachine) SetPlugboard(pairs ...string) error {
+	plugboard, err := m.Alphabet.plugboard(pairs)
+	if err != nil {
+		return err
+	}
+	m.Plugboard = plugboard
+	return nil
+}
+
+// EncodeRune encodes a single character, moving the rotors. It's an
+// error if it isn't in the alphabet, or the rotors can't be stepped
+// (see AlphabetMachine), and the rotors don't move then.
+func (m *AlphabetMachine) EncodeRune(r rune) (rune, error) {
+	index, ok := m.Alphabet.Index(r)
+	if !ok {
+		return r, fmt.Errorf(`"%c" isn't in the alphabet "%s"`, r, m.Alphabet)
+	}
+	if err := m.moveRotors(); err != nil {
+		return r, err
+	}
+	if m.Plugboard != nil {
+		index = m.Plugboard[index]
+	}
+	if m.EntryWheel != nil {
+		index = m.EntryWheel.step(index, false)
+	}
+	for i := len(m.Rotors) - 1; i >= 0; i-- {
+		index = m.Rotors[i].step(index, false)
+	}
+	index = m.Reflector.reflect(index)
+	for _, rotor := range m.Rotors {
+		index = rotor.step(index, true)
+	}
+	if m.EntryWheel != nil {
+		index = m.EntryWheel.step(index, true)
+	}
+	if m.Plugboard != nil {
+		index = m.Plugboard[index]
+	}
+	return m.Alphabet.Char(index), nil
+}
+
+// EncodeText encodes the text like Enigma.EncodeText, with the
+// characters of the alphabet for the letters: lowercase ones are taken
+// as capitals if the alphabet has those, and the rest are dealt with
+// according to the policy. NonAlphaStrip strips them, NonAlphaReject
+// turns down the first one, NonAlphaPreserve passes them through
+// without moving the rotors, NonAlphaSubstitute encodes the filler of
+// the machine in their place, and NonAlphaSpaceToX does that for the
+// spaces between words, stripping the rest. A text with nothing left
+// after sanitizing is ErrEmptyAfterSanitize, and a filler set outside
+// the alphabet is an error too. The rotors don't move if it's an error.
+func (m *AlphabetMachine) EncodeText(text string, policy NonAlphaPolicy) (string, error) {
+	if err := m.checkFiller(); err != nil {
+		return "", err
+	}
+	clean, err := m.Alphabet.sanitize(text, policy, m.filler())
+	if err != nil {
+		return "", err
+	}
+	if len(clean) == 0 && text != "" {
+		return "", ErrEmptyAfterSanitize
+	}
+	var b strings.Builder
+	for i, r := range clean {
+		if _, ok := m.Alphabet.Index(r); !ok {
+			b.WriteRune(r)
+			continue
+		}
+		lamp, err := m.EncodeRune(r)
+		if err != nil {
+			return b.String(), fmt.Errorf("at %d: %w", i, err)
+		}
+		b.WriteRune(lamp)
+	}
+	return b.String(), nil
+}
+
+// checkFiller tells if the filler set on the machine, if any, is in
+// its alphabet.
+func (m *AlphabetMachine) checkFiller() error {
+	if _, ok := m.Alphabet.Index(m.Filler); m.Filler != 0 && !ok {
+		return fmt.Errorf(`filler "%c" isn't in the alphabet "%s"`, m.Filler, m.Alphabet)
+	}
+	return nil
+}
+
+// filler returns the character encoded in place of the ones the
+// alphabet hasn't got.
+func (m *AlphabetMachine) filler() rune {
+	if m.Filler != 0 {
+		return m.Filler
+	}
+	return m.Alphabet.Filler()
+}
+
+// sanitize prepares a text to be encoded on a machine over the alphabet
+// (see AlphabetMachine.EncodeText), with the filler of the machine.
+// Whatever it returns is in the alphabet, but for the characters
+// NonAlphaPreserve keeps.
+func (a *Alphabet) sanitize(text string, policy NonAlphaPolicy, filler rune) ([]rune, error) {
+	if policy < NonAlphaSpaceToX || policy > NonAlphaSubstitute {
+		return nil, fmt.Errorf("unknown policy %d", policy)
+	}
+	if policy == NonAlphaSpaceToX {
+		text = strings.TrimSpace(text)
+	}
+	var clean []rune
+	for i, r := range []rune(text) {
+		if _, ok := a.Index(r); !ok {
+			if upper := unicode.ToUpper(r); upper != r {
+				if _, ok := a.Index(upper); ok {
+					r = upper
+				}
+			}
+		}
+		if _, ok := a.Index(r); ok {
+			clean = append(clean, r)
+			continue
+		}
+		switch policy {
+		case NonAlphaReject:
+			return nil, fmt.Errorf(`at %d: "%c" isn't in the alphabet "%s"`, i, r, a)
+		case NonAlphaPreserve:
+			clean = append(clean, r)
+		case NonAlphaSubstitute:
+			clean = append(clean, filler)
+		case NonAlphaSpaceToX:
+			if r == ' ' {
+				clean = append(clean, filler)
+			}
+		}
+	}
+	return clean, nil
+}
+
+// EncodeString encodes the text, which has to be in the alphabet
+// throughout.
+func (m *AlphabetMachine) EncodeString(text string) (string, error) {
+	var b strings.Builder
+	for i, r := range []rune(text) {
+		lamp, err := m.EncodeRune(r)
+		if err != nil {
+			return b.String(), fmt.Errorf("at %d: %w", i, err)
+		}
+		b.WriteRune(lamp)
+	}
+	return b.String(), nil
+}
+
+// Positions returns the characters in the rotor windows, from the left.
+func (m *AlphabetMachine) Positions() string {
+	positions := make([]rune, len(m.Rotors))
+	for i, rotor := range m.Rotors {
+		positions[i] = m.Alphabet.Char(rotor.Offset)
+	}
+	return string(positions)
+}
+
+// Reset sets the rotors and the reflector back to the positions the
+// machine was built with.
+func (m *AlphabetMachine) Reset() {
+	for i, rotor := range m.Rotors {
+		if i < len(m.start) {
+			rotor.Offset = m.start[i]
+		}
+	}
+	m.Reflector.Position = m.reflectorStart
+}
+
+// The machine is a train of rotors for the stepping mechanisms.
+func (m *AlphabetMachine) slots() int            { return len(m.Rotors) }
+func (m *AlphabetMachine) fixed(slot int) bool   { return m.Rotors[slot].Fixed }
+func (m *AlphabetMachine) drivenBy(slot int) int { return m.Rotors[slot].DrivenBy }
+func (m *AlphabetMachine) atNotch(slot int) bool { return m.Rotors[slot].atNotch() }
+func (m *AlphabetMachine) doubleSteps() bool     { return !m.NoDoubleStep }
+
+// stepping returns the mechanism of the machine.
+func (m *AlphabetMachine) stepping() SteppingMechanism {
+	if m.Stepping == nil {
+		return LeverStepping
+	}
+	return m.Stepping
+}
+
+// moveRotors steps the rotors with the mechanism of the machine, the
+// way Enigma.moveRotors does, reusing the slices of the machine.
+func (m *AlphabetMachine) moveRotors() error {
+	filler, ok := m.stepping().(stepFiller)
+	if !ok {
+		return fmt.Errorf(`stepping mechanism "%s" only steps the Latin machines`, m.stepping().Name())
+	}
+	n := len(m.Rotors)
+	if cap(m.moves) < n {
+		m.moves, m.doubles = make([]bool, n), make([]bool, n)
+	}
+	moves, doubles := m.moves[:n], m.doubles[:n]
+	for i := range moves {
+		moves[i], doubles[i] = false, false
+	}
+	filler.fill(m, moves, doubles)
+	for i, rotor := range m.Rotors {
+		if moves[i] {
+			rotor.Offset = m.Alphabet.mod(rotor.Offset + 1)
+		}
+	}
+	return nil
+}
+
+// Validate checks the machine as it is now, like Enigma.Validate: every
+// part is wired for the alphabet of the machine, the wirings are still
+// permutations, the reflector and the plugboard swap characters in
+// pairs, the rotors are driven by slots there are, and the rotors can
+// be stepped. Every problem found is reported.
+func (m *AlphabetMachine) Validate() error {
+	var errs []error
+	if m.Alphabet == nil {
+		return fmt.Errorf("an alphabet is required")
+	}
+	n := m.Alphabet.Size()
+	if len(m.Rotors) == 0 {
+		errs = append(errs, fmt.Errorf("at least one rotor is required"))
+	}
+	for i, rotor := range m.Rotors {
+		switch {
+		case rotor == nil:
+			errs = append(errs, fmt.Errorf("slot %d: no rotor", i+1))
+			continue
+		case rotor.Alphabet != m.Alphabet || !permutes(rotor.straight, n) || !inverts(rotor.reverse, rotor.straight):
+			errs = append(errs, fmt.Errorf(`slot %d: wiring of rotor "%s" is not a permutation of "%s"`, i+1, rotor.ID, m.Alphabet))
+		}
+		for _, turnover := range rotor.turnover {
+			if turnover < 0 || turnover >= n {
+				errs = append(errs, fmt.Errorf(`slot %d: notch of rotor "%s" out of range: %d`, i+1, rotor.ID, turnover))
+			}
+		}
+		if drivenBy := rotor.DrivenBy; drivenBy < 0 || drivenBy > len(m.Rotors) || drivenBy == i+1 {
+			errs = append(errs, fmt.Errorf("slot %d cannot be driven by slot %d", i+1, drivenBy))
+		}
+	}
+	switch r := m.Reflector; {
+	case r == nil:
+		errs = append(errs, fmt.Errorf("a reflector is required"))
+	case r.Alphabet != m.Alphabet || !permutes(r.sequence, n):
+		errs = append(errs, fmt.Errorf(`reflector "%s": wiring is not a permutation of "%s"`, r.ID, m.Alphabet))
+	case unpaired(r.sequence) >= 0:
+		errs = append(errs, fmt.Errorf(`reflector "%s": characters are not swapped in pairs`, r.ID))
+	}
+	switch {
+	case m.Plugboard == nil:
+	case !permutes(m.Plugboard, n):
+		errs = append(errs, fmt.Errorf("plugboard: not a permutation"))
+	case !inverts(m.Plugboard, m.Plugboard):
+		err := settingError(ErrPlugConflict, "plugboard: letters are not plugged in pairs")
+		err.ID = "plugboard"
+		errs = append(errs, err)
+	}
+	if w := m.EntryWheel; w != nil && (w.Alphabet != m.Alphabet || !permutes(w.straight, n) || !inverts(w.reverse, w.straight)) {
+		errs = append(errs, fmt.Errorf(`entry wheel "%s": wiring is not a permutation of "%s"`, w.ID, m.Alphabet))
+	}
+	if _, ok := m.stepping().(stepFiller); !ok {
+		errs = append(errs, fmt.Errorf(`stepping mechanism "%s" only steps the Latin machines`, m.stepping().Name()))
+	}
+	if err := m.checkFiller(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// AlphabetConfig is the complete configuration of a machine over an
+// alphabet, like Config: the characters of the alphabet, the rotors with
+// their wirings, rings and starting positions, the reflector, the entry
+// wheel (none if not set), the plugboard, the stepping, and the filler
+// of the alphabet (the one it starts with, if not set). There are no
+// lists of rotors for other alphabets, so the wirings always come with
+// it.
+type AlphabetConfig struct {
+	Alphabet     string                    `json:"alphabet"`
+	Filler       string                    `json:"filler,omitempty"`
+	Rotors       []AlphabetRotorConfig     `json:"rotors"`
+	Reflector    AlphabetReflectorConfig   `json:"reflector"`
+	EntryWheel   *AlphabetEntryWheelConfig `json:"entryWheel,omitempty"`
+	Plugboard    []string                  `json:"plugboard,omitempty"`
+	Stepping     string                    `json:"stepping,omitempty"`
+	NoDoubleStep bool                      `json:"noDoubleStep,omitempty"`
+}
+
+// AlphabetRotorConfig is a rotor of an AlphabetConfig: the wiring and the
+// notches in the characters of the alphabet, the starting position as
+// one of them, and the ring counting from 1, like RotorConfig.
+type AlphabetRotorConfig struct {
+	ID       string `json:"id"`
+	Wiring   string `json:"wiring"`
+	Notches  string `json:"notches,omitempty"`
+	Start    string `json:"start"`
+	Ring     int    `json:"ring"`
+	Fixed    bool   `json:"fixed,omitempty"`
+	DrivenBy int    `json:"drivenBy,omitempty"`
+}
+
+// AlphabetReflectorConfig is the reflector of an AlphabetConfig, with its
+// position, the first character if not set.
+type AlphabetReflectorConfig struct {
+	ID     string `json:"id"`
+	Wiring string `json:"wiring"`
+	Start  string `json:"start,omitempty"`
+}
+
+// AlphabetEntryWheelConfig is the entry wheel of an AlphabetConfig.
+type AlphabetEntryWheelConfig struct {
+	ID     string `json:"id"`
+	Wiring string `json:"wiring"`
+}
+
+// New builds the machine of the configuration, checked with Validate.
+func (c AlphabetConfig) New() (*AlphabetMachine, error) {
+	alphabet, err := NewAlphabet(c.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	filler := []rune(c.Filler)
+	if len(filler) > 1 {
+		return nil, fmt.Errorf(`filler should be a single character, got "%s"`, c.Filler)
+	}
+	rotors := make([]*AlphabetRotor, len(c.Rotors))
+	for i, rc := range c.Rotors {
+		rotor, err := NewAlphabetRotor(alphabet, rc.Wiring, rc.ID, rc.Notches)
+		if err != nil {
+			return nil, err
+		}
+		if rotor.Offset, err = alphabet.position(rc.Start); err != nil {
+			return nil, fmt.Errorf(`rotor "%s": %w`, rc.ID, err)
+		}
+		if rc.Ring < 1 || rc.Ring > alphabet.Size() {
+			return nil, fmt.Errorf(`rotor "%s": ring should be from 1 to %d, got %d`, rc.ID, alphabet.Size(), rc.Ring)
+		}
+		rotor.Ring = rc.Ring - 1
+		rotor.Fixed, rotor.DrivenBy = rc.Fixed, rc.DrivenBy
+		rotors[i] = rotor
+	}
+	reflector, err := NewAlphabetReflector(alphabet, c.Reflector.Wiring, c.Reflector.ID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Reflector.Start != "" {
+		if reflector.Position, err = alphabet.position(c.Reflector.Start); err != nil {
+			return nil, fmt.Errorf(`reflector "%s": %w`, c.Reflector.ID, err)
+		}
+	}
+	m, err := NewAlphabetMachine(rotors, reflector)
+	if err != nil {
+		return nil, err
+	}
+	if c.EntryWheel != nil {
+		if m.EntryWheel, err = NewAlphabetEntryWheel(alphabet, c.EntryWheel.Wiring, c.EntryWheel.ID); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.Plugboard) > 0 {
+		if err := m.SetPlugboard(c.Plugboard...); err != nil {
+			return nil, err
+		}
+	}
+	if c.Stepping != "" {
+		if m.Stepping = SteppingMechanisms[c.Stepping]; m.Stepping == nil {
+			return nil, fmt.Errorf(`unknown stepping mechanism "%s"`, c.Stepping)
+		}
+	}
+	m.NoDoubleStep = c.NoDoubleStep
+	if len(filler) == 1 {
+		m.Filler = filler[0]
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// position reads a starting position, a single character of the
+// alphabet.
+func (a *Alphabet) position(start string) (int, error) {
+	letters := []rune(start)
+	if len(letters) == 1 {
+		if index, ok := a.Index(letters[0]); ok {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf(`position should be a character of "%s", got "%s"`, a, start)
+}
+
+// Config returns the current configuration of the machine, like
+// Enigma.Config: the starting positions are the ones the rotors are at
+// now, so a machine built from it continues where this one is.
+func (m *AlphabetMachine) Config() AlphabetConfig {
+	config := AlphabetConfig{
+		Alphabet:     m.Alphabet.String(),
+		Rotors:       make([]AlphabetRotorConfig, len(m.Rotors)),
+		Reflector:    AlphabetReflectorConfig{ID: m.Reflector.ID, Wiring: m.Alphabet.spell(m.Reflector.sequence)},
+		NoDoubleStep: m.NoDoubleStep,
+	}
+	for i, rotor := range m.Rotors {
+		wiring, notches := rotor.wiring()
+		config.Rotors[i] = AlphabetRotorConfig{
+			ID:       rotor.ID,
+			Wiring:   wiring,
+			Notches:  notches,
+			Start:    string(m.Alphabet.Char(rotor.Offset)),
+			Ring:     m.Alphabet.mod(rotor.Ring) + 1,
+			Fixed:    rotor.Fixed,
+			DrivenBy: rotor.DrivenBy,
+		}
+	}
+	if filler := m.filler(); filler != m.Alphabet.Filler() {
+		config.Filler = string(filler)
+	}
+	if m.Alphabet.mod(m.Reflector.Position) != 0 {
+		config.Reflector.Start = string(m.Alphabet.Char(m.Reflector.Position))
+	}
+	if m.EntryWheel != nil {
+		config.EntryWheel = &AlphabetEntryWheelConfig{ID: m.EntryWheel.ID, Wiring: m.Alphabet.spell(m.EntryWheel.reverse)}
+	}
+	if m.Plugboard != nil {
+		config.Plugboard = m.Alphabet.pairs(m.Plugboard)
+	}
+	if name := m.stepping().Name(); name != LeverStepping.Name() {
+		config.Stepping = name
+	}
+	return config
+}
@@ -0,0 +1,387 @@
+package enigma
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// latinConfig is the machine over Latin wired like the Enigma.
+func latinConfig(e *Enigma) AlphabetConfig {
+	config := AlphabetConfig{
+		Alphabet:     Latin.String(),
+		Rotors:       make([]AlphabetRotorConfig, len(e.Rotors)),
+		Reflector:    AlphabetReflectorConfig{ID: e.Reflector.ID, Wiring: letters(e.Reflector.Sequence)},
+		EntryWheel:   &AlphabetEntryWheelConfig{ID: e.EntryWheel.ID, Wiring: letters(e.EntryWheel.ReverseSeq)},
+		Plugboard:    e.Plugboard.Pairs(),
+		Stepping:     e.stepping().Name(),
+		NoDoubleStep: e.NoDoubleStep,
+	}
+	for i, rotor := range e.Rotors {
+		wiring, notches := rotor.wiring()
+		config.Rotors[i] = AlphabetRotorConfig{
+			ID:       rotor.ID,
+			Wiring:   wiring,
+			Notches:  notches,
+			Start:    string(IndexToChar(mod26(rotor.Offset))),
+			Ring:     mod26(rotor.Ring) + 1,
+			Fixed:    rotor.Fixed,
+			DrivenBy: rotor.DrivenBy,
+		}
+	}
+	if e.Reflector.Position != 0 {
+		config.Reflector.Start = string(IndexToChar(mod26(e.Reflector.Position)))
+	}
+	return config
+}
+
+// Wired for Latin, the machine over an alphabet is the Enigma: the same
+// plugboard, entry wheel and stepping, double steps and fixed rotors
+// included.
+func TestAlphabetMachineIsEnigma(t *testing.T) {
+	plaintext := strings.Repeat("ANGRIFFIMMORGENGRAUENXWETTERBERICHTX", 30)
+	models := []*Model{&EnigmaI, &M4, &EnigmaT, &EnigmaK}
+	for _, model := range models {
+		for seed := int64(0); seed < 5; seed++ {
+			settings, err := GenerateRandomConfig(model, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := settings.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := latinConfig(e).New()
+			if err != nil {
+				t.Fatalf("%s: %v", settings, err)
+			}
+			got, err := m.EncodeString(plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := e.EncodeString(plaintext); got != want {
+				t.Errorf("%s: encodes to %s, expected %s", settings, got, want)
+			}
+			if m.Positions() != e.Positions() {
+				t.Errorf("%s: leaves the rotors at %s, expected %s", settings, m.Positions(), e.Positions())
+			}
+		}
+	}
+}
+
+// The stepping that never existed steps the same too.
+func TestAlphabetMachineStepping(t *testing.T) {
+	plaintext := strings.Repeat("WETTERBERICHT", 60)
+	tests := []struct {
+		name   string
+		config func(c *Config)
+	}{
+		{"gear", func(c *Config) { c.Stepping = "gear" }},
+		{"no double step", func(c *Config) { c.NoDoubleStep = true }},
+		{"driven by hand", func(c *Config) { c.Rotors[0].DrivenBy = 3 }},
+		{"fixed", func(c *Config) { c.Rotors[1].Fixed = true }},
+	}
+	for _, tt := range tests {
+		config := classicConfig()
+		config.Rotors[1].Start = 'D'
+		config.AllowNonHistorical = true
+		tt.config(&config)
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := latinConfig(e).New()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := m.EncodeString(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := e.EncodeString(plaintext); got != want {
+			t.Errorf("%s: encodes to %s, expected %s", tt.name, got, want)
+		}
+	}
+}
+
+// digitsConfig is a three-rotor machine over the digits, with two
+// cables and an entry wheel.
+func digitsConfig() AlphabetConfig {
+	return AlphabetConfig{
+		Alphabet: Digits.String(),
+		Rotors: []AlphabetRotorConfig{
+			{ID: "I", Wiring: "6418270359", Notches: "9", Start: "0", Ring: 1},
+			{ID: "II", Wiring: "5182740369", Notches: "9", Start: "8", Ring: 3},
+			{ID: "III", Wiring: "3810592764", Notches: "4", Start: "3", Ring: 1},
+		},
+		Reflector:  AlphabetReflectorConfig{ID: "UKW", Wiring: "5932807641"},
+		EntryWheel: &AlphabetEntryWheelConfig{ID: "ETW", Wiring: "1234567890"},
+		Plugboard:  []string{"01", "57"},
+	}
+}
+
+func TestAlphabetMachineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    func() AlphabetConfig
+		plaintext string
+	}{
+		{"digits", digitsConfig, strings.Repeat("0123456789", 30)},
+		{"alphanumeric", func() AlphabetConfig {
+			return AlphabetConfig{
+				Alphabet: Alphanumeric.String(),
+				Rotors: []AlphabetRotorConfig{
+					{ID: "A", Wiring: "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ", Notches: "Q", Start: "7", Ring: 5},
+					{ID: "B", Wiring: "QWERTYUIOPASDFGHJKLZXCVBNM1234567890", Notches: "E", Start: "A", Ring: 1},
+				},
+				Reflector: AlphabetReflectorConfig{ID: "R", Wiring: "BADCFEHGJILKNMPORQTSVUXWZY1032547698"},
+				Plugboard: []string{"A9", "Z0"},
+			}
+		}, strings.Repeat("ATTACK0600HOURS", 20)},
+	}
+	for _, tt := range tests {
+		m, err := tt.config().New()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		ciphertext, err := m.EncodeString(tt.plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range ciphertext {
+			if i < len(tt.plaintext) && r == rune(tt.plaintext[i]) {
+				t.Fatalf("%s: %c encodes to itself at %d", tt.name, r, i)
+			}
+		}
+		m.Reset()
+		if got, err := m.EncodeString(ciphertext); err != nil || got != tt.plaintext {
+			t.Errorf("%s: decodes to %s (%v), expected %s", tt.name, got, err, tt.plaintext)
+		}
+	}
+}
+
+// Reset sets the rotors back to the positions the machine was built
+// with, and the saved configuration continues where the machine is,
+// through JSON too.
+func TestAlphabetMachineConfig(t *testing.T) {
+	m, err := digitsConfig().New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.EncodeString("31415926535897932384"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(m.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config AlphabetConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := config.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.Positions() != m.Positions() {
+		t.Errorf("the rebuilt machine is at %s, expected %s", rebuilt.Positions(), m.Positions())
+	}
+	want, _ := m.EncodeString("27182818284590452353")
+	if got, _ := rebuilt.EncodeString("27182818284590452353"); got != want {
+		t.Errorf("the rebuilt machine encodes to %s, expected %s", got, want)
+	}
+	m.Reset()
+	if got, want := m.Positions(), "083"; got != want {
+		t.Errorf("Reset leaves the rotors at %s, expected %s", got, want)
+	}
+}
+
+// Validate finds what was changed by hand, and the configurations that
+// don't build are turned down with the errors of the Latin machines.
+func TestAlphabetMachineValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *AlphabetMachine)
+		want   string
+	}{
+		{"rotor wiring", func(m *AlphabetMachine) { m.Rotors[1].straight[0] = m.Rotors[1].straight[1] }, `slot 2: wiring of rotor "II" is not a permutation of "0123456789"`},
+		{"reflector", func(m *AlphabetMachine) {
+			m.Reflector.sequence[0], m.Reflector.sequence[1] = m.Reflector.sequence[1], m.Reflector.sequence[0]
+		}, `reflector "UKW": characters are not swapped in pairs`},
+		{"rotor way back", func(m *AlphabetMachine) {
+			m.Rotors[0].reverse[0], m.Rotors[0].reverse[1] = m.Rotors[0].reverse[1], m.Rotors[0].reverse[0]
+		}, `slot 1: wiring of rotor "I" is not a permutation of "0123456789"`},
+		{"entry wheel", func(m *AlphabetMachine) { m.EntryWheel.straight[2] = 0 }, `entry wheel "ETW": wiring is not a permutation of "0123456789"`},
+		{"plugboard", func(m *AlphabetMachine) { m.Plugboard[0] = 3 }, "plugboard: not a permutation"},
+		{"driven by", func(m *AlphabetMachine) { m.Rotors[0].DrivenBy = 1 }, "slot 1 cannot be driven by slot 1"},
+		{"stepping", func(m *AlphabetMachine) { m.Stepping = foreignStepping{} }, `stepping mechanism "foreign" only steps the Latin machines`},
+	}
+	for _, tt := range tests {
+		m, err := digitsConfig().New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatalf("%s: the machine as built doesn't validate: %v", tt.name, err)
+		}
+		tt.change(m)
+		if err := m.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate returns %v, expected %s", tt.name, err, tt.want)
+		}
+	}
+	m, _ := digitsConfig().New()
+	if err := m.SetPlugboard("01", "12"); !errors.Is(err, ErrPlugConflict) {
+		t.Errorf("plugging 1 twice returns %v, expected ErrPlugConflict", err)
+	}
+	if _, err := m.EncodeRune('A'); err == nil {
+		t.Error("A is encoded over the digits")
+	}
+	for mapping, want := range map[string]string{
+		"1234567890": `reflector "X": "0" should be wired in pairs, not to "1"`,
+		"1032547689": `reflector "X": "8" should be wired in pairs, not to "8"`,
+	} {
+		if _, err := NewAlphabetReflector(Digits, mapping, "X"); err == nil || err.Error() != want {
+			t.Errorf("reflector %s is refused with %v, expected %s", mapping, err, want)
+		}
+	}
+}
+
+// foreignStepping is a mechanism from outside the package.
+type foreignStepping struct{}
+
+func (foreignStepping) Name() string { return "foreign" }
+
+func (foreignStepping) Next(e *Enigma) (moves, doubles []bool) {
+	return LeverStepping.Next(e)
+}
+
+// toyLetters are the letters of a toy machine, without the J and the Q.
+// A reflector pairs up the contacts, so an alphabet without just one of
+// them couldn't be reflected.
+const toyLetters = "ABCDEFGHIKLMNOPRSTUVWXYZ"
+
+// toyConfig is a three-rotor machine over the toy letters, wired at
+// random.
+func toyConfig() AlphabetConfig {
+	rng := rand.New(rand.NewSource(153))
+	shuffled := func() []rune {
+		letters := []rune(toyLetters)
+		rng.Shuffle(len(letters), func(i, j int) { letters[i], letters[j] = letters[j], letters[i] })
+		return letters
+	}
+	config := AlphabetConfig{Alphabet: toyLetters, Plugboard: []string{"AZ", "KV"}}
+	for _, id := range []string{"I", "II", "III"} {
+		config.Rotors = append(config.Rotors, AlphabetRotorConfig{ID: id, Wiring: string(shuffled()), Notches: "R", Start: "A", Ring: 1})
+	}
+	pairs, reflector := shuffled(), make([]rune, len(toyLetters))
+	for i := 0; i < len(pairs); i += 2 {
+		a, b := strings.IndexRune(toyLetters, pairs[i]), strings.IndexRune(toyLetters, pairs[i+1])
+		reflector[a], reflector[b] = pairs[i+1], pairs[i]
+	}
+	config.Reflector = AlphabetReflectorConfig{ID: "UKW", Wiring: string(reflector)}
+	return config
+}
+
+// The policies deal with the letters the toy machine hasn't got, a J
+// and a q among them, like with the rest that aren't letters: Strip
+// strips them, Reject names the alphabet, Preserve passes them through
+// without stepping, and Substitute encodes the filler in their place.
+func TestAlphabetPolicies(t *testing.T) {
+	text := "die Q-Boote um JENA"
+	tests := []struct {
+		policy NonAlphaPolicy
+		clean  string
+	}{
+		{NonAlphaStrip, "DIEBOOTEUMENA"},
+		{NonAlphaSubstitute, "DIEXXXBOOTEXUMXXENA"},
+		{NonAlphaSpaceToX, "DIEXBOOTEXUMXENA"},
+	}
+	for _, tt := range tests {
+		m, err := toyConfig().New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.EncodeText(text, tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fresh, _ := toyConfig().New()
+		if want, _ := fresh.EncodeString(tt.clean); got != want {
+			t.Errorf("policy %d encodes %q to %s, expected %s as for %s", tt.policy, text, got, want, tt.clean)
+		}
+	}
+
+	m, _ := toyConfig().New()
+	if _, err := m.EncodeText("DIEQBOOTE", NonAlphaReject); err == nil || err.Error() != `at 3: "Q" isn't in the alphabet "`+toyLetters+`"` {
+		t.Errorf("the Q is rejected with %v", err)
+	}
+	if m.Positions() != "AAA" {
+		t.Errorf("rejecting the Q moves the rotors to %s", m.Positions())
+	}
+
+	got, err := m.EncodeText("die q-Boote um JENA", NonAlphaPreserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := toyConfig().New()
+	letters, _ := fresh.EncodeString("DIEBOOTEUMENA")
+	want := letters[:3] + " q-" + letters[3:8] + " " + letters[8:10] + " J" + letters[10:]
+	if got != want || m.Positions() != fresh.Positions() {
+		t.Errorf("the q and the J are preserved as %s at %s, expected %s at %s", got, m.Positions(), want, fresh.Positions())
+	}
+
+	if _, err := m.EncodeText(" J- ", NonAlphaStrip); err != ErrEmptyAfterSanitize {
+		t.Errorf("nothing to encode is %v, expected ErrEmptyAfterSanitize", err)
+	}
+	if _, _, err := Sanitize("Q-BOOTE", NonAlphaPreserve); err == nil {
+		t.Error("the Latin machines take NonAlphaPreserve")
+	}
+	if _, err := m.EncodeText("DIEBOOTE", NonAlphaPolicy(42)); err == nil || err.Error() != "unknown policy 42" {
+		t.Errorf("policy 42 is refused with %v", err)
+	}
+}
+
+// The filler is the X unless the machine sets it, and it has to be in
+// the alphabet, also when it comes with the configuration, which keeps
+// it.
+func TestAlphabetFiller(t *testing.T) {
+	if Latin.Filler() != 'X' || Digits.Filler() != '0' {
+		t.Errorf("the fillers are %c and %c, expected X and 0", Latin.Filler(), Digits.Filler())
+	}
+	config := toyConfig()
+	config.Filler = "Z"
+	m, err := config.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.EncodeText("UM J", NonAlphaSubstitute)
+	fresh, _ := toyConfig().New()
+	if want, _ := fresh.EncodeString("UMZZ"); got != want {
+		t.Errorf("the J is substituted to %s, expected %s", got, want)
+	}
+	if m.Config().Filler != "Z" || fresh.Config().Filler != "" {
+		t.Errorf("the fillers are saved as %q and %q", m.Config().Filler, fresh.Config().Filler)
+	}
+	for _, filler := range []string{"J", "ZZ"} {
+		config.Filler = filler
+		if _, err := config.New(); err == nil {
+			t.Errorf("the filler %s is taken", filler)
+		}
+	}
+	if m.Alphabet.Filler() != 'X' {
+		t.Errorf("the filler Z of the machine is set on its alphabet, %c", m.Alphabet.Filler())
+	}
+	m.Filler = 'Q'
+	if err := m.Validate(); err == nil {
+		t.Error("the filler Q is taken")
+	}
+	positions := m.Positions()
+	if got, err := m.EncodeText("UM J", NonAlphaSubstitute); err == nil {
+		t.Errorf("the J is substituted by the filler Q to %s", got)
+	}
+	if m.Positions() != positions {
+		t.Errorf("the rotors moved to %s encoding with the filler Q, expected %s", m.Positions(), positions)
+	}
+}
@@ -0,0 +1,108 @@
+package enigma
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ArchiveHeader is the header of an intercept as transcriptions have
+// it, on two lines: the frequency, the time of the message, and the
+// number of letters, then the two indicator groups, the ground setting
+// and the encrypted message key:
+//
+//	3580kHz 1840 179
+//	WXC KCH
+//
+// Warnings tell what couldn't be read.
+type ArchiveHeader struct {
+	Frequency string
+	Time      string
+	Count     int
+	Ground    string
+	Key       string
+	Warnings  []string
+}
+
+// ParseArchiveHeader reads the header from its two lines. The fields
+// of the first line are told apart by their shape, so any of them can
+// be missing, and a field that can't be read, e.g. a damaged indicator,
+// is left empty with a warning instead of failing the whole header.
+// Only a header without the lines is an error.
+func ParseArchiveHeader(lines []string) (ArchiveHeader, error) {
+	var h ArchiveHeader
+	if len(lines) < 2 {
+		return h, fmt.Errorf("archive header should have 2 lines, got %d", len(lines))
+	}
+	for _, field := range strings.Fields(lines[0]) {
+		lower := strings.ToLower(field)
+		number, err := strconv.Atoi(field)
+		switch {
+		case strings.HasSuffix(lower, "khz") || strings.HasSuffix(lower, "kc"):
+			h.Frequency = field
+		case err == nil && len(field) == 4 && h.Time == "" && number%100 < 60 && number/100 < 24:
+			h.Time = field
+		case err == nil && h.Count == 0:
+			h.Count = number
+		default:
+			h.Warnings = append(h.Warnings, fmt.Sprintf(`unknown header field "%s"`, field))
+		}
+	}
+	if h.Count == 0 {
+		h.Warnings = append(h.Warnings, "no letter count in the header")
+	}
+	indicator := strings.Fields(strings.ToUpper(lines[1]))
+	for i, group := range indicator {
+		if i > 1 {
+			h.Warnings = append(h.Warnings, fmt.Sprintf(`unknown indicator group "%s"`, group))
+			continue
+		}
+		if _, err := ParsePositions(group); err != nil || strings.IndexAny(group, "0123456789") >= 0 {
+			h.Warnings = append(h.Warnings, fmt.Sprintf(`damaged indicator group "%s"`, group))
+			continue
+		}
+		if i == 0 {
+			h.Ground = group
+		} else {
+			h.Key = group
+		}
+	}
+	if len(indicator) < 2 {
+		h.Warnings = append(h.Warnings, "the indicator should have 2 groups")
+	}
+	return h, nil
+}
+
+// Render writes the header back on its two lines, leaving out what's
+// missing.
+func (h ArchiveHeader) Render() string {
+	var first []string
+	if h.Frequency != "" {
+		first = append(first, h.Frequency)
+	}
+	if h.Time != "" {
+		first = append(first, h.Time)
+	}
+	if h.Count != 0 {
+		first = append(first, strconv.Itoa(h.Count))
+	}
+	return strings.Join(first, " ") + "\n" + strings.TrimSpace(h.Ground+" "+h.Key)
+}
+
+// Preamble returns the header as the preamble of a part of a
+// transmission (see BuildTransmission), e.g. "1840 = 179 = WXC KCH =",
+// so that the intercept can go to ReceiveMessage.
+func (h ArchiveHeader) Preamble() string {
+	var fields []string
+	if h.Time != "" {
+		fields = append(fields, h.Time)
+	}
+	fields = append(fields, strconv.Itoa(h.Count), h.Ground+" "+h.Key)
+	return strings.Join(fields, " = ") + " ="
+}
+
+// Message returns the intercept with the ciphertext as a message for
+// DecryptBatch.
+func (h ArchiveHeader) Message(ciphertext string) ReceivedMessage {
+	return ReceivedMessage{Indicator: h.Ground + " " + h.Key, Ciphertext: ciphertext}
+}
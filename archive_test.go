@@ -0,0 +1,91 @@
+package enigma_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// barbarossaHeader is the header of the first part of the Barbarossa
+// message, transcribed from its published preamble, "1840 = 2TLE = 1TL
+// = 179 = WXC KCH =": sent at 18:40, part 1 of 2, of 179 letters, at
+// the ground setting WXC with the message key enciphered as KCH.
+var barbarossaHeader = []string{"1840 2TLE 1TL 179", "WXC KCH"}
+
+// The archived header of the Barbarossa message goes straight to
+// DecryptBatch, and its indicator gives the message key of the
+// published decrypt, BLA. The part numbers aren't fields of the
+// archives, so they come with a warning each.
+func TestArchiveHeaderBarbarossa(t *testing.T) {
+	h, err := enigma.ParseArchiveHeader(barbarossaHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Time != "1840" || h.Count != 179 || h.Ground != "WXC" || h.Key != "KCH" || len(h.Warnings) != 2 {
+		t.Errorf("the header is read as %+v", h)
+	}
+	vector := testvectors.Barbarossa
+	// The count takes in the Kenngruppe, the group before the text,
+	// which the vector leaves out.
+	if letters := len(strings.Replace(vector.Ciphertext, " ", "", -1)); letters+5 != h.Count {
+		t.Errorf("the ciphertext has %d letters after the Kenngruppe, the header says %d", letters, h.Count)
+	}
+	e, err := enigma.Generic.New(vector.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.ResetTo(h.Ground)
+	if key := e.EncodeString(h.Key); key != "BLA" {
+		t.Errorf("the indicator gives the message key %s, expected BLA", key)
+	}
+	results, err := enigma.DecryptBatch(context.Background(), enigma.DailyKey{Config: vector.Config}, []enigma.ReceivedMessage{h.Message(vector.Ciphertext)}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[0].Plaintext != vector.Plaintext {
+		t.Errorf("the archived message decrypts to %s (%v)", results[0].Plaintext, results[0].Err)
+	}
+	if got, want := h.Preamble(), "1840 = 179 = WXC KCH ="; got != want {
+		t.Errorf("the preamble is %s, expected %s", got, want)
+	}
+}
+
+// Damaged fields are left out with a warning, the rest is read all the
+// same, and the header is written back without what's missing.
+func TestArchiveHeaderDamaged(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		want     enigma.ArchiveHeader
+		warnings int
+		render   string
+	}{
+		{[]string{"3580kHz 1840 179", "WXC KCH"}, enigma.ArchiveHeader{Frequency: "3580kHz", Time: "1840", Count: 179, Ground: "WXC", Key: "KCH"}, 0, "3580kHz 1840 179\nWXC KCH"},
+		{[]string{"1840 179", "wxc kch"}, enigma.ArchiveHeader{Time: "1840", Count: 179, Ground: "WXC", Key: "KCH"}, 0, "1840 179\nWXC KCH"},
+		{[]string{"3580kc 17?0 179", "WX3 KCH"}, enigma.ArchiveHeader{Frequency: "3580kc", Count: 179, Key: "KCH"}, 2, "3580kc 179\nKCH"},
+		{[]string{"", "WXC"}, enigma.ArchiveHeader{Ground: "WXC"}, 2, "\nWXC"},
+		{[]string{"2590 179", "WXC KCH VJN"}, enigma.ArchiveHeader{Count: 2590, Ground: "WXC", Key: "KCH"}, 2, "2590\nWXC KCH"},
+	}
+	for _, tt := range tests {
+		h, err := enigma.ParseArchiveHeader(tt.lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(h.Warnings) != tt.warnings {
+			t.Errorf("%q: the warnings are %q, expected %d", tt.lines, h.Warnings, tt.warnings)
+		}
+		h.Warnings = nil
+		if !reflect.DeepEqual(h, tt.want) {
+			t.Errorf("%q is read as %+v, expected %+v", tt.lines, h, tt.want)
+		}
+		if got := h.Render(); got != tt.render {
+			t.Errorf("%q is written back as %q, expected %q", tt.lines, got, tt.render)
+		}
+	}
+	if _, err := enigma.ParseArchiveHeader([]string{"1840 179"}); err == nil {
+		t.Error("a header of one line is read")
+	}
+}
@@ -0,0 +1,187 @@
+package enigma
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// AuditEntry is a line of an audit log (see StartAuditLog): the number
+// of the keypress, counting from 1, where the rotors were before it (as
+// letters, the reflector's first if it turns), whether they were set
+// there by hand since the keypress before, the key and the lamp, and
+// whether it was decoded rather than encoded, which only makes a
+// difference on machines that aren't reciprocal.
+type AuditEntry struct {
+	Step   int    `json:"step"`
+	Before string `json:"before"`
+	Set    bool   `json:"set,omitempty"`
+	Key    string `json:"key"`
+	Lamp   string `json:"lamp"`
+	Decode bool   `json:"decode,omitempty"`
+}
+
+// auditHeader is the first line of an audit log: the machine as it was
+// when the log was started.
+type auditHeader struct {
+	Settings Settings `json:"settings"`
+}
+
+// auditLog is where the machine writes its audit log to.
+type auditLog struct {
+	encoder *json.Encoder
+	step    int
+	// after is where the rotors were after the last keypress, to tell
+	// if they were set by hand since.
+	after string
+	err   error
+}
+
+// StartAuditLog makes the machine write every keypress from now on to
+// the writer, as JSON lines: the settings of the machine as it is now
+// first, then an AuditEntry for every key encoded or decoded, written
+// as it's pressed. The log can be read back with ReadAuditLog, and the
+// machine rebuilt at any keypress with ReplayAuditLog. It's an error if
+// there's no writer or the settings can't be written.
+func (e *Enigma) StartAuditLog(w io.Writer) error {
+	if w == nil {
+		return fmt.Errorf("an audit log needs a writer")
+	}
+	log := &auditLog{encoder: json.NewEncoder(w), after: e.auditPositions()}
+	if err := log.encoder.Encode(auditHeader{e.settings()}); err != nil {
+		return err
+	}
+	e.audit = log
+	return nil
+}
+
+// StopAuditLog stops the audit log, and returns the first error writing
+// it, if any: the keypresses after it weren't written.
+func (e *Enigma) StopAuditLog() error {
+	if e.audit == nil {
+		return fmt.Errorf("the machine is not writing an audit log")
+	}
+	err := e.audit.err
+	e.audit = nil
+	return err
+}
+
+// auditPositions returns where the rotors are, the way an AuditEntry
+// has them.
+func (e *Enigma) auditPositions() string {
+	positions := string(e.Windows())
+	if e.RotatingReflector {
+		positions = string(IndexToChar(mod26(e.Reflector.Position))) + positions
+	}
+	return positions
+}
+
+// logKeypress writes the keypress to the audit log, with the positions
+// the rotors were at before it.
+func (e *Enigma) logKeypress(before string, key, lamp byte, decode bool) {
+	log := e.audit
+	log.step++
+	entry := AuditEntry{log.step, before, before != log.after, string(key), string(lamp), decode}
+	log.after = e.auditPositions()
+	if log.err == nil {
+		log.err = log.encoder.Encode(entry)
+	}
+}
+
+// ReadAuditLog reads an audit log: the settings of the machine when it
+// was started, and the keypresses.
+func ReadAuditLog(r io.Reader) (Settings, []AuditEntry, error) {
+	scanner := bufio.NewScanner(r)
+	var header *auditHeader
+	var entries []AuditEntry
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if header == nil {
+			header = &auditHeader{}
+			if err := json.Unmarshal(scanner.Bytes(), header); err != nil {
+				return Settings{}, nil, fmt.Errorf("audit log line %d: %w", line, err)
+			}
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return Settings{}, nil, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if len(entry.Key) != 1 || len(entry.Lamp) != 1 {
+			return Settings{}, nil, fmt.Errorf(`audit log line %d: key and lamp should be single letters, got "%s" and "%s"`,
+				line, entry.Key, entry.Lamp)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return Settings{}, nil, err
+	}
+	if header == nil {
+		return Settings{}, nil, fmt.Errorf("the audit log is empty")
+	}
+	return header.Settings, entries, nil
+}
+
+// ReplayAuditLog rebuilds the machine of an audit log as it was after
+// the first steps keypresses of it (0 for where the log was started),
+// pressing the keys again on a fresh one. Where the rotors were set by
+// hand, they're set the same; anywhere else, they have to be where the
+// log says, and the same lamps have to light up. The first keypress
+// that differs is returned as a DivergenceError, with the positions
+// before it. The settings can't tell how a machine that isn't
+// reciprocal (see IsReciprocal) was rewired, so its log can be read but
+// not replayed.
+func ReplayAuditLog(r io.Reader, steps int) (*Enigma, error) {
+	settings, entries, err := ReadAuditLog(r)
+	if err != nil {
+		return nil, err
+	}
+	if steps < 0 || steps > len(entries) {
+		return nil, fmt.Errorf("the audit log has %d keypresses, can't replay %d", len(entries), steps)
+	}
+	e, err := settings.New()
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries[:steps] {
+		if entry.Set {
+			if err := e.ResetTo(entry.Before); err != nil {
+				return nil, fmt.Errorf("audit log keypress %d: %w", entry.Step, err)
+			}
+		}
+		key := entry.Key[0]
+		if key < 'A' || key > 'Z' {
+			return nil, fmt.Errorf(`audit log keypress %d: only capital letters can be pressed, got "%c"`, entry.Step, key)
+		}
+		before := e.auditPositions()
+		if before != entry.Before {
+			return nil, divergence(i, entry, 0, before)
+		}
+		var lamp byte
+		if entry.Decode {
+			lamp = e.DecodeChar(key)
+		} else {
+			lamp = e.EncodeChar(key)
+		}
+		if lamp != entry.Lamp[0] {
+			return nil, divergence(i, entry, lamp, before)
+		}
+	}
+	return e, nil
+}
+
+// divergence returns the DivergenceError of the entry of an audit log,
+// with the positions before the keypress.
+func divergence(index int, entry AuditEntry, lamp byte, positions string) error {
+	return &DivergenceError{
+		Index:             index,
+		Key:               entry.Key[0],
+		Expected:          entry.Lamp[0],
+		Got:               lamp,
+		ExpectedPositions: entry.Before,
+		Positions:         positions,
+	}
+}
@@ -0,0 +1,222 @@
+package enigma
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// auditConfig is the machine the audit logs are written on, with a
+// plugboard and the middle rotor about to turn over.
+func auditConfig() Config {
+	config := classicConfig()
+	config.Rotors[1].Start = 'D'
+	config.Rotors[2].Start = 'Q'
+	config.Plugboard = []string{"AT", "BL", "DF"}
+	return config
+}
+
+// audited encodes the text on a machine of the configuration writing
+// its audit log, and returns the machine and the log.
+func audited(t *testing.T, config Config, text string) (*Enigma, []byte) {
+	t.Helper()
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	if err := e.StartAuditLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString(text)
+	if err := e.StopAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+	return e, log.Bytes()
+}
+
+// Without a writer there's no audit log, and the machine encodes as if
+// it had never been asked for one.
+func TestAuditNilWriter(t *testing.T) {
+	e, err := Generic.New(auditConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.StartAuditLog(nil); err == nil {
+		t.Errorf("the audit log is started without a writer")
+	}
+	want := e.Clone().EncodeString("WETTER")
+	if got := e.EncodeString("WETTER"); got != want {
+		t.Errorf("the machine encodes to %s, expected %s", got, want)
+	}
+	if err := e.StopAuditLog(); err == nil {
+		t.Errorf("the audit log is stopped without being started")
+	}
+}
+
+// Replaying the log to any keypress gives the machine that pressed
+// them, through the double step too.
+func TestAuditReplay(t *testing.T) {
+	text := receiveText[:60]
+	e, log := audited(t, auditConfig(), text)
+	settings, entries, err := ReadAuditLog(bytes.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(text) || settings.Config.Rotors[2].Start != 'Q' {
+		t.Fatalf("the log starts at %s with %d keypresses, expected %d", settings.Config, len(entries), len(text))
+	}
+	for _, steps := range []int{0, 1, 2, 3, 27, len(text)} {
+		replayed, err := ReplayAuditLog(bytes.NewReader(log), steps)
+		if err != nil {
+			t.Fatalf("replaying %d keypresses: %v", steps, err)
+		}
+		pressed, _ := Generic.New(auditConfig())
+		pressed.EncodeString(text[:steps])
+		if replayed.Positions() != pressed.Positions() {
+			t.Errorf("after %d keypresses, the rotors are at %s, expected %s", steps, replayed.Positions(), pressed.Positions())
+		}
+		if got, want := replayed.EncodeString(text[steps:]), pressed.EncodeString(text[steps:]); got != want {
+			t.Errorf("after %d keypresses, the rest encodes to %s, expected %s", steps, got, want)
+		}
+	}
+	if replayed, err := ReplayAuditLog(bytes.NewReader(log), len(text)); err != nil || replayed.Positions() != e.Positions() {
+		t.Errorf("the whole log is replayed to %v (%v), expected %s", replayed, err, e.Positions())
+	}
+	for _, steps := range []int{-1, len(text) + 1} {
+		if _, err := ReplayAuditLog(bytes.NewReader(log), steps); err == nil {
+			t.Errorf("%d keypresses of %d are replayed", steps, len(text))
+		}
+	}
+}
+
+// A ResetTo between keypresses is logged as the rotors set by hand, and
+// replayed the same.
+func TestAuditSet(t *testing.T) {
+	e, err := Generic.New(auditConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	if err := e.StartAuditLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("WETTER")
+	if err := e.ResetTo("KCH"); err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("BERICHT")
+	if err := e.StopAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+	_, entries, err := ReadAuditLog(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if entry.Step != i+1 || entry.Set != (i == 6) {
+			t.Errorf("keypress %d is logged as %+v", i+1, entry)
+		}
+	}
+	if entries[6].Before != "KCH" {
+		t.Errorf("the rotors are logged as set to %s, expected KCH", entries[6].Before)
+	}
+	replayed, err := ReplayAuditLog(bytes.NewReader(log.Bytes()), len(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Positions() != e.Positions() {
+		t.Errorf("the log is replayed to %s, expected %s", replayed.Positions(), e.Positions())
+	}
+	if err := e.StopAuditLog(); err == nil {
+		t.Error("the audit log is stopped twice")
+	}
+}
+
+// A lamp changed in the log is where the replay stops, with what lit up
+// instead; the keypresses before it replay fine.
+func TestAuditTampered(t *testing.T) {
+	_, log := audited(t, auditConfig(), "WETTERBERICHT")
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	var entry AuditEntry
+	if err := json.Unmarshal([]byte(lines[5]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	lamp := entry.Lamp
+	entry.Lamp = string(IndexToChar(mod26(CharToIndex(lamp[0]) + 1)))
+	tampered, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines[5] = string(tampered)
+	log = []byte(strings.Join(lines, "\n"))
+
+	_, err = ReplayAuditLog(bytes.NewReader(log), 13)
+	var divergence *DivergenceError
+	if !errors.As(err, &divergence) {
+		t.Fatalf("the tampered log is replayed with %v", err)
+	}
+	if divergence.Index != 4 || divergence.Key != 'E' || divergence.Expected != entry.Lamp[0] || divergence.Got != lamp[0] ||
+		divergence.Positions != divergence.ExpectedPositions {
+		t.Errorf("the replay diverges with %+v", divergence)
+	}
+	if _, err := ReplayAuditLog(bytes.NewReader(log), 4); err != nil {
+		t.Errorf("the keypresses before the tampered one are replayed with %v", err)
+	}
+}
+
+// On a machine that isn't reciprocal, decoding goes the other way, and
+// the log tells the keypresses decoded from the ones encoded.
+func TestAuditDecode(t *testing.T) {
+	e, err := Generic.New(auditConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Reflector.Sequence = rotation(1)
+	fresh := e.Clone()
+	var log bytes.Buffer
+	if err := e.StartAuditLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := e.EncodeString("WETTER")
+	if decrypt := e.DecodeString("BERICHT"); decrypt == fresh.Clone().EncodeString("BERICHT") {
+		t.Fatalf("the machine decodes the way it encodes: %s", decrypt)
+	}
+	if err := e.StopAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+	_, entries, err := ReadAuditLog(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lamps []byte
+	for i, entry := range entries {
+		if entry.Decode != (i >= 6) {
+			t.Errorf("keypress %d is logged as %+v", i+1, entry)
+		}
+		lamps = append(lamps, entry.Lamp[0])
+	}
+	if string(lamps[:6]) != ciphertext {
+		t.Errorf("the lamps logged are %s, expected %s", lamps[:6], ciphertext)
+	}
+	// The lamps are the ones a fresh machine lights up for the keys,
+	// each pressed the way the log says.
+	for _, entry := range entries {
+		var lamp byte
+		if entry.Decode {
+			lamp = fresh.DecodeChar(entry.Key[0])
+		} else {
+			lamp = fresh.EncodeChar(entry.Key[0])
+		}
+		if lamp != entry.Lamp[0] {
+			t.Errorf("keypress %d lights %c on a fresh machine, logged %s", entry.Step, lamp, entry.Lamp)
+		}
+	}
+	// The settings can't say how a reflector that doesn't swap letters in
+	// pairs is wired, so the log can't be replayed.
+	if _, err := ReplayAuditLog(bytes.NewReader(log.Bytes()), 0); err == nil {
+		t.Error("the log of a machine that isn't reciprocal is replayed")
+	}
+}
@@ -0,0 +1,101 @@
+package enigma
+
+import (
+	"sort"
+	"strings"
+)
+
+// romanNumerals are the values of the letters of the rotor numerals.
+var romanNumerals = map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50}
+
+// greekRotors are the thin rotors of the M4, in the order they came.
+var greekRotors = map[string]int{"Beta": 1, "Gamma": 2}
+
+// AvailableRotors returns the rotors of the registry (see RotorFamilies)
+// in a fixed order: the historic family first, then the others by name,
+// and within each family the numbered rotors in the order of their
+// numerals (I, II, ..., VIII), then Beta and Gamma, then anything else
+// by ID.
+func AvailableRotors() []CatalogueEntry {
+	families := make([]string, 0, len(rotorFamilies))
+	for family := range rotorFamilies {
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		if (families[i] == "historic") != (families[j] == "historic") {
+			return families[i] == "historic"
+		}
+		return families[i] < families[j]
+	})
+	var entries []CatalogueEntry
+	for _, family := range families {
+		rotors := append(Rotors(nil), *rotorFamilies[family]...)
+		sort.SliceStable(rotors, func(i, j int) bool { return rotorBefore(rotors[i].ID, rotors[j].ID) })
+		for _, rotor := range rotors {
+			wiring, notches := rotor.wiring()
+			entries = append(entries, CatalogueEntry{family, rotor.ID, wiring, notches})
+		}
+	}
+	return entries
+}
+
+// AvailableReflectors returns the IDs of the reflectors of all the
+// KnownModels, the UKW-D included, in alphabetical order, which puts
+// the thin ones right after their thick counterparts (B, B-thin, C...).
+func AvailableReflectors() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, model := range KnownModels {
+		for _, id := range model.Capabilities().Reflectors {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// rotorBefore tells if the rotor with the first ID comes before the
+// other one in the order of AvailableRotors.
+func rotorBefore(a, b string) bool {
+	rankA, valueA := rotorRank(a)
+	rankB, valueB := rotorRank(b)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+	if valueA != valueB {
+		return valueA < valueB
+	}
+	return a < b
+}
+
+// rotorRank returns the group of the rotor ID (numerals, Greek letters,
+// anything else) and its value within the group.
+func rotorRank(id string) (int, int) {
+	if value, ok := romanValue(id); ok {
+		return 0, value
+	}
+	if value, ok := greekRotors[id]; ok {
+		return 1, value
+	}
+	return 2, 0
+}
+
+// romanValue returns the value of the Roman numeral, if it is one.
+func romanValue(numeral string) (int, bool) {
+	if numeral == "" || strings.Trim(numeral, "IVXL") != "" {
+		return 0, false
+	}
+	value := 0
+	for i := 0; i < len(numeral); i++ {
+		digit := romanNumerals[numeral[i]]
+		if i+1 < len(numeral) && romanNumerals[numeral[i+1]] > digit {
+			value -= digit
+		} else {
+			value += digit
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,62 @@
+package enigma
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The order of the built-in rotors and reflectors is locked, so that a
+// new one goes where it's meant to.
+func TestAvailableOrder(t *testing.T) {
+	var got []string
+	for _, entry := range AvailableRotors() {
+		got = append(got, entry.Family+"/"+entry.ID)
+	}
+	want := strings.Fields(`historic/I historic/II historic/III historic/IV historic/V
+		historic/VI historic/VII historic/VIII historic/Beta historic/Gamma
+		D/I D/II D/III G260/I G260/II G260/III G312/I G312/II G312/III
+		K/I K/II K/III KD/I KD/II KD/III
+		tirpitz/I tirpitz/II tirpitz/III tirpitz/IV tirpitz/V tirpitz/VI tirpitz/VII tirpitz/VIII`)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the rotors are listed as\n%v\nexpected\n%v", got, want)
+	}
+	if got, want := AvailableReflectors(), []string{"A", "B", "B-thin", "C", "C-thin", "D", "K", "T", "UKW", "UKW-D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the reflectors are listed as %v, expected %v", got, want)
+	}
+	if !reflect.DeepEqual(Catalogue(), AvailableRotors()) {
+		t.Error("the catalogue isn't in the order of AvailableRotors")
+	}
+}
+
+// Whatever order rotors were added in, the numerals come by value, the
+// Greek letters after them, and the rest by ID, in the families sorted
+// by name after the historic one.
+func TestAvailableRotorsAdded(t *testing.T) {
+	defer keepRegistry()()
+	wiring := historicRotors.GetByID("I")
+	family := Rotors{}
+	for _, id := range []string{"Zeta", "XII", "Gamma", "IX", "Alpha", "IV", "Beta", "XL"} {
+		rotor := *wiring
+		rotor.ID = id
+		family = append(family, rotor)
+	}
+	rotorFamilies["A0"] = &family
+	var got []string
+	for _, entry := range AvailableRotors() {
+		if entry.Family == "A0" {
+			got = append(got, entry.ID)
+		}
+	}
+	if want := []string{"IV", "IX", "XII", "XL", "Beta", "Gamma", "Alpha", "Zeta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the rotors are listed as %v, expected %v", got, want)
+	}
+	if entries := AvailableRotors(); entries[0].Family != "historic" || entries[10].Family != "A0" {
+		t.Errorf("the families are listed as %s, %s", entries[0].Family, entries[10].Family)
+	}
+	for i := 0; i < 10; i++ {
+		if a, b := AvailableRotors(), AvailableRotors(); !reflect.DeepEqual(a, b) {
+			t.Fatal("the rotors are listed in another order the second time")
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package enigma
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DailyKey is the key of a day: the machine configuration from the key
+// sheet (the starting positions don't matter, every message has its own
+// ground setting), and the Kenngruppen the messages of the day start
+// with, if any.
+type DailyKey struct {
+	Config      Config
+	Kenngruppen []string
+}
+
+// ReceivedMessage is a message as it was taken down: the indicator, the
+// ground setting and the encrypted message key (e.g. "WXC KCH"), and the
+// ciphertext, in groups or not.
+type ReceivedMessage struct {
+	Indicator  string
+	Ciphertext string
+}
+
+// Errors of DecryptBatch, to be checked with errors.Is.
+var (
+	ErrBadIndicator    = errors.New("bad indicator")
+	ErrWrongKenngruppe = errors.New("no Kenngruppe of the day")
+	ErrGarbled         = errors.New("garbled text")
+)
+
+// BatchResult is the outcome of decrypting a message: the plaintext and
+// its GarbleScore, or what went wrong. A garbled text or a missing
+// Kenngruppe is an error, but the plaintext is there all the same.
+type BatchResult struct {
+	Plaintext   string
+	GarbleScore float64
+	Err         error
+}
+
+// DecryptBatch decrypts the traffic of a day, on as many goroutines as
+// workers (one if fewer), each with its own machine. The results are in
+// the order of the messages. Messages not decrypted by the time the
+// context is done get its error. The machine of the key is checked
+// before the first message (see Validate), once per call, like
+// EncodeStream does; the workers' clones aren't checked again.
+func DecryptBatch(ctx context.Context, key DailyKey, messages []ReceivedMessage, workers int) ([]BatchResult, error) {
+	started := metricsStart()
+	e, err := Generic.New(key.Config)
+	if err == nil {
+		err = e.Validate()
+	}
+	if err != nil {
+		countError("enigma.batch", err)
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]BatchResult, len(messages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(e *Enigma) {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = decryptMessage(e, key.Kenngruppen, messages[i])
+			}
+		}(e.Clone())
+	}
+	next := 0
+feed:
+	for ; next < len(messages); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	for i := next; i < len(messages); i++ {
+		results[i].Err = ctx.Err()
+	}
+	if metrics != nil {
+		letters := 0
+		for _, result := range results {
+			letters += len(result.Plaintext)
+			countError("enigma.batch", result.Err)
+		}
+		observe("enigma.batch", len(messages), letters, started)
+	}
+	return results, nil
+}
+
+// decryptMessage decrypts the message the way the operator would: the
+// message key at the ground setting, and the text at the message key.
+func decryptMessage(e *Enigma, kenngruppen []string, message ReceivedMessage) BatchResult {
+	indicator := strings.Fields(message.Indicator)
+	if len(indicator) != 2 || len(indicator[1]) != len(e.Rotors) {
+		return BatchResult{Err: fmt.Errorf(`%w "%s"`, ErrBadIndicator, message.Indicator)}
+	}
+	if err := e.ResetTo(indicator[0]); err != nil {
+		return BatchResult{Err: fmt.Errorf(`%w "%s": %v`, ErrBadIndicator, message.Indicator, err)}
+	}
+	messageKey, err := e.EncodeText(indicator[1], NonAlphaReject)
+	if err != nil {
+		return BatchResult{Err: fmt.Errorf(`%w "%s": %v`, ErrBadIndicator, message.Indicator, err)}
+	}
+	if err := e.ResetTo(messageKey); err != nil {
+		return BatchResult{Err: fmt.Errorf(`%w "%s": %v`, ErrBadIndicator, message.Indicator, err)}
+	}
+	text, err := ciphertextLetters(ParseGroups(message.Ciphertext, ClassicGroups))
+	if err != nil {
+		return BatchResult{Err: err}
+	}
+	var result BatchResult
+	if len(kenngruppen) > 0 {
+		if len(text) < 5 || !containsString(kenngruppen, text[2:5]) {
+			result.Err = ErrWrongKenngruppe
+		}
+		if len(text) >= 5 {
+			text = text[5:]
+		}
+	}
+	result.Plaintext = e.EncodeString(text)
+	result.GarbleScore = GarbleScore(result.Plaintext)
+	if result.Err == nil && result.GarbleScore > garbledThreshold {
+		result.Err = ErrGarbled
+	}
+	return result
+}
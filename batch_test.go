@@ -0,0 +1,112 @@
+package enigma
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// batchMessage enciphers the text the way the operator would: the
+// message key at the ground setting for the indicator, the Kenngruppe
+// in the clear (in a group of its own, if there's one), and the text at
+// the message key, in groups.
+func batchMessage(t *testing.T, config Config, ground, key, kenngruppe, text string) ReceivedMessage {
+	t.Helper()
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ResetTo(ground); err != nil {
+		t.Fatal(err)
+	}
+	indicator := ground + " " + e.EncodeString(key)
+	if err := e.ResetTo(key); err != nil {
+		t.Fatal(err)
+	}
+	if kenngruppe != "" {
+		kenngruppe = "XY" + kenngruppe
+	}
+	return ReceivedMessage{indicator, FormatGroups(kenngruppe+e.EncodeString(text), ClassicGroups)}
+}
+
+// A day's traffic comes back in order, whatever the workers: the good
+// messages decrypted, the one sent with another key garbled, the one
+// with no Kenngruppe of the day flagged, and the malformed indicators
+// turned down.
+func TestDecryptBatch(t *testing.T) {
+	key := DailyKey{Config: classicConfig(), Kenngruppen: []string{"KLM", "RTZ", "BDG"}}
+	key.Config.Plugboard = []string{"AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX"}
+	other := key.Config
+	other.Plugboard = []string{"AB", "CD", "EF"}
+	texts := []string{receiveText, "ANXOBERKOMMANDOXDERXWEHRMACHTXDIEXTRUPPENXSINDXINXSTELLUNGXWEITEREXBEFEHLEXFOLGENXMORGENXFRUEH", receiveText[20:]}
+	messages := []ReceivedMessage{
+		batchMessage(t, key.Config, "WXC", "KCH", "KLM", texts[0]),
+		batchMessage(t, key.Config, "QRS", "PDL", "RTZ", texts[1]),
+		batchMessage(t, other, "UFW", "EMV", "BDG", texts[0]),
+		{"WXC", "ABCDE FGHIJ"},
+		{"WXC KC1", "ABCDE FGHIJ"},
+		batchMessage(t, key.Config, "JNA", "ZZQ", "QQQ", texts[2]),
+		batchMessage(t, key.Config, "BBB", "OHM", "BDG", texts[2]),
+	}
+	want := []struct {
+		plaintext string
+		err       error
+	}{
+		{texts[0], nil},
+		{texts[1], nil},
+		{"", ErrGarbled},
+		{"", ErrBadIndicator},
+		{"", ErrBadIndicator},
+		{texts[2], ErrWrongKenngruppe},
+		{texts[2], nil},
+	}
+	for _, workers := range []int{0, 1, 3, 16} {
+		results, err := DecryptBatch(context.Background(), key, messages, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(messages) {
+			t.Fatalf("%d workers: %d results for %d messages", workers, len(results), len(messages))
+		}
+		for i, result := range results {
+			if !errors.Is(result.Err, want[i].err) || (want[i].err == nil && result.Err != nil) {
+				t.Errorf("%d workers: message %d is decrypted with %v, expected %v", workers, i, result.Err, want[i].err)
+			}
+			if want[i].plaintext != "" && result.Plaintext != want[i].plaintext {
+				t.Errorf("%d workers: message %d decrypts to %s", workers, i, result.Plaintext)
+			}
+		}
+	}
+	if _, err := DecryptBatch(context.Background(), DailyKey{Config: Config{}}, messages, 1); err == nil {
+		t.Error("the traffic is decrypted with no machine at all")
+	}
+}
+
+// Once the context is done, the messages not yet decrypted get its
+// error, and the rest are decrypted as ever.
+func TestDecryptBatchCancel(t *testing.T) {
+	key := DailyKey{Config: classicConfig()}
+	message := batchMessage(t, key.Config, "WXC", "KCH", "", receiveText)
+	messages := make([]ReceivedMessage, 64)
+	for i := range messages {
+		messages[i] = message
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := DecryptBatch(ctx, key, messages, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled := 0
+	for i, result := range results {
+		switch {
+		case errors.Is(result.Err, context.Canceled):
+			canceled++
+		case result.Err != nil || result.Plaintext != receiveText:
+			t.Errorf("message %d is decrypted to %s with %v", i, result.Plaintext, result.Err)
+		}
+	}
+	if canceled == 0 {
+		t.Error("every message is decrypted once canceled")
+	}
+}
@@ -0,0 +1,190 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// barbarossa is the Enigma I of the Barbarossa intercepts, and its
+// plaintext the German the benchmarks encode, over and over.
+var barbarossa = struct {
+	config    Config
+	plaintext string
+}{
+	Config{
+		Rotors: []RotorConfig{
+			{ID: "II", Start: 'B', Ring: 2},
+			{ID: "IV", Start: 'L', Ring: 21},
+			{ID: "V", Start: 'A', Ring: 12},
+		},
+		Reflector: ReflectorConfig{ID: "B"},
+		Plugboard: []string{"AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX"},
+	},
+	"AUFKLXABTEILUNGXVONXKURTINOWAXKURTINOWAXNORDWESTLXSEBEZXSEBEZX" +
+		"UAFFLIEGERSTRASZERIQTUNGXDUBROWKIXDUBROWKIXOPOTSCHKAXOPOTSCHKAX" +
+		"UMXEINSAQTDREINULLXUHRANGETRETENXANGRIFFXINFXRGTX",
+}
+
+// benchMachine returns the machine of the benchmarks, and n letters of
+// plaintext.
+func benchMachine(b *testing.B, n int) (*Enigma, string) {
+	e, err := EnigmaI.New(barbarossa.config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return e, strings.Repeat(barbarossa.plaintext, n/len(barbarossa.plaintext)+1)[:n]
+}
+
+func BenchmarkEncodeChar(b *testing.B) {
+	e, _ := benchMachine(b, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e.EncodeChar('A' + byte(i%26))
+	}
+}
+
+func BenchmarkEncodeString1KB(b *testing.B) { benchmarkEncodeString(b, 1<<10) }
+func BenchmarkEncodeString1MB(b *testing.B) { benchmarkEncodeString(b, 1<<20) }
+func BenchmarkEncodeBytes1KB(b *testing.B)  { benchmarkEncodeBytes(b, 1<<10) }
+func BenchmarkEncodeBytes1MB(b *testing.B)  { benchmarkEncodeBytes(b, 1<<20) }
+
+// benchmarkEncodeString encodes n letters a key at a time.
+func benchmarkEncodeString(b *testing.B, n int) {
+	e, text := benchMachine(b, n)
+	b.SetBytes(int64(n))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.EncodeString(text)
+	}
+}
+
+// benchmarkEncodeBytes encodes n letters with the tables of EncodeBytes.
+func benchmarkEncodeBytes(b *testing.B, n int) {
+	e, text := benchMachine(b, n)
+	src, dst := []byte(text), make([]byte, n)
+	b.SetBytes(int64(n))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.EncodeBytes(dst, src)
+	}
+}
+
+// BenchmarkPlugboard encodes with the plugboard empty, with the cables,
+// and with the Uhr, which all take a table lookup each way.
+func BenchmarkPlugboard(b *testing.B) {
+	for _, plugs := range []struct {
+		name   string
+		config func(*Config)
+	}{
+		{"none", func(c *Config) { c.Plugboard = nil }},
+		{"cables", func(c *Config) {}},
+		{"Uhr", func(c *Config) { c.Uhr = &UhrConfig{Position: 27} }},
+	} {
+		b.Run(plugs.name, func(b *testing.B) {
+			config := barbarossa.config
+			plugs.config(&config)
+			e, err := Generic.New(config)
+			if err != nil {
+				b.Fatal(err)
+			}
+			src := []byte(strings.Repeat(barbarossa.plaintext, 1<<10/len(barbarossa.plaintext)+1)[:1<<10])
+			dst := make([]byte, len(src))
+			b.SetBytes(int64(len(src)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.EncodeBytes(dst, src)
+			}
+		})
+	}
+}
+
+// BenchmarkPlugboardTable puts 1KB of letter indexes through the
+// tables of the cables and of the Uhr, the way in and back, the way a
+// pipeline of indexes of its own does: a lookup each way, with nothing
+// left of the plugboard to show in a profile.
+func BenchmarkPlugboardTable(b *testing.B) {
+	uhr, err := NewUhr(barbarossa.config.Plugboard, 27)
+	if err != nil {
+		b.Fatal(err)
+	}
+	uhrIn, uhrOut := uhr.Table()
+	cables := NewPlugboard(barbarossa.config.Plugboard).Table()
+	for _, plugs := range []struct {
+		name    string
+		in, out [26]int8
+	}{
+		{"cables", cables, cables},
+		{"Uhr", uhrIn, uhrOut},
+	} {
+		b.Run(plugs.name, func(b *testing.B) {
+			src := make([]int8, 1<<10)
+			for i := range src {
+				src[i] = int8(CharToIndex(barbarossa.plaintext[i%len(barbarossa.plaintext)]))
+			}
+			dst := make([]int8, len(src))
+			b.SetBytes(int64(len(src)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j, letter := range src {
+					dst[j] = plugs.out[plugs.in[letter]]
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncode encodes a short message with Encode, and with a
+// machine built for it by hand, which is what Encode does.
+func BenchmarkEncode(b *testing.B) {
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Encode(barbarossa.config, barbarossa.plaintext); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("by hand", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e, err := Generic.New(barbarossa.config)
+			if err != nil {
+				b.Fatal(err)
+			}
+			e.EncodeString(barbarossa.plaintext)
+		}
+	})
+}
+
+// BenchmarkMetrics encodes 1KB with no collector, the default, and with
+// one that does nothing: the first should be as fast as before there
+// were metrics, the second shows what the hook costs.
+func BenchmarkMetrics(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		collector Collector
+	}{
+		{"none", nil},
+		{"discard", discard{}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			SetMetricsCollector(bench.collector)
+			defer SetMetricsCollector(nil)
+			e, plaintext := benchMachine(b, 1<<10)
+			b.SetBytes(int64(len(plaintext)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.EncodeString(plaintext)
+			}
+		})
+	}
+}
+
+// discard is a collector throwing the metrics away.
+type discard struct{}
+
+func (discard) IncCounter(string, int64)              {}
+func (discard) ObserveDuration(string, time.Duration) {}
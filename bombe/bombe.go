@@ -0,0 +1,391 @@
+// Package bombe is a software Turing-Welchman bombe: it takes a crib, a
+// bit of plaintext known to be in the message, lined up with the
+// ciphertext, draws the menu of the letters it links, and tries every
+// rotor order and starting position for the plugboard pairs that would
+// make the crib fit. The positions it stops at are few enough to try by
+// hand, which is what the bombe was for.
+//
+// The rotors step the way they do on the machine, with the rings of the
+// search space, so a stop is the setting the message starts at rather
+// than the core position of the real bombe. There's no plugboard on the
+// rotors side: finding it is the point.
+package bombe
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/cryptanalysis"
+)
+
+// Link is a connection of the menu: the letter of the crib and the one
+// of the ciphertext it encodes to, at a keypress of the message
+// (counting from 0).
+type Link struct {
+	Plain, Cipher byte
+	Keypress      int
+}
+
+// Menu is what the bombe is wired up with: the links of the crib, and
+// the letter the test register is on, the most connected one.
+type Menu struct {
+	Links []Link
+	Test  byte
+}
+
+// NewMenu lines up the crib with the ciphertext, starting at the letter
+// at (counting from 0), and draws the menu. Both are capital letters
+// without anything else, and since no letter encodes to itself, the
+// crib can't go where any of its letters meets the same one of the
+// ciphertext.
+func NewMenu(crib, ciphertext string, at int) (*Menu, error) {
+	if len(crib) == 0 {
+		return nil, fmt.Errorf("the crib is empty")
+	}
+	if at < 0 || at+len(crib) > len(ciphertext) {
+		return nil, fmt.Errorf("the crib doesn't fit at %d: it's %d letters, the ciphertext %d", at, len(crib), len(ciphertext))
+	}
+	menu := &Menu{}
+	var links [26]int
+	for i := 0; i < len(crib); i++ {
+		plain, cipher := crib[i], ciphertext[at+i]
+		if !letter(plain) || !letter(cipher) {
+			return nil, fmt.Errorf(`only capital letters can be on the menu, got "%c" and "%c" at %d`, plain, cipher, at+i)
+		}
+		if plain == cipher {
+			return nil, fmt.Errorf(`the crib cannot be at %d: "%c" would encode to itself at %d`, at, plain, at+i)
+		}
+		menu.Links = append(menu.Links, Link{plain, cipher, at + i})
+		links[plain-'A']++
+		links[cipher-'A']++
+	}
+	menu.Test = 'A'
+	for i, n := range links {
+		if n > links[menu.Test-'A'] {
+			menu.Test = byte('A' + i)
+		}
+	}
+	return menu, nil
+}
+
+// Letters returns the letters on the menu, in order.
+func (m *Menu) Letters() []byte {
+	var on [26]bool
+	for _, link := range m.Links {
+		on[link.Plain-'A'], on[link.Cipher-'A'] = true, true
+	}
+	var letters []byte
+	for i, ok := range on {
+		if ok {
+			letters = append(letters, byte('A'+i))
+		}
+	}
+	return letters
+}
+
+// Loops returns the number of closed loops of the menu: the more there
+// are, the fewer false stops the bombe makes. With none, it stops
+// almost everywhere.
+func (m *Menu) Loops() int {
+	var parent [26]int
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+	loops := 0
+	for _, link := range m.Links {
+		a, b := root(int(link.Plain-'A')), root(int(link.Cipher-'A'))
+		if a == b {
+			loops++
+		} else {
+			parent[a] = b
+		}
+	}
+	return loops
+}
+
+// keypresses returns how many keypresses the menu goes up to.
+func (m *Menu) keypresses() int {
+	n := 0
+	for _, link := range m.Links {
+		if link.Keypress >= n {
+			n = link.Keypress + 1
+		}
+	}
+	return n
+}
+
+// Stop is a setting the bombe stopped at: the configuration, with the
+// starting positions of the message and the plugboard pairs found, and
+// the pairs once more on their own, and the Model it's for. The letters
+// of the menu that turned out not to be plugged aren't in the pairs, and
+// the letters not on the menu are left for the operator to find (or for
+// ResolveStop).
+type Stop struct {
+	Config   enigma.Config
+	Steckers []string
+	Model    *enigma.Model
+}
+
+// Options tune Run: the number of Workers (GOMAXPROCS if not set), and
+// an optional Progress callback, called with the settings tried so far
+// and their total from the workers' goroutines. Report, if set, is
+// called at the same times with more to show (see Report), one call at
+// a time.
+type Options struct {
+	Workers  int
+	Progress func(tried, total int64)
+	Report   func(Report)
+}
+
+// Report is how far Run got: the settings tried so far and their total,
+// the stops found so far, and the rotor order (the IDs from the left)
+// and the reflector of the settings just done.
+type Report struct {
+	Tried, Total int64
+	Stops        int
+	Order        string
+	Reflector    string
+}
+
+// Run tries every rotor order, reflector, and rings of the space (see
+// cryptanalysis.Space, whose plugboard is left out) at every starting
+// position against the menu, on several goroutines, and returns the
+// stops in the order of their settings. When the context is done, it
+// returns the stops found so far along with the context's error.
+func Run(ctx context.Context, menu *Menu, space cryptanalysis.Space, options Options) ([]Stop, error) {
+	if len(menu.Links) == 0 {
+		return nil, fmt.Errorf("the menu is empty")
+	}
+	space.Plugboard = nil
+	if options.Workers < 1 {
+		options.Workers = runtime.GOMAXPROCS(0)
+	}
+	total := space.Size()
+	var tried int64
+	var reporting sync.Mutex
+	found := 0
+	jobs := make(chan enigma.Config)
+	results := make([][]Stop, options.Workers)
+	var wg sync.WaitGroup
+	for w := 0; w < options.Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for config := range jobs {
+				stops, n := run(ctx, menu, space.Model, config)
+				results[w] = append(results[w], stops...)
+				done := atomic.AddInt64(&tried, n)
+				if options.Progress != nil {
+					options.Progress(done, total)
+				}
+				if options.Report != nil {
+					reporting.Lock()
+					found += len(stops)
+					options.Report(Report{atomic.LoadInt64(&tried), total, found, config.RotorOrder(), config.Reflector.ID})
+					reporting.Unlock()
+				}
+			}
+		}(w)
+	}
+	accepted, rejected := space.Each(func(config enigma.Config) bool {
+		select {
+		case jobs <- config:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(jobs)
+	wg.Wait()
+	err := ctx.Err()
+	if accepted == 0 && rejected != nil {
+		err = fmt.Errorf("no setting of the space can be used: %w", rejected)
+	}
+	var stops []Stop
+	for _, found := range results {
+		stops = append(stops, found...)
+	}
+	sort.Slice(stops, func(i, j int) bool {
+		return stops[i].Config.String() < stops[j].Config.String()
+	})
+	return stops, err
+}
+
+// run tries every starting position of the configuration, and returns
+// the stops and the number of positions tried.
+func run(ctx context.Context, menu *Menu, model *enigma.Model, config enigma.Config) ([]Stop, int64) {
+	if model == nil {
+		model = &enigma.EnigmaI
+	}
+	e, err := model.New(config)
+	if err != nil {
+		return nil, 0
+	}
+	offsets := make([]int, len(config.Rotors))
+	positions := make([]byte, len(offsets))
+	keypresses := menu.keypresses()
+	var stops []Stop
+	var tried int64
+	for {
+		if tried%(26*26) == 0 && ctx.Err() != nil {
+			return stops, tried
+		}
+		for i, offset := range offsets {
+			positions[i] = byte('A' + offset)
+		}
+		e.ResetTo(string(positions))
+		if steckers, ok := menu.stop(scramblers(e.NextSubstitutions(keypresses))); ok {
+			stop := Stop{Config: config, Steckers: steckers, Model: model}
+			stop.Config.Rotors = append([]enigma.RotorConfig(nil), config.Rotors...)
+			for i := range stop.Config.Rotors {
+				stop.Config.Rotors[i].Start = positions[i]
+			}
+			stop.Config.Plugboard = steckers
+			stops = append(stops, stop)
+		}
+		tried++
+		if !next(offsets) {
+			return stops, tried
+		}
+	}
+}
+
+// scramblers turns the alphabets of the keypresses into indexes.
+func scramblers(series [][26]rune) [][26]int {
+	tables := make([][26]int, len(series))
+	for i, alphabet := range series {
+		for j, lamp := range alphabet {
+			tables[i][j] = int(lamp - 'A')
+		}
+	}
+	return tables
+}
+
+// board is the diagonal board: the wire of every letter of every
+// register, live or not. Wire b of register a being live means a might
+// be plugged to b.
+type board [26][26]bool
+
+// stop tells if the bombe stops with the rotors making the scramblers:
+// it does when the test register doesn't light up all the way from a
+// first guess, and a guess for the plug of the test letter holds with
+// every letter of the menu plugged to exactly one other. It returns the
+// pairs found.
+func (m *Menu) stop(scramblers [][26]int) ([]string, bool) {
+	test := int(m.Test - 'A')
+	lit := m.energize(scramblers, test, 0)
+	var guesses []int
+	switch n := count(lit[test]); {
+	case n == 26:
+		return nil, false
+	case n == 1:
+		guesses = []int{0}
+	default:
+		for b, live := range lit[test] {
+			if !live {
+				guesses = append(guesses, b)
+			}
+		}
+	}
+	for _, guess := range guesses {
+		if steckers, ok := m.consistent(m.energize(scramblers, test, guess)); ok {
+			return steckers, true
+		}
+	}
+	return nil, false
+}
+
+// energize makes the wire b of register a live, and everything that
+// follows from it through the scramblers and the diagonal board.
+func (m *Menu) energize(scramblers [][26]int, a, b int) *board {
+	var lit board
+	type wire struct{ register, letter int }
+	queue := []wire{{a, b}}
+	light := func(register, letter int) {
+		if !lit[register][letter] {
+			lit[register][letter] = true
+			queue = append(queue, wire{register, letter})
+		}
+	}
+	type scrambler struct{ to, keypress int }
+	var links [26][]scrambler
+	for _, link := range m.Links {
+		plain, cipher := int(link.Plain-'A'), int(link.Cipher-'A')
+		links[plain] = append(links[plain], scrambler{cipher, link.Keypress})
+		links[cipher] = append(links[cipher], scrambler{plain, link.Keypress})
+	}
+	light(a, b)
+	for len(queue) > 0 {
+		w := queue[0]
+		queue = queue[1:]
+		light(w.letter, w.register)
+		for _, link := range links[w.register] {
+			light(link.to, scramblers[link.keypress][w.letter])
+		}
+	}
+	return &lit
+}
+
+// consistent tells if every letter of the menu has exactly one live
+// wire, and returns the pairs of the ones not plugged to themselves.
+func (m *Menu) consistent(lit *board) ([]string, bool) {
+	letters := m.Letters()
+	var onMenu [26]bool
+	for _, letter := range letters {
+		onMenu[letter-'A'] = true
+	}
+	var steckers []string
+	for _, letter := range letters {
+		a := int(letter - 'A')
+		if count(lit[a]) != 1 {
+			return nil, false
+		}
+		for b, live := range lit[a] {
+			// A pair with both letters on the menu is only taken once.
+			if live && a != b && (a < b || !onMenu[b]) {
+				steckers = append(steckers, string([]byte{byte('A' + a), byte('A' + b)}))
+			}
+		}
+	}
+	return steckers, true
+}
+
+// count returns the number of live wires of a register.
+func count(register [26]bool) int {
+	n := 0
+	for _, live := range register {
+		if live {
+			n++
+		}
+	}
+	return n
+}
+
+// next steps the positions like an odometer, the rightmost first, and
+// tells if it didn't wrap around to the start.
+func next(offsets []int) bool {
+	for i := len(offsets) - 1; i >= 0; i-- {
+		if offsets[i]++; offsets[i] < 26 {
+			return true
+		}
+		offsets[i] = 0
+	}
+	return false
+}
+
+// letter tells if the byte is a capital letter.
+func letter(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
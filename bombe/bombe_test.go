@@ -0,0 +1,68 @@
+package bombe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/cryptanalysis"
+)
+
+// Run reports once an order, never going backwards, counting the stops
+// as they're found, and the last report has every setting tried.
+func TestRunReports(t *testing.T) {
+	_, ciphertext := message(t)
+	menu, err := NewMenu(crib, ciphertext, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := cryptanalysis.Space{Model: &enigma.EnigmaI, Rotors: []string{"I", "II", "III"}, Reflectors: []string{"B"}}
+	var reports []Report
+	stops, err := Run(context.Background(), menu, space, Options{Report: func(r Report) { reports = append(reports, r) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 6 {
+		t.Fatalf("%d reports for the 6 orders", len(reports))
+	}
+	orders := make(map[string]bool)
+	for i, r := range reports {
+		if r.Total != space.Size() || r.Reflector != "B" {
+			t.Errorf("report %d is %+v", i, r)
+		}
+		if i > 0 && (r.Tried < reports[i-1].Tried || r.Stops < reports[i-1].Stops) {
+			t.Errorf("report %d, %+v, goes back from %+v", i, r, reports[i-1])
+		}
+		orders[r.Order] = true
+	}
+	if last := reports[len(reports)-1]; len(orders) != 6 || last.Tried != space.Size() || last.Stops != len(stops) {
+		t.Errorf("the orders %v are reported, the last report being %+v with %d stops", orders, last, len(stops))
+	}
+}
+
+// A run cancelled or out of time stops with the context's error.
+func TestRunCancel(t *testing.T) {
+	_, ciphertext := message(t)
+	menu, err := NewMenu(crib, ciphertext, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := cryptanalysis.Space{Model: &enigma.EnigmaI, Rotors: []string{"I", "II", "III", "IV", "V"}, RingSlots: []int{2, 3}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, menu, space, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("the cancelled run ends with %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var tried int64
+	started := time.Now()
+	if _, err := Run(ctx, menu, space, Options{Report: func(r Report) { tried = r.Tried }}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the run out of time ends with %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second || tried >= space.Size() {
+		t.Errorf("the run stops %v after it's out of time, with %d of %d settings tried", elapsed, tried, space.Size())
+	}
+}
@@ -0,0 +1,210 @@
+package bombe
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/score"
+)
+
+// Candidate is a stop worked out by ResolveStop: the configuration with
+// the whole plugboard, the decrypt, and its score.
+type Candidate struct {
+	Config    enigma.Config
+	Plaintext string
+	Score     float64
+}
+
+// ResolveStop does what the operators did with a stop: it follows the
+// pairs of the stop through the crib to whatever they imply, checks the
+// crib comes out of the ciphertext, where it starts at offset, and
+// plugs the letters left over, two at a time, for as long as the decrypt
+// reads more like German for it (by score.GermanBigrams). A false stop,
+// whose pairs don't hold, is an error.
+func ResolveStop(stop Stop, ciphertext, crib string, offset int) (Candidate, error) {
+	if _, err := NewMenu(crib, ciphertext, offset); err != nil {
+		return Candidate{}, err
+	}
+	for i := 0; i < len(ciphertext); i++ {
+		if !letter(ciphertext[i]) {
+			return Candidate{}, fmt.Errorf(`only capital letters can be decrypted, got "%c" at %d`, ciphertext[i], i)
+		}
+	}
+	model := stop.Model
+	if model == nil {
+		model = &enigma.EnigmaI
+	}
+	config := stop.Config
+	config.Plugboard = nil
+	e, err := model.New(config)
+	if err != nil {
+		return Candidate{}, err
+	}
+	s := &steckering{scramblers: scramblers(e.NextSubstitutions(len(ciphertext)))}
+	for i := range s.plug {
+		s.plug[i] = i
+	}
+	for _, pair := range stop.Steckers {
+		if err := s.connect(int(pair[0]-'A'), int(pair[1]-'A')); err != nil {
+			return Candidate{}, err
+		}
+	}
+	// The letters of the menu the stop doesn't plug aren't plugged.
+	for _, letter := range (&Menu{Links: links(crib, ciphertext, offset)}).Letters() {
+		s.known[letter-'A'] = true
+	}
+	if err := s.follow(crib, ciphertext, offset); err != nil {
+		return Candidate{}, err
+	}
+	if plaintext := s.decrypt(ciphertext); string(plaintext[offset:offset+len(crib)]) != crib {
+		return Candidate{}, fmt.Errorf("the stop doesn't hold: the crib decrypts to %s", plaintext[offset:offset+len(crib)])
+	}
+	max := model.MaxPlugPairs
+	if max == 0 || max > 13 {
+		max = 13
+	}
+	rating := score.GermanBigrams.Score(s.decrypt(ciphertext))
+	for s.pairs() < max {
+		best, a, b := rating, -1, -1
+		for i := 0; i < 26; i++ {
+			for j := i + 1; j < 26; j++ {
+				if s.known[i] || s.known[j] {
+					continue
+				}
+				s.plug[i], s.plug[j] = j, i
+				if r := score.GermanBigrams.Score(s.decrypt(ciphertext)); r > best {
+					best, a, b = r, i, j
+				}
+				s.plug[i], s.plug[j] = i, j
+			}
+		}
+		if a < 0 {
+			break
+		}
+		s.connect(a, b)
+		rating = best
+	}
+	config.Plugboard = s.steckers()
+	if _, err := model.New(config); err != nil {
+		return Candidate{}, err
+	}
+	return Candidate{config, string(s.decrypt(ciphertext)), rating}, nil
+}
+
+// RankStops resolves every stop (see ResolveStop), and returns the
+// candidates the best first. The false stops are left out, and it's
+// only an error if all of them are.
+func RankStops(stops []Stop, ciphertext, crib string, offset int) ([]Candidate, error) {
+	var candidates []Candidate
+	var last error
+	for _, stop := range stops {
+		candidate, err := ResolveStop(stop, ciphertext, crib, offset)
+		if err != nil {
+			last = err
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 && last != nil {
+		return nil, fmt.Errorf("none of the %d stops holds: %w", len(stops), last)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// links returns the links of the crib at offset, which NewMenu has
+// already checked.
+func links(crib, ciphertext string, offset int) []Link {
+	links := make([]Link, len(crib))
+	for i := range links {
+		links[i] = Link{crib[i], ciphertext[offset+i], offset + i}
+	}
+	return links
+}
+
+// steckering is a plugboard being worked out: the letter every letter is
+// plugged to, whether that's known yet, and the scramblers of every
+// keypress of the message.
+type steckering struct {
+	plug       [26]int
+	known      [26]bool
+	scramblers [][26]int
+}
+
+// connect plugs a to b (or leaves a unplugged, if they're the same), if
+// neither of them is known to be plugged to something else.
+func (s *steckering) connect(a, b int) error {
+	if s.known[b] && s.plug[b] != a {
+		a, b = b, a
+	}
+	if s.known[a] && s.plug[a] != b {
+		return fmt.Errorf(`"%c" can't be plugged to "%c": it's plugged to "%c"`, 'A'+a, 'A'+b, 'A'+s.plug[a])
+	}
+	s.plug[a], s.plug[b] = b, a
+	s.known[a], s.known[b] = true, true
+	return nil
+}
+
+// follow goes through the crib for as long as a known pair implies
+// another: with the plain letter of a keypress plugged to a, the cipher
+// one must be plugged to what the scrambler turns a into, and the other
+// way round.
+func (s *steckering) follow(crib, ciphertext string, offset int) error {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(crib); i++ {
+			plain, cipher := int(crib[i]-'A'), int(ciphertext[offset+i]-'A')
+			scrambler := s.scramblers[offset+i]
+			for _, link := range [][2]int{{plain, cipher}, {cipher, plain}} {
+				from, to := link[0], link[1]
+				if !s.known[from] {
+					continue
+				}
+				want := scrambler[s.plug[from]]
+				if s.known[to] && s.plug[to] == want {
+					continue
+				}
+				if err := s.connect(to, want); err != nil {
+					return fmt.Errorf("the stop doesn't hold at %d: %w", offset+i, err)
+				}
+				changed = true
+			}
+		}
+	}
+	return nil
+}
+
+// decrypt returns the plaintext of the ciphertext with the plugboard as
+// it is.
+func (s *steckering) decrypt(ciphertext string) []byte {
+	plaintext := make([]byte, len(ciphertext))
+	for i := 0; i < len(ciphertext); i++ {
+		plaintext[i] = byte('A' + s.plug[s.scramblers[i][s.plug[ciphertext[i]-'A']]])
+	}
+	return plaintext
+}
+
+// pairs returns the number of cables in.
+func (s *steckering) pairs() int {
+	n := 0
+	for i, j := range s.plug {
+		if i < j {
+			n++
+		}
+	}
+	return n
+}
+
+// steckers returns the pairs, e.g. "AQ".
+func (s *steckering) steckers() []string {
+	var steckers []string
+	for i, j := range s.plug {
+		if i < j {
+			steckers = append(steckers, string([]byte{byte('A' + i), byte('A' + j)}))
+		}
+	}
+	return steckers
+}
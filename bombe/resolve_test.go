@@ -0,0 +1,141 @@
+package bombe
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/cryptanalysis"
+)
+
+// A small bombe search over the orders of rotors I, II and III stops at
+// the true setting, and resolving the stop finds the pairs of the
+// letters off the menu too.
+func TestResolveStop(t *testing.T) {
+	config, ciphertext := message(t)
+	menu, err := NewMenu(crib, ciphertext, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := cryptanalysis.Space{Model: &enigma.EnigmaI, Rotors: []string{"I", "II", "III"}, Reflectors: []string{"B"}}
+	stops, err := Run(context.Background(), menu, space, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 1 {
+		t.Fatalf("the bombe stops %d times, expected once", len(stops))
+	}
+	if len(stops[0].Steckers) == len(config.Plugboard) {
+		t.Fatalf("the stop already has the whole plugboard, %v: nothing is left to resolve", stops[0].Steckers)
+	}
+	candidates, err := RankStops(stops, ciphertext, crib, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, candidates[0], config)
+}
+
+// With a crib too short to tell the settings apart, the bombe stops
+// dozens of times at the setting of one rotor order alone, and the true
+// setting still comes first.
+func TestRankStops(t *testing.T) {
+	config, ciphertext := message(t)
+	short := crib[:11]
+	menu, err := NewMenu(short, ciphertext, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := config
+	order.Plugboard = nil
+	order.Rotors = append([]enigma.RotorConfig(nil), config.Rotors...)
+	for i := range order.Rotors {
+		order.Rotors[i].Start = 'A'
+	}
+	stops, _ := run(context.Background(), menu, &enigma.EnigmaI, order)
+	truth := Stop{Config: config, Steckers: config.Plugboard, Model: &enigma.EnigmaI}
+	candidates, err := RankStops(append(stops, truth), ciphertext, short, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) < 5 {
+		t.Fatalf("%d of the %d stops hold, expected the false ones to hold too", len(candidates), len(stops)+1)
+	}
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score > candidates[i-1].Score {
+			t.Fatalf("candidate %d scores %f, more than the one before it", i, candidates[i].Score)
+		}
+	}
+	check(t, candidates[0], config)
+}
+
+// crib is the beginning of the message of TestResolveStop.
+const crib = "OBERKOMMANDOXDER"
+
+// message returns the configuration of the message and its ciphertext.
+func message(t *testing.T) (enigma.Config, string) {
+	config := enigma.Config{
+		Rotors: []enigma.RotorConfig{
+			{ID: "III", Start: 'K', Ring: 1},
+			{ID: "I", Start: 'D', Ring: 1},
+			{ID: "II", Start: 'R', Ring: 1},
+		},
+		Reflector: enigma.ReflectorConfig{ID: "B"},
+		Plugboard: []string{"AQ", "BJ", "CP", "DV", "FY", "GW", "HL", "IU", "NS", "TZ"},
+	}
+	e, err := enigma.EnigmaI.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config, e.EncodeString(plaintext)
+}
+
+const plaintext = crib + "XWEHRMACHTXGIBTXBEKANNTXDIEXFEINDLICHENXTRUPPENXHABENXDIEXSTADTXERREICHTXSTOPXALLEXEINHEITENXSOFORTXZURUECKZIEHENXNEUEXSTELLUNGXAMXFLUSSXBEZIEHENXMELDUNGXBISXMORGENXFRUEH"
+
+// check tells if the candidate is the configuration, with the whole
+// plugboard.
+func check(t *testing.T, candidate Candidate, config enigma.Config) {
+	t.Helper()
+	if candidate.Plaintext != plaintext {
+		t.Errorf("the best candidate, %v, decrypts to %s", candidate.Config, candidate.Plaintext)
+	}
+	plugboard := append([]string(nil), candidate.Config.Plugboard...)
+	sort.Strings(plugboard)
+	if !reflect.DeepEqual(plugboard, config.Plugboard) {
+		t.Errorf("the plugboard is %v, expected %v", plugboard, config.Plugboard)
+	}
+	for i, rotor := range candidate.Config.Rotors {
+		if rotor.ID != config.Rotors[i].ID || rotor.Start != config.Rotors[i].Start {
+			t.Errorf("rotor %d is %s at %c, expected %s at %c", i+1, rotor.ID, rotor.Start, config.Rotors[i].ID, config.Rotors[i].Start)
+		}
+	}
+}
+
+// A stop whose pairs contradict the crib doesn't hold, and neither do
+// stops that won't fit the ciphertext.
+func TestResolveStopErrors(t *testing.T) {
+	config := enigma.Config{
+		Rotors:    []enigma.RotorConfig{{ID: "I", Start: 'A', Ring: 1}, {ID: "II", Start: 'A', Ring: 1}, {ID: "III", Start: 'A', Ring: 1}},
+		Reflector: enigma.ReflectorConfig{ID: "B"},
+	}
+	e, err := enigma.EnigmaI.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := e.EncodeString("WETTERVORHERSAGE")
+	stop := Stop{Config: config, Model: &enigma.EnigmaI}
+	if _, err := ResolveStop(stop, ciphertext, "WETTER", 0); err != nil {
+		t.Errorf("the true stop doesn't hold: %v", err)
+	}
+	stop.Steckers = []string{"WE"}
+	if _, err := ResolveStop(stop, ciphertext, "WETTER", 0); err == nil {
+		t.Error("a stop plugging W to E holds")
+	}
+	if _, err := ResolveStop(Stop{Config: config}, ciphertext, "WETTERVORHERSAGEXX", 0); err == nil {
+		t.Error("a crib longer than the ciphertext is resolved")
+	}
+	if _, err := RankStops([]Stop{stop}, ciphertext, "WETTER", 0); err == nil {
+		t.Error("a false stop is ranked")
+	}
+}
@@ -0,0 +1,128 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Builder sets up a machine step by step, as an alternative to passing
+// options to the constructors:
+//
+//	e, err := enigma.Build().Model("M3").
+//		Rotors("III", "II", "I").Rings(22, 13, 5).Positions("QEV").
+//		Reflector("B").Plug("AB", "CD").Machine()
+//
+// Problems don't stop the chain: they are collected and reported all
+// at once by Machine. A builder can be used more than once, and every
+// machine it builds is independent from the others.
+type Builder struct {
+	model     *Model
+	rotors    []string
+	rings     []int
+	positions string
+	options   []Option
+	errs      []error
+}
+
+// Build starts a new builder for a generic machine (see NewMachine).
+func Build() *Builder {
+	return &Builder{model: &Generic}
+}
+
+// Model sets the model by its name, e.g. "M3".
+func (b *Builder) Model(name string) *Builder {
+	if model := KnownModels.GetByName(name); model != nil {
+		b.model = model
+	} else {
+		b.errs = append(b.errs, fmt.Errorf(`unknown model "%s"`, name))
+	}
+	return b
+}
+
+// Rotors sets the rotors by their IDs, from the leftmost to the
+// rightmost one.
+func (b *Builder) Rotors(ids ...string) *Builder {
+	b.rotors = ids
+	return b
+}
+
+// Rings sets the ring of each rotor, from 1 to 26. Rings default to 1.
+func (b *Builder) Rings(rings ...int) *Builder {
+	b.rings = rings
+	return b
+}
+
+// Positions sets the starting positions of the rotors, as letters or
+// numbers (see ParsePositions). Positions default to A.
+func (b *Builder) Positions(positions string) *Builder {
+	if _, err := ParsePositions(positions); err != nil {
+		b.errs = append(b.errs, err)
+	} else {
+		b.positions = positions
+	}
+	return b
+}
+
+// Reflector sets the reflector by its ID.
+func (b *Builder) Reflector(id string) *Builder {
+	return b.With(WithReflector(id))
+}
+
+// Plug adds plugboard pairs.
+func (b *Builder) Plug(pairs ...string) *Builder {
+	return b.With(func(c *Config) error {
+		c.Plugboard = append(c.Plugboard[:len(c.Plugboard):len(c.Plugboard)], pairs...)
+		return nil
+	})
+}
+
+// With adds options for everything else, e.g. the reflector position.
+func (b *Builder) With(options ...Option) *Builder {
+	b.options = append(b.options, options...)
+	return b
+}
+
+// Machine builds the machine, or reports everything that was wrong
+// along the way.
+func (b *Builder) Machine() (*Enigma, error) {
+	errs := append([]error(nil), b.errs...)
+	rotors := make([]RotorConfig, len(b.rotors))
+	for i, id := range b.rotors {
+		rotors[i] = RotorConfig{ID: id, Start: 'A', Ring: 1}
+	}
+	if b.rings != nil {
+		if len(b.rings) != len(rotors) {
+			errs = append(errs, fmt.Errorf("expected %d rings, got %d", len(rotors), len(b.rings)))
+		} else {
+			for i, ring := range b.rings {
+				rotors[i].Ring = ring
+			}
+		}
+	}
+	var config Config
+	options := append([]Option{WithRotors(rotors...)}, b.options...)
+	if b.positions != "" {
+		options = append(options, WithPositions(b.positions))
+	}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := b.model.Validate(config); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return b.model.New(config)
+}
+
+// CompatibleWith tells if the two machines are set up the same way, so
+// that they encode everything identically from now on.
+func (e *Enigma) CompatibleWith(other *Enigma) bool {
+	a, b := e.Config(), other.Config()
+	a.Display, b.Display = 0, 0
+	return reflect.DeepEqual(a, b)
+}
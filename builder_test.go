@@ -0,0 +1,68 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+// The builder builds the machine the options do.
+func TestBuilderCompatible(t *testing.T) {
+	built, err := Build().Model("M3").
+		Rotors("III", "II", "I").Rings(22, 13, 5).Positions("QEV").
+		Reflector("B").Plug("AB", "CD").Plug("EF").Machine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := NewEnigmaM3(WithRotors(
+		RotorConfig{ID: "III", Ring: 22, Start: 'Q'},
+		RotorConfig{ID: "II", Ring: 13, Start: 'E'},
+		RotorConfig{ID: "I", Ring: 5, Start: 'V'},
+	), WithReflector("B"), WithPlugboard("AB", "CD", "EF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !built.CompatibleWith(options) {
+		t.Errorf("the builder builds %s, the options %s", built.Config(), options.Config())
+	}
+	if a, b := built.EncodeString("WETTERBERICHT"), options.EncodeString("WETTERBERICHT"); a != b {
+		t.Errorf("the built machine encodes to %s, the one of the options to %s", a, b)
+	}
+}
+
+// A builder can be used again, and the machines don't share anything.
+func TestBuilderReuse(t *testing.T) {
+	b := Build().Rotors("I", "II", "III").Reflector("B")
+	first, err := b.Machine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.EncodeString("AAAAAAAAAA")
+	first.Rotors[0].Turnover = append(first.Rotors[0].Turnover, 3)
+	second, err := b.Machine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Positions() != "AAA" || len(second.Rotors[0].Turnover) != 1 {
+		t.Errorf("the second machine is at %s with notches %v, expected AAA with one", second.Positions(), second.Rotors[0].Turnover)
+	}
+	third, err := b.Plug("AB").Positions("QEV").Machine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := third.Config().String(); got != "B I II III 01 01 01 QEV AB" {
+		t.Errorf("the builder changed afterwards builds %s", got)
+	}
+}
+
+// Everything wrong along the chain is reported at once.
+func TestBuilderErrors(t *testing.T) {
+	_, err := Build().Model("M5").Rotors("I", "II", "III").Rings(1, 2).Positions("Q!V").Reflector("B").Machine()
+	if err == nil {
+		t.Fatal("the machine is built")
+	}
+	for _, want := range []string{`unknown model "M5"`, "expected 3 rings, got 2", `rotor positions should be letters or numbers, got "Q!V"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q doesn't report %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package enigma
+
+// bulkMinimum is how many letters EncodeBytes takes before it builds
+// the tables of the rotors, rather than stepping through them.
+const bulkMinimum = 256
+
+// bulkTables are the rotors of a machine at every offset, in both
+// directions, the ring taken into account: forward[i*676+s*26+x] is what
+// rotor i turns x into at the shift s, its offset less its ring.
+type bulkTables struct {
+	forward, reverse []uint8
+	shifts           []int
+}
+
+// shift works out the shifts of the rotors where they are now.
+func (t *bulkTables) shift(rotors []*Rotor) {
+	for i, rotor := range rotors {
+		t.shifts[i] = i*676 + mod26(rotor.Offset-rotor.Ring)*26
+	}
+}
+
+// build makes the tables of the rotors as they are wired now.
+func (t *bulkTables) build(rotors []*Rotor) {
+	if len(t.shifts) != len(rotors) {
+		t.forward = make([]uint8, len(rotors)*26*26)
+		t.reverse = make([]uint8, len(rotors)*26*26)
+		t.shifts = make([]int, len(rotors))
+	}
+	for i, rotor := range rotors {
+		for s := 0; s < 26; s++ {
+			for x := 0; x < 26; x++ {
+				t.forward[i*676+s*26+x] = uint8(mod26(rotor.StraightSeq[mod26(x+s)] - s))
+				t.reverse[i*676+s*26+x] = uint8(mod26(rotor.ReverseSeq[mod26(x+s)] - s))
+			}
+		}
+	}
+}
+
+// EncodeBytes encodes the letters of src, upper or lower case, into dst
+// as capitals, skipping everything else, and returns how many it wrote.
+// It stops when dst is full, so a dst as long as src always takes all
+// of it. Nothing is allocated on the way but the double steps StepBack
+// remembers, unless the machine keeps a transcript or has a stepping
+// mechanism of its own. For a lot of text, the rotors are looked up in
+// tables, which the machine keeps for the next time, rather than
+// stepped through.
+func (e *Enigma) EncodeBytes(dst, src []byte) int {
+	e.mustBeSealed()
+	started := metricsStart()
+	var tables *bulkTables
+	if len(src) >= bulkMinimum {
+		if e.bulk == nil {
+			e.bulk = &bulkTables{}
+		}
+		tables = e.bulk
+		tables.build(e.Rotors)
+	}
+	c := e.circuit()
+	n := 0
+	for _, letter := range src {
+		if n == len(dst) {
+			break
+		}
+		if letter >= 'a' && letter <= 'z' {
+			letter -= 'a' - 'A'
+		}
+		if letter < 'A' || letter > 'Z' {
+			continue
+		}
+		dst[n] = e.keypress(letter, &c, tables, false)
+		n++
+	}
+	if n > 0 {
+		e.logEncode(n)
+		observe("enigma.encode", 1, n, started)
+	}
+	return n
+}
@@ -0,0 +1,88 @@
+package enigma
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// EncodeBytes looks the rotors up in its tables for a lot of text, and
+// lights the same lamps as a key at a time.
+func TestEncodeBytesTables(t *testing.T) {
+	plaintext := strings.Repeat("ANGRIFFIMMORGENGRAUENXWETTERBERICHTX", 40)
+	models := []*Model{&EnigmaI, &M4, &EnigmaT, &EnigmaG312, &EnigmaKD}
+	for _, model := range models {
+		for seed := int64(0); seed < 5; seed++ {
+			settings, err := GenerateRandomConfig(model, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := settings.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := e.Clone().EncodeString(plaintext)
+			got := make([]byte, len(plaintext))
+			e.EncodeBytes(got, []byte(plaintext))
+			if string(got) != want {
+				t.Errorf("%s: EncodeBytes encodes %s to %s, expected %s", settings, plaintext, got, want)
+			}
+		}
+	}
+}
+
+// Lowercase letters are encoded as capitals and anything else skipped,
+// short texts and long ones alike, and a dst too short stops the
+// encoding where it's full, with the rotors stepped for what was.
+func TestEncodeBytes(t *testing.T) {
+	for _, n := range []int{20, 2000} {
+		plaintext := strings.Repeat("Wetter-Bericht 1940! ", n/20)
+		letters := strings.ToUpper(strings.NewReplacer("-", "", " ", "", "1", "", "9", "", "4", "", "0", "", "!", "").Replace(plaintext))
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := e.Clone()
+		want := c.EncodeString(letters)
+		dst := make([]byte, len(plaintext))
+		if got := e.EncodeBytes(dst, []byte(plaintext)); got != len(letters) || string(dst[:got]) != want {
+			t.Errorf("%d letters: EncodeBytes writes %d, %s, expected %s", len(letters), got, dst[:got], want)
+		}
+		if e.Positions() != c.Positions() {
+			t.Errorf("%d letters: the rotors are at %s, expected %s", len(letters), e.Positions(), c.Positions())
+		}
+		short := make([]byte, 7)
+		e.Reset()
+		if got := e.EncodeBytes(short, []byte(plaintext)); got != 7 || string(short) != want[:7] || e.Positions() != "AAH" {
+			t.Errorf("%d letters: 7 of them encode to %d, %s, with the rotors at %s", len(letters), got, short, e.Positions())
+		}
+	}
+}
+
+// Once the tables are built, encoding a lot of text doesn't allocate:
+// the only thing that would is a double step, for StepBack to remember,
+// and the middle rotor, set just past its notch, doesn't get to one.
+func TestEncodeBytesAllocations(t *testing.T) {
+	config := classicConfig()
+	config.Rotors = rotorsAt("I II III", "AFA")
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte(strings.Repeat(barbarossa.plaintext, 3))
+	dst := make([]byte, len(src))
+	e.EncodeBytes(dst, src)
+	if allocs := testing.AllocsPerRun(50, func() {
+		// Reset would clear the statistics, which are made again on
+		// the next keypress, so the rotors are only turned back.
+		for i, rotor := range e.Rotors {
+			rotor.Offset = e.start[i]
+		}
+		e.EncodeBytes(dst, src)
+	}); allocs != 0 {
+		t.Errorf("EncodeBytes allocates %v times", allocs)
+	}
+	if len(src) < bulkMinimum || len(e.doubles) != 0 {
+		t.Errorf("%d letters, encoded with %d double steps, don't make the test", len(src), len(e.doubles))
+	}
+}
@@ -0,0 +1,119 @@
+package enigma
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Capabilities describe what can be set on a model, e.g. to build a
+// configuration form: the number of slots (0 for any), the rotors each
+// slot takes, from the left (for any number of slots, every slot takes
+// all of them), which slots never step (counting from 1), the
+// reflectors, whether the reflector can be set to a position or rewired
+// (the UKW-D, which then needs its pairs), the entry wheels to choose
+// from, whether there's a plugboard and its limit (0 for none), the
+// rules for picking the rotors (see SelectionRule), and how the
+// positions are shown.
+type Capabilities struct {
+	Model             string      `json:"model"`
+	Slots             int         `json:"slots"`
+	SlotRotors        [][]string  `json:"slotRotors,omitempty"`
+	Rotors            []string    `json:"rotors"`
+	FixedSlots        []int       `json:"fixedSlots,omitempty"`
+	Reflectors        []string    `json:"reflectors"`
+	SettableReflector bool        `json:"settableReflector,omitempty"`
+	UKWD              bool        `json:"ukwd,omitempty"`
+	EntryWheels       []string    `json:"entryWheels,omitempty"`
+	Plugboard         bool        `json:"plugboard"`
+	MaxPlugPairs      int         `json:"maxPlugPairs,omitempty"`
+	Rules             []string    `json:"rules,omitempty"`
+	Display           DisplayMode `json:"display,omitempty"`
+}
+
+// ModelCapabilities returns the capabilities of one of the KnownModels
+// by its name, e.g. "M4".
+func ModelCapabilities(name string) (Capabilities, error) {
+	model := KnownModels.GetByName(name)
+	if model == nil {
+		return Capabilities{}, fmt.Errorf(`unknown model "%s"`, name)
+	}
+	return model.Capabilities(), nil
+}
+
+// Capabilities returns what can be set on the model.
+func (m *Model) Capabilities() Capabilities {
+	c := Capabilities{
+		Model:             m.Name,
+		Slots:             m.Slots,
+		Rotors:            rotorIDs(m.Rotors),
+		SettableReflector: m.SettableReflector,
+		UKWD:              m.UKWD,
+		Plugboard:         m.Plugboard,
+		MaxPlugPairs:      m.MaxPlugPairs,
+	}
+	for _, rule := range m.Rules {
+		c.Rules = append(c.Rules, rule.Description)
+	}
+	for slot := 0; slot < m.Slots; slot++ {
+		c.SlotRotors = append(c.SlotRotors, m.slotRotors(slot))
+	}
+	for _, slot := range m.FixedSlots {
+		c.FixedSlots = append(c.FixedSlots, slot+1)
+	}
+	for _, reflector := range m.Reflectors {
+		c.Reflectors = append(c.Reflectors, reflector.ID)
+	}
+	if m.UKWD {
+		c.Reflectors = append(c.Reflectors, UKWD)
+	}
+	sort.Strings(c.Reflectors)
+	if m.EntryWheel != "" {
+		c.EntryWheels = []string{m.EntryWheel}
+	} else {
+		for _, wheel := range historicEntryWheels {
+			c.EntryWheels = append(c.EntryWheels, wheel.ID)
+		}
+	}
+	return c
+}
+
+// Capabilities returns what can be set on the model the machine was
+// built as, the generic one if it wasn't built from a model, with the
+// display mode of the machine.
+func (e *Enigma) Capabilities() Capabilities {
+	model := e.model
+	if model == nil {
+		model = &Generic
+	}
+	c := model.Capabilities()
+	c.Display = e.Display
+	return c
+}
+
+// slotRotors returns the IDs of the rotors the slot takes. On models
+// with fixed slots, those take the rotors without notches, like Beta and
+// Gamma of the M4, and the stepping slots take the rest.
+func (m *Model) slotRotors(slot int) []string {
+	if len(m.FixedSlots) == 0 {
+		return rotorIDs(m.Rotors)
+	}
+	var ids []string
+	for _, rotor := range m.Rotors {
+		if (len(rotor.Turnover) == 0) == m.isFixed(slot) {
+			ids = append(ids, rotor.ID)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return rotorBefore(ids[i], ids[j]) })
+	return ids
+}
+
+// rotorIDs returns the IDs of the rotors, in the order of
+// AvailableRotors.
+func rotorIDs(rotors Rotors) []string {
+	ids := make([]string, len(rotors))
+	for i, rotor := range rotors {
+		ids[i] = rotor.ID
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return rotorBefore(ids[i], ids[j]) })
+	return ids
+}
@@ -0,0 +1,68 @@
+package enigma
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The Greek wheels, Beta and Gamma, only go in the fixed slot of the
+// M4, at the left (the fourth rotor, counting the way the rotors step),
+// and the other slots take the eight others.
+func TestM4Capabilities(t *testing.T) {
+	c, err := ModelCapabilities("M4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stepping := []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII"}
+	want := [][]string{{"Beta", "Gamma"}, stepping, stepping, stepping}
+	if c.Slots != 4 || !reflect.DeepEqual(c.SlotRotors, want) || !reflect.DeepEqual(c.FixedSlots, []int{1}) {
+		t.Errorf("the M4 has %d slots taking %v, fixed %v", c.Slots, c.SlotRotors, c.FixedSlots)
+	}
+	if !reflect.DeepEqual(c.Reflectors, []string{"B-thin", "C-thin"}) || !c.Plugboard || c.MaxPlugPairs != 10 || c.SettableReflector || c.UKWD {
+		t.Errorf("the M4 is capable of %+v", c)
+	}
+	for _, rotors := range []string{"I Beta II III", "Beta Gamma II III"} {
+		if _, err := NewEnigmaM4(WithRotors(rotorsAt(rotors, "AAAA")...), WithReflector("B-thin")); err == nil {
+			t.Errorf("the M4 takes %s", rotors)
+		}
+	}
+}
+
+// Each model's capabilities come from its definition, and a machine has
+// those of the model it was built as, with its display.
+func TestCapabilities(t *testing.T) {
+	for _, model := range KnownModels {
+		c, err := ModelCapabilities(model.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Model != model.Name || c.Slots != model.Slots || c.Plugboard != model.Plugboard || c.MaxPlugPairs != model.MaxPlugPairs || len(c.SlotRotors) != model.Slots {
+			t.Errorf("%s is capable of %+v", model.Name, c)
+		}
+		if model.EntryWheel != "" && !reflect.DeepEqual(c.EntryWheels, []string{model.EntryWheel}) {
+			t.Errorf("%s takes the entry wheels %v, expected %s", model.Name, c.EntryWheels, model.EntryWheel)
+		}
+	}
+	c, _ := ModelCapabilities("I")
+	if want := []string{"I", "II", "III", "IV", "V"}; !reflect.DeepEqual(c.SlotRotors, [][]string{want, want, want}) || len(c.FixedSlots) != 0 {
+		t.Errorf("the Enigma I takes %v, fixed %v", c.SlotRotors, c.FixedSlots)
+	}
+	if _, err := ModelCapabilities("Z"); err == nil {
+		t.Error("model Z is capable of something")
+	}
+
+	e, err := NewEnigmaM4(WithRotors(rotorsAt("Beta I II III", "AAAA")...), WithReflector("B-thin"), WithDisplay(DisplayNumbers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := e.Capabilities(); c.Model != "M4" || c.Display != DisplayNumbers {
+		t.Errorf("the M4 machine is capable of %+v", c)
+	}
+	e, err = NewMachine(WithRotors(rotorsAt("I II", "AA")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := e.Capabilities(); c.Model != Generic.Name || c.Slots != 0 || c.SlotRotors != nil {
+		t.Errorf("the generic machine is capable of %+v", c)
+	}
+}
@@ -0,0 +1,200 @@
+package enigma
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RotorFamilies returns a copy of the registry of rotors, by the family
+// they belong to. The registry is what SaveCatalogue writes and
+// LoadCatalogue adds to; changing the copy leaves it as it was.
+func RotorFamilies() map[string]Rotors {
+	families := make(map[string]Rotors, len(rotorFamilies))
+	for family, rotors := range rotorFamilies {
+		families[family] = rotors.Copy()
+	}
+	return families
+}
+
+var rotorFamilies = map[string]*Rotors{
+	"historic": &historicRotors,
+	"tirpitz":  &tirpitzRotors,
+	"D":        &enigmaDRotors,
+	"K":        &enigmaKRotors,
+	"KD":       &kdRotors,
+	"G312":     &enigmaG312Rotors,
+	"G260":     &enigmaG260Rotors,
+}
+
+// CatalogueEntry is a rotor as written in a catalogue: its family, ID,
+// wiring (the letters the contacts from A to Z are wired to), and the
+// notches.
+type CatalogueEntry struct {
+	Family  string `json:"family"`
+	ID      string `json:"id"`
+	Wiring  string `json:"wiring"`
+	Notches string `json:"notches"`
+}
+
+// ConflictPolicy tells LoadCatalogue what to do with a rotor that's
+// already in the registry.
+type ConflictPolicy int
+
+// Conflict policies.
+const (
+	ConflictSkip ConflictPolicy = iota
+	ConflictOverwrite
+	ConflictError
+)
+
+// Catalogue returns all the rotors of the registry, in the order of
+// AvailableRotors.
+func Catalogue() []CatalogueEntry {
+	return AvailableRotors()
+}
+
+// SaveCatalogue writes the registry as "csv" (with a header row) or as
+// "json" (an array of entries).
+func SaveCatalogue(w io.Writer, format string) error {
+	entries := Catalogue()
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"family", "id", "wiring", "notches"})
+		for _, entry := range entries {
+			writer.Write([]string{entry.Family, entry.ID, entry.Wiring, entry.Notches})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf(`unknown catalogue format "%s", use "csv" or "json"`, format)
+}
+
+// LoadCatalogue reads a catalogue, in either format, and adds it to the
+// registry; new families are created as needed. Every entry is checked
+// first, and nothing is added if any of them is wrong, the error telling
+// the row (counting the CSV header) or the index in the JSON array.
+func LoadCatalogue(r io.Reader, policy ConflictPolicy) error {
+	reader := bufio.NewReader(r)
+	entries, err := readCatalogue(reader)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if policy != ConflictError || rotorFamilies[entry.Family] == nil {
+			continue
+		}
+		if rotorFamilies[entry.Family].GetByID(entry.ID) != nil {
+			return fmt.Errorf(`rotor "%s" of family "%s" is already registered`, entry.ID, entry.Family)
+		}
+	}
+	for _, entry := range entries {
+		rotor := NewRotor(entry.Wiring, entry.ID, entry.Notches)
+		family := rotorFamilies[entry.Family]
+		if family == nil {
+			family = &Rotors{}
+			rotorFamilies[entry.Family] = family
+		}
+		replaced := false
+		for i := range *family {
+			if (*family)[i].ID == entry.ID {
+				if policy == ConflictOverwrite {
+					(*family)[i] = *rotor
+				}
+				replaced = true
+			}
+		}
+		if !replaced {
+			*family = append(*family, *rotor)
+		}
+	}
+	return nil
+}
+
+// readCatalogue reads and checks the entries, telling the format by
+// the first character.
+func readCatalogue(reader *bufio.Reader) ([]CatalogueEntry, error) {
+	var entries []CatalogueEntry
+	first, err := firstNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+	if first == '[' {
+		if err := json.NewDecoder(reader).Decode(&entries); err != nil {
+			return nil, err
+		}
+		for i, entry := range entries {
+			if err := entry.validate(); err != nil {
+				return nil, fmt.Errorf("catalogue entry %d: %w", i, err)
+			}
+		}
+		return entries, nil
+	}
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "family" {
+			continue
+		}
+		if len(record) != 4 {
+			return nil, fmt.Errorf("catalogue row %d: expected 4 fields, got %d", i+1, len(record))
+		}
+		entry := CatalogueEntry{record[0], record[1], record[2], record[3]}
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("catalogue row %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// firstNonSpace peeks at the first character that isn't whitespace.
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if strings.TrimSpace(string(b)) != "" {
+			return b[0], nil
+		}
+		reader.ReadByte()
+	}
+}
+
+// validate checks that the entry is a rotor that can be built.
+func (entry CatalogueEntry) validate() error {
+	if entry.Family == "" || entry.ID == "" {
+		return fmt.Errorf("rotor family and ID are required")
+	}
+	var used [26]bool
+	for i := 0; i < len(entry.Wiring); i++ {
+		letter := entry.Wiring[i]
+		if letter < 'A' || letter > 'Z' || used[CharToIndex(letter)] {
+			return fmt.Errorf(`rotor "%s" should be wired to every letter once, check "%c"`, entry.ID, letter)
+		}
+		used[CharToIndex(letter)] = true
+	}
+	if len(entry.Wiring) != 26 {
+		return fmt.Errorf(`rotor "%s" should be wired to 26 letters, got %d`, entry.ID, len(entry.Wiring))
+	}
+	for i := 0; i < len(entry.Notches); i++ {
+		if entry.Notches[i] < 'A' || entry.Notches[i] > 'Z' {
+			return fmt.Errorf(`rotor "%s" notches should be letters, got "%s"`, entry.ID, entry.Notches)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package enigma
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// keepRegistry returns a function putting the registry back the way it
+// is now.
+func keepRegistry() func() {
+	saved := map[string]Rotors{}
+	for family, rotors := range rotorFamilies {
+		saved[family] = append(Rotors(nil), *rotors...)
+	}
+	return func() {
+		for family := range rotorFamilies {
+			if _, ok := saved[family]; !ok {
+				delete(rotorFamilies, family)
+			}
+		}
+		for family, rotors := range saved {
+			*rotorFamilies[family] = rotors
+		}
+	}
+}
+
+// The registry saved, cleared, and loaded again is what it was, in
+// either format.
+func TestCatalogueRoundTrip(t *testing.T) {
+	defer keepRegistry()()
+	want := Catalogue()
+	for _, format := range []string{"csv", "json"} {
+		var saved bytes.Buffer
+		if err := SaveCatalogue(&saved, format); err != nil {
+			t.Fatal(err)
+		}
+		for _, rotors := range rotorFamilies {
+			*rotors = nil
+		}
+		if got := Catalogue(); len(got) != 0 {
+			t.Fatalf("%s: %d rotors left in the cleared registry", format, len(got))
+		}
+		if err := LoadCatalogue(&saved, ConflictError); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got := Catalogue(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: the loaded registry is\n%v\nexpected\n%v", format, got, want)
+		}
+	}
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.EncodeString("AAAAA"); got != "BDZGO" {
+		t.Errorf("the reloaded rotors encode AAAAA to %s, expected BDZGO", got)
+	}
+	if err := SaveCatalogue(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("the catalogue is saved as XML")
+	}
+}
+
+// A bad entry is reported by its row, and nothing of the catalogue is
+// loaded.
+func TestLoadCorruptCatalogue(t *testing.T) {
+	defer keepRegistry()()
+	before := Catalogue()
+	tests := []struct {
+		catalogue, want string
+	}{
+		{"" +
+			"family,id,wiring,notches\n" +
+			"lab,X1,ABCDEFGHIJKLMNOPQRSTUVWXYZ,Q\n" +
+			"lab,X2,ABCDEFGHIJKLMNOPQRSTUVWXYA,Q\n",
+			"catalogue row 3"},
+		{"lab,X1,ABCDEFGHIJKLMNOPQRSTUVWXYZ,Q\nlab,X2,ABC,Q\n", "catalogue row 2"},
+		{"lab,X1,ABCDEFGHIJKLMNOPQRSTUVWXYZ\n", "catalogue row 1: expected 4 fields"},
+		{"lab,X1,ABCDEFGHIJKLMNOPQRSTUVWXYZ,1\n", "catalogue row 1"},
+		{`[{"family": "lab", "id": "X1", "wiring": "ABCDEFGHIJKLMNOPQRSTUVWXYZ"}, {"id": "X2"}]`, "catalogue entry 1"},
+	}
+	for _, tt := range tests {
+		err := LoadCatalogue(strings.NewReader(tt.catalogue), ConflictOverwrite)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q is loaded with %v, expected %s", tt.catalogue, err, tt.want)
+		}
+	}
+	if got := Catalogue(); !reflect.DeepEqual(got, before) {
+		t.Error("the corrupt catalogues changed the registry")
+	}
+}
+
+func TestLoadCatalogueConflicts(t *testing.T) {
+	defer keepRegistry()()
+	original := *historicRotors.GetByID("I")
+	catalogue := "historic,I,ABCDEFGHIJKLMNOPQRSTUVWXYZ,A\nlab,X1,BADCFEHGJILKNMPORQTSVUXWZY,\n"
+	if err := LoadCatalogue(strings.NewReader(catalogue), ConflictError); err == nil {
+		t.Error("rotor I is registered twice")
+	}
+	if rotorFamilies["lab"] != nil {
+		t.Error("the conflicting catalogue added a family")
+	}
+	if err := LoadCatalogue(strings.NewReader(catalogue), ConflictSkip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*historicRotors.GetByID("I"), original) || rotorFamilies["lab"].GetByID("X1") == nil {
+		t.Error("skipping the conflict didn't keep rotor I and add X1")
+	}
+	if err := LoadCatalogue(strings.NewReader(catalogue), ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	if wiring, notches := historicRotors.GetByID("I").wiring(); wiring != "ABCDEFGHIJKLMNOPQRSTUVWXYZ" || notches != "A" {
+		t.Errorf("rotor I is overwritten with %s notched at %s", wiring, notches)
+	}
+	if len(*rotorFamilies["lab"]) != 1 {
+		t.Errorf("rotor X1 is registered %d times", len(*rotorFamilies["lab"]))
+	}
+}
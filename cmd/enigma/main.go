@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/template"
@@ -18,11 +20,22 @@ type CLIOpts struct {
 	Condensed bool `cli:"c,condensed" name:"false" usage:"Output the result without additional information."`
 
 	Rotors    []string `cli:"rotors" name:"I II III" usage:"Rotor configuration. Supported: I, II, III, IV, V, VI, VII, VIII, Beta, Gamma."`
-	Rings     []int    `cli:"rings" name:"1 1 1" usage:"Rotor rings offset: from 1 (default) to 26 for each rotor."`
-	Position  []string `cli:"position" name:"A A A" usage:"Starting position of the rotors: from A (default) to Z for each."`
-	Plugboard []string `cli:"plugboard" name:"[]" usage:"Optional plugboard pairs to scramble the message further."`
+	Rings     []string `cli:"rings" name:"1 1 1" usage:"Rotor rings offset: from 1 (default) to 26, or from A to Z, for each rotor."`
+	Position  []string `cli:"position" name:"A A A" usage:"Starting position of the rotors: from A (default) to Z, or from 1 to 26, for each."`
+	Plugboard []string `cli:"plugboard" name:"[]" usage:"Optional plugboard pairs to scramble the message further, or a preset like @barbarossa-1941-07-07."`
 
 	Reflector string `cli:"reflector" name:"C" usage:"Reflector. Supported: A, B, C, B-Thin, C-Thin."`
+
+	Config string `cli:"config" name:"machine.json" usage:"Machine configuration file in JSON (see enigma.Config), instead of the parameters above."`
+	Input  string `cli:"i,input" name:"file" usage:"Read the text from a file, or from standard input with -, and write just the result."`
+}
+
+// TablesOpts are the parameters of the tables command: the machine, and
+// how many keypresses to write the alphabets of, in which format.
+type TablesOpts struct {
+	CLIOpts
+	N      int    `cli:"n" dft:"26" usage:"Number of keypresses."`
+	Format string `cli:"format" dft:"csv" usage:"Output format: csv or json."`
 }
 
 // CLIDefaults is used to populate default values in case
@@ -31,12 +44,12 @@ type CLIOpts struct {
 // rotors if not set explicitly, so only one value is stored.
 var CLIDefaults = struct {
 	Reflector string
-	Ring      int
+	Ring      string
 	Position  string
 	Rotors    []string
 }{
 	Reflector: "B",
-	Ring:      1,
+	Ring:      "1",
 	Position:  "A",
 	Rotors:    []string{"I", "II", "III"},
 }
@@ -67,44 +80,174 @@ func SetDefaults(argv *CLIOpts) {
 	}
 }
 
+// newMachine sets up the Enigma from the command line parameters, or
+// from the configuration file.
+func newMachine(argv *CLIOpts) (*enigma.Enigma, error) {
+	if argv.Config != "" {
+		data, err := os.ReadFile(argv.Config)
+		if err != nil {
+			return nil, err
+		}
+		var config enigma.Config
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("%s: %v", argv.Config, err)
+		}
+		return enigma.Generic.New(config)
+	}
+	config := make([]enigma.RotorConfig, len(argv.Rotors))
+	for index, rotor := range argv.Rotors {
+		rings, _ := enigma.ParseRings(argv.Rings[index])
+		ring := rings[0]
+		offsets, _ := enigma.ParsePositions(argv.Position[index])
+		value := enigma.IndexToChar(offsets[0])
+		config[index] = enigma.RotorConfig{ID: rotor, Start: value, Ring: ring}
+	}
+	return enigma.NewEnigma(config, argv.Reflector, argv.Plugboard), nil
+}
+
 func main() {
 
 	cli.SetUsageStyle(cli.DenseManualStyle)
-	cli.Run(new(CLIOpts), func(ctx *cli.Context) error {
-		argv := ctx.Argv().(*CLIOpts)
-		originalPlaintext := strings.Join(ctx.Args(), " ")
-		plaintext := enigma.SanitizePlaintext(originalPlaintext)
-
-		if argv.Help || len(plaintext) == 0 {
-			com := ctx.Command()
-			com.Text = DescriptionTemplate
-			ctx.String(com.Usage(ctx))
+	root := &cli.Command{
+		Name: os.Args[0],
+		Argv: func() interface{} { return new(CLIOpts) },
+		Fn:   encode,
+	}
+	repl := &cli.Command{
+		Name: "repl",
+		Desc: "Type into the machine, one keypress at a time (:help for commands)",
+		Argv: func() interface{} { return new(CLIOpts) },
+		Fn: func(ctx *cli.Context) error {
+			e, err := newMachine(ctx.Argv().(*CLIOpts))
+			if err != nil {
+				return err
+			}
+			terminal := struct {
+				io.Reader
+				io.Writer
+			}{os.Stdin, os.Stdout}
+			return enigma.RunREPL(terminal, e, enigma.REPLOptions{Lampboard: true})
+		},
+	}
+	list := &cli.Command{
+		Name: "list",
+		Desc: "List the rotors, by family, and the reflectors",
+		Fn: func(ctx *cli.Context) error {
+			for _, rotor := range enigma.AvailableRotors() {
+				fmt.Printf("rotor      %-9s %-6s %s %s\n", rotor.Family, rotor.ID, rotor.Wiring, rotor.Notches)
+			}
+			for _, reflector := range enigma.AvailableReflectors() {
+				fmt.Printf("reflector  %s\n", reflector)
+			}
 			return nil
-		}
-
-		config := make([]enigma.RotorConfig, len(argv.Rotors))
-		for index, rotor := range argv.Rotors {
-			ring := argv.Rings[index]
-			value := argv.Position[index][0]
-			config[index] = enigma.RotorConfig{ID: rotor, Start: value, Ring: ring}
-		}
+		},
+	}
+	verify := &cli.Command{
+		Name: "verify",
+		Desc: "Check a transcribed transmission (a file, or standard input) before decrypting it",
+		Fn: func(ctx *cli.Context) error {
+			var in io.Reader = os.Stdin
+			if len(ctx.Args()) > 0 {
+				f, err := os.Open(ctx.Args()[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			transmission, err := io.ReadAll(in)
+			if err != nil {
+				return err
+			}
+			issues := enigma.VerifyTranscription(string(transmission))
+			for _, issue := range issues {
+				fmt.Println(issue)
+			}
+			if len(issues) > 0 {
+				return fmt.Errorf("%d issues found", len(issues))
+			}
+			fmt.Println("the transcription looks fine")
+			return nil
+		},
+	}
+	tables := &cli.Command{
+		Name: "tables",
+		Desc: "Write the alphabet the machine substitutes with for every keypress",
+		Argv: func() interface{} { return new(TablesOpts) },
+		Fn: func(ctx *cli.Context) error {
+			argv := ctx.Argv().(*TablesOpts)
+			e, err := newMachine(&argv.CLIOpts)
+			if err != nil {
+				return err
+			}
+			series, err := enigma.SubstitutionSeries(e.Config(), argv.N)
+			if err != nil {
+				return err
+			}
+			return enigma.WriteSubstitutionSeries(os.Stdout, series, argv.Format)
+		},
+	}
+	if err := cli.Root(root, cli.Tree(repl), cli.Tree(list), cli.Tree(verify), cli.Tree(tables)).Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-		e := enigma.NewEnigma(config, argv.Reflector, argv.Plugboard)
-		encoded := e.EncodeString(plaintext)
+}
 
-		if argv.Condensed {
-			fmt.Print(encoded)
-			return nil
-		}
+// encode encodes the arguments and shows the result.
+func encode(ctx *cli.Context) error {
+	argv := ctx.Argv().(*CLIOpts)
+	if argv.Input != "" && !argv.Help {
+		return encodeInput(argv)
+	}
+	originalPlaintext := strings.Join(ctx.Args(), " ")
+	plaintext := enigma.SanitizePlaintext(originalPlaintext)
+
+	if argv.Help || len(plaintext) == 0 {
+		com := ctx.Command()
+		com.Text = DescriptionTemplate
+		ctx.String(com.Usage(ctx))
+		return nil
+	}
 
-		tmpl, _ := template.New("cli").Parse(OutputTemplate)
-		err := tmpl.Execute(os.Stdout, struct {
-			Original, Plain, Encoded string
-			Args                     *CLIOpts
-			Ctx                      *cli.Context
-		}{originalPlaintext, plaintext, encoded, argv, ctx})
+	e, err := newMachine(argv)
+	if err != nil {
 		return err
+	}
+	encoded := e.EncodeString(plaintext)
+
+	if argv.Condensed {
+		fmt.Print(encoded)
+		return nil
+	}
 
-	})
+	tmpl, _ := template.New("cli").Parse(OutputTemplate)
+	err = tmpl.Execute(os.Stdout, struct {
+		Original, Plain, Encoded string
+		Args                     *CLIOpts
+		Ctx                      *cli.Context
+	}{originalPlaintext, plaintext, encoded, argv, ctx})
+	return err
 
 }
+
+// encodeInput encodes the input file, or standard input, writing just
+// the result, so that the command can go in a pipeline.
+func encodeInput(argv *CLIOpts) error {
+	e, err := newMachine(argv)
+	if err != nil {
+		return err
+	}
+	in := os.Stdin
+	if argv.Input != "-" {
+		if in, err = os.Open(argv.Input); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	if _, err := e.EncodeStream(in, os.Stdout, enigma.StreamOptions{}); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
@@ -4,6 +4,7 @@ package main
 const DescriptionTemplate = `
 usage: enigma <text> [--rotors=I II III] [--rings=3 4 3] [--reflector=C]
                      [--plugboard=AB CD] [--position=A A A]
+                     [--config=machine.json] [--input=file]
 
 Enigma cipher machine emulator
 
@@ -32,8 +32,14 @@ func (argv *CLIOpts) Validate(ctx *cli.Context) error {
 }
 
 // ValidatePlugboard checks that all plugboard pairs are formatted correctly,
-// and letters in pairs do not repeat.
+// and letters in pairs do not repeat. A preset ("@name") is replaced with
+// its pairs first.
 func ValidatePlugboard(argv *CLIOpts, ctx *cli.Context) error {
+	pairs, err := enigma.ResolvePlugboard(argv.Plugboard)
+	if err != nil {
+		return err
+	}
+	argv.Plugboard = pairs
 	var plugboard string
 	for _, pair := range argv.Plugboard {
 		if matched, _ := regexp.MatchString(`^[A-Z]{2}$`, pair); !matched {
@@ -54,8 +60,9 @@ func ValidatePlugboard(argv *CLIOpts, ctx *cli.Context) error {
 // ValidateRotors checks that the requested rotors are present
 // in the pre-defined list.
 func ValidateRotors(argv *CLIOpts, ctx *cli.Context) error {
+	rotors := enigma.HistoricRotors()
 	for _, rotor := range argv.Rotors {
-		if r := enigma.HistoricRotors.GetByID(rotor); r == nil {
+		if r := rotors.GetByID(rotor); r == nil {
 			return fmt.Errorf(`unknown rotor "%s"`, ctx.Color().Yellow(rotor))
 		}
 	}
@@ -65,19 +72,20 @@ func ValidateRotors(argv *CLIOpts, ctx *cli.Context) error {
 // ValidateReflector checks that the requested reflector is present
 // in the pre-defined list.
 func ValidateReflector(argv *CLIOpts, ctx *cli.Context) error {
-	if r := enigma.HistoricReflectors.GetByID(argv.Reflector); r == nil {
+	reflectors := enigma.HistoricReflectors()
+	if r := reflectors.GetByID(argv.Reflector); r == nil {
 		return fmt.Errorf(`unknown reflector "%s"`, ctx.Color().Yellow(argv.Reflector))
 	}
 	return nil
 }
 
 // ValidatePosition checks that the rotor positions are in the right
-// range and format.
+// range and format: a letter (A-Z) or a number (1-26) for each rotor.
 func ValidatePosition(argv *CLIOpts, ctx *cli.Context) error {
 	for _, char := range argv.Position {
-		if matched, _ := regexp.MatchString(`^[A-Z]$`, char); !matched {
+		if offsets, err := enigma.ParsePositions(char); err != nil || len(offsets) != 1 {
 			return fmt.Errorf(
-				`rotor positions should be single letters in the A-Z range, got "%s"`,
+				`rotor positions should be single letters in the A-Z range or numbers from 1 to 26, got "%s"`,
 				ctx.Color().Yellow(char))
 		}
 	}
@@ -85,12 +93,12 @@ func ValidatePosition(argv *CLIOpts, ctx *cli.Context) error {
 }
 
 // ValidateRings checks that the rotor rings are in the right
-// range and format.
+// range and format: a number (1-26) or a letter (A-Z) for each rotor.
 func ValidateRings(argv *CLIOpts, ctx *cli.Context) error {
 	for _, ring := range argv.Rings {
-		if ring < 1 || ring > 26 {
+		if rings, err := enigma.ParseRings(ring); err != nil || len(rings) != 1 {
 			return fmt.Errorf(
-				`ring out of range: must be 1-26, got "%s"`,
+				`ring out of range: must be 1-26 or A-Z, got "%s"`,
 				ctx.Color().Yellow(ring))
 		}
 	}
@@ -0,0 +1,22 @@
+// Command enigmad serves Enigma machines over HTTP (see package enigmad).
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/emedvedev/enigma/enigmad"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8026", "Address to listen on.")
+	sessions := flag.Int("sessions", enigmad.DefaultMaxSessions, "Maximum number of sessions at once.")
+	idle := flag.Duration("idle", enigmad.DefaultIdleTimeout, "How long to keep a session without requests.")
+	flag.Parse()
+	server := enigmad.NewServer()
+	server.MaxSessions = *sessions
+	server.IdleTimeout = *idle
+	log.Printf("serving Enigma sessions on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
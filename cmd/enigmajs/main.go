@@ -0,0 +1,18 @@
+//go:build js && wasm
+
+// Command enigmajs runs the machines in the browser (see package
+// enigmajs). Build it, and copy the loader of the Go toolchain next to
+// it and to index.html:
+//
+//	GOOS=js GOARCH=wasm go build -o enigma.wasm ./cmd/enigmajs
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// Older toolchains have the loader in misc/wasm instead.
+package main
+
+import "github.com/emedvedev/enigma/enigmajs"
+
+func main() {
+	enigmajs.Register()
+	select {}
+}
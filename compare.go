@@ -0,0 +1,214 @@
+package enigma
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TraceFormat is the layout of the trace of another simulator, one
+// keypress per row: the Comma between the columns (a comma if not set),
+// whether the first row is a Header, the columns with the rotor windows
+// from the left (a single column like "ADU" or "01 04 21", or a column
+// per rotor), and the column of the Output letter, all counting from 0.
+// The windows are the ones shown after the key went down, the way
+// Transcript has them.
+type TraceFormat struct {
+	Comma     rune
+	Header    bool
+	Positions []int
+	Output    int
+}
+
+// Trace formats for the usual exports with a header row: the windows in
+// the first column, the output letter in the second one.
+var (
+	CSVTrace = TraceFormat{Comma: ',', Header: true, Positions: []int{0}, Output: 1}
+	TSVTrace = TraceFormat{Comma: '\t', Header: true, Positions: []int{0}, Output: 1}
+)
+
+// SignalStep is where the current is on its way through the machine: the
+// part it just went through, and the letter (the contact) it's at. Slot
+// is the one of the rotor (counting from 1), zero for the other parts.
+type SignalStep struct {
+	Part   string
+	Letter byte
+	Slot   int
+}
+
+// ComparisonReport tells how a trace of another simulator compares to
+// this machine: the number of keypresses compared and, if they went
+// differently, the first one that did (Index counts from 0), the key,
+// the lamps and the rotor windows of both machines, what was different,
+// and the path of the current through this machine at that keypress.
+type ComparisonReport struct {
+	Keypresses int
+	Diverged   bool
+
+	Index             int
+	Key               byte
+	Lamp              byte
+	ExternalLamp      byte
+	Positions         string
+	ExternalPositions string
+	Reason            string
+	Path              []SignalStep
+}
+
+// CompareTrace encodes the plaintext (its letters, see NonAlphaStrip)
+// with a machine of the configuration (see NewMachine) and compares
+// every keypress with the trace of another simulator, stopping at the
+// first one that's different. A trace that can't be read is an error;
+// one that is shorter or longer than the plaintext is a divergence.
+func CompareTrace(cfg Config, plaintext string, external io.Reader, format TraceFormat) (ComparisonReport, error) {
+	e, err := Generic.New(cfg)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	rows, err := readTrace(external, format)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	text, _, _ := Sanitize(plaintext, NonAlphaStrip)
+	var report ComparisonReport
+	for i := 0; i < len(text) || i < len(rows); i++ {
+		switch {
+		case i >= len(rows):
+			report.diverge(i, "the trace ends here")
+			report.Key = text[i]
+			return report, nil
+		case i >= len(text):
+			report.diverge(i, "the trace goes on past the plaintext")
+			report.ExternalLamp, report.ExternalPositions = rows[i].lamp, rows[i].positions
+			return report, nil
+		}
+		key, row := text[i], rows[i]
+		lamp := e.EncodeChar(key)
+		report.Keypresses++
+		offsets, err := ParsePositions(row.positions)
+		if err != nil {
+			return report, fmt.Errorf("trace row %d: %w", row.line, err)
+		}
+		reason := ""
+		switch {
+		case !sameOffsets(offsets, e.offsets()):
+			reason = "the rotors are at different positions"
+		case row.lamp != lamp:
+			reason = "a different lamp is lit"
+		}
+		if reason != "" {
+			report.diverge(i, reason)
+			report.Key, report.Lamp, report.ExternalLamp = key, lamp, row.lamp
+			report.Positions, report.ExternalPositions = e.Positions(), row.positions
+			report.Path = e.signalPath(CharToIndex(key))
+			return report, nil
+		}
+	}
+	return report, nil
+}
+
+// diverge records the keypress where the machines went differently.
+func (r *ComparisonReport) diverge(index int, reason string) {
+	r.Diverged, r.Index, r.Reason = true, index, reason
+}
+
+// traceRow is a keypress of an external trace.
+type traceRow struct {
+	line      int
+	positions string
+	lamp      byte
+}
+
+// readTrace reads the rows of an external trace.
+func readTrace(r io.Reader, format TraceFormat) ([]traceRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = format.Comma
+	if reader.Comma == 0 {
+		reader.Comma = ','
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if format.Header && len(records) > 0 {
+		records = records[1:]
+	}
+	rows := make([]traceRow, 0, len(records))
+	for i, record := range records {
+		line := i + 1
+		if format.Header {
+			line++
+		}
+		columns := append([]int{format.Output}, format.Positions...)
+		for _, column := range columns {
+			if column < 0 || column >= len(record) {
+				return nil, fmt.Errorf("trace row %d has no column %d", line, column)
+			}
+		}
+		output := strings.ToUpper(strings.TrimSpace(record[format.Output]))
+		if len(output) != 1 || output[0] < 'A' || output[0] > 'Z' {
+			return nil, fmt.Errorf(`trace row %d: output should be a letter, got "%s"`, line, output)
+		}
+		windows := make([]string, len(format.Positions))
+		for j, column := range format.Positions {
+			windows[j] = strings.TrimSpace(record[column])
+		}
+		rows = append(rows, traceRow{line, strings.Join(windows, " "), output[0]})
+	}
+	return rows, nil
+}
+
+// sameOffsets tells if the rotors are at the same offsets.
+func sameOffsets(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// signalPath returns the path of the current of the key through the
+// machine as it is, the same way it goes for a keypress once the rotors
+// have moved.
+func (e *Enigma) signalPath(letterIndex int) []SignalStep {
+	letters := []int{letterIndex}
+	c := e.circuit()
+	e.signal(letterIndex, &c, nil, func(_ part, letterIndex int) {
+		letters = append(letters, letterIndex)
+	})
+	parts := []string{"key"}
+	slots := make([]int, len(letters))
+	if e.Keyboard != nil {
+		parts = append(parts, "keyboard "+e.Keyboard.Layout)
+	}
+	plugboard := "plugboard"
+	if e.Uhr != nil {
+		plugboard = fmt.Sprintf("Uhr at %02d", e.Uhr.Position())
+	}
+	parts = append(parts, plugboard, "entry wheel "+e.EntryWheel.ID)
+	for i := len(e.Rotors) - 1; i >= 0; i-- {
+		slots[len(parts)] = i + 1
+		parts = append(parts, fmt.Sprintf("rotor %s in slot %d", e.Rotors[i].ID, i+1))
+	}
+	parts = append(parts, "reflector "+e.Reflector.ID)
+	for i := range e.Rotors {
+		slots[len(parts)] = i + 1
+		parts = append(parts, fmt.Sprintf("rotor %s in slot %d, back", e.Rotors[i].ID, i+1))
+	}
+	parts = append(parts, "entry wheel "+e.EntryWheel.ID+", back", plugboard+", back")
+	if e.Keyboard != nil {
+		parts = append(parts, "lamps "+e.Keyboard.Layout)
+	}
+	path := make([]SignalStep, len(letters))
+	for i, letter := range letters {
+		path[i] = SignalStep{parts[i], IndexToChar(letter), slots[i]}
+	}
+	return path
+}
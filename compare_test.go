@@ -0,0 +1,100 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+// doubleStepConfig is I II III at ADU, two keypresses from the double
+// step.
+func doubleStepConfig() Config {
+	config := classicConfig()
+	config.Rotors = rotorsAt("I II III", "ADU")
+	return config
+}
+
+// A trace that agrees is compared to the end, however its windows are
+// laid out.
+func TestCompareTraceAgrees(t *testing.T) {
+	tests := []struct {
+		name   string
+		trace  string
+		format TraceFormat
+	}{
+		{"CSV", "Position,Output\nAAB,B\nAAC,D\nAAD,Z\nAAE,G\nAAF,O\n", CSVTrace},
+		{"TSV", "Position\tOutput\naab\tb\naac\td\naad\tz\naae\tg\naaf\to\n", TSVTrace},
+		{"numbers", "Rotors,Lamp\n01 01 02,B\n01 01 03,D\n01 01 04,Z\n01 01 05,G\n01 01 06,O\n", CSVTrace},
+		{"a column per rotor", "Key;L;M;R;Lamp\nA;A;A;B;B\nA;A;A;C;D\nA;A;A;D;Z\nA;A;A;E;G\nA;A;A;F;O\n",
+			TraceFormat{Comma: ';', Header: true, Positions: []int{1, 2, 3}, Output: 4}},
+		{"no header", "AAB,B\nAAC,D\nAAD,Z\nAAE,G\nAAF,O\n", TraceFormat{Positions: []int{0}, Output: 1}},
+	}
+	for _, tt := range tests {
+		report, err := CompareTrace(classicConfig(), "aaa aa", strings.NewReader(tt.trace), tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if report.Diverged || report.Keypresses != 5 {
+			t.Errorf("%s: the trace compares as %+v", tt.name, report)
+		}
+	}
+}
+
+// A simulator without the double step goes differently on the third
+// keypress, and the report has both machines' windows and the path of
+// the current through this one.
+func TestCompareTraceDiverges(t *testing.T) {
+	trace := "Position,Output\nADV,O\nAEW,B\nAEX,Q\nAEY,F\n"
+	report, err := CompareTrace(doubleStepConfig(), "WETTER", strings.NewReader(trace), CSVTrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Diverged || report.Index != 2 || report.Keypresses != 3 || report.Reason != "the rotors are at different positions" {
+		t.Fatalf("the trace compares as %+v", report)
+	}
+	if report.Key != 'T' || report.Lamp != 'N' || report.ExternalLamp != 'Q' || report.Positions != "BFX" || report.ExternalPositions != "AEX" {
+		t.Errorf("the keypress is reported as %+v", report)
+	}
+	path := report.Path
+	if len(path) < 2 || path[0].Part != "key" || path[0].Letter != 'T' || path[len(path)-1].Letter != 'N' {
+		t.Errorf("the path is %+v", path)
+	}
+	tests := []struct {
+		trace  string
+		index  int
+		reason string
+	}{
+		{"Position,Output\nADV,O\nAEW,C\n", 1, "a different lamp is lit"},
+		{"Position,Output\nADV,O\nAEW,B\n", 2, "the trace ends here"},
+		{"Position,Output\nADV,O\nAEW,B\nBFX,N\nBFY,F\nBFZ,G\nBFA,H\nBFB,K\n", 6, "the trace goes on past the plaintext"},
+	}
+	for _, tt := range tests {
+		report, err := CompareTrace(doubleStepConfig(), "WETTER", strings.NewReader(tt.trace), CSVTrace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Diverged || report.Index != tt.index || report.Reason != tt.reason {
+			t.Errorf("%q compares as %+v, expected %s at %d", tt.trace, report, tt.reason, tt.index)
+		}
+	}
+}
+
+// Traces that can't be read are errors, with the row.
+func TestCompareTraceErrors(t *testing.T) {
+	tests := []struct {
+		trace string
+		want  string
+	}{
+		{"Position,Output\nADV,1\n", `trace row 2: output should be a letter, got "1"`},
+		{"Position,Output\nADV\n", "trace row 2 has no column 1"},
+		{"Position,Output\nAD?,O\n", "trace row 2: "},
+	}
+	for _, tt := range tests {
+		_, err := CompareTrace(doubleStepConfig(), "WETTER", strings.NewReader(tt.trace), CSVTrace)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q is read with %v, expected %s", tt.trace, err, tt.want)
+		}
+	}
+	if _, err := CompareTrace(Config{}, "WETTER", strings.NewReader(""), CSVTrace); err == nil {
+		t.Error("the trace is compared with no machine at all")
+	}
+}
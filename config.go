@@ -0,0 +1,432 @@
+package enigma
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Config is the complete configuration of a machine, i.e. everything an
+// operator would set up from the key sheet: rotors with their rings and
+// starting positions, the reflector, the entry wheel, and the plugboard.
+// MaxPlugPairs limits the number of plugboard cables, zero meaning
+// there's no limit other than the one of the model, Display sets how
+// the rotor positions are shown, Keyboard the keyboard layout of the
+// user (see NewKeyMap), Groups the formatted output, and Procedure the
+// indicator procedure (see EncryptWithIndicator). Stepping names the
+// stepping mechanism (see SteppingMechanisms), the one of the model if
+// not set. NoDoubleStep and DrivenBy make a machine that never existed,
+// and so does a mechanism the model didn't have, so the models only
+// accept them with AllowNonHistorical. Era, if set, is the year the
+// machine is from, limiting it to what was issued by then. Uhr plugs
+// the Uhr in with the plugboard pairs (see WithUhr). Strict makes a
+// strict machine (see WithStrict).
+type Config struct {
+	Rotors       []RotorConfig      `json:"rotors"`
+	Reflector    ReflectorConfig    `json:"reflector"`
+	EntryWheel   string             `json:"entryWheel,omitempty"`
+	Plugboard    []string           `json:"plugboard,omitempty"`
+	MaxPlugPairs int                `json:"maxPlugPairs,omitempty"`
+	Display      DisplayMode        `json:"display,omitempty"`
+	Keyboard     string             `json:"keyboard,omitempty"`
+	Groups       *GroupOptions      `json:"groups,omitempty"`
+	Procedure    IndicatorProcedure `json:"procedure,omitempty"`
+	Stepping     string             `json:"stepping,omitempty"`
+	Era          int                `json:"era,omitempty"`
+	Uhr          *UhrConfig         `json:"uhr,omitempty"`
+
+	NoDoubleStep       bool `json:"noDoubleStep,omitempty"`
+	AllowNonHistorical bool `json:"allowNonHistorical,omitempty"`
+	Strict             bool `json:"strict,omitempty"`
+}
+
+// String returns the configuration on a single line: the reflector (with
+// its position, if set), the rotors from left to right, the rings, the
+// starting positions, and the plugboard pairs. Fixed rotors are shown
+// in brackets, e.g. "B-thin [Beta] II IV I 01 01 01 22 VJNA AT BL", and
+// UKW-D pairs in parentheses after the reflector. The positions are
+// always letters, so that they can't be confused with the rings.
+func (c Config) String() string {
+	return c.Format(DisplayNumbers)
+}
+
+// Format returns the configuration on a single line like String, with
+// the rings as numbers or as letters ("B-thin [Beta] II IV I A A A V
+// VJNA AT BL"), the way key sheets had them.
+func (c Config) Format(rings DisplayMode) string {
+	var parts []string
+	reflector := c.Reflector.ID
+	if c.Reflector.Start != 0 {
+		reflector += ":" + string(c.Reflector.Start)
+	}
+	if len(c.Reflector.Pairs) > 0 {
+		reflector += "(" + strings.Join(c.Reflector.Pairs, " ") + ")"
+	}
+	parts = append(parts, reflector)
+	positions := make([]byte, len(c.Rotors))
+	for i, rotor := range c.Rotors {
+		if rotor.Fixed {
+			parts = append(parts, "["+rotor.ID+"]")
+		} else {
+			parts = append(parts, rotor.ID)
+		}
+		positions[i] = rotor.Start
+	}
+	for _, rotor := range c.Rotors {
+		if rings == DisplayLetters {
+			parts = append(parts, string(IndexToChar(rotor.Ring-1)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%02d", rotor.Ring))
+		}
+	}
+	parts = append(parts, string(positions))
+	parts = append(parts, c.Plugboard...)
+	if c.Uhr != nil {
+		parts = append(parts, fmt.Sprintf("Uhr %02d", c.Uhr.Position))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RotorOrder returns the IDs of the rotors from the left, e.g. "II IV
+// V", the way searches report what they're trying.
+func (c Config) RotorOrder() string {
+	return rotorOrder(c.Rotors)
+}
+
+// SetRingLetter sets the ring from a letter, the way some key sheets
+// gave it: A is 1, Z is 26.
+func (rc *RotorConfig) SetRingLetter(letter rune) error {
+	if letter >= 'a' && letter <= 'z' {
+		letter -= 'a' - 'A'
+	}
+	if letter < 'A' || letter > 'Z' {
+		err := settingError(ErrRingOutOfRange, `ring should be a letter, got "%c"`, letter)
+		err.ID, err.Letter = rc.ID, byte(letter)
+		return err
+	}
+	rc.Ring = CharToIndex(byte(letter)) + 1
+	return nil
+}
+
+// rotorConfigJSON is the JSON form of RotorConfig, with the starting
+// position as a letter rather than a byte value.
+type rotorConfigJSON struct {
+	ID       string   `json:"id"`
+	Start    string   `json:"start"`
+	Ring     ringJSON `json:"ring"`
+	Fixed    bool     `json:"fixed,omitempty"`
+	DrivenBy int      `json:"drivenBy,omitempty"`
+	Wiring   string   `json:"wiring,omitempty"`
+	Notches  string   `json:"notches,omitempty"`
+}
+
+// ringJSON is a ring setting, written as a number but read from a
+// number or a string, either a letter or a number ("B", "02").
+type ringJSON int
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *ringJSON) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*r = ringJSON(number)
+		return nil
+	}
+	var setting string
+	if err := json.Unmarshal(data, &setting); err != nil {
+		return fmt.Errorf("ring should be a number or a letter, got %s", data)
+	}
+	rings, err := ParseRings(setting)
+	if err != nil {
+		return err
+	}
+	if len(rings) != 1 {
+		return fmt.Errorf(`ring should be a single letter or number, got "%s"`, setting)
+	}
+	*r = ringJSON(rings[0])
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (rc RotorConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rotorConfigJSON{rc.ID, string(rc.Start), ringJSON(rc.Ring), rc.Fixed, rc.DrivenBy, rc.Wiring, rc.Notches})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (rc *RotorConfig) UnmarshalJSON(data []byte) error {
+	var v rotorConfigJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Start) != 1 {
+		return fmt.Errorf(`rotor position should be a single letter, got "%s"`, v.Start)
+	}
+	*rc = RotorConfig{ID: v.ID, Start: v.Start[0], Ring: int(v.Ring), Fixed: v.Fixed, DrivenBy: v.DrivenBy,
+		Wiring: v.Wiring, Notches: v.Notches}
+	return nil
+}
+
+// reflectorConfigJSON is the JSON form of ReflectorConfig.
+type reflectorConfigJSON struct {
+	ID     string   `json:"id"`
+	Start  string   `json:"start,omitempty"`
+	Pairs  []string `json:"pairs,omitempty"`
+	Wiring string   `json:"wiring,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (rc ReflectorConfig) MarshalJSON() ([]byte, error) {
+	v := reflectorConfigJSON{ID: rc.ID, Pairs: rc.Pairs, Wiring: rc.Wiring}
+	if rc.Start != 0 {
+		v.Start = string(rc.Start)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (rc *ReflectorConfig) UnmarshalJSON(data []byte) error {
+	var v reflectorConfigJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*rc = ReflectorConfig{ID: v.ID, Pairs: v.Pairs, Wiring: v.Wiring}
+	switch len(v.Start) {
+	case 0:
+	case 1:
+		rc.Start = v.Start[0]
+	default:
+		return fmt.Errorf(`reflector position should be a single letter, got "%s"`, v.Start)
+	}
+	return nil
+}
+
+// Option sets a part of the machine configuration for NewMachine and
+// the model presets.
+type Option func(*Config) error
+
+// WithRotors sets the rotors, from the leftmost to the rightmost (fast)
+// one. Any number of rotors is accepted.
+func WithRotors(rotors ...RotorConfig) Option {
+	return func(c *Config) error {
+		c.Rotors = rotors
+		return nil
+	}
+}
+
+// WithReflector sets the reflector by its ID.
+func WithReflector(id string) Option {
+	return func(c *Config) error {
+		c.Reflector.ID = id
+		return nil
+	}
+}
+
+// WithReflectorPosition sets the reflector to a position (A to Z).
+func WithReflectorPosition(start byte) Option {
+	return func(c *Config) error {
+		c.Reflector.Start = start
+		return nil
+	}
+}
+
+// WithReflectorWiring sets the letter pairs of the rewirable UKW-D.
+func WithReflectorWiring(pairs ...string) Option {
+	return func(c *Config) error {
+		c.Reflector.Pairs = pairs
+		return nil
+	}
+}
+
+// WithEntryWheel sets the entry wheel by its ID.
+func WithEntryWheel(id string) Option {
+	return func(c *Config) error {
+		c.EntryWheel = id
+		return nil
+	}
+}
+
+// WithPlugboard sets the plugboard pairs, or a named preset given as
+// "@name" (see PlugboardPresets).
+func WithPlugboard(pairs ...string) Option {
+	return func(c *Config) error {
+		pairs, err := ResolvePlugboard(pairs)
+		if err != nil {
+			return err
+		}
+		c.Plugboard = pairs
+		return nil
+	}
+}
+
+// WithMaxPlugPairs limits the number of plugboard pairs, the way the
+// number of cables shipped with the machine did.
+func WithMaxPlugPairs(max int) Option {
+	return func(c *Config) error {
+		c.MaxPlugPairs = max
+		return nil
+	}
+}
+
+// WithDisplay sets how the rotor positions are shown.
+func WithDisplay(mode DisplayMode) Option {
+	return func(c *Config) error {
+		c.Display = mode
+		return nil
+	}
+}
+
+// WithKeyboardLayout remaps the keys of a modern keyboard to the ones
+// of the machine, e.g. "QWERTY-positional" (see KeyboardLayouts).
+func WithKeyboardLayout(layout string) Option {
+	return func(c *Config) error {
+		c.Keyboard = layout
+		return nil
+	}
+}
+
+// WithGroups makes EncodeFormatted write the result in groups, e.g.
+// the ClassicGroups.
+func WithGroups(options GroupOptions) Option {
+	return func(c *Config) error {
+		c.Groups = &options
+		return nil
+	}
+}
+
+// WithoutDoubleStep makes the middle rotor step only when the fast
+// one passes its notch, never on its own notch, as if the lever
+// mechanism had no anomaly.
+func WithoutDoubleStep() Option {
+	return func(c *Config) error {
+		c.NoDoubleStep = true
+		return nil
+	}
+}
+
+// WithEra limits the machine to what was issued by the year: rotors
+// introduced later are turned down, and before 1939, when key sheets
+// went up to ten, only six plugboard pairs were used. E.g. an Enigma I
+// of 1937 has just the rotors I to III, and one of 1939 adds IV and V.
+func WithEra(year int) Option {
+	return func(c *Config) error {
+		c.Era = year
+		return nil
+	}
+}
+
+// AllowNonHistorical lets the models accept settings their originals
+// didn't have, like WithoutDoubleStep.
+func AllowNonHistorical() Option {
+	return func(c *Config) error {
+		c.AllowNonHistorical = true
+		return nil
+	}
+}
+
+// WithPositions sets the starting positions of the rotors configured
+// so far, given as letters or numbers (see ParsePositions).
+func WithPositions(positions string) Option {
+	return func(c *Config) error {
+		offsets, err := ParsePositions(positions)
+		if err != nil {
+			return err
+		}
+		if len(offsets) != len(c.Rotors) {
+			return fmt.Errorf("expected %d rotor positions, got %d", len(c.Rotors), len(offsets))
+		}
+		c.Rotors = append([]RotorConfig(nil), c.Rotors...)
+		for i := range c.Rotors {
+			c.Rotors[i].Start = IndexToChar(offsets[i])
+		}
+		return nil
+	}
+}
+
+// configure applies the options to an empty configuration, stopping
+// at the first one that fails.
+func configure(options []Option) (Config, error) {
+	var config Config
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// NewMachine is the constructor for machines that never existed: there
+// are no historical constraints, so a toy machine with two rotors or a
+// fictional one with seven are both fine. Every rotor steps, driving its
+// left neighbour through the notches, just like in the three-rotor
+// machines.
+func NewMachine(options ...Option) (*Enigma, error) {
+	return Generic.NewWith(options...)
+}
+
+// Encode encodes the plaintext with a machine of the configuration (see
+// NewMachine) built just for it, so nothing is left set up or changed
+// afterwards, the configuration included. Machines don't share any
+// state, so it's safe to call from any number of goroutines at once,
+// with the same configuration too. Letters can be of either case; for
+// anything else, the error is a *CharacterError (see EncodeLetters).
+func Encode(config Config, plaintext string) (string, error) {
+	e, err := Generic.New(config)
+	if err != nil {
+		return "", err
+	}
+	return e.EncodeLetters(plaintext)
+}
+
+// Config returns the current configuration of the machine. The starting
+// positions are the ones the rotors are at now, so a machine built from
+// the returned configuration continues where this one is, for its
+// settings that never existed too (see AllowNonHistorical). Rotors and
+// reflectors that aren't on the lists of the machine, or are wired
+// differently, come with their wirings.
+func (e *Enigma) Config() Config {
+	config := Config{
+		Rotors:     make([]RotorConfig, len(e.Rotors)),
+		Reflector:  ReflectorConfig{ID: e.Reflector.ID, Pairs: e.Reflector.Pairs()},
+		EntryWheel: e.EntryWheel.ID,
+		Plugboard:  e.Plugboard.Pairs(),
+		Display:    e.Display,
+		Groups:     e.Groups,
+		Procedure:  e.Procedure,
+
+		NoDoubleStep: e.NoDoubleStep,
+		Strict:       e.seal != nil,
+	}
+	if e.Uhr != nil {
+		config.Plugboard = e.Uhr.Pairs()
+		config.Uhr = &UhrConfig{Position: e.Uhr.Position()}
+	}
+	for i, rotor := range e.Rotors {
+		config.Rotors[i] = RotorConfig{
+			ID:       rotor.ID,
+			Start:    IndexToChar(mod26(rotor.Offset)),
+			Ring:     mod26(rotor.Ring) + 1,
+			Fixed:    rotor.Fixed,
+			DrivenBy: rotor.DrivenBy,
+		}
+		if !e.registeredRotor(rotor) {
+			config.Rotors[i].Wiring, config.Rotors[i].Notches = rotor.wiring()
+		}
+	}
+	if e.Reflector.ID != UKWD && !e.registeredReflector() {
+		config.Reflector.Wiring = letters(e.Reflector.Sequence)
+	}
+	if position := mod26(e.Reflector.Position); position != 0 {
+		config.Reflector.Start = IndexToChar(position)
+	}
+	for _, rotor := range config.Rotors {
+		config.AllowNonHistorical = config.AllowNonHistorical || rotor.DrivenBy != 0
+	}
+	config.AllowNonHistorical = config.AllowNonHistorical || config.NoDoubleStep
+	if name := e.stepping().Name(); name != LeverStepping.Name() || (e.model != nil && e.model.Stepping != "") {
+		config.Stepping = name
+		config.AllowNonHistorical = config.AllowNonHistorical || e.model == nil || name != e.model.stepping()
+	}
+	if e.Keyboard != nil {
+		config.Keyboard = e.Keyboard.Layout
+	}
+	return config
+}
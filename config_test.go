@@ -0,0 +1,161 @@
+package enigma
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rotorsAt is the rotors from the left at the positions, with the rings
+// at 1.
+func rotorsAt(ids, positions string) []RotorConfig {
+	var rotors []RotorConfig
+	for i, id := range strings.Fields(ids) {
+		rotors = append(rotors, RotorConfig{ID: id, Start: positions[i], Ring: 1})
+	}
+	return rotors
+}
+
+func TestNewMachineRoundTrip(t *testing.T) {
+	plaintext := strings.Repeat("ANGRIFFIMMORGENGRAUEN", 40)
+	for _, rotors := range []string{"I", "I II", "I II III IV V", "VIII VII VI V IV III II"} {
+		positions := strings.Repeat("Q", len(strings.Fields(rotors)))
+		e, err := NewMachine(WithRotors(rotorsAt(rotors, positions)...), WithReflector("C"), WithPlugboard("AZ", "QX"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertext := e.EncodeString(plaintext)
+		e.Reset()
+		if got := e.EncodeString(ciphertext); got != plaintext {
+			t.Errorf("%s: the ciphertext deciphers to %s, expected %s", rotors, got, plaintext)
+		}
+		if len(e.Positions()) != len(positions) {
+			t.Errorf("%s: the windows show %s", rotors, e.Positions())
+		}
+		if err := e.ResetTo(positions); err != nil || e.Positions() != positions {
+			t.Errorf("%s: set to %s, the windows show %s (%v)", rotors, positions, e.Positions(), err)
+		}
+	}
+	if _, err := NewMachine(WithReflector("B")); err == nil {
+		t.Error("a machine without rotors is built")
+	}
+	if _, err := NewEnigmaM3(WithRotors(rotorsAt("I II III IV V", "AAAAA")...), WithReflector("B")); err == nil {
+		t.Error("the M3 takes five rotors")
+	}
+}
+
+// Ring B and ring 2 are the same ring, whether set from a letter, read
+// from a mix of letters and numbers, or loaded from JSON.
+func TestRingLetters(t *testing.T) {
+	byNumber := classicConfig()
+	byNumber.Rotors = rotorsAt("I II III", "QDV")
+	byNumber.Rotors[1].Ring, byNumber.Rotors[2].Ring = 2, 26
+	byLetter := classicConfig()
+	byLetter.Rotors = rotorsAt("I II III", "QDV")
+	if err := byLetter.Rotors[1].SetRingLetter('B'); err != nil {
+		t.Fatal(err)
+	}
+	if err := byLetter.Rotors[2].SetRingLetter('z'); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Generic.New(byNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generic.New(byLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.EncodeString("WETTERBERICHT"), a.EncodeString("WETTERBERICHT"); got != want {
+		t.Errorf("with rings A B Z, the text encodes to %s, expected %s as with 01 02 26", got, want)
+	}
+
+	for _, rings := range []string{"01 02 26", "A B Z", "a 02 Z", "AbZ"} {
+		if got, err := ParseRings(rings); err != nil || !reflect.DeepEqual(got, []int{1, 2, 26}) {
+			t.Errorf("%s is read as %v (%v)", rings, got, err)
+		}
+	}
+	for _, rings := range []string{"01 02 26", "A B Z", "a 02 Z"} {
+		settings, err := ParseSettingsString("B I II III " + rings + " QDV")
+		if err != nil || !reflect.DeepEqual(settings.Config.Rotors, byNumber.Rotors) {
+			t.Errorf("the settings with rings %s are read as %v (%v)", rings, settings.Config.Rotors, err)
+		}
+	}
+	for _, ring := range []string{`2`, `"B"`, `"b"`, `"02"`} {
+		var rotor RotorConfig
+		if err := json.Unmarshal([]byte(`{"id": "II", "start": "D", "ring": `+ring+`}`), &rotor); err != nil || rotor.Ring != 2 {
+			t.Errorf("ring %s is loaded as %d (%v)", ring, rotor.Ring, err)
+		}
+	}
+	for _, rings := range []string{"27", "A1", "B 00"} {
+		if _, err := ParseRings(rings); !errors.Is(err, ErrRingOutOfRange) {
+			t.Errorf("rings %s are read with %v", rings, err)
+		}
+	}
+
+	if got, want := byNumber.Format(DisplayLetters), "B I II III A B Z QDV"; got != want {
+		t.Errorf("the rings are written as %s, expected %s", got, want)
+	}
+	if got, want := byLetter.String(), "B I II III 01 02 26 QDV"; got != want {
+		t.Errorf("the rings are written as %s, expected %s", got, want)
+	}
+}
+
+// Encode changes nothing, neither the configuration nor the rotors of
+// the registry, so goroutines can share a configuration.
+func TestEncodeConcurrent(t *testing.T) {
+	config := classicConfig()
+	config.Plugboard = []string{"AV", "BS", "CG"}
+	config.Rotors[1].Start = 'D'
+	saved := config
+	saved.Rotors = append([]RotorConfig(nil), config.Rotors...)
+	saved.Plugboard = append([]string(nil), config.Plugboard...)
+	registry := append(Rotors(nil), historicRotors...)
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := e.EncodeString(receiveText)
+
+	var wg sync.WaitGroup
+	got := make([]string, 32)
+	for g := range got {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				ciphertext, err := Encode(config, receiveText)
+				if err != nil || (got[g] != "" && ciphertext != got[g]) {
+					t.Errorf("goroutine %d encodes to %s (%v)", g, ciphertext, err)
+					return
+				}
+				got[g] = ciphertext
+			}
+		}(g)
+	}
+	wg.Wait()
+	for g, ciphertext := range got {
+		if ciphertext != want {
+			t.Errorf("goroutine %d encodes to %s, expected %s", g, ciphertext, want)
+		}
+	}
+	if !reflect.DeepEqual(config, saved) {
+		t.Errorf("the configuration is now %v, expected %v", config, saved)
+	}
+	if !reflect.DeepEqual(historicRotors, registry) {
+		t.Error("the rotors of the registry have changed")
+	}
+	if _, err := Encode(Config{}, "A"); err == nil {
+		t.Error("a machine of nothing encodes")
+	}
+	if ciphertext, err := Encode(config, strings.ToLower(receiveText)); err != nil || ciphertext != want {
+		t.Errorf("the plaintext in small letters encodes to %s (%v), expected %s", ciphertext, err, want)
+	}
+	var charErr *CharacterError
+	if _, err := Encode(config, "hello world"); !errors.As(err, &charErr) || charErr.Rune != ' ' || charErr.Index != 5 {
+		t.Errorf("the space is encoded with %v", err)
+	}
+}
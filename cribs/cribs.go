@@ -0,0 +1,67 @@
+// Package cribs lines up a crib, a bit of plaintext believed to be in a
+// message, with the ciphertext. Since the Enigma never encodes a letter
+// to itself, the crib can't be wherever one of its letters would fall on
+// the same letter of the ciphertext, which usually rules out most of the
+// places it could go. What's left is what a bombe menu is drawn for (see
+// bombe.NewMenu).
+package cribs
+
+import (
+	"strings"
+
+	"github.com/emedvedev/enigma"
+)
+
+// Alignment is a place the crib could go: the letter of the ciphertext
+// it starts at (counting from 0), and the letters of the crib that fall
+// on the same letter of the ciphertext there, if any, which make it
+// impossible.
+type Alignment struct {
+	At      int
+	Clashes []int
+}
+
+// Possible tells if the crib can go there.
+func (a Alignment) Possible() bool {
+	return len(a.Clashes) == 0
+}
+
+// Alignments returns every place the crib fits in the ciphertext, in
+// order, possible or not. The ciphertext can be in groups, the places
+// count its letters only, and neither is case sensitive.
+func Alignments(ciphertext, crib string) []Alignment {
+	ciphertext, crib = normalize(ciphertext, crib)
+	if len(crib) == 0 || len(crib) > len(ciphertext) {
+		return nil
+	}
+	alignments := make([]Alignment, 0, len(ciphertext)-len(crib)+1)
+	for at := 0; at+len(crib) <= len(ciphertext); at++ {
+		alignment := Alignment{At: at}
+		for i := 0; i < len(crib); i++ {
+			if crib[i] == ciphertext[at+i] {
+				alignment.Clashes = append(alignment.Clashes, i)
+			}
+		}
+		alignments = append(alignments, alignment)
+	}
+	return alignments
+}
+
+// FindCribPositions returns the places the crib can go in the
+// ciphertext (see Alignments).
+func FindCribPositions(ciphertext, crib string) []int {
+	var positions []int
+	for _, alignment := range Alignments(ciphertext, crib) {
+		if alignment.Possible() {
+			positions = append(positions, alignment.At)
+		}
+	}
+	return positions
+}
+
+// normalize joins the groups of the ciphertext and puts both in
+// capitals.
+func normalize(ciphertext, crib string) (string, string) {
+	ciphertext = enigma.ParseGroups(ciphertext, enigma.ClassicGroups)
+	return strings.ToUpper(ciphertext), strings.ToUpper(crib)
+}
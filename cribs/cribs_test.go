@@ -0,0 +1,87 @@
+package cribs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// A crib can't go where one of its letters falls on itself, and the
+// places count the letters of the ciphertext, not its groups.
+func TestAlignments(t *testing.T) {
+	tests := []struct {
+		ciphertext string
+		crib       string
+		want       []Alignment
+	}{
+		{"QWERT", "WE", []Alignment{{0, nil}, {1, []int{0, 1}}, {2, nil}, {3, nil}}},
+		{"QWE RT", "ert", []Alignment{{0, nil}, {1, nil}, {2, []int{0, 1, 2}}}},
+		{"abcab", "ABX", []Alignment{{0, []int{0, 1}}, {1, nil}, {2, nil}}},
+		{"QWERT", "QWERT", []Alignment{{0, []int{0, 1, 2, 3, 4}}}},
+		{"QWE", "QWERT", nil},
+		{"QWERT", "", nil},
+	}
+	for _, tt := range tests {
+		if got := Alignments(tt.ciphertext, tt.crib); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s lines up in %s as %v, expected %v", tt.crib, tt.ciphertext, got, tt.want)
+		}
+	}
+}
+
+func TestFindCribPositions(t *testing.T) {
+	tests := []struct {
+		ciphertext string
+		crib       string
+		want       []int
+	}{
+		{"QWERT", "WE", []int{0, 2, 3}},
+		{"QWERT ZUIOP", "TZ", []int{0, 1, 2, 3, 5, 6, 7, 8}},
+		{"AAAAA", "A", nil},
+		{"AAAAA", "B", []int{0, 1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		if got := FindCribPositions(tt.ciphertext, tt.crib); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s can go in %s at %v, expected %v", tt.crib, tt.ciphertext, got, tt.want)
+		}
+	}
+}
+
+// BEIANGRIFF, at letter 52 of the Dönitz message, is among its 156
+// possible places out of 223, and every place ruled out has a letter of
+// the crib on the same letter of the ciphertext.
+func TestDoenitzCrib(t *testing.T) {
+	const crib = "BEIANGRIFF"
+	vector := testvectors.Doenitz
+	at := strings.Index(vector.Plaintext, crib)
+	if at != 52 {
+		t.Fatalf("the crib is at %d of the plaintext, expected 52", at)
+	}
+	ciphertext := strings.Replace(vector.Ciphertext, " ", "", -1)
+	alignments := Alignments(vector.Ciphertext, crib)
+	if len(alignments) != 223 {
+		t.Errorf("the crib fits in %d places, expected 223", len(alignments))
+	}
+	for _, alignment := range alignments {
+		for i := range crib {
+			clash := crib[i] == ciphertext[alignment.At+i]
+			if clash != contains(alignment.Clashes, i) {
+				t.Errorf("at %d, letter %d of the crib clashes: %v", alignment.At, i, alignment.Clashes)
+			}
+		}
+	}
+	positions := FindCribPositions(vector.Ciphertext, crib)
+	if len(positions) != 156 || !contains(positions, at) {
+		t.Errorf("the crib can go in %d places, expected 156 with %d among them", len(positions), at)
+	}
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
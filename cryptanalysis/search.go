@@ -0,0 +1,417 @@
+// Package cryptanalysis breaks messages the brute-force way: it tries
+// the rotor orders, the reflectors, the rings, and the starting positions
+// of a model, and keeps the settings whose decrypts read most like
+// language, as rated by a scorer of the score package. The UKW-D,
+// which has far too many wirings to try, is hill-climbed instead. The
+// plugboard isn't searched, it's taken as given.
+package cryptanalysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/score"
+)
+
+// DefaultBest is how many candidates Search keeps if not told.
+const DefaultBest = 10
+
+// Space is the part of the key space to search. Model is the machine
+// (Enigma I if not set), and Rotors the rotors to pick from, Slots of
+// them at a time (those of the model, and the number of its slots or
+// three, if not set). Every reflector of Reflectors is tried (those of
+// the model if not set), and the rings are searched in RingSlots only
+// (counting from 1 on the left), the others being 1. The plugboard is
+// set to Plugboard in every candidate. Only the starting Positions are
+// tried, if set (e.g. the ones the indicators give), every one of them
+// if not. With UKWDSearch, the UKW-D is tried too, after the
+// Reflectors: there are far too many ways to plug it to try them all,
+// so at each rotor order and position its pairs are hill-climbed with
+// the scorer instead. Orders the model doesn't take, such as a Greek
+// wheel where it can't be, are passed over.
+type Space struct {
+	Model      *enigma.Model
+	Rotors     []string
+	Slots      int
+	Reflectors []string
+	RingSlots  []int
+	Plugboard  []string
+	Positions  []string
+	UKWDSearch bool
+}
+
+// Options tune Search: the Scorer rating the decrypts (score.IoC if not
+// set), the number of Workers (GOMAXPROCS if not set), how many of the
+// Best candidates to keep (DefaultBest if not set), and an optional
+// Progress callback, called with the settings tried so far and their
+// total from the workers' goroutines. Report, if set, is called at the
+// same times with more to show (see Report), one call at a time.
+type Options struct {
+	Scorer   score.Scorer
+	Workers  int
+	Best     int
+	Progress func(tried, total int64)
+	Report   func(Report)
+}
+
+// Report is how far Search got: the settings tried so far and their
+// total, the best score found so far (minus infinity until there's
+// one), and the rotor order (the IDs from the left) and the reflector
+// of the settings just done.
+type Report struct {
+	Tried, Total int64
+	Best         float64
+	Order        string
+	Reflector    string
+}
+
+// Candidate is a setting found by Search: the configuration, with the
+// starting positions the message was decrypted at, the decrypt, and its
+// score.
+type Candidate struct {
+	Config    enigma.Config
+	Plaintext string
+	Score     float64
+}
+
+// Size returns the number of settings in the space: the rotor orders
+// times the reflectors (the UKW-D counting as one), the rings, and the
+// starting positions. Orders the model doesn't take are counted too.
+func (s Space) Size() int64 {
+	s = s.complete()
+	orders := int64(1)
+	for i := 0; i < s.Slots; i++ {
+		orders *= int64(len(s.Rotors) - i)
+	}
+	if orders < 0 {
+		orders = 0
+	}
+	reflectors := int64(len(s.Reflectors))
+	if s.UKWDSearch {
+		reflectors++
+	}
+	positions := power(26, s.Slots)
+	if s.Positions != nil {
+		positions = int64(len(s.Positions))
+	}
+	return orders * reflectors * power(26, len(s.RingSlots)) * positions
+}
+
+// Search decrypts the ciphertext with every setting of the space, on
+// several goroutines, and returns the best candidates, the best first.
+// When the context is done, it stops and returns the best ones found
+// so far along with the context's error.
+func Search(ctx context.Context, ciphertext string, space Space, options Options) ([]Candidate, error) {
+	space = space.complete()
+	if len(ciphertext) == 0 {
+		return nil, fmt.Errorf("nothing to search with: the ciphertext is empty")
+	}
+	for i := 0; i < len(ciphertext); i++ {
+		if ciphertext[i] < 'A' || ciphertext[i] > 'Z' {
+			return nil, fmt.Errorf(`only capital letters can be searched with, got "%c" at %d`, ciphertext[i], i)
+		}
+	}
+	for _, slot := range space.RingSlots {
+		if slot < 1 || slot > space.Slots {
+			return nil, fmt.Errorf("no slot %d to search the ring of: there are %d", slot, space.Slots)
+		}
+	}
+	for _, positions := range space.Positions {
+		if len(positions) != space.Slots || strings.Trim(positions, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, fmt.Errorf(`positions should be %d capital letters, got "%s"`, space.Slots, positions)
+		}
+	}
+	if space.UKWDSearch && !space.Model.UKWD {
+		return nil, fmt.Errorf("Enigma %s takes no %s to search", space.Model.Name, enigma.UKWD)
+	}
+	if options.Scorer == nil {
+		options.Scorer = score.IoC
+	}
+	if options.Workers < 1 {
+		options.Workers = runtime.GOMAXPROCS(0)
+	}
+	if options.Best < 1 {
+		options.Best = DefaultBest
+	}
+	total := space.Size()
+	var tried int64
+	var reporting sync.Mutex
+	bestScore := math.Inf(-1)
+	jobs := make(chan enigma.Config)
+	results := make([][]Candidate, options.Workers)
+	var wg sync.WaitGroup
+	for w := 0; w < options.Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			best := &results[w]
+			for config := range jobs {
+				n := space.try(ctx, config, ciphertext, options.Scorer, options.Best, best)
+				done := atomic.AddInt64(&tried, n)
+				if options.Progress != nil {
+					options.Progress(done, total)
+				}
+				if options.Report != nil {
+					reporting.Lock()
+					if len(*best) > 0 && (*best)[0].Score > bestScore {
+						bestScore = (*best)[0].Score
+					}
+					options.Report(Report{atomic.LoadInt64(&tried), total, bestScore, config.RotorOrder(), config.Reflector.ID})
+					reporting.Unlock()
+				}
+			}
+		}(w)
+	}
+	accepted, rejected := space.Each(func(config enigma.Config) bool {
+		select {
+		case jobs <- config:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(jobs)
+	wg.Wait()
+	err := ctx.Err()
+	if accepted == 0 && rejected != nil {
+		err = fmt.Errorf("no setting of the space can be used on Enigma %s: %w", space.Model.Name, rejected)
+	}
+	var best []Candidate
+	for _, found := range results {
+		best = append(best, found...)
+	}
+	sortCandidates(best)
+	if len(best) > options.Best {
+		best = best[:options.Best]
+	}
+	return best, err
+}
+
+// complete fills in the defaults of the space.
+func (s Space) complete() Space {
+	if s.Model == nil {
+		s.Model = &enigma.EnigmaI
+	}
+	if s.Rotors == nil {
+		for _, rotor := range s.Model.Rotors {
+			s.Rotors = append(s.Rotors, rotor.ID)
+		}
+	}
+	if s.Slots == 0 {
+		s.Slots = s.Model.Slots
+	}
+	if s.Slots == 0 {
+		s.Slots = 3
+	}
+	if s.Reflectors == nil {
+		for _, reflector := range s.Model.Reflectors {
+			s.Reflectors = append(s.Reflectors, reflector.ID)
+		}
+	}
+	if len(s.Reflectors) == 0 && !s.UKWDSearch {
+		// The model picks its own, e.g. the UKW-D.
+		s.Reflectors = []string{""}
+	}
+	return s
+}
+
+// Each calls yield with the configuration of every rotor order,
+// reflector, and rings of the space the model takes, at positions A,
+// until yield returns false. It returns how many there were, and why
+// the first one the model doesn't take was rejected.
+func (s Space) Each(yield func(enigma.Config) bool) (accepted int, rejected error) {
+	s = s.complete()
+	order := make([]string, 0, s.Slots)
+	used := make([]bool, len(s.Rotors))
+	var orders func() bool
+	orders = func() bool {
+		if len(order) == s.Slots {
+			return s.eachRings(order, func(config enigma.Config) bool {
+				if err := s.Model.Validate(config); err != nil {
+					if rejected == nil {
+						rejected = err
+					}
+					return true
+				}
+				accepted++
+				return yield(config)
+			})
+		}
+		for i, id := range s.Rotors {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			order = append(order, id)
+			more := orders()
+			order, used[i] = order[:len(order)-1], false
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	orders()
+	return accepted, rejected
+}
+
+// eachRings calls yield with the configuration of the rotor order with
+// every reflector (and the UKW-D, wired to start the climb from) and
+// every setting of the rings searched, until yield returns false.
+func (s Space) eachRings(order []string, yield func(enigma.Config) bool) bool {
+	reflectors := s.Reflectors
+	if s.UKWDSearch {
+		reflectors = append(reflectors[:len(reflectors):len(reflectors)], enigma.UKWD)
+	}
+	for _, reflector := range reflectors {
+		rings := make([]int, s.Slots)
+		for {
+			config := s.config(order, reflector, rings)
+			if reflector == enigma.UKWD {
+				config.Reflector.Pairs = ukwdStart()
+			}
+			if !yield(config) {
+				return false
+			}
+			if !next(rings, s.RingSlots) {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// config returns the configuration of the candidate at positions A.
+func (s Space) config(order []string, reflector string, rings []int) enigma.Config {
+	config := enigma.Config{
+		Rotors:    make([]enigma.RotorConfig, len(order)),
+		Reflector: enigma.ReflectorConfig{ID: reflector},
+		Plugboard: s.Plugboard,
+	}
+	for i, id := range order {
+		config.Rotors[i] = enigma.RotorConfig{ID: id, Start: 'A', Ring: rings[i] + 1, Fixed: isFixed(s.Model, i)}
+	}
+	return config
+}
+
+// try decrypts the ciphertext at every starting position of the
+// configuration (those of the space, if it has any), keeping the best
+// candidates, and returns the number of positions tried. A UKW-D is
+// climbed at each of them, from the wiring of the configuration.
+func (s Space) try(ctx context.Context, base enigma.Config, ciphertext string, scorer score.Scorer, keep int, best *[]Candidate) int64 {
+	e, err := s.Model.New(base)
+	if err != nil {
+		return 0
+	}
+	climb := s.UKWDSearch && base.Reflector.ID == enigma.UKWD
+	var tried int64
+	s.eachPosition(func(positions string) bool {
+		if tried%(26*26) == 0 && ctx.Err() != nil {
+			return false
+		}
+		config := base
+		var plaintext string
+		var rating float64
+		if climb {
+			e.RewireReflector(base.Reflector.Pairs)
+			config.Reflector.Pairs, plaintext, rating = climbUKWD(e, positions, ciphertext, scorer)
+		} else {
+			e.ResetTo(positions)
+			plaintext = e.EncodeString(ciphertext)
+			rating = scorer.Score([]byte(plaintext))
+		}
+		tried++
+		if len(*best) < keep || rating > (*best)[len(*best)-1].Score {
+			config.Rotors = append([]enigma.RotorConfig(nil), config.Rotors...)
+			for i := range config.Rotors {
+				config.Rotors[i].Start = positions[i]
+			}
+			*best = append(*best, Candidate{config, plaintext, rating})
+			sortCandidates(*best)
+			if len(*best) > keep {
+				*best = (*best)[:keep]
+			}
+		}
+		return true
+	})
+	return tried
+}
+
+// eachPosition calls yield with the starting positions of the space, or
+// every one there is if it has none, until yield returns false.
+func (s Space) eachPosition(yield func(positions string) bool) {
+	if s.Positions != nil {
+		for _, positions := range s.Positions {
+			if !yield(positions) {
+				return
+			}
+		}
+		return
+	}
+	all := make([]int, s.Slots)
+	for i := range all {
+		all[i] = i + 1
+	}
+	offsets := make([]int, s.Slots)
+	positions := make([]byte, s.Slots)
+	for {
+		for i, offset := range offsets {
+			positions[i] = byte('A' + offset)
+		}
+		if !yield(string(positions)) || !next(offsets, all) {
+			return
+		}
+	}
+}
+
+// next steps the settings in the slots (counting from 1) like an
+// odometer, the rightmost first, and tells if it didn't wrap around to
+// the start.
+func next(settings []int, slots []int) bool {
+	for i := len(slots) - 1; i >= 0; i-- {
+		slot := slots[i] - 1
+		if settings[slot]++; settings[slot] < 26 {
+			return true
+		}
+		settings[slot] = 0
+	}
+	return false
+}
+
+// sortCandidates puts the best candidates first, and those that score
+// the same in the order of their settings, so that the result doesn't
+// depend on which worker found what.
+func sortCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Config.String() < candidates[j].Config.String()
+	})
+}
+
+// isFixed tells if the slot (counting from 0) never steps on the model.
+func isFixed(m *enigma.Model, slot int) bool {
+	for _, fixed := range m.FixedSlots {
+		if fixed == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// power returns base to the exponent.
+func power(base, exponent int) int64 {
+	result := int64(1)
+	for i := 0; i < exponent; i++ {
+		result *= int64(base)
+	}
+	return result
+}
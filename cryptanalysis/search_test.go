@@ -0,0 +1,288 @@
+package cryptanalysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/score"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// BenchmarkSearch searches the Enigma I with the rotors I to III and
+// reflector B with one worker, half the cores, and all of them, and
+// reports the settings tried a second, to tune Options.Workers with.
+func BenchmarkSearch(b *testing.B) {
+	space := Space{
+		Model:      &enigma.EnigmaI,
+		Rotors:     []string{"I", "II", "III"},
+		Reflectors: []string{"B"},
+	}
+	ciphertext := enigma.ParseGroups(testvectors.Barbarossa.Ciphertext, enigma.ClassicGroups)
+	counts := []int{1}
+	for _, workers := range []int{runtime.GOMAXPROCS(0) / 2, runtime.GOMAXPROCS(0)} {
+		if workers > counts[len(counts)-1] {
+			counts = append(counts, workers)
+		}
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Search(context.Background(), ciphertext, space, Options{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(space.Size())*float64(b.N)/b.Elapsed().Seconds(), "settings/s")
+		})
+	}
+}
+
+// quadgrams counts the quadgrams of the plaintexts of the test vectors,
+// for a table to search with that's expensive enough to matter.
+func quadgrams() map[string]float64 {
+	counts := make(map[string]float64)
+	for _, vector := range testvectors.Vectors {
+		for i := 0; i+4 <= len(vector.Plaintext); i++ {
+			counts[vector.Plaintext[i:i+4]]++
+		}
+	}
+	return counts
+}
+
+// BenchmarkSearchScorers searches the orders of rotors II, IV and V,
+// with the quadgrams rating every decrypt, and with the quadgrams
+// rating only the decrypts the index of coincidence lets through,
+// reporting how many of them the quadgrams had to rate.
+func BenchmarkSearchScorers(b *testing.B) {
+	table, err := score.NewNGrams("quadgrams", quadgrams())
+	if err != nil {
+		b.Fatal(err)
+	}
+	e, err := enigma.NewEnigmaI(enigma.WithRotors(
+		enigma.RotorConfig{ID: "IV", Start: 'Q', Ring: 1},
+		enigma.RotorConfig{ID: "II", Start: 'D', Ring: 1},
+		enigma.RotorConfig{ID: "V", Start: 'V', Ring: 1},
+	), enigma.WithReflector("B"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ciphertext := e.EncodeString(testvectors.Barbarossa.Plaintext)
+	space := Space{
+		Model:      &enigma.EnigmaI,
+		Rotors:     []string{"II", "IV", "V"},
+		Reflectors: []string{"B"},
+	}
+	for _, scorer := range []struct {
+		name  string
+		build func(fine score.Scorer) score.Scorer
+	}{
+		{"quadgrams", func(fine score.Scorer) score.Scorer { return fine }},
+		{"staged", func(fine score.Scorer) score.Scorer { return score.Staged(score.IoC, fine, 0.05) }},
+	} {
+		b.Run(scorer.name, func(b *testing.B) {
+			var rated int64
+			fine := score.Func("quadgrams", func(text []byte) float64 {
+				atomic.AddInt64(&rated, 1)
+				return table.Score(text)
+			})
+			for i := 0; i < b.N; i++ {
+				candidates, err := Search(context.Background(), ciphertext, space, Options{Scorer: scorer.build(fine), Best: 1})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if got := candidates[0].Config.String(); !strings.HasPrefix(got, "B IV II V") {
+					b.Fatalf("the best candidate is %s", got)
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&rated))/float64(b.N), "quadgrams/op")
+		})
+	}
+}
+
+// plant enciphers the plaintext on a machine of the model, for the
+// search to find the configuration again.
+func plant(t *testing.T, model *enigma.Model, config enigma.Config, plaintext string) string {
+	t.Helper()
+	e, err := model.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e.EncodeString(plaintext)
+}
+
+// The reflector is searched with the rest: a message planted with C is
+// found with C, in its rotor order, among the orders of B and C.
+func TestSearchReflector(t *testing.T) {
+	config := enigma.Config{
+		Rotors:    []enigma.RotorConfig{{ID: "II", Start: 'Q', Ring: 1}, {ID: "III", Start: 'E', Ring: 1}, {ID: "I", Start: 'V', Ring: 1}},
+		Reflector: enigma.ReflectorConfig{ID: "C"},
+	}
+	ciphertext := plant(t, &enigma.EnigmaI, config, testvectors.Barbarossa.Plaintext)
+	space := Space{Rotors: []string{"I", "II", "III"}, Reflectors: []string{"B", "C"}}
+	if got, want := space.Size(), int64(6*2*26*26*26); got != want {
+		t.Errorf("the space has %d settings, expected %d", got, want)
+	}
+	candidates, err := Search(context.Background(), ciphertext, space, Options{Best: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := candidates[0].Config.String(), config.String(); got != want {
+		t.Errorf("the best candidate is %s, expected %s", got, want)
+	}
+	for _, candidate := range candidates[1:] {
+		if candidate.Config.Reflector.ID == "B" && candidate.Score >= candidates[0].Score {
+			t.Errorf("B at %s scores as well as the planted C", candidate.Config)
+		}
+	}
+}
+
+// The UKW-D is climbed rather than tried: on 1000 letters, at the
+// positions and among the orders of the message, most of its pairs are
+// found, and the decrypt reads like the plaintext. It takes a while, so
+// it's left out of the short tests.
+func TestSearchUKWD(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the UKW-D is climbed at every order")
+	}
+	var plaintext string
+	for len(plaintext) < 1000 {
+		for _, vector := range testvectors.Vectors {
+			plaintext += vector.Plaintext
+		}
+	}
+	plaintext = plaintext[:1000]
+	pairs := strings.Fields("AZ CX DY EW FV GU HT IS JR KQ LP MN")
+	config := enigma.Config{
+		Rotors:    []enigma.RotorConfig{{ID: "I", Start: 'K', Ring: 1}, {ID: "II", Start: 'D', Ring: 1}, {ID: "III", Start: 'Q', Ring: 1}},
+		Reflector: enigma.ReflectorConfig{ID: enigma.UKWD, Pairs: pairs},
+	}
+	ciphertext := plant(t, &enigma.Generic, config, plaintext)
+	space := Space{
+		Model:      &enigma.Generic,
+		Rotors:     []string{"I", "II", "III"},
+		Reflectors: []string{},
+		Positions:  []string{"KDQ"},
+		UKWDSearch: true,
+	}
+	if got := space.Size(); got != 6 {
+		t.Errorf("the space has %d settings, expected 6", got)
+	}
+	candidates, err := Search(context.Background(), ciphertext, space, Options{Scorer: score.German, Best: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	best := candidates[0]
+	if got := best.Config.RotorOrder(); got != "I II III" || best.Config.Rotors[0].Start != 'K' {
+		t.Errorf("the best candidate is %s", best.Config)
+	}
+	found := make(map[string]bool)
+	for _, pair := range best.Config.Reflector.Pairs {
+		found[pair] = true
+	}
+	right := 0
+	for _, pair := range pairs {
+		if found[pair] {
+			right++
+		}
+	}
+	if right < 9 {
+		t.Errorf("%d of the 12 pairs are found: %s", right, best.Config)
+	}
+	report := enigma.CompareDecrypts(plaintext, best.Plaintext)
+	if len(report.Positions) > len(plaintext)/10 {
+		t.Errorf("the decrypt is off from the plaintext by %s", report)
+	}
+}
+
+// The UKW-D is only searched on the models that take it, and the
+// positions searched have to be the model's.
+func TestSearchUKWDErrors(t *testing.T) {
+	ciphertext := "QWERTZUIOP"
+	spaces := []Space{
+		{Rotors: []string{"I", "II", "III"}, UKWDSearch: true},
+		{Rotors: []string{"I", "II", "III"}, Positions: []string{"AAAA"}},
+		{Rotors: []string{"I", "II", "III"}, Positions: []string{"AaA"}},
+	}
+	for _, space := range spaces {
+		if _, err := Search(context.Background(), ciphertext, space, Options{}); err == nil {
+			t.Errorf("%+v is searched", space)
+		}
+	}
+}
+
+// Reports come one order at a time, never going backwards, with the
+// best score so far, and the last one has every setting tried, as does
+// the last of Progress.
+func TestSearchReports(t *testing.T) {
+	config := enigma.Config{
+		Rotors:    []enigma.RotorConfig{{ID: "II", Start: 'Q', Ring: 1}, {ID: "III", Start: 'E', Ring: 1}, {ID: "I", Start: 'V', Ring: 1}},
+		Reflector: enigma.ReflectorConfig{ID: "B"},
+	}
+	ciphertext := plant(t, &enigma.EnigmaI, config, testvectors.Barbarossa.Plaintext)
+	space := Space{Rotors: []string{"I", "II", "III"}, Reflectors: []string{"B"}}
+	var reports []Report
+	var progress int64
+	candidates, err := Search(context.Background(), ciphertext, space, Options{
+		Report: func(r Report) { reports = append(reports, r) },
+		Progress: func(tried, total int64) {
+			if tried > atomic.LoadInt64(&progress) {
+				atomic.StoreInt64(&progress, tried)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 6 {
+		t.Fatalf("%d reports for the 6 orders", len(reports))
+	}
+	orders := make(map[string]bool)
+	for i, r := range reports {
+		if r.Total != space.Size() || r.Reflector != "B" {
+			t.Errorf("report %d is %+v", i, r)
+		}
+		if i > 0 && (r.Tried < reports[i-1].Tried || r.Best < reports[i-1].Best) {
+			t.Errorf("report %d, %+v, goes back from %+v", i, r, reports[i-1])
+		}
+		orders[r.Order] = true
+	}
+	last := reports[len(reports)-1]
+	if len(orders) != 6 || last.Tried != space.Size() || last.Best != candidates[0].Score {
+		t.Errorf("the orders %v are reported, the last report being %+v", orders, last)
+	}
+	if got := atomic.LoadInt64(&progress); got != space.Size() {
+		t.Errorf("the progress ends at %d of %d", got, space.Size())
+	}
+}
+
+// A search cancelled or out of time stops, with the context's error
+// and the best candidates so far.
+func TestSearchCancel(t *testing.T) {
+	ciphertext := enigma.ParseGroups(testvectors.Barbarossa.Ciphertext, enigma.ClassicGroups)
+	space := Space{Rotors: []string{"I", "II", "III", "IV", "V"}, RingSlots: []int{3}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Search(ctx, ciphertext, space, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("the cancelled search ends with %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var tried int64
+	started := time.Now()
+	candidates, err := Search(ctx, ciphertext, space, Options{Report: func(r Report) { tried = r.Tried }})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the search out of time ends with %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("the search stops %v after it's out of time", elapsed)
+	}
+	if tried >= space.Size() || len(candidates) == 0 {
+		t.Errorf("%d of %d settings are tried, and %d candidates kept", tried, space.Size(), len(candidates))
+	}
+}
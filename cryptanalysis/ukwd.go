@@ -0,0 +1,59 @@
+package cryptanalysis
+
+import (
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/score"
+)
+
+// ukwdLetters are the letters the UKW-D is plugged with, all but the B
+// and the O of its fixed pair.
+const ukwdLetters = "ACDEFGHIJKLMNPQRSTUVWXYZ"
+
+// ukwdStart is the wiring the climb starts from: the letters paired in
+// order, AC DE FG and so on.
+func ukwdStart() []string {
+	pairs := make([]string, 0, len(ukwdLetters)/2)
+	for i := 0; i < len(ukwdLetters); i += 2 {
+		pairs = append(pairs, ukwdLetters[i:i+2])
+	}
+	return pairs
+}
+
+// climbUKWD rewires the UKW-D of the machine, a hill climb from the
+// wiring it has: two pairs at a time are plugged the other two ways
+// there are, and the way the decrypt at the positions scores best with
+// is kept, until no two pairs do any better. It returns the pairs, the
+// decrypt, and its score, leaving the machine wired with them. The
+// pairs are in the order the reflector lists them, AC before DE.
+func climbUKWD(e *enigma.Enigma, positions, ciphertext string, scorer score.Scorer) ([]string, string, float64) {
+	pairs := e.Reflector.Pairs()
+	decrypt := func() (string, float64) {
+		e.RewireReflector(pairs)
+		e.ResetTo(positions)
+		plaintext := e.EncodeString(ciphertext)
+		return plaintext, scorer.Score([]byte(plaintext))
+	}
+	plaintext, best := decrypt()
+	for improved := true; improved; {
+		improved = false
+		for i := range pairs {
+			for j := i + 1; j < len(pairs); j++ {
+				a, b := pairs[i], pairs[j]
+				for _, swap := range [2][2]string{
+					{string([]byte{a[0], b[0]}), string([]byte{a[1], b[1]})},
+					{string([]byte{a[0], b[1]}), string([]byte{a[1], b[0]})},
+				} {
+					pairs[i], pairs[j] = swap[0], swap[1]
+					if text, rating := decrypt(); rating > best {
+						plaintext, best, improved = text, rating, true
+						a, b = pairs[i], pairs[j]
+					} else {
+						pairs[i], pairs[j] = a, b
+					}
+				}
+			}
+		}
+	}
+	e.RewireReflector(pairs)
+	return e.Reflector.Pairs(), plaintext, best
+}
@@ -0,0 +1,77 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CustomRotors returns a copy of the rotors added with RegisterRotor,
+// for machines that never were on the lists, like the commercial K or the Swiss-K. They
+// are the family "custom" of the registry (see RotorFamilies).
+//
+// The registry isn't guarded: machines are built from it without a
+// lock, so register from an init function, or at least before any
+// machine is built. Registering while others are being built, e.g. by
+// enigmad or DecryptBatch, is a data race.
+func CustomRotors() Rotors {
+	return customRotors.Copy()
+}
+
+// CustomReflectors returns a copy of the reflectors added with
+// RegisterReflector (see CustomRotors).
+func CustomReflectors() Reflectors {
+	return customReflectors.Copy()
+}
+
+var (
+	customRotors     = Rotors{}
+	customReflectors = Reflectors{}
+)
+
+func init() {
+	rotorFamilies["custom"] = &customRotors
+}
+
+// RegisterRotor adds a rotor of the wiring (the letters the contacts
+// from A to Z are wired to) and the notches, which machines built with
+// NewMachine can then use by its ID, just like the historical ones. IDs
+// already taken can't be registered again. Register from init only (see
+// CustomRotors).
+func RegisterRotor(id, wiring string, notches []rune) error {
+	if id == "" {
+		return fmt.Errorf("a rotor needs an ID")
+	}
+	if Generic.Rotors.GetByID(id) != nil {
+		return fmt.Errorf(`rotor "%s" is already registered`, id)
+	}
+	config := RotorConfig{ID: id, Wiring: wiring, Notches: string(notches)}
+	if err := validateRotorWiring(config); err != nil {
+		return err
+	}
+	rotor := NewRotor(strings.ToUpper(wiring), id, strings.ToUpper(config.Notches))
+	customRotors = append(customRotors, *rotor)
+	n := len(Generic.Rotors)
+	Generic.Rotors = append(Generic.Rotors[:n:n], *rotor)
+	return nil
+}
+
+// RegisterReflector adds a reflector of the wiring, which has to swap
+// the letters in pairs, for machines built with NewMachine. IDs already
+// taken can't be registered again. Register from init only (see
+// CustomRotors).
+func RegisterReflector(id, wiring string) error {
+	if id == "" {
+		return fmt.Errorf("a reflector needs an ID")
+	}
+	if id == UKWD || Generic.Reflectors.GetByID(id) != nil {
+		return fmt.Errorf(`reflector "%s" is already registered`, id)
+	}
+	if err := validateReflectorWiring(ReflectorConfig{ID: id, Wiring: wiring}); err != nil {
+		return err
+	}
+	reflector := NewReflector(strings.ToUpper(wiring), id)
+	customReflectors = append(customReflectors, *reflector)
+	n := len(Generic.Reflectors)
+	Generic.Reflectors = append(Generic.Reflectors[:n:n], *reflector)
+	return nil
+}
@@ -0,0 +1,109 @@
+package enigma
+
+import "testing"
+
+// Wirings of the commercial Enigma K.
+const (
+	kRotorI     = "LPGSZMHAEOQKVXRFYBUTNICJDW"
+	kRotorII    = "SLVGBTFXJQOHEWIRZYAMKPCNDU"
+	kRotorIII   = "CJGDPSHKTURAWZXFMYNQOBVLIE"
+	kReflectorK = "IMETCGFRAYSQBZXWLHKDVUPOJN"
+)
+
+// restoreRegistry puts the registry back the way it was once the test
+// is over, so that what it registered doesn't leak into the others.
+func restoreRegistry(t *testing.T) {
+	rotors, reflectors := Generic.Rotors, Generic.Reflectors
+	custom, customRefs := customRotors, customReflectors
+	t.Cleanup(func() {
+		Generic.Rotors, Generic.Reflectors = rotors, reflectors
+		customRotors, customReflectors = custom, customRefs
+	})
+}
+
+// A machine of registered rotors and reflector encodes the same as one
+// with the wirings given in its configuration.
+func TestRegister(t *testing.T) {
+	restoreRegistry(t)
+	for _, rotor := range []struct{ id, wiring, notch string }{
+		{"K-I", kRotorI, "Y"}, {"K-II", kRotorII, "E"}, {"K-III", kRotorIII, "N"},
+	} {
+		if err := RegisterRotor(rotor.id, rotor.wiring, []rune(rotor.notch)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RegisterReflector("K", kReflectorK); err != nil {
+		t.Fatal(err)
+	}
+	if len(customRotors) != 3 || len(customReflectors) != 1 || rotorFamilies["custom"].GetByID("K-II") == nil {
+		t.Errorf("the registry has the rotors %v and the reflectors %v", customRotors, customReflectors)
+	}
+
+	registered, err := NewMachine(WithRotors(rotorsAt("K-I K-II K-III", "AZM")...), WithReflector("K"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wired, err := Generic.New(Config{
+		Rotors: []RotorConfig{
+			{ID: "K-I", Wiring: kRotorI, Notches: "Y", Start: 'A', Ring: 1},
+			{ID: "K-II", Wiring: kRotorII, Notches: "E", Start: 'Z', Ring: 1},
+			{ID: "K-III", Wiring: kRotorIII, Notches: "N", Start: 'M', Ring: 1},
+		},
+		Reflector: ReflectorConfig{ID: "K", Wiring: kReflectorK},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := registered.EncodeString(receiveText), wired.EncodeString(receiveText); got != want {
+		t.Errorf("the registered machine encodes to %s, expected %s", got, want)
+	}
+	if _, err := EnigmaI.New(Config{Rotors: rotorsAt("K-I II III", "AAA"), Reflector: ReflectorConfig{ID: "B"}}); err == nil {
+		t.Error("the Enigma I takes a registered rotor")
+	}
+}
+
+// Wirings that aren't permutations, reflectors that don't swap letters
+// in pairs, and IDs already taken aren't registered.
+func TestRegisterErrors(t *testing.T) {
+	restoreRegistry(t)
+	if err := RegisterRotor("K-I", kRotorI, []rune("Y")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterReflector("K", kReflectorK); err != nil {
+		t.Fatal(err)
+	}
+	rotors := []struct {
+		name, id, wiring, notches string
+	}{
+		{"no ID", "", kRotorII, "E"},
+		{"letter twice", "X1", "AACDEFGHIJKLMNOPQRSTUVWXYZ", "A"},
+		{"short wiring", "X2", "ABC", "A"},
+		{"notch", "X3", kRotorII, "1"},
+		{"historical ID", "I", kRotorII, "E"},
+		{"registered ID", "K-I", kRotorII, "E"},
+	}
+	for _, tt := range rotors {
+		if err := RegisterRotor(tt.id, tt.wiring, []rune(tt.notches)); err == nil {
+			t.Errorf("%s: the rotor is registered", tt.name)
+		}
+	}
+	reflectors := []struct {
+		name, id, wiring string
+	}{
+		{"no ID", "", kReflectorK},
+		{"not an involution", "X1", "BCDEFGHIJKLMNOPQRSTUVWXYZA"},
+		{"fixed points", "X2", "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+		{"not a permutation", "X3", "AACDEFGHIJKLMNOPQRSTUVWXYZ"},
+		{"historical ID", "B", kReflectorK},
+		{"UKW-D", UKWD, kReflectorK},
+		{"registered ID", "K", kReflectorK},
+	}
+	for _, tt := range reflectors {
+		if err := RegisterReflector(tt.id, tt.wiring); err == nil {
+			t.Errorf("%s: the reflector is registered", tt.name)
+		}
+	}
+	if len(customRotors) != 1 || len(customReflectors) != 1 {
+		t.Errorf("the registry has the rotors %v and the reflectors %v", customRotors, customReflectors)
+	}
+}
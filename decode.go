@@ -0,0 +1,150 @@
+package enigma
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// IsReciprocal tells if the machine decodes what it encodes at the same
+// setting, the way every Enigma did thanks to the reflector: it does as
+// long as the reflector and the plugboard swap letters in pairs, and the
+// lamps of the keyboard map undo its keys. Only then is DecodeString
+// the same as EncodeString. The Uhr doesn't swap letters in pairs, but
+// it undoes on the way out what it did on the way in, which does too.
+func (e *Enigma) IsReciprocal() bool {
+	return e.decoding().reciprocal
+}
+
+// decoder is the circuit decoding goes through: the one of the machine
+// inverted, the current going from the lamps to the keys. It's worked
+// out for the wiring in from, and again only once that changes.
+type decoder struct {
+	from       wiring
+	reciprocal bool
+	inverse    wiring
+}
+
+// wiring is what a circuit is wired with, the keyboard map left out
+// (keys and lamps the same way round) if keyboard isn't set.
+type wiring struct {
+	keyboard                              bool
+	keys, lamps, plugIn, plugOut, reflect [26]int
+}
+
+// wired tells if the circuit is wired with w.
+func (w *wiring) wired(c circuit) bool {
+	if w.keyboard != (c.keys != nil) || w.keyboard && (w.keys != *c.keys || w.lamps != *c.lamps) {
+		return false
+	}
+	return w.plugIn == *c.plugIn && w.plugOut == *c.plugOut && w.reflect == *c.reflector
+}
+
+// decoding returns the decoder of the machine as it's wired now.
+func (e *Enigma) decoding() *decoder {
+	c := e.circuit()
+	if e.decoder != nil && e.decoder.from.wired(c) {
+		return e.decoder
+	}
+	d := &decoder{from: wiring{plugIn: *c.plugIn, plugOut: *c.plugOut, reflect: *c.reflector}}
+	d.reciprocal = Permutation(d.from.reflect).IsInvolution() && d.from.plugOut == Permutation(d.from.plugIn).Inverse()
+	d.inverse = wiring{
+		keyboard: c.keys != nil,
+		plugIn:   Permutation(d.from.plugOut).Inverse(),
+		plugOut:  Permutation(d.from.plugIn).Inverse(),
+		reflect:  Permutation(d.from.reflect).Inverse(),
+	}
+	if c.keys != nil {
+		d.from.keyboard, d.from.keys, d.from.lamps = true, *c.keys, *c.lamps
+		d.reciprocal = d.reciprocal && d.from.lamps == Permutation(d.from.keys).Inverse()
+		d.inverse.keys, d.inverse.lamps = Permutation(d.from.lamps).Inverse(), Permutation(d.from.keys).Inverse()
+	}
+	e.decoder = d
+	return d
+}
+
+// circuit returns the circuit decoding goes through.
+func (d *decoder) circuit() circuit {
+	c := circuit{plugIn: &d.inverse.plugIn, plugOut: &d.inverse.plugOut, reflector: &d.inverse.reflect}
+	if d.inverse.keyboard {
+		c.keys, c.lamps = &d.inverse.keys, &d.inverse.lamps
+	}
+	return c
+}
+
+// DecodeChar decodes a single character: the rotors move the same way,
+// but the current goes through the machine backwards if it isn't
+// reciprocal (see IsReciprocal). The keypress is recorded as the one
+// encoding to the character. Like EncodeChar, it panics with a
+// CharacterError for anything but a capital letter.
+func (e *Enigma) DecodeChar(letter byte) byte {
+	if letter < 'A' || letter > 'Z' {
+		panic(&CharacterError{Rune: rune(letter), Index: -1, verb: "decoded"})
+	}
+	d := e.decoding()
+	if d.reciprocal {
+		return e.EncodeChar(letter)
+	}
+	e.mustBeSealed()
+	c := d.circuit()
+	return e.keypress(letter, &c, nil, true)
+}
+
+// DecodeRune decodes a single letter, the way EncodeRune encodes it.
+func (e *Enigma) DecodeRune(r rune) (rune, error) {
+	key, err := keyOf(r, -1, "decoded")
+	if err != nil {
+		return r, err
+	}
+	if err := e.checkSeal(); err != nil {
+		return rune(key), err
+	}
+	return rune(e.DecodeChar(key)), nil
+}
+
+// DecodeString decodes a string of capital letters: EncodeString
+// undone. Like EncodeString, an empty one decodes to nothing, and
+// anything but capital letters panics with a CharacterError before the
+// rotors move (see DecodeLetters for an error instead).
+func (e *Enigma) DecodeString(text string) string {
+	return string(e.DecodeBytes([]byte(text)))
+}
+
+// DecodeBytes decodes the letters in a byte slice, which is left as is,
+// checking them first the way DecodeString does.
+func (e *Enigma) DecodeBytes(text []byte) []byte {
+	if len(text) == 0 {
+		return nil
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] < 'A' || text[i] > 'Z' {
+			r, _ := utf8.DecodeRune(text[i:])
+			panic(&CharacterError{Rune: r, Index: i, verb: "decoded"})
+		}
+	}
+	started := metricsStart()
+	var result bytes.Buffer
+	for _, letter := range text {
+		result.WriteByte(e.DecodeChar(letter))
+	}
+	e.logEncode(len(text))
+	observe("enigma.decode", 1, len(text), started)
+	return result.Bytes()
+}
+
+// DecodeLetters decodes a text of letters, either upper or lower case,
+// in capitals, like EncodeLetters: anything else is a CharacterError
+// with its index, and the rotors don't move.
+func (e *Enigma) DecodeLetters(text string) (string, error) {
+	keys := make([]byte, 0, len(text))
+	for i, r := range text {
+		key, err := keyOf(r, i, "decoded")
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	if err := e.checkSeal(); err != nil {
+		return "", err
+	}
+	return e.DecodeString(string(keys)), nil
+}
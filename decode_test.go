@@ -0,0 +1,163 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// rotation returns the wiring taking every letter n letters on, which
+// swaps no letters in pairs.
+func rotation(n int) [26]int {
+	var wiring [26]int
+	for i := range wiring {
+		wiring[i] = mod26(i + n)
+	}
+	return wiring
+}
+
+// roundTrip encodes the text on a copy of the machine and decodes it
+// on another, returning the ciphertext and the decrypt.
+func roundTrip(e *Enigma, text string) (string, string) {
+	ciphertext := e.Clone().EncodeString(text)
+	return ciphertext, e.Clone().DecodeString(ciphertext)
+}
+
+func TestDecodeNotReciprocal(t *testing.T) {
+	const plaintext = "ANGRIFFIMMORGENGRAUENXANGRIFFIMMORGENGRAUEN"
+	tests := []struct {
+		name string
+		wire func(e *Enigma)
+	}{
+		{"reflector", func(e *Enigma) { e.Reflector.Sequence = rotation(1) }},
+		{"keyboard", func(e *Enigma) { e.Keyboard = &KeyMap{Keys: rotation(3), Lamps: rotation(3)} }},
+		{"both", func(e *Enigma) {
+			e.Reflector.Sequence = rotation(7)
+			e.Keyboard = &KeyMap{Keys: rotation(3), Lamps: rotation(5)}
+		}},
+	}
+	for _, tt := range tests {
+		config := classicConfig()
+		config.Plugboard = []string{"AB", "CD", "EF"}
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.wire(e)
+		if e.IsReciprocal() {
+			t.Errorf("%s: the machine is reciprocal", tt.name)
+		}
+		ciphertext, decrypt := roundTrip(e, plaintext)
+		if decrypt != plaintext {
+			t.Errorf("%s: %s decodes to %s, expected %s", tt.name, ciphertext, decrypt, plaintext)
+		}
+		if e.Clone().EncodeString(ciphertext) == plaintext {
+			t.Errorf("%s: %s encodes back to the plaintext", tt.name, ciphertext)
+		}
+	}
+}
+
+// The Uhr doesn't swap letters in pairs at 3, but the way back undoes
+// the way in, so the machine is reciprocal all the same.
+func TestDecodeUhr(t *testing.T) {
+	const plaintext = "QRSTUVWXYZANGRIFFIMMORGENGRAUENABCDEFGHIJKLMNOP"
+	config := classicConfig()
+	config.Plugboard = uhrPairs
+	config.Uhr = &UhrConfig{Position: 3}
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.IsReciprocal() {
+		t.Error("the machine with the Uhr at 3 isn't reciprocal")
+	}
+	ciphertext, decrypt := roundTrip(e, plaintext)
+	if decrypt != plaintext {
+		t.Errorf("%s decodes to %s, expected %s", ciphertext, decrypt, plaintext)
+	}
+	if again := e.Clone().EncodeString(ciphertext); again != plaintext {
+		t.Errorf("%s encodes to %s, expected %s", ciphertext, again, plaintext)
+	}
+}
+
+// Decoding follows the wiring when it changes between keypresses.
+func TestDecodeRewired(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Reflector.Sequence = rotation(1)
+	encoder := e.Clone()
+	ciphertext := encoder.EncodeString("WETTER")
+	encoder.Reflector.Sequence = rotation(2)
+	encoder.Plugboard = *NewPlugboard([]string{"WX", "YZ"})
+	ciphertext += encoder.EncodeString("BERICHT")
+
+	decrypt := e.DecodeString(ciphertext[:6])
+	e.Reflector.Sequence = rotation(2)
+	e.Plugboard = *NewPlugboard([]string{"WX", "YZ"})
+	decrypt += e.DecodeString(ciphertext[6:])
+	if decrypt != "WETTERBERICHT" {
+		t.Errorf("%s decodes to %s, expected WETTERBERICHT", ciphertext, decrypt)
+	}
+}
+
+// DecodeRune and DecodeBytes go the same way back as DecodeString.
+func TestDecodeRuneBytes(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Reflector.Sequence = rotation(5)
+	ciphertext, want := roundTrip(e, "WETTERBERICHT")
+	if got := string(e.Clone().DecodeBytes([]byte(ciphertext))); got != want {
+		t.Errorf("DecodeBytes decodes %s to %s, expected %s", ciphertext, got, want)
+	}
+	var got []rune
+	for _, r := range ciphertext {
+		if r%2 == 0 {
+			r += 'a' - 'A'
+		}
+		plain, err := e.DecodeRune(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, plain)
+	}
+	if string(got) != want {
+		t.Errorf("DecodeRune decodes %s to %s, expected %s", ciphertext, string(got), want)
+	}
+	if _, err := e.DecodeRune('ß'); err == nil {
+		t.Error("ß is decoded")
+	}
+}
+
+// Like EncodeString, DecodeString checks the whole text before the
+// rotors move, and DecodeLetters returns the error instead.
+func TestDecodeCharacterError(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			charErr, ok := recover().(*CharacterError)
+			if !ok || charErr.Rune != '1' || charErr.Index != 2 || charErr.Error() != `only letters can be decoded, got "1" at 2` {
+				t.Errorf("DecodeString panics with %v", charErr)
+			}
+		}()
+		e.DecodeString("AB1")
+	}()
+	if e.Positions() != "AAA" {
+		t.Errorf("the rotors moved to %s", e.Positions())
+	}
+	if _, err := e.DecodeLetters("AB 1"); !errors.Is(err, ErrInvalidCharacter) || err.Error() != `only letters can be decoded, got " " at 2` {
+		t.Errorf("DecodeLetters returns %v", err)
+	}
+	if e.Positions() != "AAA" {
+		t.Errorf("the rotors moved to %s", e.Positions())
+	}
+	want := e.Clone().DecodeString("BDZGO")
+	if got, err := e.DecodeLetters("bdZgo"); got != want || err != nil {
+		t.Errorf("DecodeLetters decodes bdZgo to %s (%v), expected %s", got, err, want)
+	}
+}
@@ -41,103 +41,527 @@
 // are pre-loaded.
 //
 // — Reflector: reflectors A, B, and C — as well as the thin B and C
-// versions used in M4 — are supported.
+// versions used in M4 — are supported, and so is the field-rewirable
+// UKW-D.
 //
 // — Plugboard: any number of letter pairs is accepted. Plugboard
-// configuration is optional.
+// configuration is optional. The historical presets limit it to the ten
+// cables used for most of the war (six before 1939).
 //
 // — Ring offsets and starting position of the rotors.
 //
+// — Entry wheel: the alphabetical military one, the QWERTZ-ordered
+// commercial one, and the one from the Tirpitz machine.
+//
+// — Presets for the Enigma I (NewEnigmaI), the M3 (NewEnigmaM3), and the
+// M4 (NewEnigmaM4) check the configuration against what the machines
+// actually supported. NewEnigmaM3Navy adds the rule of the naval nets:
+// one of the rotors VI to VIII always has to be in.
+//
+// — Presets for other models: NewEnigmaT builds the Tirpitz (Enigma T)
+// with its eight five-notch rotors and no plugboard. NewEnigmaD builds
+// the commercial Enigma D with its settable reflector, and NewEnigmaK the
+// Swiss Enigma K, both with the QWERTZ entry wheel. NewEnigmaKD adds the
+// rewirable UKW-D to the commercial K machine, and NewEnigmaG312 and
+// NewEnigmaG260 build the Abwehr Enigma G, whose many-notch rotors are
+// driven by gears and whose reflector steps along with them.
+//
+// — Machines that never existed: NewMachine accepts any number of rotors,
+// from a two-rotor toy for teaching to a seven-rotor monster. Any rotor
+// can be marked as fixed to keep it from stepping. NewAlphabetMachine goes
+// beyond the 26 letters, with rotors wired for the digits of the Enigma Z,
+// a ring of 36, or any other Alphabet, stepping like the Latin machines,
+// with a plugboard and an entry wheel, and saved as an AlphabetConfig.
+//
 // M3 and M4 can be fully emulated with the right parameters, and if it's
 // not enough, new rotors and reflectors can be added quite easily: just
 // add a new entry to the list in `rotors.go`, and that's it. Notches for
 // rotor turnover are optional.
 //
-// Some exotic Enigma variants and implementations, as well
-// as devices such as Uhr, are not supported due to my chronic lack of
-// spare time. Your pull requests would be most welcome!
+// The Uhr can be plugged in instead of the plugboard cables with
+// `WithUhr`, and its dial turned with `SetUhrPosition`. Some exotic
+// Enigma variants and implementations, as well as other devices, are
+// not supported due to my chronic lack of spare time. Your pull
+// requests would be most welcome!
 package enigma
 
-import "bytes"
+import (
+	"bytes"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+)
 
 // Enigma represents an Enigma machine with configured rotors, plugs,
-// and a reflector. Most states are stored in the rotors themselves.
+// an entry wheel, and a reflector. Most states are stored in the rotors
+// themselves. Display sets how Positions shows the rotor windows,
+// Keyboard, if set, remaps the keys of a modern keyboard, and Groups, if
+// set, is how EncodeFormatted writes the result. Procedure is how
+// EncryptWithIndicator sends the message key. Stepping is the stepping
+// mechanism, the lever one if not set, and NoDoubleStep turns off its
+// double step, for showing what difference it makes. RotatingReflector
+// makes the reflector step like a rotor to the left of the others, as
+// on the Enigma G. Uhr, if set, takes the place of the plugboard, which
+// it's plugged in with.
 type Enigma struct {
-	Reflector Reflector
-	Plugboard Plugboard
-	Rotors    []*Rotor
+	Reflector  Reflector
+	Plugboard  Plugboard
+	Uhr        *Uhr
+	EntryWheel EntryWheel
+	Rotors     []*Rotor
+	Display    DisplayMode
+	Keyboard   *KeyMap
+	Groups     *GroupOptions
+	Procedure  IndicatorProcedure
+
+	Stepping          SteppingMechanism
+	NoDoubleStep      bool
+	RotatingReflector bool
+
+	start          []int
+	reflectorStart int
+	stats          MachineStats
+	timeStages     bool
+	audit          *auditLog
+	// moves counts the keypresses since the rotors were last set, and
+	// doubles lists the ones that made a double step, so that StepBack
+	// can tell apart the positions that step to the same one.
+	moves   int
+	doubles []int
+
+	model      *Model
+	transcript Transcript
+	logger     *slog.Logger
+
+	stepHooks []stepHook
+	hookID    int
+	// lit is the lamp lit by the key held down (see KeyDown), if any.
+	lit rune
+
+	// moving and doubling are what next tells the moves in, bulk the
+	// tables of EncodeBytes, and decoder the circuit of DecodeChar.
+	moving, doubling []bool
+	bulk             *bulkTables
+	decoder          *decoder
+
+	seal *seal
 }
 
 // RotorConfig reprensents a configuration for a rotor as set by the user:
 // ID from the pre-defined list, a starting position (A to Z), and a ring
-// setting (1 to 26).
+// setting (1 to 26). Every slot steps unless it's marked as Fixed, and
+// is driven by its right neighbour unless DrivenBy says otherwise (the
+// slot of the driving rotor, counting from 1 on the left). A rotor
+// that isn't on the list (see WithRotorInstances) comes with its Wiring
+// and Notches instead, as NewRotor takes them.
 type RotorConfig struct {
-	ID    string
-	Start byte
-	Ring  int
+	ID       string
+	Start    byte
+	Ring     int
+	Fixed    bool
+	DrivenBy int
+	Wiring   string
+	Notches  string
+}
+
+// ReflectorConfig represents a configuration for a reflector: ID from
+// the pre-defined list and, on models where the reflector can be set,
+// its position (A to Z, A if not set). The rewirable UKW-D takes its
+// letter pairs instead, and a reflector that isn't on the list (see
+// WithReflectorInstance) its Wiring, as NewReflector takes it.
+type ReflectorConfig struct {
+	ID     string
+	Start  byte
+	Pairs  []string
+	Wiring string
 }
 
 // NewEnigma is the Enigma constructor, accepting an array of RotorConfig objects
 // for rotors, a reflector ID/name, and an array of plugboard pairs.
+// Only the three rightmost rotors step, so with four rotors the machine
+// behaves like the M4. Use NewMachine for other rotor counts.
 func NewEnigma(rotorConfiguration []RotorConfig, refID string, plugs []string) *Enigma {
+	e := newEnigma(historicRotors, rotorConfiguration,
+		historicReflectors.GetByID(refID), historicEntryWheels.GetByID("ABC"), plugs)
+	for i := 0; i < len(e.Rotors)-3; i++ {
+		e.Rotors[i].Fixed = true
+	}
+	e.makeStrict(strictDefault())
+	return e
+}
+
+func newEnigma(set Rotors, rotorConfiguration []RotorConfig, ref *Reflector, etw *EntryWheel, plugs []string) *Enigma {
 	rotors := make([]*Rotor, len(rotorConfiguration))
+	start := make([]int, len(rotorConfiguration))
 	for i, configuration := range rotorConfiguration {
-		rotors[i] = HistoricRotors.GetByID(configuration.ID)
+		rotors[i] = configuration.rotor(set)
 		rotors[i].Offset = CharToIndex(configuration.Start)
 		rotors[i].Ring = configuration.Ring - 1
+		rotors[i].Fixed = configuration.Fixed
+		rotors[i].DrivenBy = configuration.DrivenBy
+		start[i] = rotors[i].Offset
+	}
+	return &Enigma{
+		Reflector:  *ref,
+		Plugboard:  *NewPlugboard(plugs),
+		EntryWheel: *etw,
+		Rotors:     rotors,
+		start:      start,
 	}
-	return &Enigma{*HistoricReflectors.GetByID(refID), *NewPlugboard(plugs), rotors}
 }
 
+// Clone returns an independent copy of the machine in its current
+// state, e.g. to give every goroutine a machine of its own.
+func (e *Enigma) Clone() *Enigma {
+	c := *e
+	c.Rotors = make([]*Rotor, len(e.Rotors))
+	for i, rotor := range e.Rotors {
+		r := *rotor
+		r.Turnover = append([]int(nil), rotor.Turnover...)
+		c.Rotors[i] = &r
+	}
+	c.start = append([]int(nil), e.start...)
+	c.stats.Steps = append([]int(nil), e.stats.Steps...)
+	c.doubles = append([]int(nil), e.doubles...)
+	if e.transcript != nil {
+		c.transcript = append(Transcript{}, e.transcript...)
+	}
+	c.stepHooks, c.audit = nil, nil
+	c.moving, c.doubling, c.bulk, c.decoder = nil, nil, nil, nil
+	if e.Uhr != nil {
+		u := *e.Uhr
+		u.pairs = e.Uhr.Pairs()
+		c.Uhr = &u
+	}
+	if e.seal != nil {
+		s := *e.seal
+		s.offsets = append([]int(nil), e.seal.offsets...)
+		c.seal = &s
+	}
+	return &c
+}
+
+// moveRotors steps the rotors for a keypress, and counts it.
 func (e *Enigma) moveRotors() {
-	var (
-		rotorLen            = len(e.Rotors)
-		farRight            = e.Rotors[rotorLen-1]
-		farRightTurnover    = farRight.ShouldTurnOver()
-		secondRight         = e.Rotors[rotorLen-2]
-		secondRightTurnover = secondRight.ShouldTurnOver()
-		thirdRight          = e.Rotors[rotorLen-3]
-	)
-	if secondRightTurnover {
-		if !farRightTurnover {
-			secondRight.move(1)
+	if len(e.stats.Steps) != len(e.Rotors) {
+		e.stats.Steps = make([]int, len(e.Rotors))
+	}
+	e.stats.Keypresses++
+	e.moves++
+	for _, rotor := range e.Rotors {
+		rotor.Offset = mod26(rotor.Offset)
+	}
+	var moved []bool
+	if len(e.stepHooks) > 0 {
+		moved = e.NextStep().Moves
+	}
+	reflector := e.reflectorMoves()
+	for doubles := e.turn(&e.stats); doubles > 0; doubles-- {
+		e.doubles = append(e.doubles, e.moves)
+	}
+	if reflector {
+		e.Reflector.Position = mod26(e.Reflector.Position + 1)
+	}
+	e.sealOffsets()
+	if moved != nil {
+		e.notifySteps(moved)
+	}
+}
+
+// reflectorMoves tells if a rotating reflector moves on the next
+// keypress: it does when the leftmost stepping rotor moves past a notch,
+// as if it were driving one more rotor.
+func (e *Enigma) reflectorMoves() bool {
+	if !e.RotatingReflector {
+		return false
+	}
+	moving, _ := e.next()
+	for i, rotor := range e.Rotors {
+		if !rotor.Fixed && i < len(moving) {
+			return moving[i] && rotor.ShouldTurnOver()
+		}
+	}
+	return false
+}
+
+// turn steps the rotors the way the stepping mechanism has them move
+// (see SteppingMechanism). The steps are counted in stats, unless it's
+// nil, and the number of double steps is returned.
+func (e *Enigma) turn(stats *MachineStats) int {
+	doubles := 0
+	moving, doubling := e.next()
+	for i, rotor := range e.Rotors {
+		if rotor.Fixed || i >= len(moving) {
+			continue
+		}
+		moves, double := moving[i], i < len(doubling) && doubling[i]
+		if moves {
+			rotor.move(1)
+			if double {
+				doubles++
+			}
+			if stats != nil {
+				stats.Steps[i]++
+				if double {
+					stats.DoubleSteps++
+				}
+			}
 		}
-		thirdRight.move(1)
 	}
-	if farRightTurnover {
-		secondRight.move(1)
+	return doubles
+}
+
+// StepPrediction tells which rotors move on a keypress, from left to
+// right, and whether it's a double step.
+type StepPrediction struct {
+	Moves      []bool
+	DoubleStep bool
+}
+
+// NextStep tells how the rotors will move on the next keypress,
+// without moving them.
+func (e *Enigma) NextStep() StepPrediction {
+	p := StepPrediction{Moves: make([]bool, len(e.Rotors))}
+	moving, doubling := e.next()
+	for i, rotor := range e.Rotors {
+		if rotor.Fixed || i >= len(moving) {
+			continue
+		}
+		p.Moves[i] = moving[i]
+		p.DoubleStep = p.DoubleStep || (i < len(doubling) && doubling[i])
+	}
+	return p
+}
+
+// StepSchedule tells how the rotors of a machine of the configuration
+// (see NewMachine) move on each of the first keypresses, the same way
+// NextStep does, without encoding anything. A negative number of
+// keypresses is an error.
+func StepSchedule(config Config, keypresses int) ([]StepPrediction, error) {
+	if err := checkKeypresses(keypresses); err != nil {
+		return nil, err
+	}
+	e, err := Generic.New(config)
+	if err != nil {
+		return nil, err
 	}
-	farRight.move(1)
+	schedule := make([]StepPrediction, keypresses)
+	for i := range schedule {
+		schedule[i] = e.NextStep()
+		e.turn(nil)
+	}
+	return schedule, nil
 }
 
-// EncodeChar encodes a single character.
+// EncodeChar encodes a single capital letter. Anything else panics with
+// a CharacterError before the rotors move (see EncodeRune for an error
+// instead).
 func (e *Enigma) EncodeChar(letter byte) byte {
+	if letter < 'A' || letter > 'Z' {
+		panic(&CharacterError{Rune: rune(letter), Index: -1})
+	}
+	e.mustBeSealed()
+	c := e.circuit()
+	return e.keypress(letter, &c, nil, false)
+}
+
+// keypress presses the key: the rotors move, and the current goes
+// through the circuit and the rest of the machine (see signal), with
+// every stage timed if they're timed, to the lamp it returns. The
+// keypress is counted, and written to the transcript and the audit log
+// as one decoding if decode is set, i.e. with the lamp as the key.
+func (e *Enigma) keypress(letter byte, c *circuit, t *bulkTables, decode bool) byte {
+	var before string
+	if e.audit != nil {
+		before = e.auditPositions()
+	}
+	var timer *stageTimer
+	var hook func(part, int)
+	if e.timeStages {
+		timer = &stageTimer{stages: &e.stats.Stages, started: time.Now()}
+		hook = timer.lap
+	}
 	e.moveRotors()
+	if timer != nil {
+		timer.stepped()
+	}
+	lamp := IndexToChar(e.signal(CharToIndex(letter), c, t, hook))
+	e.stats.Letters++
 
-	letterIndex := CharToIndex(letter)
-	letterIndex = e.Plugboard[letterIndex]
+	if e.transcript != nil {
+		keypress := Keypress{letter, lamp, e.Positions()}
+		if decode {
+			keypress.Key, keypress.Lamp = lamp, letter
+		}
+		e.transcript = append(e.transcript, keypress)
+	}
+	if e.audit != nil {
+		e.logKeypress(before, letter, lamp, decode)
+	}
+	return lamp
+}
 
-	for i := len(e.Rotors) - 1; i >= 0; i-- {
-		letterIndex = e.Rotors[i].Step(letterIndex, false)
+// circuit is what the current goes through on a keypress but for the
+// rotors and the entry wheel: the keyboard map, if any, the plugboard
+// (or the Uhr) on the way in and on the way out, and the wiring of the
+// reflector. Encoding goes through the machine's own, decoding on a
+// machine that isn't reciprocal through them inverted (see decoder).
+type circuit struct {
+	keys, lamps     *[26]int
+	plugIn, plugOut *[26]int
+	reflector       *[26]int
+}
+
+// circuit returns the machine's own circuit.
+func (e *Enigma) circuit() circuit {
+	plugboard := (*[26]int)(&e.Plugboard)
+	c := circuit{plugIn: plugboard, plugOut: plugboard, reflector: &e.Reflector.Sequence}
+	if e.Uhr != nil {
+		c.plugIn, c.plugOut = &e.Uhr.in, &e.Uhr.out
+	}
+	if e.Keyboard != nil {
+		c.keys, c.lamps = &e.Keyboard.Keys, &e.Keyboard.Lamps
 	}
+	return c
+}
+
+// part is a part of the machine the current goes through, as told to
+// the hook of signal.
+type part int
 
-	letterIndex = e.Reflector.Sequence[letterIndex]
+const (
+	partKeyboard part = iota
+	partPlugboard
+	partEntryWheel
+	partRotor
+	partReflector
+)
 
-	for i := 0; i < len(e.Rotors); i++ {
-		letterIndex = e.Rotors[i].Step(letterIndex, true)
+// signal sends the current of the key through the circuit and the
+// machine as it is, without moving the rotors, returning the lamp it
+// lights. It's the one way every keypress goes. The rotors are looked
+// up in the tables, if any, rather than stepped through. The hook, if
+// any, is told the letter (the contact) the current is at after every
+// part on the way, the keyboard map left out if there isn't one.
+func (e *Enigma) signal(letterIndex int, c *circuit, t *bulkTables, hook func(part, int)) int {
+	if c.keys != nil {
+		letterIndex = c.keys[letterIndex]
+		if hook != nil {
+			hook(partKeyboard, letterIndex)
+		}
 	}
+	letterIndex = c.plugIn[letterIndex]
+	if hook != nil {
+		hook(partPlugboard, letterIndex)
+	}
+	letterIndex = e.EntryWheel.Step(letterIndex, false)
+	if hook != nil {
+		hook(partEntryWheel, letterIndex)
+	}
+	if t != nil {
+		t.shift(e.Rotors)
+	}
+	for i := len(e.Rotors) - 1; i >= 0; i-- {
+		if t != nil {
+			letterIndex = int(t.forward[t.shifts[i]+letterIndex])
+		} else {
+			letterIndex = e.Rotors[i].Step(letterIndex, false)
+		}
+		if hook != nil {
+			hook(partRotor, letterIndex)
+		}
+	}
+	position := e.Reflector.Position
+	letterIndex = mod26(c.reflector[mod26(letterIndex+position)] - position)
+	if hook != nil {
+		hook(partReflector, letterIndex)
+	}
+	for i, rotor := range e.Rotors {
+		if t != nil {
+			letterIndex = int(t.reverse[t.shifts[i]+letterIndex])
+		} else {
+			letterIndex = rotor.Step(letterIndex, true)
+		}
+		if hook != nil {
+			hook(partRotor, letterIndex)
+		}
+	}
+	letterIndex = e.EntryWheel.Step(letterIndex, true)
+	if hook != nil {
+		hook(partEntryWheel, letterIndex)
+	}
+	letterIndex = c.plugOut[letterIndex]
+	if hook != nil {
+		hook(partPlugboard, letterIndex)
+	}
+	if c.lamps != nil {
+		letterIndex = c.lamps[letterIndex]
+		if hook != nil {
+			hook(partKeyboard, letterIndex)
+		}
+	}
+	return letterIndex
+}
 
-	letterIndex = e.Plugboard[letterIndex]
-	letter = IndexToChar(letterIndex)
+// substitute is signal through the machine's own circuit.
+func (e *Enigma) substitute(letterIndex int) int {
+	c := e.circuit()
+	return e.signal(letterIndex, &c, nil, nil)
+}
 
-	return letter
+// EncodeRune encodes a single letter, either upper or lower case; the
+// lamps only have capital letters, so that's what is returned. Anything
+// else is a CharacterError, and the rotors don't move.
+func (e *Enigma) EncodeRune(r rune) (rune, error) {
+	key, err := keyOf(r, -1, "encoded")
+	if err != nil {
+		return r, err
+	}
+	if err := e.checkSeal(); err != nil {
+		return rune(key), err
+	}
+	return rune(e.EncodeChar(key)), nil
 }
 
-// EncodeString encodes a string.
+// EncodeString encodes a string of capital letters. An empty one encodes
+// to nothing, and the rotors stay where they are. Anything but capital
+// letters panics with a CharacterError before the rotors move (see
+// EncodeLetters for an error instead).
 func (e *Enigma) EncodeString(text string) string {
+	if text == "" {
+		return ""
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] < 'A' || text[i] > 'Z' {
+			r, _ := utf8.DecodeRuneInString(text[i:])
+			panic(&CharacterError{Rune: r, Index: i})
+		}
+	}
+	started := metricsStart()
 	var result bytes.Buffer
 	for i := range text {
 		result.WriteByte(e.EncodeChar(text[i]))
 	}
+	e.logEncode(len(text))
+	observe("enigma.encode", 1, len(text), started)
 	return result.String()
 }
+
+// EncodeLetters encodes a text of letters, either upper or lower case,
+// in capitals. Anything else, spaces included, is a CharacterError with
+// its index, and the rotors don't move (see EncodeText to have the rest
+// sanitized).
+func (e *Enigma) EncodeLetters(text string) (string, error) {
+	keys := make([]byte, 0, len(text))
+	for i, r := range text {
+		key, err := keyOf(r, i, "encoded")
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	if err := e.checkSeal(); err != nil {
+		return "", err
+	}
+	return e.EncodeString(string(keys)), nil
+}
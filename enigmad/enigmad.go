@@ -0,0 +1,394 @@
+// Package enigmad serves machines over HTTP with JSON, for those who'd
+// rather not write Go: a session is a machine built from a settings
+// payload, whose rotors stay where the last chunk left them, so a long
+// message can be sent a chunk at a time.
+//
+//	POST   /sessions             {"model": "M3", "config": {...}}
+//	GET    /sessions/{id}
+//	POST   /sessions/{id}/encode {"text": "..."}
+//	POST   /sessions/{id}/decode {"text": "..."}
+//	POST   /sessions/{id}/reset
+//	DELETE /sessions/{id}
+//	GET    /rotors
+//
+// The configuration is an enigma.Config as it's written in JSON, and the
+// model one of enigma.KnownModels, the generic one if left out. Every
+// response is the state of the session, with the text encoded or decoded,
+// if any; errors come as {"error": "..."}. Sessions left without
+// requests are ended after a while (see Server). GET /rotors lists what
+// can go in a configuration instead (see Listing).
+//
+// With a metrics collector set (see enigma.SetMetricsCollector), the
+// encode and decode requests are counted the way the machines count
+// theirs, under enigmad.encode and enigmad.decode: .messages for the
+// chunks, .characters for their letters, the time each request took,
+// and .errors.bad_request or .errors.no_session for the ones that went
+// wrong.
+package enigmad
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emedvedev/enigma"
+)
+
+// DefaultMaxSessions is how many sessions a server keeps at once, unless
+// set otherwise.
+const DefaultMaxSessions = 1024
+
+// DefaultIdleTimeout is how long a server keeps a session nobody sends
+// requests to, unless set otherwise.
+const DefaultIdleTimeout = 30 * time.Minute
+
+// maxBody is the largest request the server reads.
+const maxBody = 1 << 20
+
+// Server is the HTTP handler of the sessions. MaxSessions limits how many
+// there can be at once (DefaultMaxSessions if not set), and a session
+// without a request for IdleTimeout (DefaultIdleTimeout if not set) is
+// ended, as if deleted, when it's next asked for or when there's no
+// room for a new one. It's safe to serve any number of requests at
+// once: the requests of a session are taken one at a time, like
+// keypresses, and the others don't wait.
+type Server struct {
+	MaxSessions int
+	IdleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	// now is the clock sessions go idle by, time.Now if not set.
+	now func() time.Time
+}
+
+// session is a machine and the lock of its requests. used is when it
+// last had one, guarded by the lock of the server.
+type session struct {
+	mu    sync.Mutex
+	model string
+	e     *enigma.Enigma
+	used  time.Time
+}
+
+// NewServer returns a server with no sessions.
+func NewServer() *Server {
+	return &Server{sessions: make(map[string]*session)}
+}
+
+// CreateRequest is the payload creating a session.
+type CreateRequest struct {
+	Model  string        `json:"model,omitempty"`
+	Config enigma.Config `json:"config"`
+}
+
+// TextRequest is the payload of a chunk to encode or decode: letters
+// only, in either case.
+type TextRequest struct {
+	Text string `json:"text"`
+}
+
+// Session is what the server answers with: the session, the model and
+// configuration of its machine (with the rotors where they are now),
+// what the rotor windows show, and the text, if the request had one.
+type Session struct {
+	ID        string        `json:"id"`
+	Model     string        `json:"model"`
+	Config    enigma.Config `json:"config"`
+	Positions string        `json:"positions"`
+	Text      string        `json:"text,omitempty"`
+}
+
+// Listing is what the server answers GET /rotors with: the rotors
+// and reflectors there are, in the order of enigma.AvailableRotors and
+// enigma.AvailableReflectors, and what can be set on each model.
+type Listing struct {
+	Rotors     []enigma.CatalogueEntry `json:"rotors"`
+	Reflectors []string                `json:"reflectors"`
+	Models     []enigma.Capabilities   `json:"models"`
+}
+
+// errorResponse is what the server answers with when it can't do what
+// was asked.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP routes the request (see the package documentation).
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] == "rotors" && len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not supported on %s", r.Method, r.URL.Path))
+			return
+		}
+		writeJSON(w, http.StatusOK, listing())
+		return
+	}
+	if parts[0] != "sessions" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no such resource "%s"`, r.URL.Path))
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.create(w, r)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.withSession(w, parts[1], func(id string, ss *session) (int, interface{}) {
+			return http.StatusOK, ss.state(id, "")
+		})
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.delete(w, parts[1])
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		s.act(w, r, parts[1], parts[2])
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not supported on %s", r.Method, r.URL.Path))
+	}
+}
+
+// listing returns what the registry and the models have now.
+func listing() Listing {
+	l := Listing{Rotors: enigma.AvailableRotors(), Reflectors: enigma.AvailableReflectors()}
+	for _, model := range enigma.KnownModels {
+		l.Models = append(l.Models, model.Capabilities())
+	}
+	return l
+}
+
+// create builds a machine from the payload and starts a session of it.
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var request CreateRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	model := &enigma.Generic
+	if request.Model != "" {
+		if model = enigma.KnownModels.GetByName(request.Model); model == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(`unknown model "%s"`, request.Model))
+			return
+		}
+	}
+	e, err := model.New(request.Config)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.mu.Lock()
+	now := s.clock()
+	ss := &session{model: model.Name, e: e, used: now}
+	if s.sessions == nil {
+		s.sessions = make(map[string]*session)
+	}
+	max := s.MaxSessions
+	if max <= 0 {
+		max = DefaultMaxSessions
+	}
+	if len(s.sessions) >= max {
+		s.endIdle(now)
+	}
+	if len(s.sessions) >= max {
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("too many sessions, %d at most", max))
+		return
+	}
+	s.sessions[id] = ss
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, ss.state(id, ""))
+}
+
+// act encodes or decodes a chunk, or resets the machine. The chunks
+// are counted in the metrics (see the package documentation).
+func (s *Server) act(w http.ResponseWriter, r *http.Request, id, action string) {
+	started := time.Now()
+	if action != "encode" && action != "decode" && action != "reset" {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no such action "%s"`, action))
+		return
+	}
+	var request TextRequest
+	if action != "reset" {
+		if err := readJSON(r, &request); err != nil {
+			countError(action, "bad_request")
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	text, err := letters(request.Text)
+	if err != nil {
+		countError(action, "bad_request")
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	found := s.withSession(w, id, func(id string, ss *session) (int, interface{}) {
+		switch action {
+		case "encode":
+			return http.StatusOK, ss.state(id, ss.e.EncodeString(text))
+		case "decode":
+			return http.StatusOK, ss.state(id, ss.e.DecodeString(text))
+		}
+		ss.e.Reset()
+		return http.StatusOK, ss.state(id, "")
+	})
+	if !found {
+		countError(action, "no_session")
+		return
+	}
+	observe(action, len(text), started)
+}
+
+// counted tells if the requests of the action are counted in the
+// metrics: the encodings and the decodings.
+func counted(action string) bool {
+	return action == "encode" || action == "decode"
+}
+
+// countError counts a request of the action that went wrong, by kind.
+func countError(action, kind string) {
+	if collector := enigma.MetricsCollector(); collector != nil && counted(action) {
+		collector.IncCounter("enigmad."+action+".errors."+kind, 1)
+	}
+}
+
+// observe counts a chunk of the action and its letters, along with the
+// time the request took since it started.
+func observe(action string, letters int, started time.Time) {
+	collector := enigma.MetricsCollector()
+	if collector == nil || !counted(action) {
+		return
+	}
+	collector.IncCounter("enigmad."+action+".messages", 1)
+	collector.IncCounter("enigmad."+action+".characters", int64(letters))
+	collector.ObserveDuration("enigmad."+action, time.Since(started))
+}
+
+// delete ends a session.
+func (s *Server) delete(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no such session "%s"`, id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withSession calls f with the session locked, and writes what it
+// returns. A session idle for too long is ended instead. It tells if
+// there was a session to call f with.
+func (s *Server) withSession(w http.ResponseWriter, id string, f func(string, *session) (int, interface{})) bool {
+	s.mu.Lock()
+	now := s.clock()
+	ss := s.sessions[id]
+	if ss != nil && s.idle(ss, now) {
+		delete(s.sessions, id)
+		ss = nil
+	} else if ss != nil {
+		ss.used = now
+	}
+	s.mu.Unlock()
+	if ss == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no such session "%s"`, id))
+		return false
+	}
+	status, response := ss.do(id, f)
+	writeJSON(w, status, response)
+	return true
+}
+
+// do calls f with the session locked.
+func (ss *session) do(id string, f func(string, *session) (int, interface{})) (int, interface{}) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return f(id, ss)
+}
+
+// clock returns the time now, by the clock of the server.
+func (s *Server) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// idle tells if the session had no request for too long by now.
+func (s *Server) idle(ss *session, now time.Time) bool {
+	timeout := s.IdleTimeout
+	if timeout <= 0 {
+		timeout = DefaultIdleTimeout
+	}
+	return now.Sub(ss.used) > timeout
+}
+
+// endIdle ends the sessions that had no request for too long by now.
+// The server has to be locked.
+func (s *Server) endIdle(now time.Time) {
+	for id, ss := range s.sessions {
+		if s.idle(ss, now) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// state returns the session as the server answers with it.
+func (ss *session) state(id, text string) Session {
+	return Session{
+		ID:        id,
+		Model:     ss.model,
+		Config:    ss.e.Config(),
+		Positions: ss.e.Positions(),
+		Text:      text,
+	}
+}
+
+// letters returns the text in capitals, or an error if it has anything
+// but letters, which the machine has no keys for.
+func letters(text string) (string, error) {
+	for i, r := range text {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return "", fmt.Errorf(`only letters can be encoded or decoded, got "%c" at %d`, r, i)
+		}
+	}
+	return strings.ToUpper(text), nil
+}
+
+// newID returns a random session ID.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// readJSON decodes the body of the request.
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("the request cannot be read: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes the response with the status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error with the status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{err.Error()})
+}
@@ -0,0 +1,222 @@
+package enigmad
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emedvedev/enigma"
+)
+
+// classicConfig is the Enigma I with rotors I II III at AAA, rings
+// 1 1 1, and reflector B, and classic the request creating it.
+var (
+	classicConfig = enigma.Config{
+		Rotors: []enigma.RotorConfig{
+			{ID: "I", Start: 'A', Ring: 1},
+			{ID: "II", Start: 'A', Ring: 1},
+			{ID: "III", Start: 'A', Ring: 1},
+		},
+		Reflector: enigma.ReflectorConfig{ID: "B"},
+	}
+	classic = func() string {
+		body, err := json.Marshal(CreateRequest{Model: "I", Config: classicConfig})
+		if err != nil {
+			panic(err)
+		}
+		return string(body)
+	}()
+)
+
+// request sends the request to the server, and decodes the session it
+// answers with, if any.
+func request(t *testing.T, s *Server, method, path, body string) (int, Session) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var session Session
+	if recorder.Code < 300 && recorder.Code != http.StatusNoContent {
+		if err := json.NewDecoder(recorder.Body).Decode(&session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return recorder.Code, session
+}
+
+func TestSession(t *testing.T) {
+	s := NewServer()
+	status, created := request(t, s, http.MethodPost, "/sessions", classic)
+	if status != http.StatusCreated {
+		t.Fatalf("creating a session answers %d, expected %d", status, http.StatusCreated)
+	}
+	path := "/sessions/" + created.ID
+	_, first := request(t, s, http.MethodPost, path+"/encode", `{"text": "hello"}`)
+	_, second := request(t, s, http.MethodPost, path+"/encode", `{"text": "world"}`)
+	if got, want := first.Text+second.Text, "ILBDAAMTAZ"; got != want {
+		t.Errorf("the chunks encode to %s, expected %s", got, want)
+	}
+	if second.Positions != "AAK" {
+		t.Errorf("the rotors are at %s, expected AAK", second.Positions)
+	}
+	request(t, s, http.MethodPost, path+"/reset", "")
+	if _, decoded := request(t, s, http.MethodPost, path+"/decode", `{"text": "ILBDAAMTAZ"}`); decoded.Text != "HELLOWORLD" {
+		t.Errorf("the chunks decode to %s, expected HELLOWORLD", decoded.Text)
+	}
+	if status, _ := request(t, s, http.MethodDelete, path, ""); status != http.StatusNoContent {
+		t.Errorf("deleting the session answers %d, expected %d", status, http.StatusNoContent)
+	}
+	if status, _ := request(t, s, http.MethodGet, path, ""); status != http.StatusNotFound {
+		t.Errorf("the deleted session answers %d, expected %d", status, http.StatusNotFound)
+	}
+}
+
+// Sessions left behind don't keep new ones out for good.
+func TestIdleSessions(t *testing.T) {
+	now := time.Date(1944, 7, 1, 12, 0, 0, 0, time.UTC)
+	s := NewServer()
+	s.MaxSessions = 2
+	s.IdleTimeout = time.Minute
+	s.now = func() time.Time { return now }
+
+	_, kept := request(t, s, http.MethodPost, "/sessions", classic)
+	_, left := request(t, s, http.MethodPost, "/sessions", classic)
+	if status, _ := request(t, s, http.MethodPost, "/sessions", classic); status != http.StatusServiceUnavailable {
+		t.Fatalf("a session too many answers %d, expected %d", status, http.StatusServiceUnavailable)
+	}
+	now = now.Add(50 * time.Second)
+	request(t, s, http.MethodGet, "/sessions/"+kept.ID, "")
+	now = now.Add(50 * time.Second)
+	if status, _ := request(t, s, http.MethodPost, "/sessions", classic); status != http.StatusCreated {
+		t.Errorf("a session with one of the others idle answers %d, expected %d", status, http.StatusCreated)
+	}
+	if status, _ := request(t, s, http.MethodGet, "/sessions/"+left.ID, ""); status != http.StatusNotFound {
+		t.Errorf("the idle session answers %d, expected %d", status, http.StatusNotFound)
+	}
+	if status, _ := request(t, s, http.MethodGet, "/sessions/"+kept.ID, ""); status != http.StatusOK {
+		t.Errorf("the session in use answers %d, expected %d", status, http.StatusOK)
+	}
+	now = now.Add(2 * time.Minute)
+	if status, _ := request(t, s, http.MethodGet, "/sessions/"+kept.ID, ""); status != http.StatusNotFound {
+		t.Errorf("the session idle for two minutes answers %d, expected %d", status, http.StatusNotFound)
+	}
+}
+
+// Chunks sent at once are taken one at a time, each where the one
+// before left the rotors.
+func TestConcurrentChunks(t *testing.T) {
+	s := NewServer()
+	_, created := request(t, s, http.MethodPost, "/sessions", classic)
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request(t, s, http.MethodPost, "/sessions/"+created.ID+"/encode", `{"text": "abcdefghijklm"}`)
+		}()
+	}
+	wg.Wait()
+	e, err := enigma.EnigmaI.New(classicConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString(strings.Repeat("ABCDEFGHIJKLM", 32))
+	if _, state := request(t, s, http.MethodGet, "/sessions/"+created.ID, ""); state.Positions != e.Positions() {
+		t.Errorf("after the chunks the rotors are at %s, expected %s", state.Positions, e.Positions())
+	}
+}
+
+// The listing has the rotors and reflectors in the order of the
+// registry, and what can be set on every model.
+func TestRotors(t *testing.T) {
+	s := NewServer()
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rotors", nil))
+	var listing Listing
+	if err := json.NewDecoder(recorder.Body).Decode(&listing); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("the listing answers %d with %v", recorder.Code, err)
+	}
+	if !reflect.DeepEqual(listing.Rotors, enigma.AvailableRotors()) || !reflect.DeepEqual(listing.Reflectors, enigma.AvailableReflectors()) {
+		t.Errorf("the listing has the rotors %v and the reflectors %v", listing.Rotors, listing.Reflectors)
+	}
+	if len(listing.Models) != len(enigma.KnownModels) {
+		t.Fatalf("the listing has %d models, expected %d", len(listing.Models), len(enigma.KnownModels))
+	}
+	want, err := enigma.ModelCapabilities("M4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range listing.Models {
+		if model.Model == "M4" && !reflect.DeepEqual(model, want) {
+			t.Errorf("the M4 is listed as %+v, expected %+v", model, want)
+		}
+	}
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rotors", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("posting to the listing answers %d, expected %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// counters is a collector keeping the counters of the server.
+type counters struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	timed  map[string]int
+}
+
+func (c *counters) IncCounter(name string, n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name] += n
+}
+
+func (c *counters) ObserveDuration(name string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timed[name]++
+}
+
+// The chunks encoded and decoded are counted with their letters, and
+// the requests that went wrong by kind; resets aren't.
+func TestMetrics(t *testing.T) {
+	collector := &counters{counts: make(map[string]int64), timed: make(map[string]int)}
+	enigma.SetMetricsCollector(collector)
+	defer enigma.SetMetricsCollector(nil)
+
+	s := NewServer()
+	_, created := request(t, s, http.MethodPost, "/sessions", classic)
+	path := "/sessions/" + created.ID
+	request(t, s, http.MethodPost, path+"/encode", `{"text": "hello"}`)
+	request(t, s, http.MethodPost, path+"/encode", `{"text": "world"}`)
+	request(t, s, http.MethodPost, path+"/encode", `{"text": "hello world"}`)
+	request(t, s, http.MethodPost, path+"/encode", `{"letters": "hello"}`)
+	request(t, s, http.MethodPost, "/sessions/none/encode", `{"text": "hello"}`)
+	request(t, s, http.MethodPost, path+"/reset", "")
+	request(t, s, http.MethodPost, path+"/decode", `{"text": "ILBDAAMTAZ"}`)
+	want := map[string]int64{
+		"enigmad.encode.messages":           2,
+		"enigmad.encode.characters":         10,
+		"enigmad.encode.errors.bad_request": 2,
+		"enigmad.encode.errors.no_session":  1,
+		"enigmad.decode.messages":           1,
+		"enigmad.decode.characters":         10,
+	}
+	for name, count := range collector.counts {
+		if strings.HasPrefix(name, "enigmad.") && want[name] != count {
+			t.Errorf("%s is %d, expected %d", name, count, want[name])
+		}
+	}
+	for name, count := range want {
+		if collector.counts[name] != count {
+			t.Errorf("%s is %d, expected %d", name, collector.counts[name], count)
+		}
+	}
+	if collector.timed["enigmad.encode"] != 2 || collector.timed["enigmad.decode"] != 1 || collector.timed["enigmad.reset"] != 0 {
+		t.Errorf("the requests timed are %v", collector.timed)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build js && wasm
+
+// Package enigmajs binds the machines to JavaScript, so that they run in
+// the browser, e.g. on a teaching page (see cmd/enigmajs). Register puts
+// an enigma object on the global scope, with a single function:
+//
+//	const machine = enigma.createMachine(settingsJSON)
+//	machine.encode("Angriff im Morgengrauen") // the ciphertext
+//	machine.decode("QBLTW LDAHH")             // the plaintext
+//	machine.press("a")                        // the lamp it lights
+//	machine.positions()                       // e.g. "ADU"
+//	machine.reset()
+//
+// The settings are a JSON string the way enigma.Settings has them. The
+// text to encode is sanitized the way operators wrote it down (see
+// enigma.SanitizePlaintext), and the ciphertext can be in groups.
+// Errors aren't thrown: they're returned, as Error objects.
+package enigmajs
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"github.com/emedvedev/enigma"
+)
+
+// Register puts the enigma object on the global scope. The functions
+// stay bound for as long as the program runs, so it shouldn't exit.
+func Register() {
+	js.Global().Set("enigma", js.ValueOf(map[string]interface{}{
+		"createMachine": js.FuncOf(createMachine),
+	}))
+}
+
+// createMachine builds a machine from the settings JSON, and returns the
+// object driving it.
+func createMachine(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return jsError(fmt.Errorf("createMachine takes the settings as a JSON string"))
+	}
+	e, err := enigma.LoadSettings([]byte(args[0].String()))
+	if err != nil {
+		return jsError(err)
+	}
+	return js.ValueOf(map[string]interface{}{
+		"encode": text(func(text string) (string, error) {
+			return e.EncodeText(text, enigma.NonAlphaSpaceToX)
+		}),
+		"decode": text(func(text string) (string, error) {
+			letters := enigma.ParseGroups(text, enigma.ClassicGroups)
+			for i, r := range letters {
+				if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+					return "", fmt.Errorf(`only letters can be decoded, got "%c" at %d`, r, i)
+				}
+			}
+			return e.DecodeString(strings.ToUpper(letters)), nil
+		}),
+		"press": text(func(key string) (string, error) {
+			if len([]rune(key)) != 1 {
+				return "", fmt.Errorf(`press takes a single letter, got "%s"`, key)
+			}
+			lamp, err := e.EncodeRune([]rune(key)[0])
+			return string(lamp), err
+		}),
+		"positions": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return e.Positions()
+		}),
+		"reset": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			e.Reset()
+			return nil
+		}),
+	})
+}
+
+// text binds a function of a string taking a single string argument.
+func text(f func(string) (string, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return jsError(fmt.Errorf("a single string is expected"))
+		}
+		result, err := f(args[0].String())
+		if err != nil {
+			return jsError(err)
+		}
+		return result
+	})
+}
+
+// jsError returns the error as a JavaScript Error.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
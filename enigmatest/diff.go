@@ -0,0 +1,94 @@
+package enigmatest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// diffContext is how many letters of context DiffCiphertext shows
+// before the divergence.
+const diffContext = 20
+
+// DiffCiphertext returns a report of where the ciphertexts diverge, or
+// an empty string if they don't: the index of the first letter that
+// differs, and the letters around it in groups of five, with a caret
+// under it.
+func DiffCiphertext(expected, actual string) string {
+	index := Divergence(expected, actual)
+	if index < 0 {
+		return ""
+	}
+	start := index - index%5 - diffContext
+	if start < 0 {
+		start = 0
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "ciphertext differs at %d (%d letters, expected %d)\n", index, len(actual), len(expected))
+	fmt.Fprintf(&b, "expected: %s\n", window(expected, start))
+	fmt.Fprintf(&b, "actual:   %s\n", window(actual, start))
+	fmt.Fprintf(&b, "          %s^", strings.Repeat(" ", index-start+(index-start)/5))
+	return b.String()
+}
+
+// DiffCiphertext is the package DiffCiphertext with the rotor positions
+// at the keypress that diverged, taking the machine to be where the
+// ciphertext started. The machine itself is left as it is.
+func (h *Harness) DiffCiphertext(expected, actual string) string {
+	diff := DiffCiphertext(expected, actual)
+	if diff == "" {
+		return ""
+	}
+	e := h.Machine.Clone()
+	e.FastForward(Divergence(expected, actual) + 1)
+	return diff + fmt.Sprintf("\nrotors at %s on that keypress", e.Positions())
+}
+
+// ExpectCiphertext fails the test if the plaintext doesn't encode to
+// the ciphertext, with the diff (see DiffCiphertext). It's encoded on
+// a copy of the machine, which is left as it is.
+func (h *Harness) ExpectCiphertext(t testing.TB, plaintext, ciphertext string) {
+	t.Helper()
+	if got := h.Machine.Clone().EncodeString(plaintext); got != ciphertext {
+		t.Error(h.DiffCiphertext(ciphertext, got))
+	}
+}
+
+// Divergence returns the index of the first letter that differs, or -1
+// if the texts are the same. A text that stops short differs where it
+// stops.
+func Divergence(expected, actual string) int {
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		if expected[i] != actual[i] {
+			return i
+		}
+	}
+	if len(expected) == len(actual) {
+		return -1
+	}
+	if len(expected) < len(actual) {
+		return len(expected)
+	}
+	return len(actual)
+}
+
+// window returns the letters of the text from start in groups of five,
+// with a little more context after the divergence than before.
+func window(text string, start int) string {
+	end := start + 2*diffContext + 5
+	if end > len(text) {
+		end = len(text)
+	}
+	if start >= end {
+		return ""
+	}
+	var groups []string
+	for i := start; i < end; i += 5 {
+		j := i + 5
+		if j > end {
+			j = end
+		}
+		groups = append(groups, text[i:j])
+	}
+	return strings.Join(groups, " ")
+}
@@ -0,0 +1,66 @@
+package enigmatest
+
+import "testing"
+
+// A text that stops short diverges where it stops.
+func TestDivergence(t *testing.T) {
+	tests := []struct {
+		expected, actual string
+		want             int
+	}{
+		{"ABCDE", "ABCDE", -1},
+		{"", "", -1},
+		{"ABCDE", "ABXDE", 2},
+		{"ABCDE", "ABC", 3},
+		{"ABC", "ABCDE", 3},
+	}
+	for _, tt := range tests {
+		if got := Divergence(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("%q and %q diverge at %d, expected %d", tt.expected, tt.actual, got, tt.want)
+		}
+	}
+}
+
+// The report points at the first letter that differs, with the groups
+// around it lined up and a caret under the column.
+func TestDiffCiphertext(t *testing.T) {
+	expected := "ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	actual := expected[:27] + "Q" + expected[28:]
+	want := `ciphertext differs at 27 (52 letters, expected 52)
+expected: FGHIJ KLMNO PQRST UVWXY ZABCD EFGHI JKLMN OPQRS TUVWX
+actual:   FGHIJ KLMNO PQRST UVWXY ZAQCD EFGHI JKLMN OPQRS TUVWX
+                                    ^`
+	if got := DiffCiphertext(expected, actual); got != want {
+		t.Errorf("the report is\n%s\nexpected\n%s", got, want)
+	}
+	tests := []struct {
+		expected, actual string
+		want             string
+	}{
+		{"BDZGO", "BDZGO", ""},
+		{"ABCDE", "ABCDEFG", "ciphertext differs at 5 (7 letters, expected 5)\nexpected: ABCDE\nactual:   ABCDE FG\n                ^"},
+		{"ABCDEFG", "ABX", "ciphertext differs at 2 (3 letters, expected 7)\nexpected: ABCDE FG\nactual:   ABX\n            ^"},
+	}
+	for _, tt := range tests {
+		if got := DiffCiphertext(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("%s against %s is reported as %q, expected %q", tt.actual, tt.expected, got, tt.want)
+		}
+	}
+}
+
+// The harness adds where the rotors were on the keypress that diverged,
+// counting from where the machine is.
+func TestHarnessDiffCiphertext(t *testing.T) {
+	h := New(classic(t, "AAA"))
+	expected := h.Machine.Clone().EncodeString("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	actual := expected[:27] + "A" + expected[28:]
+	got := h.DiffCiphertext(expected, actual)
+	want := DiffCiphertext(expected, actual) + "\nrotors at ABC on that keypress"
+	if got != want {
+		t.Errorf("the report is\n%s\nexpected\n%s", got, want)
+	}
+	if h.DiffCiphertext(expected, expected) != "" {
+		t.Error("the same ciphertext is reported to differ")
+	}
+	h.ExpectPositions(t, "AAA")
+}
@@ -0,0 +1,65 @@
+// Package enigmatest helps testing code built on top of the enigma
+// package, e.g. new rotor families or machines with unusual stepping,
+// by checking the machine state between keypresses.
+package enigmatest
+
+import (
+	"testing"
+
+	"github.com/emedvedev/enigma"
+)
+
+// StepPrediction tells which rotors move on a keypress (see
+// Enigma.NextStep).
+type StepPrediction = enigma.StepPrediction
+
+// Harness drives a machine, keeping the positions it went through in
+// History (the positions before the first step included).
+type Harness struct {
+	Machine *enigma.Enigma
+	History []string
+}
+
+// New returns a harness for the machine.
+func New(e *enigma.Enigma) *Harness {
+	return &Harness{Machine: e, History: []string{e.Positions()}}
+}
+
+// StepN steps the rotors as if n keys were pressed, recording every
+// position on the way.
+func (h *Harness) StepN(n int) {
+	if len(h.History) == 0 {
+		h.History = append(h.History, h.Machine.Positions())
+	}
+	for i := 0; i < n; i++ {
+		h.Machine.FastForward(1)
+		h.History = append(h.History, h.Machine.Positions())
+	}
+}
+
+// ExpectPositions fails the test if the rotor windows don't show the
+// positions (as returned by Positions, e.g. "QEW").
+func (h *Harness) ExpectPositions(t testing.TB, positions string) {
+	t.Helper()
+	if got := h.Machine.Positions(); got != positions {
+		t.Errorf("rotors at %s, expected %s", got, positions)
+	}
+}
+
+// ExpectNextStep fails the test if the next keypress wouldn't move the
+// rotors as predicted. The machine itself is left as it is.
+func (h *Harness) ExpectNextStep(t testing.TB, want StepPrediction) {
+	t.Helper()
+	got := h.Machine.NextStep()
+	if len(got.Moves) != len(want.Moves) {
+		t.Fatalf("machine has %d rotors, prediction is for %d", len(got.Moves), len(want.Moves))
+	}
+	for i := range got.Moves {
+		if got.Moves[i] != want.Moves[i] {
+			t.Errorf("rotor %d: moves is %t, expected %t", i+1, got.Moves[i], want.Moves[i])
+		}
+	}
+	if got.DoubleStep != want.DoubleStep {
+		t.Errorf("double step is %t, expected %t", got.DoubleStep, want.DoubleStep)
+	}
+}
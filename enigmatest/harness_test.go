@@ -0,0 +1,54 @@
+package enigmatest
+
+import (
+	"testing"
+
+	"github.com/emedvedev/enigma"
+)
+
+func classic(t *testing.T, positions string) *enigma.Enigma {
+	t.Helper()
+	var rotors []enigma.RotorConfig
+	for i, id := range []string{"I", "II", "III"} {
+		rotors = append(rotors, enigma.RotorConfig{ID: id, Start: positions[i], Ring: 1})
+	}
+	e, err := enigma.NewEnigmaI(enigma.WithRotors(rotors...), enigma.WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// The history starts where the machine was handed over, and a harness
+// put together by hand starts it on the first step.
+func TestStepNHistory(t *testing.T) {
+	h := New(classic(t, "ADU"))
+	h.StepN(3)
+	h.StepN(0)
+	want := []string{"ADU", "ADV", "AEW", "BFX"}
+	if len(h.History) != len(want) {
+		t.Fatalf("the history is %v, expected %v", h.History, want)
+	}
+	for i := range want {
+		if h.History[i] != want[i] {
+			t.Errorf("the history is %v, expected %v", h.History, want)
+			break
+		}
+	}
+	bare := &Harness{Machine: classic(t, "ADU")}
+	bare.StepN(1)
+	if len(bare.History) != 2 || bare.History[0] != "ADU" {
+		t.Errorf("the history is %v, expected to start at ADU", bare.History)
+	}
+}
+
+// Expecting things of the machine doesn't move it.
+func TestExpectLeavesMachine(t *testing.T) {
+	h := New(classic(t, "AEW"))
+	h.ExpectNextStep(t, StepPrediction{Moves: []bool{true, true, true}, DoubleStep: true})
+	h.ExpectCiphertext(t, "AAAAA", h.Machine.Clone().EncodeString("AAAAA"))
+	h.ExpectPositions(t, "AEW")
+	if len(h.History) != 1 {
+		t.Errorf("the history is %v, expected nothing but AEW", h.History)
+	}
+}
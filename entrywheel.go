@@ -0,0 +1,51 @@
+package enigma
+
+// EntryWheel (Eintrittswalze, ETW) connects the plugboard to the
+// rotors. Military machines wired it in alphabetical order, so it
+// didn't change anything, but commercial models followed the keyboard
+// layout instead, and the Tirpitz machine had a wiring all of its own.
+type EntryWheel struct {
+	ID          string
+	StraightSeq [26]int
+	ReverseSeq  [26]int
+}
+
+// NewEntryWheel is a constructor, taking the letters in the order they
+// are wired to the entry wheel contacts (e.g. "QWERTZ...") and its ID.
+func NewEntryWheel(mapping string, id string) *EntryWheel {
+	w := &EntryWheel{ID: id}
+	for i, letter := range mapping {
+		index := CharToIndex(byte(letter))
+		w.StraightSeq[index] = i
+		w.ReverseSeq[i] = index
+	}
+	return w
+}
+
+// Step passes the signal through the entry wheel: from the plugboard
+// to the rotors, or back when invert is set.
+func (w *EntryWheel) Step(letter int, invert bool) int {
+	if invert {
+		return w.ReverseSeq[letter]
+	}
+	return w.StraightSeq[letter]
+}
+
+// EntryWheels is a simple list of entry wheels.
+type EntryWheels []EntryWheel
+
+// Copy returns a copy of the entry wheels.
+func (ws EntryWheels) Copy() EntryWheels {
+	return append(EntryWheels(nil), ws...)
+}
+
+// GetByID takes a "name" of the entry wheel (e.g. "QWERTZ") and returns
+// the EntryWheel pointer.
+func (ws *EntryWheels) GetByID(id string) *EntryWheel {
+	for _, wheel := range *ws {
+		if wheel.ID == id {
+			return &wheel
+		}
+	}
+	return nil
+}
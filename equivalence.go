@@ -0,0 +1,42 @@
+package enigma
+
+import "io"
+
+// Encoder is what it takes to be compared to a machine: encode a text,
+// and go back to the starting positions. Enigma, SyncEnigma, and any
+// test double can be used.
+type Encoder interface {
+	Reset()
+	EncodeString(text string) string
+}
+
+// Divergence tells where two encoders went differently: the sample,
+// the index of the first different letter, the input, and both outputs.
+type Divergence struct {
+	Sample int
+	Index  int
+	Input  string
+	A, B   string
+}
+
+// Equivalent encodes random texts of msgLen letters with both
+// encoders, resetting them before each sample, and reports the first
+// difference, if any. The texts are made from rng, so the check can be
+// repeated with a seeded source.
+func Equivalent(a, b Encoder, samples, msgLen int, rng io.Reader) (bool, Divergence) {
+	for sample := 0; sample < samples; sample++ {
+		input, err := randomLetters(rng, msgLen)
+		if err != nil {
+			return false, Divergence{Sample: sample, Index: -1}
+		}
+		a.Reset()
+		b.Reset()
+		outA, outB := a.EncodeString(input), b.EncodeString(input)
+		for i := 0; i < len(outA) || i < len(outB); i++ {
+			if i >= len(outA) || i >= len(outB) || outA[i] != outB[i] {
+				return false, Divergence{sample, i, input, outA, outB}
+			}
+		}
+	}
+	return true, Divergence{}
+}
@@ -0,0 +1,75 @@
+package enigma
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// bulkEncoder encodes through EncodeBytes, which looks the rotors up in
+// its tables for anything longer than bulkMinimum.
+type bulkEncoder struct{ *Enigma }
+
+func (b bulkEncoder) EncodeString(text string) string {
+	dst := make([]byte, len(text))
+	return string(dst[:b.EncodeBytes(dst, []byte(text))])
+}
+
+// truncating is an encoder that loses the last letter.
+type truncating struct{ *Enigma }
+
+func (t truncating) EncodeString(text string) string {
+	encoded := t.Enigma.EncodeString(text)
+	return encoded[:len(encoded)-1]
+}
+
+// The tables of EncodeBytes step the rotors the same way as pressing
+// the keys one at a time, on every model.
+func TestBulkEquivalent(t *testing.T) {
+	for _, model := range KnownModels {
+		settings, err := GenerateRandomConfig(model, rand.New(rand.NewSource(121)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		naive, err := settings.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bulk := bulkEncoder{naive.Clone()}
+		if ok, d := Equivalent(naive, bulk, 20, 2*bulkMinimum, rand.New(rand.NewSource(1))); !ok {
+			t.Errorf("%s: sample %d differs at %d:\n%s\n%s", settings, d.Sample, d.Index, d.A, d.B)
+		}
+	}
+}
+
+func TestEquivalentDivergence(t *testing.T) {
+	a, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := classicConfig()
+	config.Plugboard = []string{"QZ"}
+	b, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, d := Equivalent(a, b, 50, 40, rand.New(rand.NewSource(2)))
+	if ok {
+		t.Fatal("the machines with and without a plug are taken as the same")
+	}
+	if d.A[:d.Index] != d.B[:d.Index] || d.A[d.Index] == d.B[d.Index] {
+		t.Errorf("the divergence at %d doesn't tell the first different letter of\n%s\n%s", d.Index, d.A, d.B)
+	}
+	a.Reset()
+	if a.EncodeString(d.Input) != d.A {
+		t.Errorf("%s doesn't encode to %s, as reported", d.Input, d.A)
+	}
+
+	ok, d = Equivalent(a, truncating{a.Clone()}, 3, 10, rand.New(rand.NewSource(3)))
+	if ok || d.Sample != 0 || d.Index != 9 || len(d.B) != 9 {
+		t.Errorf("the shorter output is reported as %+v", d)
+	}
+	if ok, d := Equivalent(a, a.Clone(), 1, 10, bytes.NewReader(nil)); ok || d.Index != -1 {
+		t.Errorf("after running out of random letters, %t %+v", ok, d)
+	}
+}
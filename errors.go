@@ -0,0 +1,121 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of configuration errors, to be checked with errors.Is. The
+// details (which rotor, which slot, which letter) are in the SettingError
+// wrapping them, in the PresetError for presets, or in the WeakKeyError
+// for message keys.
+var (
+	ErrUnknownRotor           = errors.New("unknown rotor")
+	ErrUnknownReflector       = errors.New("unknown reflector")
+	ErrRingOutOfRange         = errors.New("ring out of range")
+	ErrPositionOutOfRange     = errors.New("position out of range")
+	ErrPlugConflict           = errors.New("plug conflict")
+	ErrRotorReflectorMismatch = errors.New("rotor and reflector mismatch")
+	ErrUnknownPreset          = errors.New("unknown preset")
+	ErrWeakKey                = errors.New("weak message key")
+	ErrNotInEra               = errors.New("not available in the era")
+)
+
+// ErrEmptyAfterSanitize is returned for a text that had nothing but
+// characters the policy strips, e.g. whitespace, so that it can be told
+// apart from an empty text, which is fine and encodes to nothing.
+var ErrEmptyAfterSanitize = errors.New("nothing left to encode after sanitizing")
+
+// ErrInvalidCharacter is the kind of CharacterError, to be checked with
+// errors.Is.
+var ErrInvalidCharacter = errors.New("invalid character")
+
+// CharacterError is a character the keyboard has no key for, and its
+// byte index in the text it was in, or -1 if it came on its own.
+type CharacterError struct {
+	Rune  rune
+	Index int
+	verb  string
+}
+
+// Error implements the error interface.
+func (e *CharacterError) Error() string {
+	verb := e.verb
+	if verb == "" {
+		verb = "encoded"
+	}
+	if e.Index < 0 {
+		return fmt.Sprintf(`only letters can be %s, got "%c"`, verb, e.Rune)
+	}
+	return fmt.Sprintf(`only letters can be %s, got "%c" at %d`, verb, e.Rune, e.Index)
+}
+
+// Unwrap returns ErrInvalidCharacter, so that errors.Is works.
+func (e *CharacterError) Unwrap() error {
+	return ErrInvalidCharacter
+}
+
+// keyOf returns the key of the letter, either upper or lower case, or a
+// CharacterError with the index for anything else, telling it can't be
+// encoded, decoded, or whatever the verb says.
+func keyOf(r rune, index int, verb string) (byte, error) {
+	if r >= 'a' && r <= 'z' {
+		r -= 'a' - 'A'
+	}
+	if r < 'A' || r > 'Z' {
+		return 0, &CharacterError{Rune: r, Index: index, verb: verb}
+	}
+	return byte(r), nil
+}
+
+// SettingError is a configuration error with the setting that caused
+// it. Err is one of the error kinds above; the other fields are filled
+// in when they make sense: ID of the rotor or reflector, Slot of the
+// rotor counting from 1 on the left, the offending Letter, or Value for
+// numbers (rings and numbered positions).
+type SettingError struct {
+	Err    error
+	ID     string
+	Slot   int
+	Letter byte
+	Value  int
+	msg    string
+}
+
+// Error implements the error interface.
+func (e *SettingError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the error kind, so that errors.Is works.
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+// settingError makes a SettingError of the kind with a formatted
+// message; the caller fills in the details.
+func settingError(kind error, format string, args ...interface{}) *SettingError {
+	return &SettingError{Err: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// WeakKeyError is a message key turned down because of its weaknesses
+// (see QualityOf), for the part of the message it was picked for.
+type WeakKeyError struct {
+	Part   int
+	Report KeyQualityReport
+}
+
+// Error implements the error interface.
+func (e *WeakKeyError) Error() string {
+	weaknesses := make([]string, len(e.Report.Weaknesses))
+	for i, w := range e.Report.Weaknesses {
+		weaknesses[i] = w.String()
+	}
+	return fmt.Sprintf(`weak message key "%s" for part %d: %s`, e.Report.Key, e.Part, strings.Join(weaknesses, ", "))
+}
+
+// Unwrap returns ErrWeakKey, so that errors.Is works.
+func (e *WeakKeyError) Unwrap() error {
+	return ErrWeakKey
+}
@@ -0,0 +1,146 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// Every kind of configuration error comes as a SettingError telling
+// what caused it.
+func TestSettingErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() error
+		kind  error
+		want  SettingError
+	}{
+		{"unknown rotor", func() error {
+			_, err := NewEnigmaM3(WithRotors(rotorsAt("I IX III", "AAA")...), WithReflector("B"))
+			return err
+		}, ErrUnknownRotor, SettingError{ID: "IX", Slot: 2}},
+		{"unknown reflector", func() error {
+			_, err := NewEnigmaM3(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("Z"))
+			return err
+		}, ErrUnknownReflector, SettingError{ID: "Z"}},
+		{"ring", func() error {
+			rotors := rotorsAt("I II III", "AAA")
+			rotors[2].Ring = 27
+			_, err := NewEnigmaM3(WithRotors(rotors...), WithReflector("B"))
+			return err
+		}, ErrRingOutOfRange, SettingError{ID: "III", Slot: 3, Value: 27}},
+		{"ring letter", func() error {
+			rc := RotorConfig{ID: "IV"}
+			return rc.SetRingLetter('1')
+		}, ErrRingOutOfRange, SettingError{ID: "IV", Letter: '1'}},
+		{"position", func() error {
+			_, err := NewEnigmaM3(WithRotors(rotorsAt("I II III", "A?A")...), WithReflector("B"))
+			return err
+		}, ErrPositionOutOfRange, SettingError{ID: "II", Slot: 2, Letter: '?'}},
+		{"numbered position", func() error {
+			_, err := ParsePositions("01 02 30")
+			return err
+		}, ErrPositionOutOfRange, SettingError{Slot: 3, Value: 30}},
+		{"plugboard", func() error {
+			_, err := NewEnigmaM3(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithPlugboard("AB", "CA"))
+			return err
+		}, ErrPlugConflict, SettingError{ID: "plugboard", Letter: 'A'}},
+		{"reflector of other rotors", func() error {
+			_, err := NewEnigmaM3(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B-thin"))
+			return err
+		}, ErrRotorReflectorMismatch, SettingError{ID: "B-thin"}},
+	}
+	for _, tt := range tests {
+		err := tt.build()
+		if !errors.Is(err, tt.kind) {
+			t.Errorf("%s: %v, expected %v", tt.name, err, tt.kind)
+			continue
+		}
+		var setting *SettingError
+		if !errors.As(err, &setting) {
+			t.Errorf("%s: %v is not a SettingError", tt.name, err)
+			continue
+		}
+		got := *setting
+		got.Err, got.msg = nil, ""
+		if got != tt.want {
+			t.Errorf("%s: the error tells %+v, expected %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// EncodeLetters takes letters of either case, and for anything else
+// tells the character and its byte index, without moving the rotors.
+func TestEncodeLetters(t *testing.T) {
+	want, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text  string
+		rune  rune
+		index int
+	}{
+		{"WETTER BERICHT", ' ', 6},
+		{"wetter1", '1', 6},
+		{"Grüße", 'ü', 2},
+		{"ANGRIFFß", 'ß', 7},
+		{"\tWETTER", '\t', 0},
+	}
+	for _, tt := range tests {
+		e, _ := Generic.New(classicConfig())
+		e.EncodeString("QWE")
+		got, err := e.EncodeLetters(tt.text)
+		var charErr *CharacterError
+		if !errors.As(err, &charErr) || charErr.Rune != tt.rune || charErr.Index != tt.index {
+			t.Errorf("%q encodes to %q with %v, expected %q at %d", tt.text, got, err, tt.rune, tt.index)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidCharacter) {
+			t.Errorf("%q: %v is not ErrInvalidCharacter", tt.text, err)
+		}
+		if got != "" || e.Positions() != "AAD" {
+			t.Errorf("%q encodes to %q with the rotors at %s, expected nothing and AAD", tt.text, got, e.Positions())
+		}
+	}
+	e, _ := Generic.New(classicConfig())
+	if got, err := e.EncodeLetters("Wetterbericht"); err != nil || got != want.EncodeString("WETTERBERICHT") {
+		t.Errorf("the text in both cases encodes to %s (%v)", got, err)
+	}
+	if got, err := e.EncodeLetters(""); err != nil || got != "" {
+		t.Errorf("the empty text encodes to %q (%v)", got, err)
+	}
+}
+
+// EncodeChar panics with the same error, and its message tells what
+// was wrong and where.
+func TestCharacterError(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			charErr, ok := recover().(*CharacterError)
+			if !ok || charErr.Rune != 'a' || charErr.Index != -1 || !errors.Is(charErr, ErrInvalidCharacter) {
+				t.Errorf("EncodeChar panics with %v", charErr)
+			}
+		}()
+		e.EncodeChar('a')
+	}()
+	if e.Positions() != "AAA" {
+		t.Errorf("the rotors moved to %s", e.Positions())
+	}
+	tests := []struct {
+		err  *CharacterError
+		want string
+	}{
+		{&CharacterError{Rune: ' ', Index: 6}, `only letters can be encoded, got " " at 6`},
+		{&CharacterError{Rune: '1', Index: -1}, `only letters can be encoded, got "1"`},
+		{&CharacterError{Rune: '?', Index: 2, verb: "decoded"}, `only letters can be decoded, got "?" at 2`},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("the error reads %s, expected %s", got, tt.want)
+		}
+	}
+}
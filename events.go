@@ -0,0 +1,43 @@
+package enigma
+
+// stepHook is a function subscribed with OnRotorStep.
+type stepHook struct {
+	id int
+	f  func(slot int, newPosition rune)
+}
+
+// OnRotorStep subscribes the function to the steps of the rotors: on
+// every keypress, it's called for each rotor that moves, double steps
+// included, from right to left, with the slot of the rotor (counting
+// from 1 on the left) and the letter in its window. The calls are made
+// right after the rotors move, before the letter is encoded; a letter
+// that can't be encoded (see EncodeRune) doesn't move anything, so no
+// calls are made for it. The returned function unsubscribes. Clones
+// start with no subscriptions.
+func (e *Enigma) OnRotorStep(f func(slot int, newPosition rune)) (unsubscribe func()) {
+	e.hookID++
+	id := e.hookID
+	e.stepHooks = append(e.stepHooks, stepHook{id, f})
+	return func() {
+		for i, hook := range e.stepHooks {
+			if hook.id == id {
+				e.stepHooks = append(e.stepHooks[:i:i], e.stepHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notifySteps calls the subscribed functions for the rotors that moved.
+func (e *Enigma) notifySteps(moved []bool) {
+	hooks := e.stepHooks
+	for slot := len(moved) - 1; slot >= 0; slot-- {
+		if !moved[slot] {
+			continue
+		}
+		letter := rune(IndexToChar(e.Rotors[slot].Offset))
+		for _, hook := range hooks {
+			hook.f(slot+1, letter)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package enigma
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The subscribed functions hear of every rotor moving, right to left,
+// across the double step too, and of nothing for a letter that can't
+// be encoded.
+func TestOnRotorStep(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() (*Enigma, error)
+		want  []string
+	}{
+		// ADU ADV AEW BFX: the middle rotor steps twice in a row.
+		{"double step", func() (*Enigma, error) {
+			return NewEnigmaI(WithRotors(rotorsAt("I II III", "ADU")...), WithReflector("B"))
+		}, []string{"3V", "3W 2E", "3X 2F 1B"}},
+		// Beta, in slot 1, never moves.
+		{"M4", func() (*Enigma, error) {
+			return NewEnigmaM4(WithRotors(rotorsAt("Beta I II III", "ZADU")...), WithReflector("B-thin"))
+		}, []string{"4V", "4W 3E", "4X 3F 2B"}},
+	}
+	for _, tt := range tests {
+		e, err := tt.build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keypress []string
+		e.OnRotorStep(func(slot int, newPosition rune) {
+			keypress = append(keypress, fmt.Sprintf("%d%c", slot, newPosition))
+		})
+		var got []string
+		for range tt.want {
+			keypress = nil
+			if _, err := e.EncodeRune('A'); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := e.EncodeRune('1'); err == nil {
+				t.Fatalf("%s: 1 is encoded", tt.name)
+			}
+			got = append(got, strings.Join(keypress, " "))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: the steps are %q, expected %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Unsubscribing leaves the other subscriptions, and clones start with
+// none.
+func TestOnRotorStepUnsubscribe(t *testing.T) {
+	e, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second int
+	unsubscribe := e.OnRotorStep(func(int, rune) { first++ })
+	e.OnRotorStep(func(int, rune) { second++ })
+	e.EncodeString("AA")
+	unsubscribe()
+	unsubscribe()
+	e.EncodeString("AA")
+	if first != 2 || second != 4 {
+		t.Errorf("the functions are called %d and %d times, expected 2 and 4", first, second)
+	}
+	e.Clone().EncodeString("AA")
+	if second != 4 {
+		t.Errorf("a clone calls the function of the original, %d times", second-4)
+	}
+}
@@ -0,0 +1,87 @@
+package enigma
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// exampleWords are what the plaintexts of worked examples are made of,
+// the kind of words the reports of the time were full of.
+var exampleWords = []string{
+	"KEINE", "BESONDEREN", "EREIGNISSE", "ANGRIFF", "MORGEN", "FRUEH",
+	"FEIND", "STELLUNG", "BRUECKE", "DIVISION", "WETTER", "KLAR", "NORD",
+	"SUED", "OST", "WEST", "ZWEI", "DREI", "VIER", "FUENF", "UHR",
+}
+
+// Example is a fully worked example: a configuration, a plaintext and
+// its ciphertext, and every keypress on the way (see Transcript).
+type Example struct {
+	Config     Config
+	Plaintext  string
+	Ciphertext string
+	Transcript Transcript
+}
+
+// GenerateWorkedExample makes up an example for a class: an Enigma I
+// with a random daily key (see GenerateKeySheet) and a plaintext of the
+// length, encoded a keypress at a time. The same seed always gives the
+// same example.
+func GenerateWorkedExample(seed int64, plaintextLen int) (Example, error) {
+	if plaintextLen <= 0 {
+		return Example{}, fmt.Errorf("plaintext length must be positive, got %d", plaintextLen)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	sheet, err := GenerateKeySheet(KeySheetOptions{Days: 1, Rand: rng})
+	if err != nil {
+		return Example{}, err
+	}
+	var plaintext strings.Builder
+	for plaintext.Len() < plaintextLen {
+		if plaintext.Len() > 0 {
+			plaintext.WriteByte('X')
+		}
+		plaintext.WriteString(exampleWords[rng.Intn(len(exampleWords))])
+	}
+	example := Example{Config: sheet[0].Config, Plaintext: plaintext.String()[:plaintextLen]}
+	e, err := EnigmaI.New(example.Config)
+	if err != nil {
+		return Example{}, err
+	}
+	e.StartRecording()
+	example.Ciphertext = e.EncodeString(example.Plaintext)
+	example.Transcript, err = e.StopRecording()
+	return example, err
+}
+
+// RenderMarkdown writes the example as a handout in Markdown, with the
+// keypresses in a table.
+func (x Example) RenderMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Setting:** `%s`\n\n", x.Config)
+	fmt.Fprintf(&b, "**Plaintext:** `%s`\n\n", FormatGroups(x.Plaintext, ClassicGroups))
+	fmt.Fprintf(&b, "**Ciphertext:** `%s`\n\n", FormatGroups(x.Ciphertext, ClassicGroups))
+	b.WriteString("| # | Key | Rotors | Lamp |\n|--:|:-:|:-:|:-:|\n")
+	for i, keypress := range x.Transcript {
+		fmt.Fprintf(&b, "| %d | %c | %s | %c |\n", i+1, keypress.Key, keypress.Positions, keypress.Lamp)
+	}
+	return b.String()
+}
+
+// RenderText writes the example as a plain text handout, with the
+// keypresses in columns.
+func (x Example) RenderText() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Setting:    %s\n", x.Config)
+	fmt.Fprintf(&b, "Plaintext:  %s\n", FormatGroups(x.Plaintext, ClassicGroups))
+	fmt.Fprintf(&b, "Ciphertext: %s\n\n", FormatGroups(x.Ciphertext, ClassicGroups))
+	width := len(x.Config.Rotors)
+	if width < len("Rotors") {
+		width = len("Rotors")
+	}
+	fmt.Fprintf(&b, "%4s  Key  %-*s  Lamp\n", "#", width, "Rotors")
+	for i, keypress := range x.Transcript {
+		fmt.Fprintf(&b, "%4d   %c   %-*s   %c\n", i+1, keypress.Key, width, keypress.Positions, keypress.Lamp)
+	}
+	return b.String()
+}
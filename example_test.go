@@ -0,0 +1,77 @@
+package enigma
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The same seed always gives the same handout.
+func TestGenerateWorkedExample(t *testing.T) {
+	x, err := GenerateWorkedExample(154, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Setting:    B I III II 25 06 13 NDN ID AM RC JN YK UO WQ FV SB ZH
+Plaintext:  ZWEIX DRE
+Ciphertext: NUJJU SQV
+
+   #  Key  Rotors  Lamp
+   1   Z   NDO      N
+   2   W   NDP      U
+   3   E   NDQ      J
+   4   I   NDR      J
+   5   X   NDS      U
+   6   D   NDT      S
+   7   R   NDU      Q
+   8   E   NDV      V
+`
+	if got := x.RenderText(); got != want {
+		t.Errorf("the handout is\n%s\nexpected\n%s", got, want)
+	}
+	markdown := x.RenderMarkdown()
+	if !strings.Contains(markdown, "**Ciphertext:** `NUJJU SQV`") || !strings.HasSuffix(markdown, "| 8 | E | NDV | V |\n") {
+		t.Errorf("the Markdown handout is\n%s", markdown)
+	}
+	if again, _ := GenerateWorkedExample(154, 8); !reflect.DeepEqual(again, x) {
+		t.Error("the same seed gives another example")
+	}
+	if other, _ := GenerateWorkedExample(155, 8); other.Config.String() == x.Config.String() {
+		t.Error("another seed gives the same setting")
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := GenerateWorkedExample(154, n); err == nil {
+			t.Errorf("an example of %d letters is made", n)
+		}
+	}
+}
+
+// Every row of the handout is the keypress EncodeWithTrace makes of
+// the same key, on a machine of the same setting.
+func TestWorkedExampleTrace(t *testing.T) {
+	x, err := GenerateWorkedExample(7, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := EnigmaI.New(x.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(x.Transcript) != len(x.Plaintext) || len(x.Ciphertext) != len(x.Plaintext) {
+		t.Fatalf("%d keypresses for %d letters", len(x.Transcript), len(x.Plaintext))
+	}
+	lines := strings.Split(strings.TrimSuffix(x.RenderText(), "\n"), "\n")
+	rows := lines[len(lines)-len(x.Transcript):]
+	for i, keypress := range x.Transcript {
+		lamp, steps, err := e.EncodeWithTrace(rune(x.Plaintext[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keypress.Key != x.Plaintext[i] || keypress.Lamp != byte(lamp) || steps[len(steps)-1].Out != byte(lamp) || keypress.Positions != e.Positions() {
+			t.Errorf("keypress %d is %+v, traced as %c to %c at %s", i+1, keypress, x.Plaintext[i], lamp, e.Positions())
+		}
+		if fields := strings.Fields(rows[i]); len(fields) != 4 || fields[2] != e.Positions() || fields[3] != string(lamp) {
+			t.Errorf("row %d reads %q", i+1, rows[i])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package enigma
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Canonical returns the configuration written the one way it can be:
+// plugboard presets are resolved, pairs (of the plugboard and of the
+// UKW-D) are in capitals with their letters in order and sorted, the
+// positions and the wirings are capital letters, the notches too and
+// in order, reflector position A is the same as none, and so is the
+// alphabetical entry wheel, and keyboard layouts that don't remap
+// anything are left out. The plugboard pairs of the Uhr are only put
+// in capitals, since the plugs they go to matter, and the lever
+// stepping is the same as none.
+func (c Config) Canonical() Config {
+	canonical := c
+	switch pairs, err := ResolvePlugboard(c.Plugboard); {
+	case err != nil:
+		canonical.Plugboard = append([]string(nil), c.Plugboard...)
+	case c.Uhr != nil:
+		canonical.Plugboard = nil
+		for _, pair := range pairs {
+			canonical.Plugboard = append(canonical.Plugboard, strings.ToUpper(pair))
+		}
+		uhr := *c.Uhr
+		canonical.Uhr = &uhr
+	default:
+		canonical.Plugboard = canonicalPairs(pairs)
+	}
+	if c.Stepping == LeverStepping.Name() {
+		canonical.Stepping = ""
+	}
+	if c.EntryWheel == "ABC" {
+		canonical.EntryWheel = ""
+	}
+	canonical.Reflector.Pairs = canonicalPairs(c.Reflector.Pairs)
+	canonical.Reflector.Start = upper(c.Reflector.Start)
+	if canonical.Reflector.Start == 'A' {
+		canonical.Reflector.Start = 0
+	}
+	canonical.Reflector.Wiring = strings.ToUpper(c.Reflector.Wiring)
+	canonical.Rotors = make([]RotorConfig, len(c.Rotors))
+	for i, rotor := range c.Rotors {
+		rotor.Start = upper(rotor.Start)
+		rotor.Wiring, rotor.Notches = strings.ToUpper(rotor.Wiring), canonicalNotches(rotor.Notches)
+		canonical.Rotors[i] = rotor
+	}
+	if k, err := NewKeyMap(c.Keyboard); err == nil {
+		canonical.Keyboard = ""
+		for i, key := range k.Keys {
+			if key != i {
+				canonical.Keyboard = keyMapping(k)
+				break
+			}
+		}
+	}
+	return canonical
+}
+
+// Fingerprint returns 16 hex digits telling the configuration apart
+// from any other that encodes differently, and the same for all those
+// that encode the same. It's the start of the SHA-256 of the canonical
+// form (see Canonical) written as
+//
+//	enigma/1
+//	reflector <ID> <position letter, A if not set> <UKW-D pairs>
+//	entry <entry wheel ID, nothing for the alphabetical one>
+//	rotor <ID> <ring> <position> <fixed: 1 or 0> <driven by>
+//	plugboard <pairs>
+//	keyboard <the 26 letters typed, or nothing>
+//	doublestep <1 or 0>
+//
+// one rotor line per rotor, from the left, with all the lists separated
+// by spaces and every line ending with a newline. Reflectors and rotors
+// with a wiring of their own (see WithRotorInstances) have " wiring
+// <letters>" added to their line, and rotors " notches <letters>". A
+// machine with the Uhr has "uhr <position>" added as the last line, and
+// one stepping other than with levers "stepping <name>" after that. The
+// display and the groups don't change the ciphertext, and neither do
+// the limits of the validation, so they're left out. The format is
+// frozen: fingerprints stay the same from one version to the next, e.g.
+// the Enigma I with rotors I II III at AAA, rings 1 1 1, and reflector
+// B is 8fb0f77fcb860d7b.
+func (c Config) Fingerprint() string {
+	c = c.Canonical()
+	var b strings.Builder
+	b.WriteString("enigma/1\n")
+	start := c.Reflector.Start
+	if start == 0 {
+		start = 'A'
+	}
+	fmt.Fprintf(&b, "reflector %s %c %s", c.Reflector.ID, start, strings.Join(c.Reflector.Pairs, " "))
+	if c.Reflector.Wiring != "" {
+		fmt.Fprintf(&b, " wiring %s", c.Reflector.Wiring)
+	}
+	fmt.Fprintf(&b, "\nentry %s\n", c.EntryWheel)
+	for _, rotor := range c.Rotors {
+		fmt.Fprintf(&b, "rotor %s %d %c %d %d", rotor.ID, rotor.Ring, rotor.Start, bit(rotor.Fixed), rotor.DrivenBy)
+		if rotor.Wiring != "" {
+			fmt.Fprintf(&b, " wiring %s notches %s", rotor.Wiring, rotor.Notches)
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "plugboard %s\n", strings.Join(c.Plugboard, " "))
+	fmt.Fprintf(&b, "keyboard %s\n", c.Keyboard)
+	fmt.Fprintf(&b, "doublestep %d\n", bit(!c.NoDoubleStep))
+	if c.Uhr != nil {
+		fmt.Fprintf(&b, "uhr %d\n", c.Uhr.Position)
+	}
+	if c.Stepping != "" {
+		fmt.Fprintf(&b, "stepping %s\n", c.Stepping)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// canonicalPairs writes the letter pairs in capitals, each in order,
+// sorted.
+func canonicalPairs(pairs []string) []string {
+	var canonical []string
+	for _, pair := range pairs {
+		letters := []byte(strings.ToUpper(pair))
+		if len(letters) == 0 {
+			continue
+		}
+		sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+		canonical = append(canonical, string(letters))
+	}
+	sort.Strings(canonical)
+	return canonical
+}
+
+// canonicalNotches writes the notches in capitals, in order.
+func canonicalNotches(notches string) string {
+	letters := []byte(strings.ToUpper(notches))
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	return string(letters)
+}
+
+// keyMapping returns the letters typed by the keys from A to Z.
+func keyMapping(k *KeyMap) string {
+	letters := make([]byte, 26)
+	for i, key := range k.Keys {
+		letters[i] = IndexToChar(key)
+	}
+	return string(letters)
+}
+
+// upper returns the letter in capitals.
+func upper(letter byte) byte {
+	if letter >= 'a' && letter <= 'z' {
+		return letter - 'a' + 'A'
+	}
+	return letter
+}
+
+// bit is 1 for true and 0 for false.
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
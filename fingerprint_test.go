@@ -0,0 +1,193 @@
+package enigma
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// classicConfig is the Enigma I with rotors I II III at AAA, rings
+// 1 1 1, and reflector B.
+func classicConfig() Config {
+	return Config{
+		Rotors: []RotorConfig{
+			{ID: "I", Start: 'A', Ring: 1},
+			{ID: "II", Start: 'A', Ring: 1},
+			{ID: "III", Start: 'A', Ring: 1},
+		},
+		Reflector: ReflectorConfig{ID: "B"},
+	}
+}
+
+// The fingerprints are frozen: if one of these changes, every cache
+// keyed by them is invalid.
+func TestFingerprintGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() Config
+		want   string
+	}{
+		{"classic", classicConfig, "8fb0f77fcb860d7b"},
+		{"classic as built", func() Config {
+			e, err := Generic.New(classicConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return e.Config()
+		}, "8fb0f77fcb860d7b"},
+	}
+	for _, tt := range tests {
+		if got := tt.config().Fingerprint(); got != tt.want {
+			t.Errorf("%s: fingerprint is %s, expected %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The fingerprint is the SHA-256 of the encoding as documented, which
+// is frozen too.
+func TestFingerprintEncoding(t *testing.T) {
+	config := Config{
+		Rotors: []RotorConfig{
+			{ID: "Beta", Start: 'z', Ring: 1, Fixed: true},
+			{ID: "II", Start: 'A', Ring: 3},
+			{ID: "IV", Start: 'D', Ring: 12},
+			{ID: "I", Start: 'V', Ring: 26},
+		},
+		Reflector: ReflectorConfig{ID: "B-thin"},
+		Plugboard: []string{"zt", "AQ", "HB"},
+	}
+	encoding := "enigma/1\n" +
+		"reflector B-thin A \n" +
+		"entry \n" +
+		"rotor Beta 1 Z 1 0\n" +
+		"rotor II 3 A 0 0\n" +
+		"rotor IV 12 D 0 0\n" +
+		"rotor I 26 V 0 0\n" +
+		"plugboard AQ BH TZ\n" +
+		"keyboard \n" +
+		"doublestep 1\n"
+	sum := sha256.Sum256([]byte(encoding))
+	if got, want := config.Fingerprint(), hex.EncodeToString(sum[:8]); got != want {
+		t.Errorf("the fingerprint is %s, expected %s", got, want)
+	}
+	if got := config.Fingerprint(); got != "2557de65754c0ff9" {
+		t.Errorf("the fingerprint is %s, expected 2557de65754c0ff9", got)
+	}
+}
+
+// Canonical writes the pairs sorted and in capitals, and leaves out
+// what's the same as nothing.
+func TestCanonical(t *testing.T) {
+	c := classicConfig()
+	c.Plugboard = []string{"zt", "QA", "hb"}
+	c.EntryWheel = "ABC"
+	c.Stepping = "lever"
+	c.Reflector.Start = 'a'
+	c.Rotors[0].Start = 'q'
+	want := classicConfig()
+	want.Plugboard = []string{"AQ", "BH", "TZ"}
+	want.Rotors[0].Start = 'Q'
+	if got := c.Canonical(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the canonical configuration is %+v, expected %+v", got, want)
+	}
+	if c.Plugboard[0] != "zt" || c.Rotors[0].Start != 'q' {
+		t.Errorf("Canonical changes the configuration, to %v at %c", c.Plugboard, c.Rotors[0].Start)
+	}
+}
+
+func TestFingerprintSameRepresentation(t *testing.T) {
+	withPlugs := func(pairs ...string) Config {
+		c := classicConfig()
+		c.Plugboard = pairs
+		return c
+	}
+	tests := []struct {
+		name string
+		a, b Config
+	}{
+		{"plug order", withPlugs("AB", "CD", "EF"), withPlugs("EF", "AB", "CD")},
+		{"plug letters", withPlugs("AB", "CD"), withPlugs("BA", "DC")},
+		{"plug case", withPlugs("AB", "CD"), withPlugs("ab", "Cd")},
+		{"entry wheel", classicConfig(), func() Config {
+			c := classicConfig()
+			c.EntryWheel = "ABC"
+			return c
+		}()},
+		{"reflector at A", classicConfig(), func() Config {
+			c := classicConfig()
+			c.Reflector.Start = 'A'
+			return c
+		}()},
+		{"lever stepping", classicConfig(), func() Config {
+			c := classicConfig()
+			c.Stepping = "lever"
+			return c
+		}()},
+		{"positions case", classicConfig(), func() Config {
+			c := classicConfig()
+			for i := range c.Rotors {
+				c.Rotors[i].Start = 'a'
+			}
+			return c
+		}()},
+		{"ring letter", classicConfig(), func() Config {
+			c := classicConfig()
+			if err := c.Rotors[0].SetRingLetter('A'); err != nil {
+				t.Fatal(err)
+			}
+			return c
+		}()},
+		{"display and groups", classicConfig(), func() Config {
+			c := classicConfig()
+			c.Display = DisplayNumbers
+			c.Groups = &ClassicGroups
+			c.MaxPlugPairs = 10
+			return c
+		}()},
+	}
+	for _, tt := range tests {
+		if a, b := tt.a.Fingerprint(), tt.b.Fingerprint(); a != b {
+			t.Errorf("%s: fingerprints %s and %s differ", tt.name, a, b)
+		}
+	}
+}
+
+func TestFingerprintDifferentMachines(t *testing.T) {
+	change := func(f func(c *Config)) Config {
+		c := classicConfig()
+		c.Rotors = append([]RotorConfig(nil), c.Rotors...)
+		f(&c)
+		return c
+	}
+	tests := []struct {
+		name string
+		b    Config
+	}{
+		{"ring", change(func(c *Config) { c.Rotors[2].Ring = 2 })},
+		{"position", change(func(c *Config) { c.Rotors[2].Start = 'B' })},
+		{"rotor order", change(func(c *Config) { c.Rotors[0], c.Rotors[1] = c.Rotors[1], c.Rotors[0] })},
+		{"rotor", change(func(c *Config) { c.Rotors[0].ID = "IV" })},
+		{"reflector", change(func(c *Config) { c.Reflector.ID = "C" })},
+		{"reflector position", change(func(c *Config) { c.Reflector.Start = 'B' })},
+		{"entry wheel", change(func(c *Config) { c.EntryWheel = "QWERTZ" })},
+		{"plugboard", change(func(c *Config) { c.Plugboard = []string{"AB"} })},
+		{"fixed rotor", change(func(c *Config) { c.Rotors[0].Fixed = true })},
+		{"double step", change(func(c *Config) { c.NoDoubleStep = true })},
+		{"stepping", change(func(c *Config) { c.Stepping = "gear" })},
+		{"uhr", change(func(c *Config) { c.Uhr = &UhrConfig{Position: 4} })},
+	}
+	base := classicConfig().Fingerprint()
+	seen := map[string]string{base: "classic"}
+	for _, tt := range tests {
+		got := tt.b.Fingerprint()
+		if other, ok := seen[got]; ok {
+			t.Errorf("%s: fingerprint %s is the same as for %s", tt.name, got, other)
+		}
+		seen[got] = tt.name
+	}
+	plugs := func(pairs ...string) Config { return change(func(c *Config) { c.Plugboard = pairs }) }
+	if plugs("AB", "CD").Fingerprint() == plugs("AC", "BD").Fingerprint() {
+		t.Error("plugboards AB CD and AC BD have the same fingerprint")
+	}
+}
@@ -0,0 +1,105 @@
+// Package format writes texts down the way operators did before they
+// were encoded, with letters standing in for spaces, punctuation, and
+// numbers, and reads them back after decoding; as well as in groups,
+// the way ciphertext was sent.
+package format
+
+import (
+	"strings"
+
+	"github.com/emedvedev/enigma"
+)
+
+// Substitution is written as Written on the message form wherever the
+// Plain text has it. OneWay ones aren't undone by Postprocess, since the
+// letters they write are too likely to be in words too.
+type Substitution struct {
+	Plain   string
+	Written string
+	OneWay  bool
+}
+
+// Format is a set of substitutions, applied in order, each to the
+// result of the ones before.
+type Format struct {
+	Substitutions []Substitution
+}
+
+// Numbers spell out the digits the way they were spoken over the radio,
+// ZWO rather than ZWEI so that it isn't heard as DREI.
+var Numbers = []Substitution{
+	{"0", "NULL", true}, {"1", "EINS", true}, {"2", "ZWO", true},
+	{"3", "DREI", true}, {"4", "VIER", true}, {"5", "FUENF", true},
+	{"6", "SECHS", true}, {"7", "SIEBEN", true}, {"8", "ACHT", true},
+	{"9", "NEUN", true},
+}
+
+// Army is the Heer and Luftwaffe way: the numbers spelled out, UD for
+// a question mark, and X for a full stop and for a space.
+var Army = Format{Substitutions: append(append([]Substitution(nil), Numbers...),
+	Substitution{"?", "UD", true},
+	Substitution{".", "X", false},
+	Substitution{" ", "X", false},
+)}
+
+// Navy is the Kriegsmarine way: like Army, but with Q for CH, the
+// spelled out numbers included (SEQS, AQT), and Y for a comma.
+var Navy = Format{Substitutions: append(append([]Substitution(nil), Numbers...),
+	Substitution{"?", "UD", true},
+	Substitution{"CH", "Q", false},
+	Substitution{",", "Y", false},
+	Substitution{".", "X", false},
+	Substitution{" ", "X", false},
+)}
+
+// Preprocess writes the text down to be encoded: in capitals, with the
+// substitutions made, and the umlauts written out and everything else
+// left out, the way enigma.Sanitize does.
+func (f Format) Preprocess(text string) string {
+	text = strings.ToUpper(text)
+	for _, s := range f.Substitutions {
+		text = strings.Replace(text, strings.ToUpper(s.Plain), s.Written, -1)
+	}
+	clean, _, _ := enigma.Sanitize(text, enigma.NonAlphaStrip)
+	return clean
+}
+
+// Postprocess reads a decoded text back, undoing the substitutions in
+// the reverse order, but for the one-way ones. A letter written for
+// several things is read as the last of them, so it's for reading
+// rather than getting the very text back.
+func (f Format) Postprocess(text string) string {
+	for i := len(f.Substitutions) - 1; i >= 0; i-- {
+		if s := f.Substitutions[i]; !s.OneWay {
+			text = strings.Replace(text, s.Written, s.Plain, -1)
+		}
+	}
+	return text
+}
+
+// Tokens returns the words of more than one letter the format writes,
+// as written after all the substitutions (SEQS for the Navy's 6), for
+// enigma.SplitMessage not to cut between parts.
+func (f Format) Tokens() []string {
+	var tokens []string
+	for i, s := range f.Substitutions {
+		written := s.Written
+		for _, later := range f.Substitutions[i+1:] {
+			written = strings.Replace(written, strings.ToUpper(later.Plain), later.Written, -1)
+		}
+		if len(written) > 1 {
+			tokens = append(tokens, written)
+		}
+	}
+	return tokens
+}
+
+// GroupBy returns a formatter writing the text in groups of n letters,
+// five to a line, like enigma.FormatGroups with enigma.ClassicGroups.
+func GroupBy(n int) func(text string) string {
+	options := enigma.ClassicGroups
+	options.Size = n
+	return func(text string) string {
+		return enigma.FormatGroups(text, options)
+	}
+}
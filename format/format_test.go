@@ -0,0 +1,127 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+func TestPreprocess(t *testing.T) {
+	tests := []struct {
+		format Format
+		text   string
+		want   string
+	}{
+		{Army, "um 1830 Uhr", "UMXEINSACHTDREINULLXUHR"},
+		{Army, "Wo?", "WOUD"},
+		{Army, "Ende. Gut", "ENDEXXGUT"},
+		{Army, "Fähre über Köln", "FAEHREXUEBERXKOELN"},
+		{Army, "ich, du", "ICHXDU"},
+		{Navy, "um 1830 Uhr", "UMXEINSAQTDREINULLXUHR"},
+		{Navy, "Kurs 6", "KURSXSEQS"},
+		{Navy, "Wo?", "WOUD"},
+		{Navy, "ich, du", "IQYXDU"},
+		{Navy, "Schärfe", "SQAERFE"},
+		{Navy, "0 2 9", "NULLXZWOXNEUN"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.Preprocess(tt.text); got != tt.want {
+			t.Errorf("%q is written %s, expected %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPostprocess(t *testing.T) {
+	tests := []struct {
+		format Format
+		text   string
+		want   string
+	}{
+		{Army, "UMXEINSACHTDREINULLXUHR", "UM EINSACHTDREINULL UHR"},
+		{Army, "WOUD", "WOUD"},
+		{Navy, "IQYXDU", "ICH, DU"},
+		{Navy, "KURSXSEQS", "KURS SECHS"},
+		{Navy, "UMXEINSAQTDREINULL", "UM EINSACHTDREINULL"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.Postprocess(tt.text); got != tt.want {
+			t.Errorf("%s is read %q, expected %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	tests := []struct {
+		n    int
+		text string
+		want string
+	}{
+		{5, "ABCDEFGHIJKL", "ABCDE FGHIJ KL"},
+		{4, "ABCDEFGHIJKLMNOPQRSTUV", "ABCD EFGH IJKL MNOP QRST\nUV"},
+		{5, "", ""},
+	}
+	for _, tt := range tests {
+		if got := GroupBy(tt.n)(tt.text); got != tt.want {
+			t.Errorf("%s in groups of %d is %q, expected %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+// The Barbarossa message, written the way of the Army, is encoded and
+// decoded at its key and read back.
+func TestRoundTrip(t *testing.T) {
+	text := "Aufkl Abteilung von Kurtinowa Kurtinowa nordwestl Sebez Sebez " +
+		"uaf Fliegerstraße Richtung Dubrowki Dubrowki Opotschka Opotschka " +
+		"um 1830 Uhr angetreten. Angriff Inf Rgt"
+	want := "AUFKL ABTEILUNG VON KURTINOWA KURTINOWA NORDWESTL SEBEZ SEBEZ " +
+		"UAF FLIEGERSTRASSE RICHTUNG DUBROWKI DUBROWKI OPOTSCHKA OPOTSCHKA " +
+		"UM EINSACHTDREINULL UHR ANGETRETEN  ANGRIFF INF RGT"
+	written := Army.Preprocess(text)
+	sender, err := enigma.Generic.New(testvectors.Barbarossa.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := enigma.Generic.New(testvectors.Barbarossa.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := GroupBy(5)(sender.EncodeString(written))
+	decoded := receiver.DecodeString(enigma.ParseGroups(sent, enigma.ClassicGroups))
+	if decoded != written {
+		t.Fatalf("%s is decoded as %s", written, decoded)
+	}
+	if got := Army.Postprocess(decoded); got != want {
+		t.Errorf("the message is read as %q, expected %q", got, want)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	navy := Navy.Tokens()
+	for _, token := range []string{"SEQS", "AQT", "FUENF", "UD"} {
+		if !contains(navy, token) {
+			t.Errorf("the Navy tokens %v don't have %s", navy, token)
+		}
+	}
+	if contains(navy, "SECHS") {
+		t.Errorf("the Navy tokens %v have SECHS, which the Navy wrote SEQS", navy)
+	}
+	// Messages are split with enigma.MessageTokens unless told
+	// otherwise, so it should have them all.
+	for _, f := range []Format{Army, Navy} {
+		for _, token := range f.Tokens() {
+			if !contains(enigma.MessageTokens, token) {
+				t.Errorf("enigma.MessageTokens doesn't have %s", token)
+			}
+		}
+	}
+}
+
+func contains(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package enigma
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// fuzzConfig decodes arbitrary bytes into a configuration of one of the
+// known models: the model, the number of rotors for the models taking
+// any, the rotor, the ring and the position of every slot, the reflector
+// and its position, the plugboard pairs, and a byte of policies that
+// never existed (no double step, gears, a rotor driven by hand, a fixed
+// rotor) or that only some models had (the Uhr). Every byte is taken
+// around what it picks, and the bytes past the end read as zeros, so
+// any input is a configuration, if not always one the model builds.
+func fuzzConfig(data []byte) (*Model, Config) {
+	next := func() int {
+		if len(data) == 0 {
+			return 0
+		}
+		b := int(data[0])
+		data = data[1:]
+		return b
+	}
+	model := KnownModels[next()%len(KnownModels)]
+	slots := model.Slots
+	if slots == 0 {
+		slots = 1 + next()%7
+	}
+	var config Config
+	for i := 0; i < slots; i++ {
+		config.Rotors = append(config.Rotors, RotorConfig{
+			ID:    model.Rotors[next()%len(model.Rotors)].ID,
+			Ring:  1 + next()%26,
+			Start: IndexToChar(next() % 26),
+		})
+	}
+	if len(model.Reflectors) > 0 {
+		config.Reflector.ID = model.Reflectors[next()%len(model.Reflectors)].ID
+	}
+	if position := next() % 27; model.SettableReflector && position > 0 {
+		config.Reflector.Start = IndexToChar(position - 1)
+	}
+	for pairs := next() % 14; model.Plugboard && pairs > 0; pairs-- {
+		config.Plugboard = append(config.Plugboard, string([]byte{IndexToChar(next() % 26), IndexToChar(next() % 26)}))
+	}
+	policies := next()
+	config.AllowNonHistorical = policies&0x0f != 0
+	config.NoDoubleStep = policies&0x01 != 0
+	if policies&0x02 != 0 {
+		config.Stepping = GearStepping.Name()
+	}
+	if policies&0x04 != 0 {
+		config.Rotors[0].DrivenBy = 1 + next()%slots
+	}
+	if policies&0x08 != 0 && model.Slots == 0 {
+		config.Rotors[next()%slots].Fixed = true
+	}
+	if policies&0x10 != 0 {
+		config.Uhr = &UhrConfig{Position: next() % 40}
+	}
+	return model, config
+}
+
+// fuzzSeed encodes a configuration of the model the way fuzzConfig
+// decodes it, for the seed corpus.
+func fuzzSeed(t testing.TB, model *Model, config Config) []byte {
+	index := func(n int, found bool) byte {
+		if !found {
+			t.Fatalf("%s: %s can't be a seed", model.Name, config)
+		}
+		return byte(n)
+	}
+	var seed []byte
+	for i, m := range KnownModels {
+		if m == model {
+			seed = append(seed, byte(i))
+		}
+	}
+	if model.Slots == 0 {
+		seed = append(seed, byte(len(config.Rotors)-1))
+	}
+	for _, rotor := range config.Rotors {
+		i := 0
+		for i < len(model.Rotors) && model.Rotors[i].ID != rotor.ID {
+			i++
+		}
+		seed = append(seed, index(i, i < len(model.Rotors)), byte(rotor.Ring-1), byte(CharToIndex(rotor.Start)))
+	}
+	if len(model.Reflectors) > 0 {
+		i := 0
+		for i < len(model.Reflectors) && model.Reflectors[i].ID != config.Reflector.ID {
+			i++
+		}
+		seed = append(seed, index(i, i < len(model.Reflectors)))
+	}
+	if config.Reflector.Start != 0 {
+		seed = append(seed, byte(CharToIndex(config.Reflector.Start)+1))
+	} else {
+		seed = append(seed, 0)
+	}
+	seed = append(seed, byte(len(config.Plugboard)))
+	for _, pair := range config.Plugboard {
+		seed = append(seed, byte(CharToIndex(pair[0])), byte(CharToIndex(pair[1])))
+	}
+	return append(seed, 0)
+}
+
+// historicalSeeds are the configurations of the test vectors (see the
+// testvectors package) and one of every known model.
+func historicalSeeds(t testing.TB) [][]byte {
+	vector := func(reflector string, rotors []string, rings []int, positions string, plugs ...string) Config {
+		c := Config{Reflector: ReflectorConfig{ID: reflector}, Plugboard: plugs}
+		for i, id := range rotors {
+			c.Rotors = append(c.Rotors, RotorConfig{ID: id, Ring: rings[i], Start: positions[i]})
+		}
+		return c
+	}
+	seeds := [][]byte{
+		fuzzSeed(t, &EnigmaI, vector("B", []string{"I", "II", "III"}, []int{1, 1, 1}, "AAA")),
+		fuzzSeed(t, &EnigmaI, vector("B", []string{"II", "IV", "V"}, []int{2, 21, 12}, "BLA",
+			"AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX")),
+		fuzzSeed(t, &M3Navy, vector("B", []string{"III", "VI", "VIII"}, []int{1, 8, 13}, "UZV",
+			"AN", "EZ", "HK", "IJ", "LR", "MQ", "OT", "PV", "SW", "UX")),
+		fuzzSeed(t, &M4, vector("B-thin", []string{"Beta", "II", "IV", "I"}, []int{1, 1, 1, 22}, "VJNA",
+			"AT", "BL", "DF", "GJ", "HM", "NW", "OP", "QY", "RZ", "VX")),
+	}
+	for _, model := range KnownModels {
+		settings, err := GenerateRandomConfig(model, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings.Config.Reflector.ID == UKWD {
+			continue
+		}
+		seeds = append(seeds, fuzzSeed(t, model, settings.Config))
+	}
+	return seeds
+}
+
+// The seeds decode to the configurations they were made of.
+func TestHistoricalSeeds(t *testing.T) {
+	barbarossa := historicalSeeds(t)[1]
+	model, config := fuzzConfig(barbarossa)
+	if model != &EnigmaI {
+		t.Fatalf("the Barbarossa seed is a machine of %s", model.Name)
+	}
+	e, err := model.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.Config().String(), "B II IV V 02 21 12 BLA AV BS CG DL FU HZ IN KM OW RX"; got != want {
+		t.Errorf("the Barbarossa seed is %s, expected %s", got, want)
+	}
+}
+
+// Any configuration either doesn't build, or builds a machine that
+// validates, deciphers what it enciphers, never enciphers a letter to
+// itself, and encodes the same as its clone and as a machine built from
+// its configuration.
+func FuzzConfigRoundTrip(f *testing.F) {
+	for _, seed := range historicalSeeds(f) {
+		f.Add(seed, "ANGRIFFIMMORGENGRAUENXWETTERBERICHTX")
+	}
+	f.Fuzz(func(t *testing.T, data []byte, text string) {
+		model, config := fuzzConfig(data)
+		e, err := model.New(config)
+		if err != nil {
+			return
+		}
+		if err := e.Validate(); err != nil {
+			t.Fatalf("%s: the machine built doesn't validate: %v", config, err)
+		}
+		plaintext := SanitizePlaintext(text)
+		clone := e.Clone()
+		rebuilt, err := model.New(e.Config())
+		if err != nil {
+			t.Fatalf("%s: the configuration of the machine doesn't build: %v", config, err)
+		}
+		receiver := e.Clone()
+		ciphertext := e.EncodeString(plaintext)
+		if got := clone.EncodeString(plaintext); got != ciphertext {
+			t.Fatalf("%s: the clone encodes %s to %s, expected %s", config, plaintext, got, ciphertext)
+		}
+		if got := rebuilt.EncodeString(plaintext); got != ciphertext {
+			t.Fatalf("%s: the rebuilt machine encodes %s to %s, expected %s", config, plaintext, got, ciphertext)
+		}
+		var deciphered string
+		if receiver.IsReciprocal() {
+			deciphered = receiver.EncodeString(ciphertext)
+		} else {
+			deciphered = receiver.DecodeString(ciphertext)
+		}
+		if deciphered != plaintext {
+			t.Fatalf("%s: %s deciphers to %s, expected %s", config, ciphertext, deciphered, plaintext)
+		}
+		if !receiver.IsReciprocal() || receiver.Reflector.Permutation().FixedPoints() != "" {
+			return
+		}
+		for i := range plaintext {
+			if plaintext[i] == ciphertext[i] {
+				t.Fatalf(`%s: "%c" at %d is enciphered to itself`, config, plaintext[i], i)
+			}
+		}
+	})
+}
+
+// A machine restored from the middle of a message, from a snapshot, a
+// clone, or its saved settings, continues the same as the machine, and
+// Reset takes it back to where it started.
+func FuzzStateRestore(f *testing.F) {
+	for _, seed := range historicalSeeds(f) {
+		f.Add(seed, "ANGRIFFIMMORGENGRAUENXWETTERBERICHTX", uint16(17))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, text string, split uint16) {
+		model, config := fuzzConfig(data)
+		e, err := model.New(config)
+		if err != nil {
+			return
+		}
+		plaintext := SanitizePlaintext(text)
+		at := 0
+		if len(plaintext) > 0 {
+			at = int(split) % len(plaintext)
+		}
+		whole := e.Clone().EncodeString(plaintext)
+		head := e.EncodeString(plaintext[:at])
+		saved, err := e.MarshalSettings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadSettings(saved)
+		if err != nil {
+			t.Fatalf("%s: the saved settings don't load: %v", config, err)
+		}
+		restored := map[string]*Enigma{
+			"snapshot": e.Snapshot().Machine(),
+			"clone":    e.Clone(),
+			"settings": loaded,
+		}
+		tail := e.EncodeString(plaintext[at:])
+		if head+tail != whole {
+			t.Fatalf("%s: encodes to %s in two goes, expected %s", config, head+tail, whole)
+		}
+		for name, machine := range restored {
+			if got := machine.EncodeString(plaintext[at:]); got != tail {
+				t.Errorf("%s: the machine from the %s encodes %s to %s, expected %s", config, name, plaintext[at:], got, tail)
+			}
+			if machine.Positions() != e.Positions() {
+				t.Errorf("%s: the machine from the %s is at %s, expected %s", config, name, machine.Positions(), e.Positions())
+			}
+		}
+		e.Reset()
+		if got := e.EncodeString(plaintext); got != whole {
+			t.Errorf("%s: encodes to %s after Reset, expected %s", config, got, whole)
+		}
+	})
+}
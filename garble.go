@@ -0,0 +1,123 @@
+package enigma
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Garble simulates a bad radio channel: every letter of the ciphertext
+// is replaced with another one at random with the chance of rate (from
+// 0 to 1), with randomness from rng (crypto/rand if nil). Everything
+// else, e.g. the spaces between the groups, is left as is. It returns
+// the garbled text and the byte indexes of the letters replaced.
+func Garble(ciphertext string, rate float64, rng io.Reader) (string, []int, error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+	garbled := []byte(ciphertext)
+	var positions []int
+	buf := make([]byte, 2)
+	for i, letter := range garbled {
+		if letter < 'A' || letter > 'Z' {
+			continue
+		}
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return "", nil, err
+		}
+		if float64(int(buf[0])<<8|int(buf[1])) >= rate*65536 {
+			continue
+		}
+		other, err := randomLetters(rng, 1)
+		if err != nil {
+			return "", nil, err
+		}
+		for other[0] == letter {
+			if other, err = randomLetters(rng, 1); err != nil {
+				return "", nil, err
+			}
+		}
+		garbled[i] = other[0]
+		positions = append(positions, i)
+	}
+	return string(garbled), positions, nil
+}
+
+// AlignDespiteGarbles joins the groups back together like ParseGroups,
+// but without trusting the text to have come through whole: anything
+// in a group that isn't a letter is taken as an unreadable letter, and
+// groups with letters lost or added on the way are padded or cut to the
+// size, so that the groups after them stay in place. Every letter of
+// the ciphertext is encoded on its own, so the rest still decrypts. The
+// unreadable and padded letters are written as X, and their indexes in
+// the result returned; the last group may be short.
+func AlignDespiteGarbles(text string, options GroupOptions) (string, []int) {
+	options = options.withDefaults()
+	groups := strings.Fields(strings.Replace(text, options.Separator, " ", -1))
+	var b strings.Builder
+	var suspect []int
+	for g, group := range groups {
+		group = strings.ToUpper(group)
+		size := options.Size
+		if g == len(groups)-1 && len(group) < size {
+			size = len(group)
+		}
+		for i := 0; i < size; i++ {
+			if i < len(group) && group[i] >= 'A' && group[i] <= 'Z' {
+				b.WriteByte(group[i])
+				continue
+			}
+			suspect = append(suspect, b.Len())
+			b.WriteByte('X')
+		}
+		if len(group) > size {
+			suspect = append(suspect, b.Len()-1)
+		}
+	}
+	return b.String(), suspect
+}
+
+// DamageReport tells which letters of a decrypt are different from the
+// reference, by their indexes, out of how many.
+type DamageReport struct {
+	Positions []int
+	Total     int
+}
+
+// CompareDecrypts compares a decrypt of a garbled message against the
+// reference decrypt. Since every letter is encoded on its own, the
+// damage is where the ciphertext was garbled, and nowhere else. Letters
+// are compared ignoring anything else, and letters missing from either
+// text count as damaged.
+func CompareDecrypts(reference, decrypted string) DamageReport {
+	letters := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return unicode.ToUpper(r)
+			}
+			return -1
+		}, s)
+	}
+	reference, decrypted = letters(reference), letters(decrypted)
+	report := DamageReport{Total: len(reference)}
+	if len(decrypted) > report.Total {
+		report.Total = len(decrypted)
+	}
+	for i := 0; i < report.Total; i++ {
+		if i >= len(reference) || i >= len(decrypted) || reference[i] != decrypted[i] {
+			report.Positions = append(report.Positions, i)
+		}
+	}
+	return report
+}
+
+// String sums up the damage, e.g. "2 of 179 letters damaged: 4 17".
+func (r DamageReport) String() string {
+	positions := make([]string, len(r.Positions))
+	for i, p := range r.Positions {
+		positions[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%d of %d letters damaged: %s", len(r.Positions), r.Total, strings.Join(positions, " "))
+}
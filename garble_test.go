@@ -0,0 +1,93 @@
+package enigma
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// With 5% of the letters garbled on the way, the decrypt is damaged
+// where they were, and nowhere else: every letter is encoded on its own.
+func TestGarbleDamage(t *testing.T) {
+	plaintext := receiveText + receiveText
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := FormatGroups(e.Clone().EncodeString(plaintext), ClassicGroups)
+	garbled, positions, err := Garble(ciphertext, 0.05, rand.New(rand.NewSource(142)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(plaintext); len(positions) < n/40 || len(positions) > n/10 {
+		t.Errorf("%d of %d letters are garbled at 5%%", len(positions), n)
+	}
+	var letters []int
+	for _, p := range positions {
+		if garbled[p] == ciphertext[p] {
+			t.Errorf("the letter at %d is garbled to itself", p)
+		}
+		index := 0
+		for i := 0; i < p; i++ {
+			if ciphertext[i] >= 'A' && ciphertext[i] <= 'Z' {
+				index++
+			}
+		}
+		letters = append(letters, index)
+	}
+	for i := range ciphertext {
+		if (ciphertext[i] < 'A' || ciphertext[i] > 'Z') && garbled[i] != ciphertext[i] {
+			t.Errorf("the %q at %d is garbled", ciphertext[i], i)
+		}
+	}
+	report := CompareDecrypts(plaintext, e.EncodeString(ParseGroups(garbled, ClassicGroups)))
+	if !reflect.DeepEqual(report.Positions, letters) || report.Total != len(plaintext) {
+		t.Errorf("the damage is %s, expected letters %v", report, letters)
+	}
+}
+
+func TestGarbleRates(t *testing.T) {
+	ciphertext := "QWERT ZUIOP"
+	if garbled, positions, err := Garble(ciphertext, 0, rand.New(rand.NewSource(1))); err != nil || garbled != ciphertext || len(positions) != 0 {
+		t.Errorf("at 0, the text is garbled to %s at %v (%v)", garbled, positions, err)
+	}
+	if _, positions, err := Garble(ciphertext, 1, rand.New(rand.NewSource(1))); err != nil || !reflect.DeepEqual(positions, []int{0, 1, 2, 3, 4, 6, 7, 8, 9, 10}) {
+		t.Errorf("at 1, the text is garbled at %v (%v)", positions, err)
+	}
+}
+
+// Unreadable letters, and letters lost or added, stay in their group,
+// so the groups after them are in place.
+func TestAlignDespiteGarbles(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		suspect []int
+	}{
+		{"QWERT ZUIOP ASD", "QWERTZUIOPASD", nil},
+		{"QW?RT ZUIOP", "QWXRTZUIOP", []int{2}},
+		{"QWRT zuiop", "QWRTXZUIOP", []int{4}},
+		{"QWEERT ZUIOP", "QWEERZUIOP", []int{4}},
+		{"QWERT-ZUI", "QWERTZUI", nil},
+	}
+	for _, tt := range tests {
+		options := ClassicGroups
+		if tt.text == "QWERT-ZUI" {
+			options.Separator = "-"
+		}
+		got, suspect := AlignDespiteGarbles(tt.text, options)
+		if got != tt.want || !reflect.DeepEqual(suspect, tt.suspect) {
+			t.Errorf("%s is aligned to %s with %v suspect, expected %s with %v", tt.text, got, suspect, tt.want, tt.suspect)
+		}
+	}
+}
+
+func TestDamageReport(t *testing.T) {
+	report := CompareDecrypts("WETTER XBERICHT", "wetTAR-XBERIC")
+	if want := []int{4, 12, 13}; !reflect.DeepEqual(report.Positions, want) || report.Total != 14 {
+		t.Errorf("the damage is %+v, expected %v of 14", report, want)
+	}
+	if got, want := report.String(), "3 of 14 letters damaged: 4 12 13"; got != want {
+		t.Errorf("the report reads %q, expected %q", got, want)
+	}
+}
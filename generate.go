@@ -0,0 +1,216 @@
+package enigma
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// RotorGenOptions set the constraints for GenerateRotor: the ID of the
+// rotor, whether a letter can be wired to itself, the number of notches
+// (at random positions), and how many contacts it has to be wired
+// differently from every rotor in RotorFamilies at least.
+type RotorGenOptions struct {
+	ID               string
+	AllowFixedPoints bool
+	Notches          int
+	MinDistance      int
+}
+
+// generateAttempts is how many wirings are tried before giving up on
+// the constraints.
+const generateAttempts = 10000
+
+// GenerateRotor mints a new rotor with a random wiring for a machine
+// that never existed.
+func GenerateRotor(options RotorGenOptions, rng io.Reader) (*Rotor, error) {
+	if options.Notches < 0 || options.Notches > 26 {
+		return nil, fmt.Errorf("a rotor can have 0-26 notches, got %d", options.Notches)
+	}
+	if options.MinDistance > 26 {
+		return nil, fmt.Errorf("rotors differ in 26 contacts at most, got %d", options.MinDistance)
+	}
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		wiring, err := randomPermutation(rng)
+		if err != nil {
+			return nil, err
+		}
+		if (!options.AllowFixedPoints && Permutation(wiring).HasFixedPoint()) || !farFromRegistry(wiring, options.MinDistance) {
+			continue
+		}
+		notches, err := randomPermutation(rng)
+		if err != nil {
+			return nil, err
+		}
+		mapping := make([]byte, 26)
+		for i, index := range wiring {
+			mapping[i] = IndexToChar(index)
+		}
+		turnovers := make([]byte, options.Notches)
+		for i := range turnovers {
+			turnovers[i] = IndexToChar(notches[i])
+		}
+		return NewRotor(string(mapping), options.ID, string(turnovers)), nil
+	}
+	return nil, fmt.Errorf("no wiring found for the constraints in %d attempts", generateAttempts)
+}
+
+// GenerateReflector mints a new reflector with a random wiring: thirteen
+// pairs, no letter wired to itself.
+func GenerateReflector(rng io.Reader) (*Reflector, error) {
+	order, err := randomPermutation(rng)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reflector{ID: "random"}
+	for i := 0; i < 26; i += 2 {
+		r.Sequence[order[i]] = order[i+1]
+		r.Sequence[order[i+1]] = order[i]
+	}
+	return r, nil
+}
+
+// GenerateRandomConfig picks a random key for the model (EnigmaI if
+// nil), for practice, puzzles, or test corpora: a rotor order without
+// repeats that keeps to the slots and the rules of the model, the
+// rings, starting positions, one of its reflectors (a UKW-D rewired at
+// random, if that's the one, and set to a random position if it can
+// be), and ten plugboard pairs, or as many as the model has cables for.
+// The randomness comes from rng, crypto/rand if nil.
+func GenerateRandomConfig(model *Model, rng io.Reader) (Settings, error) {
+	if model == nil {
+		model = &EnigmaI
+	}
+	if rng == nil {
+		rng = rand.Reader
+	}
+	slots := model.Slots
+	if slots == 0 {
+		slots = 3
+	}
+	var config Config
+	for attempt := 0; ; attempt++ {
+		if attempt == generateAttempts {
+			return Settings{}, fmt.Errorf("no rotor order of Enigma %s keeps to its rules in %d attempts", model.Name, generateAttempts)
+		}
+		rotors, err := randomRotors(rng, model, model.Rotors, slots)
+		if err != nil {
+			return Settings{}, err
+		}
+		if _, ok := model.brokenRule(rotors); ok {
+			config.Rotors = rotors
+			break
+		}
+	}
+	reflector, err := randomReflector(rng, model)
+	if err != nil {
+		return Settings{}, err
+	}
+	config.Reflector = reflector
+	if model.Plugboard {
+		n := 10
+		if max := model.maxPlugPairs(config); max > 0 && n > max {
+			n = max
+		}
+		if config.Plugboard, err = randomPlugs(rng, n, false); err != nil {
+			return Settings{}, err
+		}
+	}
+	config = model.complete(config)
+	if err := model.Validate(config); err != nil {
+		return Settings{}, err
+	}
+	settings := Settings{Config: config}
+	if model != &Generic {
+		settings.Model = model.Name
+	}
+	return settings, nil
+}
+
+// randomReflector picks one of the reflectors of the model, the UKW-D
+// among them if it has one.
+func randomReflector(rng io.Reader, model *Model) (ReflectorConfig, error) {
+	var choices []string
+	for _, reflector := range model.Reflectors {
+		choices = append(choices, reflector.ID)
+	}
+	if model.UKWD {
+		choices = append(choices, UKWD)
+	}
+	if len(choices) == 0 {
+		return ReflectorConfig{}, fmt.Errorf("no reflectors for Enigma %s", model.Name)
+	}
+	i, err := randomIndex(rng, len(choices))
+	if err != nil {
+		return ReflectorConfig{}, err
+	}
+	reflector := ReflectorConfig{ID: choices[i]}
+	if reflector.ID == UKWD {
+		order, err := randomPermutation(rng)
+		if err != nil {
+			return ReflectorConfig{}, err
+		}
+		var letters []byte
+		for _, index := range order {
+			if letter := IndexToChar(index); letter != 'B' && letter != 'O' {
+				letters = append(letters, letter)
+			}
+		}
+		for i := 0; i < len(letters); i += 2 {
+			reflector.Pairs = append(reflector.Pairs, string(letters[i:i+2]))
+		}
+	}
+	if model.SettableReflector {
+		start, err := randomLetters(rng, 1)
+		if err != nil {
+			return ReflectorConfig{}, err
+		}
+		reflector.Start = start[0]
+	}
+	return reflector, nil
+}
+
+// randomPermutation shuffles the alphabet (Fisher–Yates).
+func randomPermutation(rng io.Reader) ([26]int, error) {
+	var p [26]int
+	for i := range p {
+		p[i] = i
+	}
+	buf := make([]byte, 1)
+	for i := 25; i > 0; i-- {
+		limit := 256 - 256%(i+1)
+		for {
+			if _, err := io.ReadFull(rng, buf); err != nil {
+				return p, err
+			}
+			if int(buf[0]) < limit {
+				break
+			}
+		}
+		j := int(buf[0]) % (i + 1)
+		p[i], p[j] = p[j], p[i]
+	}
+	return p, nil
+}
+
+// farFromRegistry tells if the wiring differs from every registered
+// rotor in at least distance contacts.
+func farFromRegistry(wiring [26]int, distance int) bool {
+	if distance <= 0 {
+		return true
+	}
+	for _, family := range rotorFamilies {
+		for _, rotor := range *family {
+			differ := 0
+			for i := range wiring {
+				if wiring[i] != rotor.StraightSeq[i] {
+					differ++
+				}
+			}
+			if differ < distance {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package enigma
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Every rotor minted keeps to the constraints, and the contacts are
+// wired all over the alphabet.
+func TestGenerateRotor(t *testing.T) {
+	const rotors = 2000
+	rng := rand.New(rand.NewSource(123))
+	var wired [26][26]int
+	for i := 0; i < rotors; i++ {
+		options := RotorGenOptions{ID: "X", Notches: i % 4, MinDistance: 22}
+		rotor, err := GenerateRotor(options, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for contact, to := range rotor.StraightSeq {
+			if rotor.ReverseSeq[to] != contact {
+				t.Fatalf("%s: the wiring back from %c isn't the wiring there", rotor.ID, IndexToChar(to))
+			}
+			if contact == to {
+				t.Fatalf("%c is wired to itself", IndexToChar(contact))
+			}
+			wired[contact][to]++
+		}
+		if !farFromRegistry(rotor.StraightSeq, options.MinDistance) {
+			t.Fatal("the rotor is wired too much like one of the registry")
+		}
+		notches := map[int]bool{}
+		for _, notch := range rotor.Turnover {
+			notches[notch] = true
+		}
+		if len(notches) != options.Notches || len(rotor.Turnover) != options.Notches {
+			t.Fatalf("the rotor has notches %v, expected %d different ones", rotor.Turnover, options.Notches)
+		}
+	}
+	// Wired to one of the other 25 letters at random, a contact goes to
+	// every one of them about 80 times out of 2000.
+	for contact := range wired {
+		for to, n := range wired[contact] {
+			if contact != to && (n < 40 || n > 130) {
+				t.Errorf("%c is wired to %c %d times out of %d", IndexToChar(contact), IndexToChar(to), n, rotors)
+			}
+		}
+	}
+}
+
+// Letters wired to themselves only turn up when they're allowed, and
+// then they do about once per rotor.
+func TestGenerateRotorFixedPoints(t *testing.T) {
+	rng := rand.New(rand.NewSource(124))
+	fixed := 0
+	for i := 0; i < 1000; i++ {
+		rotor, err := GenerateRotor(RotorGenOptions{ID: "X", AllowFixedPoints: true, Notches: 1}, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for contact, to := range rotor.StraightSeq {
+			if contact == to {
+				fixed++
+			}
+		}
+	}
+	if fixed < 800 || fixed > 1200 {
+		t.Errorf("%d letters wired to themselves in 1000 rotors, expected about 1000", fixed)
+	}
+	for _, options := range []RotorGenOptions{{Notches: -1}, {Notches: 27}, {MinDistance: 27}} {
+		if _, err := GenerateRotor(options, rng); err == nil {
+			t.Errorf("a rotor is minted with %+v", options)
+		}
+	}
+	// A wiring differs from another in every contact about one time in
+	// e: from every rotor of the registry at once, practically never.
+	if _, err := GenerateRotor(RotorGenOptions{MinDistance: 26}, rng); err == nil {
+		t.Error("a rotor is minted wired differently from the registry in every contact")
+	}
+}
+
+// Every reflector minted is thirteen pairs of letters.
+func TestGenerateReflector(t *testing.T) {
+	rng := rand.New(rand.NewSource(125))
+	for i := 0; i < 2000; i++ {
+		r, err := GenerateReflector(rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for letter, to := range r.Sequence {
+			if to == letter || r.Sequence[to] != letter {
+				t.Fatalf("%s: %c is wired to %c", r.ID, IndexToChar(letter), IndexToChar(to))
+			}
+		}
+	}
+}
+
+// Random keys of every model build its machine: the rotors don't
+// repeat, the rings and positions are letters of the alphabet, and the
+// plugboard has ten pairs of different letters, as far as the model
+// takes them. The M4 gets a Greek wheel on the left and a thin
+// reflector.
+func TestGenerateRandomConfig(t *testing.T) {
+	rng := rand.New(rand.NewSource(277))
+	for _, model := range KnownModels {
+		for i := 0; i < 200; i++ {
+			settings, err := GenerateRandomConfig(model, rng)
+			if err != nil {
+				t.Fatalf("%s: %v", model.Name, err)
+			}
+			if _, err := settings.New(); err != nil {
+				t.Fatalf("%s: %s doesn't build: %v", model.Name, settings, err)
+			}
+			config := settings.Config
+			seen := make(map[string]bool)
+			for _, rotor := range config.Rotors {
+				if seen[rotor.ID] {
+					t.Errorf("%s: rotor %s repeats in %s", model.Name, rotor.ID, settings)
+				}
+				seen[rotor.ID] = true
+				if rotor.Ring < 1 || rotor.Ring > 26 || rotor.Start < 'A' || rotor.Start > 'Z' {
+					t.Errorf("%s: rotor %s is set to %c at ring %d", model.Name, rotor.ID, rotor.Start, rotor.Ring)
+				}
+			}
+			plugs := 0
+			if model.Plugboard {
+				plugs = 10
+				if max := model.maxPlugPairs(config); max > 0 && max < plugs {
+					plugs = max
+				}
+			}
+			if len(config.Plugboard) != plugs {
+				t.Errorf("%s: %d plugboard pairs, expected %d", model.Name, len(config.Plugboard), plugs)
+			}
+			if err := validatePlugs(config.Plugboard); err != nil {
+				t.Errorf("%s: %v", model.Name, err)
+			}
+			if model == &M4 {
+				if id := config.Rotors[0].ID; (id != "Beta" && id != "Gamma") || !config.Rotors[0].Fixed {
+					t.Errorf("the M4 gets %s on the left", id)
+				}
+				if id := config.Reflector.ID; id != "B-thin" && id != "C-thin" {
+					t.Errorf("the M4 gets reflector %s", id)
+				}
+			}
+		}
+	}
+}
+
+// The same randomness generates the same key, crypto/rand is used with
+// none, and a source that runs dry is an error.
+func TestGenerateRandomConfigSource(t *testing.T) {
+	a, err := GenerateRandomConfig(&EnigmaI, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateRandomConfig(&EnigmaI, rand.New(rand.NewSource(1)))
+	if a.String() != b.String() {
+		t.Errorf("the same seed generates %s and %s", a, b)
+	}
+	c, err := GenerateRandomConfig(nil, nil)
+	if err != nil || c.Model != EnigmaI.Name {
+		t.Errorf("with no model and no source, %+v is generated (%v)", c, err)
+	}
+	if _, err := GenerateRandomConfig(&EnigmaI, strings.NewReader("short")); err == nil {
+		t.Error("a key is generated from five bytes")
+	}
+}
@@ -0,0 +1,93 @@
+package enigma
+
+import (
+	"strings"
+	"unicode"
+)
+
+// GroupOptions sets how a message is written down: in groups of Size
+// letters (5 if not set), PerLine groups to a line (0 for no wrapping),
+// with Separator between the groups and LineEnding between the lines
+// (a space and "\n" if not set).
+type GroupOptions struct {
+	Size       int    `json:"size,omitempty"`
+	PerLine    int    `json:"perLine,omitempty"`
+	Separator  string `json:"separator,omitempty"`
+	LineEnding string `json:"lineEnding,omitempty"`
+}
+
+// ClassicGroups is the way message bodies were written on the forms:
+// five groups of five letters to a line.
+var ClassicGroups = GroupOptions{Size: 5, PerLine: 5}
+
+// withDefaults fills in what isn't set.
+func (o GroupOptions) withDefaults() GroupOptions {
+	if o.Size <= 0 {
+		o.Size = 5
+	}
+	if o.Separator == "" {
+		o.Separator = " "
+	}
+	if o.LineEnding == "" {
+		o.LineEnding = "\n"
+	}
+	return o
+}
+
+// FormatGroups splits the text into groups and lines. The last group
+// and the last line can be shorter than the rest.
+func FormatGroups(text string, options GroupOptions) string {
+	options = options.withDefaults()
+	var b strings.Builder
+	for i, group := 0, 0; i < len(text); i, group = i+options.Size, group+1 {
+		switch {
+		case group == 0:
+		case options.PerLine > 0 && group%options.PerLine == 0:
+			b.WriteString(options.LineEnding)
+		default:
+			b.WriteString(options.Separator)
+		}
+		end := i + options.Size
+		if end > len(text) {
+			end = len(text)
+		}
+		b.WriteString(text[i:end])
+	}
+	return b.String()
+}
+
+// ParseGroups joins the groups back together, dropping the separators
+// and any whitespace, line endings included.
+func ParseGroups(text string, options GroupOptions) string {
+	if options.Separator != "" {
+		text = strings.Replace(text, options.Separator, "", -1)
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// ciphertextLetters returns the letters of a received ciphertext in
+// capitals, or an error if there's anything else, e.g. a digit from a
+// damaged transcription, which the machine has no key for.
+func ciphertextLetters(text string) (string, error) {
+	for i, r := range text {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return "", &CharacterError{Rune: r, Index: i, verb: "decrypted"}
+		}
+	}
+	return strings.ToUpper(text), nil
+}
+
+// EncodeFormatted encodes the text and, if the machine has Groups set,
+// writes the result in groups.
+func (e *Enigma) EncodeFormatted(text string) string {
+	encoded := e.EncodeString(text)
+	if e.Groups == nil {
+		return encoded
+	}
+	return FormatGroups(encoded, *e.Groups)
+}
@@ -0,0 +1,78 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatGroups(t *testing.T) {
+	alphabet := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	tests := []struct {
+		name    string
+		text    string
+		options GroupOptions
+		want    string
+	}{
+		{"classic", strings.Repeat(alphabet, 2)[:50], ClassicGroups, "" +
+			"ABCDE FGHIJ KLMNO PQRST UVWXY\n" +
+			"ZABCD EFGHI JKLMN OPQRS TUVWX"},
+		{"trailing partial line", alphabet, ClassicGroups, "" +
+			"ABCDE FGHIJ KLMNO PQRST UVWXY\n" +
+			"Z"},
+		{"no wrapping", alphabet, GroupOptions{}, "ABCDE FGHIJ KLMNO PQRST UVWXY Z"},
+		{"fours, CRLF", alphabet[:20], GroupOptions{Size: 4, PerLine: 2, Separator: "-", LineEnding: "\r\n"}, "" +
+			"ABCD-EFGH\r\n" +
+			"IJKL-MNOP\r\n" +
+			"QRST"},
+		{"whole lines", alphabet[:10], GroupOptions{PerLine: 1}, "ABCDE\nFGHIJ"},
+		{"empty", "", ClassicGroups, ""},
+	}
+	for _, tt := range tests {
+		got := FormatGroups(tt.text, tt.options)
+		if got != tt.want {
+			t.Errorf("%s: %q, expected %q", tt.name, got, tt.want)
+		}
+		if back := ParseGroups(got, tt.options); back != tt.text {
+			t.Errorf("%s: parsed back to %s", tt.name, back)
+		}
+	}
+}
+
+// Whatever whitespace a message was copied with, and the separators it
+// was written with, the groups are joined back together.
+func TestParseGroups(t *testing.T) {
+	tests := []struct {
+		text    string
+		options GroupOptions
+	}{
+		{"ABCDE FGHIJ\r\nKLMNO\tPQRST  \r\n UVWXY Z\r\n", ClassicGroups},
+		{"ABCD-EFGH\r\nIJKL-MNOP\r\nQRST-UVWX\r\nYZ", GroupOptions{Separator: "-"}},
+		{"ABC / DEF / GHI\n/ JKL / MNO / PQR / STU / VWX / YZ", GroupOptions{Separator: "/"}},
+	}
+	for _, tt := range tests {
+		if got := ParseGroups(tt.text, tt.options); got != "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+			t.Errorf("%q is parsed to %s", tt.text, got)
+		}
+	}
+}
+
+// EncodeFormatted writes the result in the groups the machine was set
+// up with.
+func TestEncodeFormatted(t *testing.T) {
+	config := classicConfig()
+	plain, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FormatGroups(plain.EncodeString(strings.Repeat("A", 30)), ClassicGroups)
+	groups := ClassicGroups
+	config.Groups = &groups
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := e.EncodeFormatted(strings.Repeat("A", 30))
+	if got != want || strings.Count(got, "\n") != 1 {
+		t.Errorf("the formatted ciphertext is %q, expected %q", got, want)
+	}
+}
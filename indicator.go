@@ -0,0 +1,119 @@
+package enigma
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// IndicatorProcedure is the way the message key goes along with the
+// message, encrypted at a ground setting sent in the clear before it.
+type IndicatorProcedure int
+
+// Indicator procedures: SingleIndicator is the one from May 1940 on,
+// with the message key encrypted once. DoubledIndicator is the one of
+// 1938 to 1940, with the message key typed twice in a row, so that a
+// garbled key shows, which is also what the Polish cryptanalysts got in
+// through.
+const (
+	SingleIndicator IndicatorProcedure = iota
+	DoubledIndicator
+)
+
+func (p IndicatorProcedure) String() string {
+	switch p {
+	case SingleIndicator:
+		return "single"
+	case DoubledIndicator:
+		return "doubled"
+	}
+	return fmt.Sprintf("IndicatorProcedure(%d)", int(p))
+}
+
+// ErrIndicatorMismatch is the error of a doubled indicator whose two
+// halves don't decrypt to the same message key.
+var ErrIndicatorMismatch = errors.New("indicator mismatch")
+
+// WithProcedure sets the indicator procedure of the machine (see
+// EncryptWithIndicator).
+func WithProcedure(procedure IndicatorProcedure) Option {
+	return func(c *Config) error {
+		c.Procedure = procedure
+		return nil
+	}
+}
+
+// EncryptWithIndicator encrypts the text (see EncodeText) the way the
+// procedure of the machine has it: a random message key is picked and
+// encrypted at the ground setting, once or twice over, and the text is
+// encrypted at the message key. The result starts with the indicator:
+// the ground setting in the clear, and the encrypted message key. The
+// rotors are left where the text ends.
+func (e *Enigma) EncryptWithIndicator(ground, plaintext string) (string, error) {
+	key, err := randomLetters(rand.Reader, len(e.Rotors))
+	if err != nil {
+		return "", err
+	}
+	return e.EncryptWithMessageKey(ground, key, plaintext)
+}
+
+// EncryptWithMessageKey is EncryptWithIndicator with the message key
+// picked by the operator.
+func (e *Enigma) EncryptWithMessageKey(ground, key, plaintext string) (string, error) {
+	if len(key) != len(e.Rotors) {
+		return "", fmt.Errorf("expected a message key of %d letters, got %d", len(e.Rotors), len(key))
+	}
+	if err := e.ResetTo(ground); err != nil {
+		return "", err
+	}
+	typed := key
+	if e.Procedure == DoubledIndicator {
+		typed += key
+	}
+	indicator, err := e.EncodeText(typed, NonAlphaReject)
+	if err != nil {
+		return "", err
+	}
+	if err := e.ResetTo(key); err != nil {
+		return "", err
+	}
+	text, err := e.EncodeText(plaintext, NonAlphaSpaceToX)
+	if err != nil {
+		return "", err
+	}
+	return ground + indicator + text, nil
+}
+
+// DecryptWithIndicator decrypts a message made by EncryptWithIndicator
+// with the same procedure, in groups or not: the message key is
+// decrypted at the ground setting it starts with, and the rest at the
+// message key. A doubled indicator with halves that don't agree is
+// ErrIndicatorMismatch.
+func (e *Enigma) DecryptWithIndicator(message string) (string, error) {
+	text, err := ciphertextLetters(ParseGroups(message, ClassicGroups))
+	if err != nil {
+		return "", err
+	}
+	n := len(e.Rotors)
+	length := 2 * n
+	if e.Procedure == DoubledIndicator {
+		length += n
+	}
+	if len(text) < length {
+		return "", fmt.Errorf("expected an indicator of %d letters, got %d", length, len(text))
+	}
+	if err := e.ResetTo(text[:n]); err != nil {
+		return "", err
+	}
+	key := e.DecodeString(text[n:length])
+	if e.Procedure == DoubledIndicator {
+		if key[:n] != key[n:] {
+			return "", fmt.Errorf(`%w: "%s" is not the same key twice`, ErrIndicatorMismatch, key)
+		}
+		key = key[:n]
+	}
+	if err := e.ResetTo(key); err != nil {
+		return "", err
+	}
+	return e.DecodeString(text[length:]), nil
+}
@@ -0,0 +1,134 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithRotorInstances sets the rotors from the rotors themselves rather
+// than their IDs, e.g. the ones made by GenerateRotor: the wirings,
+// the notches, the rings, and the positions are taken as they are now.
+// The machine is only ever given copies, so the rotors stay the
+// caller's. Only machines that never existed (see NewMachine) take
+// rotors of their own.
+func WithRotorInstances(rotors ...*Rotor) Option {
+	return func(c *Config) error {
+		c.Rotors = make([]RotorConfig, len(rotors))
+		for i, rotor := range rotors {
+			c.Rotors[i] = RotorConfig{
+				ID:       rotor.ID,
+				Start:    IndexToChar(mod26(rotor.Offset)),
+				Ring:     mod26(rotor.Ring) + 1,
+				Fixed:    rotor.Fixed,
+				DrivenBy: rotor.DrivenBy,
+			}
+			c.Rotors[i].Wiring, c.Rotors[i].Notches = rotor.wiring()
+		}
+		return nil
+	}
+}
+
+// WithReflectorInstance sets the reflector from the reflector itself,
+// e.g. one made by GenerateReflector, along with its position.
+func WithReflectorInstance(r *Reflector) Option {
+	return func(c *Config) error {
+		c.Reflector = ReflectorConfig{ID: r.ID, Wiring: letters(r.Sequence)}
+		if position := mod26(r.Position); position != 0 {
+			c.Reflector.Start = IndexToChar(position)
+		}
+		return nil
+	}
+}
+
+// rotor returns the configured rotor: the one wired as configured, or
+// the one with the ID from the set.
+func (rc RotorConfig) rotor(set Rotors) *Rotor {
+	if rc.Wiring != "" {
+		return NewRotor(strings.ToUpper(rc.Wiring), rc.ID, strings.ToUpper(rc.Notches))
+	}
+	return set.GetByID(rc.ID)
+}
+
+// wiring returns the wiring and the notches of the rotor, the way
+// NewRotor takes them.
+func (r *Rotor) wiring() (string, string) {
+	return letters(r.StraightSeq), string(r.TurnoverLetters())
+}
+
+// letters writes the mapping as the letters A to Z are mapped to.
+func letters(mapping [26]int) string {
+	b := make([]byte, 26)
+	for i, index := range mapping {
+		b[i] = IndexToChar(mod26(index))
+	}
+	return string(b)
+}
+
+// parseWiring reads a wiring of the 26 letters, each of them once.
+func parseWiring(wiring string) ([26]int, error) {
+	var mapping [26]int
+	wiring = strings.ToUpper(wiring)
+	if len(wiring) != 26 {
+		return mapping, fmt.Errorf(`wiring should have the 26 letters, got "%s"`, wiring)
+	}
+	for i := range wiring {
+		if wiring[i] < 'A' || wiring[i] > 'Z' {
+			return mapping, fmt.Errorf(`wiring should have the 26 letters, got "%s"`, wiring)
+		}
+		mapping[i] = CharToIndex(wiring[i])
+	}
+	if !Permutation(mapping).Valid() {
+		return mapping, fmt.Errorf(`wiring should have every letter once, got "%s"`, wiring)
+	}
+	return mapping, nil
+}
+
+// validateRotorWiring checks the wiring and the notches of a configured
+// rotor.
+func validateRotorWiring(rc RotorConfig) error {
+	if _, err := parseWiring(rc.Wiring); err != nil {
+		return fmt.Errorf(`rotor "%s": %w`, rc.ID, err)
+	}
+	for _, notch := range strings.ToUpper(rc.Notches) {
+		if notch < 'A' || notch > 'Z' {
+			return fmt.Errorf(`rotor "%s": notches should be letters, got "%s"`, rc.ID, rc.Notches)
+		}
+	}
+	return nil
+}
+
+// validateReflectorWiring checks the wiring of a configured reflector:
+// the letters have to be swapped in pairs.
+func validateReflectorWiring(rc ReflectorConfig) error {
+	mapping, err := parseWiring(rc.Wiring)
+	if err != nil {
+		return fmt.Errorf(`reflector "%s": %w`, rc.ID, err)
+	}
+	if !Permutation(mapping).IsInvolution() || Permutation(mapping).HasFixedPoint() {
+		return fmt.Errorf(`reflector "%s": letters should be swapped in pairs, got "%s"`, rc.ID, rc.Wiring)
+	}
+	return nil
+}
+
+// registeredRotor tells if the rotor is on the list of the machine (the
+// military rotors without a model), wired the same way.
+func (e *Enigma) registeredRotor(rotor *Rotor) bool {
+	set := historicRotors
+	if e.model != nil {
+		set = e.model.Rotors
+	}
+	listed := set.GetByID(rotor.ID)
+	return listed != nil && listed.StraightSeq == rotor.StraightSeq &&
+		string(listed.TurnoverLetters()) == string(rotor.TurnoverLetters())
+}
+
+// registeredReflector tells if the reflector is on the list of the
+// machine, wired the same way.
+func (e *Enigma) registeredReflector() bool {
+	set := historicReflectors
+	if e.model != nil {
+		set = e.model.Reflectors
+	}
+	listed := set.GetByID(e.Reflector.ID)
+	return listed != nil && listed.Sequence == e.Reflector.Sequence
+}
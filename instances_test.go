@@ -0,0 +1,94 @@
+package enigma
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// A machine of three generated rotors and a generated reflector saves
+// their wirings, and the machine built from its configuration, through
+// JSON, encodes like it.
+func TestRotorInstances(t *testing.T) {
+	rng := rand.New(rand.NewSource(156))
+	var rotors []*Rotor
+	for _, id := range []string{"X1", "X2", "X3"} {
+		rotor, err := GenerateRotor(RotorGenOptions{ID: id, Notches: 2, MinDistance: 20}, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rotor.Ring, rotor.Offset = 4, 17
+		rotors = append(rotors, rotor)
+	}
+	reflector, err := GenerateReflector(rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reflector.Position = 3
+	e, err := NewMachine(WithRotorInstances(rotors...), WithReflectorInstance(reflector), WithPlugboard("AQ", "WS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Positions() != "RRR" {
+		t.Errorf("the rotors are at %s, expected RRR", e.Positions())
+	}
+	// The rotors are the caller's to change.
+	first, _ := rotors[0].wiring()
+	rotors[0].StraightSeq, rotors[0].Offset = rotors[1].StraightSeq, 0
+	data, err := json.Marshal(e.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := rotors[1].wiring()
+	third, _ := rotors[2].wiring()
+	for _, wiring := range []string{first, second, third, letters(reflector.Sequence)} {
+		if !strings.Contains(string(data), `"wiring":"`+wiring+`"`) {
+			t.Errorf("the wiring %s isn't saved: %s", wiring, data)
+		}
+	}
+	if e.Positions() != "RRR" {
+		t.Errorf("changing a rotor moves the machine to %s", e.Positions())
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := strings.Repeat("WETTERVORHERSAGEBISKAYA", 40)
+	if got, want := rebuilt.EncodeString(plaintext), e.EncodeString(plaintext); got != want {
+		t.Errorf("the rebuilt machine encodes to\n%s\nexpected\n%s", got, want)
+	}
+}
+
+// The historical rotors are saved by their IDs, and the machines of a
+// model can't take rotors of their own.
+func TestRotorInstancesHistorical(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewMachine(WithRotorInstances(e.Rotors...), WithReflectorInstance(&e.Reflector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := again.Config()
+	for _, rotor := range config.Rotors {
+		if rotor.Wiring != "" {
+			t.Errorf("rotor %s is saved with its wiring", rotor.ID)
+		}
+	}
+	if config.Reflector.Wiring != "" {
+		t.Error("reflector B is saved with its wiring")
+	}
+	rotor, err := GenerateRotor(RotorGenOptions{ID: "X1", Notches: 1}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEnigmaI(WithRotorInstances(rotor, e.Rotors[1], e.Rotors[2]), WithReflector("B")); err == nil {
+		t.Error("the Enigma I takes a generated rotor")
+	}
+}
@@ -0,0 +1,50 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyboardLayouts are the predefined keyboard layouts for KeyMap. All
+// of them type the letter printed on the key, except for the positional
+// ones, which type the letter at the same spot of the German QWERTZ
+// keyboard instead: on QWERTY, Y and Z swap places.
+var KeyboardLayouts = map[string]string{
+	"QWERTZ":            "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"QWERTY":            "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"AZERTY":            "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"QWERTY-positional": "ABCDEFGHIJKLMNOPQRSTUVWXZY",
+}
+
+// KeyMap translates the keys of a modern keyboard to the keys of the
+// machine, before the plugboard, and the lamps back, so that a message
+// typed on the same layout decrypts to itself.
+type KeyMap struct {
+	Layout string
+	Keys   [26]int
+	Lamps  [26]int
+}
+
+// NewKeyMap is a constructor, taking either one of the KeyboardLayouts
+// or the 26 letters typed by the keys from A to Z. Every letter has to
+// be typed by exactly one key.
+func NewKeyMap(layout string) (*KeyMap, error) {
+	mapping, ok := KeyboardLayouts[layout]
+	if !ok {
+		mapping = strings.ToUpper(layout)
+	}
+	if len(mapping) != 26 {
+		return nil, fmt.Errorf(`unknown keyboard layout "%s"`, layout)
+	}
+	k := &KeyMap{Layout: layout}
+	var used [26]bool
+	for i := range mapping {
+		if mapping[i] < 'A' || mapping[i] > 'Z' || used[CharToIndex(mapping[i])] {
+			return nil, fmt.Errorf(`keyboard layout should use every letter once, check "%c"`, mapping[i])
+		}
+		used[CharToIndex(mapping[i])] = true
+		k.Keys[i] = CharToIndex(mapping[i])
+		k.Lamps[k.Keys[i]] = i
+	}
+	return k, nil
+}
@@ -0,0 +1,53 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+// swapYZ is what the positional QWERTY layout does to a text.
+var swapYZ = strings.NewReplacer("Y", "Z", "Z", "Y")
+
+// On the positional QWERTY layout, the Y key is where the Z is on the
+// German keyboard, and the other way round.
+func TestPositionalQWERTY(t *testing.T) {
+	plaintext := "ZYZZYVAXYLOPHONZEBRA"
+	plain, err := NewMachine(WithRotors(rotorsAt("I II III", "ZYX")...), WithReflector("B"), WithPlugboard("YZ", "AQ"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positional, err := NewMachine(WithRotors(rotorsAt("I II III", "ZYX")...), WithReflector("B"), WithPlugboard("YZ", "AQ"), WithKeyboardLayout("QWERTY-positional"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := positional.EncodeString(plaintext)
+	if want := swapYZ.Replace(plain.EncodeString(swapYZ.Replace(plaintext))); ciphertext != want {
+		t.Errorf("typed on the positional layout, %s encodes to %s, expected %s", plaintext, ciphertext, want)
+	}
+	positional.Reset()
+	if got := positional.EncodeString(ciphertext); got != plaintext {
+		t.Errorf("typed on the same layout, %s decrypts to %s, expected %s", ciphertext, got, plaintext)
+	}
+}
+
+func TestKeyboardLayouts(t *testing.T) {
+	plain, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := plain.EncodeString("QWERTYUIOPASDFGHJKLZXCVBNM")
+	for _, layout := range []string{"QWERTZ", "QWERTY", "AZERTY"} {
+		e, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithKeyboardLayout(layout))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.EncodeString("QWERTYUIOPASDFGHJKLZXCVBNM"); got != want {
+			t.Errorf("%s: the letters encode to %s, expected %s as on the machine", layout, got, want)
+		}
+	}
+	for _, layout := range []string{"DVORAK", "ABCDEFGHIJKLMNOPQRSTUVWXYY", "ABCDEFGHIJKLMNOPQRSTUVWXY1"} {
+		if _, err := NewKeyMap(layout); err == nil {
+			t.Errorf("%s is taken as a layout", layout)
+		}
+	}
+}
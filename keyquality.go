@@ -0,0 +1,116 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyWeakness is a way a message key can be lazy, the kind of keys
+// ("cillies") the codebreakers at Bletchley Park learned to guess.
+type KeyWeakness int
+
+// Weaknesses QualityOf looks for: the key is the ground setting, it's
+// the same letter over and over (AAA), keys next to each other on the
+// keyboard (QWE, or the other way round), letters following each other
+// in the alphabet (ABC, XYZ), or a word (see WeakKeyWords).
+const (
+	SameAsGround KeyWeakness = iota
+	RepeatedLetters
+	KeyboardRun
+	AlphabetRun
+	DictionaryWord
+)
+
+func (w KeyWeakness) String() string {
+	switch w {
+	case SameAsGround:
+		return "same as the ground setting"
+	case RepeatedLetters:
+		return "repeated letters"
+	case KeyboardRun:
+		return "keyboard run"
+	case AlphabetRun:
+		return "alphabet run"
+	case DictionaryWord:
+		return "dictionary word"
+	}
+	return fmt.Sprintf("KeyWeakness(%d)", int(w))
+}
+
+// WeakKeyWords are the words an operator in a hurry would type as a
+// message key. Add to them as needed.
+var WeakKeyWords = []string{
+	"AUF", "AUS", "BIS", "DAS", "DEM", "DEN", "DER", "DIE", "EIN", "ICH",
+	"IHR", "MIT", "NUN", "OST", "SIE", "UND", "VON", "WIR", "ZUM", "ZUR",
+}
+
+// KeyQualityReport lists what's wrong with a message key.
+type KeyQualityReport struct {
+	Key, Ground string
+	Weaknesses  []KeyWeakness
+}
+
+// Weak tells if anything is wrong with the key.
+func (r KeyQualityReport) Weak() bool {
+	return len(r.Weaknesses) > 0
+}
+
+// QualityOf checks the message key for the blunders operators made when
+// picking it. The ground setting may be left empty.
+func QualityOf(messageKey, ground string) KeyQualityReport {
+	key := strings.ToUpper(messageKey)
+	report := KeyQualityReport{Key: messageKey, Ground: ground}
+	flag := func(weak bool, w KeyWeakness) {
+		if weak {
+			report.Weaknesses = append(report.Weaknesses, w)
+		}
+	}
+	flag(ground != "" && key == strings.ToUpper(ground), SameAsGround)
+	flag(len(key) > 1 && strings.Count(key, key[:1]) == len(key), RepeatedLetters)
+	flag(keyboardRun(key), KeyboardRun)
+	flag(run(key, func(i int) int { return CharToIndex(key[i]) }, 26), AlphabetRun)
+	flag(containsString(WeakKeyWords, key), DictionaryWord)
+	return report
+}
+
+// keyboardRun tells if the key is typed along a row of the keyboard.
+func keyboardRun(key string) bool {
+	for _, row := range lampRows {
+		if run(key, func(i int) int { return strings.IndexByte(row, key[i]) }, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// run tells if the places of the letters of the key, as given by at,
+// go up or down one at a time, wrapping around the modulus if it isn't
+// zero. A place of -1 means the letter isn't there at all.
+func run(key string, at func(i int) int, modulus int) bool {
+	if len(key) < 3 {
+		return false
+	}
+	for _, direction := range []int{1, -1} {
+		i := 1
+		for ; i < len(key); i++ {
+			prev, next := at(i-1), at(i)
+			if prev < 0 || next < 0 {
+				return false
+			}
+			step := next - prev
+			if modulus > 0 {
+				step = ((step % modulus) + modulus) % modulus
+				if direction < 0 {
+					step -= modulus
+				}
+			}
+			if step != direction {
+				break
+			}
+		}
+		if i == len(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package enigma
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// The cillies, as Bletchley Park found them, are all caught.
+func TestQualityOf(t *testing.T) {
+	tests := []struct {
+		key, ground string
+		want        []KeyWeakness
+	}{
+		{"WXC", "WXC", []KeyWeakness{SameAsGround}},
+		{"wxc", "WXC", []KeyWeakness{SameAsGround}},
+		{"AAA", "", []KeyWeakness{RepeatedLetters}},
+		{"ZZZZ", "QDV", []KeyWeakness{RepeatedLetters}},
+		{"QWE", "", []KeyWeakness{KeyboardRun}},
+		{"TZU", "", []KeyWeakness{KeyboardRun}},
+		{"KJH", "", []KeyWeakness{KeyboardRun}},
+		{"YXC", "", []KeyWeakness{KeyboardRun}},
+		{"ABC", "", []KeyWeakness{AlphabetRun}},
+		{"ZYX", "", []KeyWeakness{AlphabetRun}},
+		{"YZA", "", []KeyWeakness{AlphabetRun}},
+		{"UND", "", []KeyWeakness{DictionaryWord}},
+		{"OST", "", []KeyWeakness{DictionaryWord}},
+		{"BNM", "", []KeyWeakness{KeyboardRun}},
+		{"CVB", "", []KeyWeakness{KeyboardRun}},
+		{"RST", "RST", []KeyWeakness{SameAsGround, AlphabetRun}},
+		{"KCH", "WXC", nil},
+		{"BLA", "", nil},
+		{"QAY", "", nil},
+		{"QWZ", "", nil},
+		{"AB", "", nil},
+	}
+	for _, tt := range tests {
+		report := QualityOf(tt.key, tt.ground)
+		if !reflect.DeepEqual(report.Weaknesses, tt.want) || report.Weak() != (tt.want != nil) {
+			t.Errorf("%s at %s: %v, expected %v", tt.key, tt.ground, report.Weaknesses, tt.want)
+		}
+	}
+}
+
+// The random message keys are never lazy, and the ones picked by the
+// operator are turned down if they are and that's asked for.
+func TestWeakKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(132))
+	for i := 0; i < 5000; i++ {
+		ground, key, err := randomKeys(rng, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report := QualityOf(key, ground); report.Weak() {
+			t.Fatalf("the random key %s at %s is %v", key, ground, report.Weaknesses)
+		}
+	}
+	options := TransmissionOptions{Keys: []MessageKey{{"WXC", "KCH"}, {"QDV", "QWE"}}, Limit: 10, RejectWeakKeys: true}
+	_, err := BuildTransmission(classicConfig(), "ANGRIFFBEIMORGENGRAUEN", options)
+	var weak *WeakKeyError
+	if !errors.As(err, &weak) || weak.Part != 2 || weak.Report.Key != "QWE" {
+		t.Errorf("the keyboard run is turned down with %v", err)
+	}
+	options.RejectWeakKeys = false
+	if _, err := BuildTransmission(classicConfig(), "ANGRIFFBEIMORGENGRAUEN", options); err != nil {
+		t.Errorf("the operator's own keys are turned down with %v", err)
+	}
+}
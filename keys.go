@@ -0,0 +1,65 @@
+package enigma
+
+import "errors"
+
+// ErrKeyHeld is the error of KeyDown while another key is held down:
+// the keys of the machine locked each other, so that only one could go
+// down at a time.
+var ErrKeyHeld = errors.New("another key is held down")
+
+// RotorEvent is a rotor stepping, the way OnRotorStep tells it: the
+// slot of the rotor (counting from 1 on the left) and the letter in its
+// window.
+type RotorEvent struct {
+	Slot     int
+	Position rune
+}
+
+// KeyDown presses the key, the way the operator did: the rotors step as
+// the key goes down, and the current then lights the lamp, which stays
+// lit until KeyUp. Other than that, it's EncodeRune: anything but a
+// letter is an error, and so is a key pressed while another one is held
+// down (ErrKeyHeld), and the rotors don't move for either.
+func (e *Enigma) KeyDown(r rune) (lamp rune, err error) {
+	if e.lit != 0 {
+		return 0, ErrKeyHeld
+	}
+	if lamp, err = e.EncodeRune(r); err != nil {
+		return 0, err
+	}
+	e.lit = lamp
+	return lamp, nil
+}
+
+// KeyUp lets go of the key held down, which turns its lamp off. Without
+// one, it does nothing.
+func (e *Enigma) KeyUp() {
+	e.lit = 0
+}
+
+// Lit returns the lamp lit by the key held down, or 0 if there's none.
+func (e *Enigma) Lit() rune {
+	return e.lit
+}
+
+// RotorEvents returns a channel the steps of the rotors are sent to, as
+// they happen (see OnRotorStep), with room for buffer of them. The
+// keypresses don't wait for a slow reader: the steps that don't fit are
+// dropped. The returned function unsubscribes and closes the channel.
+func (e *Enigma) RotorEvents(buffer int) (<-chan RotorEvent, func()) {
+	events := make(chan RotorEvent, buffer)
+	unsubscribe := e.OnRotorStep(func(slot int, newPosition rune) {
+		select {
+		case events <- RotorEvent{slot, newPosition}:
+		default:
+		}
+	})
+	closed := false
+	return events, func() {
+		if !closed {
+			closed = true
+			unsubscribe()
+			close(events)
+		}
+	}
+}
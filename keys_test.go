@@ -0,0 +1,100 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// Keyed down through the double step, ADU ADV AEW BFX, the machine
+// sends the steps to the channel right to left, each keypress before
+// the next one, and lights the lamp EncodeRune would.
+func TestKeyDownEvents(t *testing.T) {
+	e, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "ADU")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := e.Clone().EncodeString("AAA")
+	events, unsubscribe := e.RotorEvents(16)
+	for i := range want {
+		lamp, err := e.KeyDown('a')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lamp != rune(want[i]) || e.Lit() != lamp {
+			t.Errorf("keypress %d lights %c, with %c lit, expected %c", i+1, lamp, e.Lit(), want[i])
+		}
+		e.KeyUp()
+		if e.Lit() != 0 {
+			t.Errorf("keypress %d leaves %c lit after KeyUp", i+1, e.Lit())
+		}
+	}
+	unsubscribe()
+	var got []RotorEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	expected := []RotorEvent{{3, 'V'}, {3, 'W'}, {2, 'E'}, {3, 'X'}, {2, 'F'}, {1, 'B'}}
+	if len(got) != len(expected) {
+		t.Fatalf("the steps are %v, expected %v", got, expected)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("the steps are %v, expected %v", got, expected)
+			break
+		}
+	}
+	if e.Positions() != "BFX" {
+		t.Errorf("the rotors are at %s, expected BFX", e.Positions())
+	}
+}
+
+// A key held down locks the others, and a key that isn't a letter
+// lights nothing; neither moves the rotors. KeyUp with no key held down
+// does nothing, twice included.
+func TestKeyHeld(t *testing.T) {
+	e, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.KeyUp()
+	e.KeyUp()
+	if e.Lit() != 0 || e.Positions() != "AAA" {
+		t.Errorf("KeyUp without a key lights %c and moves the rotors to %s", e.Lit(), e.Positions())
+	}
+	if _, err := e.KeyDown('1'); err == nil || e.Lit() != 0 || e.Positions() != "AAA" {
+		t.Errorf("1 is keyed down with %v, lighting %c, the rotors at %s", err, e.Lit(), e.Positions())
+	}
+	lamp, err := e.KeyDown('A')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.KeyDown('B'); !errors.Is(err, ErrKeyHeld) || e.Lit() != lamp || e.Positions() != "AAB" {
+		t.Errorf("B is keyed down with %v while A is held, lighting %c, the rotors at %s", err, e.Lit(), e.Positions())
+	}
+	e.KeyUp()
+	if _, err := e.KeyDown('B'); err != nil || e.Positions() != "AAC" {
+		t.Errorf("B is keyed down with %v once A is let go, the rotors at %s", err, e.Positions())
+	}
+}
+
+// The steps that don't fit the channel are dropped rather than waited
+// for, and once unsubscribed, the channel is closed, and hears nothing
+// more, however often it's unsubscribed.
+func TestRotorEventsDropped(t *testing.T) {
+	e, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := e.RotorEvents(2)
+	e.EncodeString("AAAA")
+	unsubscribe()
+	unsubscribe()
+	e.EncodeString("AAAA")
+	var got []RotorEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 2 || got[0] != (RotorEvent{3, 'B'}) || got[1] != (RotorEvent{3, 'C'}) {
+		t.Errorf("the channel has the steps %v, expected the first two", got)
+	}
+}
@@ -0,0 +1,292 @@
+package enigma
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySheet is the key of every day of a month, from the 1st on.
+type KeySheet []DailyKey
+
+// KeySheetRules are the rules key sheets were drawn up by, which also
+// made the work of the codebreakers easier: no plugboard pair of letters
+// next to each other in the alphabet (AB, ST), no rotor in the same slot
+// as the day before, and no rotor order used twice in a month.
+type KeySheetRules struct {
+	NoAdjacentPlugs bool
+	NoRepeatedSlot  bool
+	NoRepeatedOrder bool
+}
+
+// DefaultKeySheetRules are the rules of the Luftwaffe key sheets, as
+// found out at Bletchley Park.
+var DefaultKeySheetRules = KeySheetRules{
+	NoAdjacentPlugs: true,
+	NoRepeatedSlot:  true,
+	NoRepeatedOrder: true,
+}
+
+// Names of the rules, for the violations.
+const (
+	RuleAdjacentPlugs = "adjacent-plugs"
+	RuleRepeatedSlot  = "repeated-slot"
+	RuleRepeatedOrder = "repeated-order"
+)
+
+// Violation is a day of a key sheet breaking one of the rules.
+type Violation struct {
+	Day    int
+	Rule   string
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("day %d: %s (%s)", v.Day, v.Detail, v.Rule)
+}
+
+// ValidateKeySheet checks the key sheet against the rules, returning
+// every violation found, in order.
+func ValidateKeySheet(sheet KeySheet, rules KeySheetRules) []Violation {
+	var violations []Violation
+	orders := make(map[string]int)
+	for i, key := range sheet {
+		day := i + 1
+		if rules.NoAdjacentPlugs {
+			for _, pair := range key.Config.Plugboard {
+				if adjacentPair(pair) {
+					violations = append(violations, Violation{day, RuleAdjacentPlugs,
+						fmt.Sprintf("plugboard pair %s of letters next to each other", pair)})
+				}
+			}
+		}
+		if rules.NoRepeatedSlot && i > 0 {
+			if slot, id := repeatedSlot(sheet[i-1].Config.Rotors, key.Config.Rotors); slot > 0 {
+				violations = append(violations, Violation{day, RuleRepeatedSlot,
+					fmt.Sprintf("rotor %s in slot %d again", id, slot)})
+			}
+		}
+		order := rotorOrder(key.Config.Rotors)
+		if first, ok := orders[order]; rules.NoRepeatedOrder && ok {
+			violations = append(violations, Violation{day, RuleRepeatedOrder,
+				fmt.Sprintf("rotor order %s already used on day %d", order, first)})
+		} else if !ok {
+			orders[order] = day
+		}
+	}
+	return violations
+}
+
+// KeySheetOptions set up GenerateKeySheet: the model (EnigmaI if not
+// set), the reflector (B or thin B if the model has it, or else its
+// first one, if not set), the number of days (31 if not set), the
+// plugboard pairs a day (10 if not set, or as many as the model and the
+// era allow), the rules (DefaultKeySheetRules if not set, so
+// &KeySheetRules{} for none), the era (see WithEra), and where the
+// randomness comes from (crypto/rand if not set).
+type KeySheetOptions struct {
+	Model     *Model
+	Reflector string
+	Days      int
+	Plugs     int
+	Rules     *KeySheetRules
+	Era       int
+	Rand      io.Reader
+}
+
+// GenerateKeySheet draws up a key sheet: for every day, the rotor order,
+// the rings, the plugboard pairs, the ground setting, and four
+// Kenngruppen, all at random but within the rules, and those of the
+// model (see SelectionRule). A configuration that can't keep to the
+// rules, e.g. a month without repeating the six orders of three rotors,
+// is an error.
+func GenerateKeySheet(options KeySheetOptions) (KeySheet, error) {
+	options = options.withDefaults()
+	model, rules := options.Model, *options.Rules
+	var pool Rotors
+	for _, rotor := range model.Rotors {
+		if options.Era == 0 || rotor.Introduced <= options.Era {
+			pool = append(pool, rotor)
+		}
+	}
+	slots := model.Slots
+	if slots == 0 {
+		slots = 3
+	}
+	sheet := make(KeySheet, 0, options.Days)
+	used := make(map[string]bool)
+	for day := 1; day <= options.Days; day++ {
+		var key DailyKey
+		key.Config = Config{Reflector: ReflectorConfig{ID: options.Reflector}, Era: options.Era}
+		for attempt := 0; ; attempt++ {
+			if attempt == 1000 {
+				return nil, fmt.Errorf("no rotor order left for day %d under the rules", day)
+			}
+			rotors, err := randomRotors(options.Rand, model, pool, slots)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := model.brokenRule(rotors); !ok {
+				continue
+			}
+			if rules.NoRepeatedOrder && used[rotorOrder(rotors)] {
+				continue
+			}
+			if slot, _ := repeatedSlot(previousRotors(sheet), rotors); rules.NoRepeatedSlot && slot > 0 {
+				continue
+			}
+			key.Config.Rotors = rotors
+			break
+		}
+		used[rotorOrder(key.Config.Rotors)] = true
+		plugs, err := randomPlugs(options.Rand, options.Plugs, rules.NoAdjacentPlugs)
+		if err != nil {
+			return nil, err
+		}
+		key.Config.Plugboard = plugs
+		for i := 0; i < 4; i++ {
+			group, err := randomLetters(options.Rand, 3)
+			if err != nil {
+				return nil, err
+			}
+			key.Kenngruppen = append(key.Kenngruppen, group)
+		}
+		if err := model.Validate(key.Config); err != nil {
+			return nil, err
+		}
+		sheet = append(sheet, key)
+	}
+	return sheet, nil
+}
+
+// withDefaults fills in what isn't set.
+func (o KeySheetOptions) withDefaults() KeySheetOptions {
+	if o.Model == nil {
+		o.Model = &EnigmaI
+	}
+	for _, id := range []string{"B", "B-thin"} {
+		if o.Reflector == "" && o.Model.Reflectors.GetByID(id) != nil {
+			o.Reflector = id
+		}
+	}
+	if o.Reflector == "" && len(o.Model.Reflectors) > 0 {
+		o.Reflector = o.Model.Reflectors[0].ID
+	}
+	if o.Rules == nil {
+		rules := DefaultKeySheetRules
+		o.Rules = &rules
+	}
+	if o.Days <= 0 {
+		o.Days = 31
+	}
+	if o.Plugs <= 0 {
+		o.Plugs = 10
+	}
+	if max := o.Model.maxPlugPairs(Config{Era: o.Era}); max > 0 && o.Plugs > max {
+		o.Plugs = max
+	}
+	if !o.Model.Plugboard {
+		o.Plugs = 0
+	}
+	if o.Rand == nil {
+		o.Rand = rand.Reader
+	}
+	return o
+}
+
+// randomRotors picks the rotors for the slots, with random rings and
+// starting positions.
+func randomRotors(rng io.Reader, model *Model, pool Rotors, slots int) ([]RotorConfig, error) {
+	rotors := make([]RotorConfig, slots)
+	taken := make(map[string]bool)
+	for slot := range rotors {
+		var choices []string
+		for _, rotor := range pool {
+			if !taken[rotor.ID] && (model.Slots == 0 || containsString(model.slotRotors(slot), rotor.ID)) {
+				choices = append(choices, rotor.ID)
+			}
+		}
+		if len(choices) == 0 {
+			return nil, fmt.Errorf("not enough rotors for %d slots", slots)
+		}
+		i, err := randomIndex(rng, len(choices))
+		if err != nil {
+			return nil, err
+		}
+		settings, err := randomLetters(rng, 2)
+		if err != nil {
+			return nil, err
+		}
+		taken[choices[i]] = true
+		rotors[slot] = RotorConfig{ID: choices[i], Start: settings[0], Ring: CharToIndex(settings[1]) + 1}
+	}
+	return rotors, nil
+}
+
+// randomPlugs picks n plugboard pairs, none of letters next to each
+// other if the rules say so.
+func randomPlugs(rng io.Reader, n int, noAdjacent bool) ([]string, error) {
+	var plugs []string
+	used := make(map[byte]bool)
+	for len(plugs) < n {
+		pair, err := randomLetters(rng, 2)
+		if err != nil {
+			return nil, err
+		}
+		if pair[0] == pair[1] || used[pair[0]] || used[pair[1]] || (noAdjacent && adjacentPair(pair)) {
+			continue
+		}
+		used[pair[0]], used[pair[1]] = true, true
+		plugs = append(plugs, pair)
+	}
+	return plugs, nil
+}
+
+// randomIndex returns a random number from 0 to n-1.
+func randomIndex(rng io.Reader, n int) (int, error) {
+	buf := make([]byte, 2)
+	limit := 65536 - 65536%n
+	for {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return 0, err
+		}
+		if v := int(buf[0])<<8 | int(buf[1]); v < limit {
+			return v % n, nil
+		}
+	}
+}
+
+// adjacentPair tells if the pair is of letters next to each other in
+// the alphabet.
+func adjacentPair(pair string) bool {
+	return len(pair) == 2 && (pair[0]+1 == pair[1] || pair[1]+1 == pair[0])
+}
+
+// repeatedSlot returns the first slot (counting from 1) with the same
+// rotor in both lists, and the rotor, or 0 if there's none.
+func repeatedSlot(before, after []RotorConfig) (int, string) {
+	for i := range after {
+		if i < len(before) && before[i].ID == after[i].ID {
+			return i + 1, after[i].ID
+		}
+	}
+	return 0, ""
+}
+
+// previousRotors returns the rotors of the last day on the sheet.
+func previousRotors(sheet KeySheet) []RotorConfig {
+	if len(sheet) == 0 {
+		return nil
+	}
+	return sheet[len(sheet)-1].Config.Rotors
+}
+
+// rotorOrder returns the rotor order, e.g. "II IV V".
+func rotorOrder(rotors []RotorConfig) string {
+	ids := make([]string, len(rotors))
+	for i, rotor := range rotors {
+		ids[i] = rotor.ID
+	}
+	return strings.Join(ids, " ")
+}
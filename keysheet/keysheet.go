@@ -0,0 +1,254 @@
+// Package keysheet reads key sheets (Tagesschlüssel) written down as
+// text, so that a machine can be set up for the day of a message the
+// way its operator did.
+//
+// A sheet is a month of days, one per line, starting with the sheet
+// settings, and with the columns of the printed sheets: the day, the
+// rotor order, the rings (numbers or letters), the plugboard pairs and,
+// optionally, the Kenngruppen and the ground setting (Grundstellung) the
+// sheets had before 1940, separated by bars:
+//
+//	# Luftwaffe, June 1944
+//	month 1944-06
+//	model I
+//	reflector B
+//	30 | I V III  | 14 09 24 | SZ GT DV KU FO MY EW JN IX LQ | WNY DGY EKB RZS
+//	29 | IV III I | 05 18 11 | IS EV MX RW DT UZ JQ AO CH NY | KTL ACW YQZ XOB
+//
+// Everything after a # is left out. The model is one of the known ones
+// (see enigma.KnownModels), the Enigma I if not set, and the reflector is
+// B, the one of most of the war, if not set. Several months can follow
+// each other, each with a month line of its own.
+package keysheet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emedvedev/enigma"
+)
+
+// dateLayout is how the days are looked up.
+const dateLayout = "2006-01-02"
+
+// day is the key of a day, with the model it's for.
+type day struct {
+	date  time.Time
+	model *enigma.Model
+	key   enigma.DailyKey
+}
+
+// Sheet is what was read from one or more key sheets: the key of every
+// day they had.
+type Sheet struct {
+	days map[string]day
+}
+
+// sheetSettings are the settings of the lines that follow.
+type sheetSettings struct {
+	year      int
+	month     time.Month
+	model     *enigma.Model
+	reflector string
+}
+
+// Parse reads key sheets (see the package documentation). Every day is
+// checked against the model; anything wrong is an error telling the
+// line.
+func Parse(r io.Reader) (*Sheet, error) {
+	sheet := &Sheet{days: make(map[string]day)}
+	settings := sheetSettings{model: &enigma.EnigmaI, reflector: "B"}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if err := sheet.parseLine(text, &settings); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// parseLine reads a line of settings or the key of a day.
+func (s *Sheet) parseLine(text string, settings *sheetSettings) error {
+	if !strings.Contains(text, "|") {
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf(`expected a setting like "month 1944-06", got "%s"`, text)
+		}
+		switch fields[0] {
+		case "month":
+			month, err := time.Parse("2006-01", fields[1])
+			if err != nil {
+				return fmt.Errorf(`month should be like "1944-06", got "%s"`, fields[1])
+			}
+			settings.year, settings.month = month.Year(), month.Month()
+		case "model":
+			settings.model = enigma.KnownModels.GetByName(fields[1])
+			if settings.model == nil {
+				return fmt.Errorf(`unknown model "%s"`, fields[1])
+			}
+		case "reflector":
+			settings.reflector = fields[1]
+		default:
+			return fmt.Errorf(`unknown setting "%s"`, fields[0])
+		}
+		return nil
+	}
+	if settings.year == 0 {
+		return fmt.Errorf(`the month has to be set before the days ("month 1944-06")`)
+	}
+	columns := strings.Split(text, "|")
+	if len(columns) < 4 || len(columns) > 6 {
+		return fmt.Errorf("expected the day, rotors, rings, plugboard, Kenngruppen and ground setting, got %d columns", len(columns))
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(columns[0]))
+	if err != nil {
+		return fmt.Errorf(`day should be a number, got "%s"`, strings.TrimSpace(columns[0]))
+	}
+	date := time.Date(settings.year, settings.month, number, 0, 0, 0, 0, time.UTC)
+	if date.Month() != settings.month || number < 1 {
+		return fmt.Errorf("%s has no day %d", time.Date(settings.year, settings.month, 1, 0, 0, 0, 0, time.UTC).Format("January 2006"), number)
+	}
+	key, err := parseKey(columns, settings)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.days[date.Format(dateLayout)]; ok {
+		return fmt.Errorf("%s is on the sheet twice", date.Format(dateLayout))
+	}
+	s.days[date.Format(dateLayout)] = day{date, settings.model, key}
+	return nil
+}
+
+// parseKey reads the columns of a day and checks the key against the
+// model. Without a ground setting, the rotors are at A.
+func parseKey(columns []string, settings *sheetSettings) (enigma.DailyKey, error) {
+	rotors := strings.Fields(columns[1])
+	rings, err := enigma.ParseRings(columns[2])
+	if err != nil {
+		return enigma.DailyKey{}, err
+	}
+	if len(rings) != len(rotors) {
+		return enigma.DailyKey{}, fmt.Errorf("expected %d rings, got %d", len(rotors), len(rings))
+	}
+	config := enigma.Config{
+		Rotors:    make([]enigma.RotorConfig, len(rotors)),
+		Reflector: enigma.ReflectorConfig{ID: settings.reflector},
+		Plugboard: strings.Fields(strings.ToUpper(columns[3])),
+	}
+	ground := make([]int, len(rotors))
+	if len(columns) == 6 && strings.TrimSpace(columns[5]) != "" {
+		if ground, err = enigma.ParsePositions(columns[5]); err != nil {
+			return enigma.DailyKey{}, err
+		}
+		if len(ground) != len(rotors) {
+			return enigma.DailyKey{}, fmt.Errorf("expected %d ground positions, got %d", len(rotors), len(ground))
+		}
+	}
+	for i, id := range rotors {
+		config.Rotors[i] = enigma.RotorConfig{ID: id, Start: enigma.IndexToChar(ground[i]), Ring: rings[i]}
+	}
+	if err := settings.model.Validate(config); err != nil {
+		return enigma.DailyKey{}, err
+	}
+	key := enigma.DailyKey{Config: config}
+	if len(columns) >= 5 {
+		key.Kenngruppen = strings.Fields(strings.ToUpper(columns[4]))
+	}
+	return key, nil
+}
+
+// Key returns the key of the day.
+func (s *Sheet) Key(date time.Time) (enigma.DailyKey, error) {
+	d, ok := s.days[date.Format(dateLayout)]
+	if !ok {
+		return enigma.DailyKey{}, fmt.Errorf("no key for %s", date.Format(dateLayout))
+	}
+	return d.key, nil
+}
+
+// Configure builds the machine of the day, given as "1944-06-06", with
+// the rotors at the ground setting of the day, if there's one, or at A;
+// the message key sets them anyway.
+func (s *Sheet) Configure(date string) (*enigma.Enigma, error) {
+	d, ok := s.days[date]
+	if !ok {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf(`date should be like "1944-06-06", got "%s"`, date)
+		}
+		return nil, fmt.Errorf("no key for %s", date)
+	}
+	return d.model.New(d.key.Config)
+}
+
+// Dates returns the days the sheets have keys for, in order.
+func (s *Sheet) Dates() []time.Time {
+	dates := make([]time.Time, 0, len(s.days))
+	for _, d := range s.days {
+		dates = append(dates, d.date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// Write writes a key sheet of the month, e.g. one drawn up by
+// enigma.GenerateKeySheet, in the format Parse reads: the last day
+// first, the way the sheets were printed so that the used days could be
+// cut off, with the ground setting of the days it isn't at A for. The
+// model is the one named, and the reflector the one of the first day. A
+// sheet of more days than the month has is an error, since it couldn't
+// be read back.
+func Write(w io.Writer, year int, month time.Month, model string, sheet enigma.KeySheet) error {
+	if len(sheet) == 0 {
+		return fmt.Errorf("the key sheet has no days")
+	}
+	if enigma.KnownModels.GetByName(model) == nil {
+		return fmt.Errorf(`unknown model "%s"`, model)
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	if days := first.AddDate(0, 1, -1).Day(); len(sheet) > days {
+		return fmt.Errorf("%s has no day %d", first.Format("January 2006"), len(sheet))
+	}
+	if _, err := fmt.Fprintf(w, "month %04d-%02d\nmodel %s\nreflector %s\n", year, int(month), model, sheet[0].Config.Reflector.ID); err != nil {
+		return err
+	}
+	for i := len(sheet) - 1; i >= 0; i-- {
+		config := sheet[i].Config
+		rotors := make([]string, len(config.Rotors))
+		rings := make([]string, len(config.Rotors))
+		ground := make([]byte, len(config.Rotors))
+		for j, rotor := range config.Rotors {
+			rotors[j] = rotor.ID
+			rings[j] = fmt.Sprintf("%02d", rotor.Ring)
+			ground[j] = rotor.Start
+			if ground[j] == 0 {
+				ground[j] = 'A'
+			}
+		}
+		line := fmt.Sprintf("%02d | %s | %s | %s | %s", i+1, strings.Join(rotors, " "),
+			strings.Join(rings, " "), strings.Join(config.Plugboard, " "),
+			strings.Join(sheet[i].Kenngruppen, " "))
+		if strings.Trim(string(ground), "A") != "" {
+			line += " | " + string(ground)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
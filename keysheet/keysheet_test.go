@@ -0,0 +1,186 @@
+package keysheet
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emedvedev/enigma"
+)
+
+// june1944 is the sheet of the package documentation.
+const june1944 = `# Luftwaffe, June 1944
+month 1944-06
+model I
+reflector B
+30 | I V III  | 14 09 24 | SZ GT DV KU FO MY EW JN IX LQ | WNY DGY EKB RZS
+29 | IV III I | 05 18 11 | IS EV MX RW DT UZ JQ AO CH NY | KTL ACW YQZ XOB | QRS
+`
+
+// The days are looked up by their date, and the machine of a day is the
+// one built from its key by hand.
+func TestParse(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(june1944))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := sheet.Dates()
+	if len(dates) != 2 || dates[0].Format(dateLayout) != "1944-06-29" || dates[1].Format(dateLayout) != "1944-06-30" {
+		t.Errorf("the sheet has the days %v", dates)
+	}
+	key, err := sheet.Key(time.Date(1944, time.June, 29, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := enigma.Config{
+		Rotors: []enigma.RotorConfig{
+			{ID: "IV", Start: 'Q', Ring: 5},
+			{ID: "III", Start: 'R', Ring: 18},
+			{ID: "I", Start: 'S', Ring: 11},
+		},
+		Reflector: enigma.ReflectorConfig{ID: "B"},
+		Plugboard: strings.Fields("IS EV MX RW DT UZ JQ AO CH NY"),
+	}
+	if !reflect.DeepEqual(key.Config, want) || !reflect.DeepEqual(key.Kenngruppen, strings.Fields("KTL ACW YQZ XOB")) {
+		t.Errorf("the key of the 29th is %+v", key)
+	}
+	if _, err := sheet.Key(time.Date(1944, time.June, 28, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("the sheet has a key for the 28th")
+	}
+
+	e, err := sheet.Configure("1944-06-29")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHand, err := enigma.EnigmaI.New(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := e.EncodeString("WETTERBERICHT"), byHand.EncodeString("WETTERBERICHT"); got != expected {
+		t.Errorf("the machine of the 29th encodes to %s, expected %s", got, expected)
+	}
+	e, err = sheet.Configure("1944-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if positions := e.Positions(); positions != "AAA" {
+		t.Errorf("without a ground setting, the rotors are at %s", positions)
+	}
+	for _, date := range []string{"1944-06-28", "30 June 1944"} {
+		if _, err := sheet.Configure(date); err == nil {
+			t.Errorf("the sheet configures a machine for %s", date)
+		}
+	}
+}
+
+// Sheets that are wrong are errors telling the line.
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		sheet string
+		want  string
+	}{
+		{"01 | I II III | 01 01 01 | AB", "line 1: the month has to be set"},
+		{"month 1944-06\n31 | I II III | 01 01 01 | AB", "line 2: June 1944 has no day 31"},
+		{"month 1944-06\nmodel Z", `line 2: unknown model "Z"`},
+		{"month 1944-06\n01 | I II VIII | 01 01 01 | AB", "line 2: "},
+		{"month 1944-06\n01 | I II III | 01 01 | AB", "line 2: expected 3 rings, got 2"},
+		{"month 1944-06\n01 | I II III | 01 01 01 | AB\n01 | I II IV | 01 01 01 | AB", "line 3: 1944-06-01 is on the sheet twice"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt.sheet)); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q is read with %v, expected %s", tt.sheet, err, tt.want)
+		}
+	}
+}
+
+// A sheet drawn up by GenerateKeySheet is read back the way it was
+// written, every day of it.
+func TestWriteRoundTrip(t *testing.T) {
+	for _, model := range []*enigma.Model{&enigma.EnigmaI, &enigma.M4} {
+		generated, err := enigma.GenerateKeySheet(enigma.KeySheetOptions{
+			Model: model,
+			Days:  30,
+			Rand:  rand.New(rand.NewSource(1944)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var text bytes.Buffer
+		if err := Write(&text, 1944, time.June, model.Name, generated); err != nil {
+			t.Fatal(err)
+		}
+		sheet, err := Parse(&text)
+		if err != nil {
+			t.Fatalf("%s: the sheet written is read with %v", model.Name, err)
+		}
+		for i, want := range generated {
+			key, err := sheet.Key(time.Date(1944, time.June, i+1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, expected := key.Config, want.Config
+			if len(got.Rotors) != len(expected.Rotors) || got.Reflector.ID != expected.Reflector.ID ||
+				!reflect.DeepEqual(got.Plugboard, expected.Plugboard) || !reflect.DeepEqual(key.Kenngruppen, want.Kenngruppen) {
+				t.Errorf("%s: day %d is read as %+v, expected %+v", model.Name, i+1, key, want)
+				continue
+			}
+			for j, rotor := range got.Rotors {
+				start := expected.Rotors[j].Start
+				if start == 0 {
+					start = 'A'
+				}
+				if rotor.ID != expected.Rotors[j].ID || rotor.Ring != expected.Rotors[j].Ring || rotor.Start != start {
+					t.Errorf("%s: day %d has the rotor %+v, expected %+v", model.Name, i+1, rotor, expected.Rotors[j])
+				}
+			}
+		}
+	}
+}
+
+// A sheet of 31 days doesn't fit in June, and the model has to be one
+// Parse knows.
+func TestWriteErrors(t *testing.T) {
+	generated, err := enigma.GenerateKeySheet(enigma.KeySheetOptions{Rand: rand.New(rand.NewSource(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		month time.Month
+		model string
+		sheet enigma.KeySheet
+		want  string
+	}{
+		{time.June, "I", generated, "June 1944 has no day 31"},
+		{time.February, "I", generated[:30], "February 1944 has no day 30"},
+		{time.July, "Z", generated, `unknown model "Z"`},
+		{time.July, "I", nil, "the key sheet has no days"},
+	}
+	for _, tt := range tests {
+		var text bytes.Buffer
+		if err := Write(&text, 1944, tt.month, tt.model, tt.sheet); err == nil || err.Error() != tt.want {
+			t.Errorf("%s, %s: the sheet is written with %v, expected %s", tt.month, tt.model, err, tt.want)
+		}
+		if text.Len() != 0 {
+			t.Errorf("%s, %s: %q is written anyway", tt.month, tt.model, text.String())
+		}
+	}
+	var text bytes.Buffer
+	if err := Write(&text, 1944, time.February, "I", generated[:29]); err != nil {
+		t.Errorf("the 29 days of February 1944 are written with %v", err)
+	}
+	if err := Write(brokenWriter{}, 1944, time.July, "I", generated); err != errBroken {
+		t.Errorf("the sheet is written to a broken writer with %v", err)
+	}
+}
+
+// errBroken is what brokenWriter fails with.
+var errBroken = errors.New("broken")
+
+// brokenWriter fails every write, the settings lines too.
+type brokenWriter struct{}
+
+func (brokenWriter) Write([]byte) (int, error) { return 0, errBroken }
@@ -0,0 +1,113 @@
+package enigma
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The key sheets of 1937 never pick rotor IV or V, and plug six cables.
+func TestGenerateKeySheetEra(t *testing.T) {
+	sheet, err := GenerateKeySheet(KeySheetOptions{Era: 1937, Days: 200, Rules: &KeySheetRules{}, Rand: rand.New(rand.NewSource(143))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	picked := map[string]bool{}
+	for day, key := range sheet {
+		for _, rotor := range key.Config.Rotors {
+			picked[rotor.ID] = true
+		}
+		if len(key.Config.Plugboard) != 6 {
+			t.Errorf("day %d plugs %d cables, expected 6", day+1, len(key.Config.Plugboard))
+		}
+	}
+	if picked["IV"] || picked["V"] || len(picked) != 3 {
+		t.Errorf("the rotors picked in 1937 are %v, expected I to III", picked)
+	}
+
+	sheet, err = GenerateKeySheet(KeySheetOptions{Era: 1939, Days: 31, Rand: rand.New(rand.NewSource(143))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orders []string
+	for _, key := range sheet {
+		orders = append(orders, rotorOrder(key.Config.Rotors))
+	}
+	if all := strings.Join(orders, " "); !strings.Contains(all, "V") {
+		t.Errorf("a month of 1939 never picks rotor IV or V: %s", all)
+	}
+}
+
+// A month drawn up with nothing but the defaults keeps to the rules of
+// the Luftwaffe, and one drawn up with no rules is let through them.
+func TestGenerateKeySheetDefaultRules(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		sheet, err := GenerateKeySheet(KeySheetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if violations := ValidateKeySheet(sheet, DefaultKeySheetRules); len(violations) != 0 {
+			t.Fatalf("a month drawn up with the defaults breaks the rules: %v", violations)
+		}
+	}
+	none := 0
+	for seed := int64(0); seed < 20; seed++ {
+		sheet, err := GenerateKeySheet(KeySheetOptions{Rules: &KeySheetRules{}, Rand: rand.New(rand.NewSource(seed))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		none += len(ValidateKeySheet(sheet, DefaultKeySheetRules))
+	}
+	if none == 0 {
+		t.Error("20 months drawn up with no rules keep to them all")
+	}
+}
+
+// A month drawn up under the rules keeps to those of the
+// Luftwaffe, and a sheet made by hand is caught where it doesn't.
+func TestKeySheetRules(t *testing.T) {
+	sheet, err := GenerateKeySheet(KeySheetOptions{Rand: rand.New(rand.NewSource(148))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet) != 31 {
+		t.Errorf("the month has %d days", len(sheet))
+	}
+	if violations := ValidateKeySheet(sheet, DefaultKeySheetRules); len(violations) != 0 {
+		t.Errorf("the month breaks the rules: %v", violations)
+	}
+
+	day := func(rotors string, plugs ...string) DailyKey {
+		config := classicConfig()
+		config.Rotors = rotorsAt(rotors, "AAA")
+		config.Plugboard = plugs
+		return DailyKey{Config: config}
+	}
+	made := KeySheet{
+		day("I II III", "AQ"),
+		day("I II III", "QA"),
+		day("II III I", "BA", "KM"),
+		day("III I II"),
+		day("III IV V", "ST"),
+	}
+	got := ValidateKeySheet(made, DefaultKeySheetRules)
+	want := []Violation{
+		{2, RuleRepeatedSlot, "rotor I in slot 1 again"},
+		{2, RuleRepeatedOrder, "rotor order I II III already used on day 1"},
+		{3, RuleAdjacentPlugs, "plugboard pair BA of letters next to each other"},
+		{5, RuleAdjacentPlugs, "plugboard pair ST of letters next to each other"},
+		{5, RuleRepeatedSlot, "rotor III in slot 1 again"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the violations are %v, expected %v", got, want)
+	}
+	if got := ValidateKeySheet(made, KeySheetRules{NoRepeatedOrder: true}); len(got) != 1 || got[0].String() != "day 2: rotor order I II III already used on day 1 (repeated-order)" {
+		t.Errorf("with the one rule, the violations are %v", got)
+	}
+
+	// Three rotors have six orders, not enough for a month.
+	if _, err := GenerateKeySheet(KeySheetOptions{Model: &EnigmaI1938, Rules: &KeySheetRules{NoRepeatedOrder: true}, Rand: rand.New(rand.NewSource(148))}); err == nil {
+		t.Error("a month of three rotors doesn't repeat a rotor order")
+	}
+}
@@ -0,0 +1,64 @@
+package enigma
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LampStyle tells how RenderLampboard shows the lit lamp.
+type LampStyle int
+
+// Lamp styles: LampTerminal lights the lamp with ANSI bold and inverse,
+// LampPlain puts it in brackets, which is better suited for logs.
+const (
+	LampTerminal LampStyle = iota
+	LampPlain
+)
+
+// lampRows is the layout of the lampboard, the same as the keyboard of
+// the German machines.
+var lampRows = []string{"QWERTZUIO", "ASDFGHJK", "PYXCVBNML"}
+
+// RenderLampboard draws the three rows of the lampboard with the lit
+// letter highlighted. Pass 0 to have no lamp lit.
+func RenderLampboard(lit rune, style LampStyle) string {
+	lit = unicode.ToUpper(lit)
+	var b strings.Builder
+	for i, row := range lampRows {
+		if len(row) < len(lampRows[0]) {
+			b.WriteString(strings.Repeat(" ", (len(lampRows[0])-len(row))*3/2))
+		}
+		for _, letter := range row {
+			switch {
+			case letter != lit:
+				b.WriteString(" " + string(letter) + " ")
+			case style == LampPlain:
+				b.WriteString("[" + string(letter) + "]")
+			default:
+				b.WriteString("\x1b[1;7m " + string(letter) + " \x1b[0m")
+			}
+		}
+		if i < len(lampRows)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// RenderRotorWindows draws the rotor windows showing the positions,
+// given as letters ("QDV") or, if separated by spaces, as anything else
+// ("17 04 22"), e.g. the way Positions returns them.
+func RenderRotorWindows(positions string) string {
+	windows := strings.Fields(positions)
+	if len(windows) == 1 {
+		windows = strings.Split(windows[0], "")
+	}
+	var border, middle strings.Builder
+	border.WriteByte('+')
+	middle.WriteByte('|')
+	for _, window := range windows {
+		border.WriteString(strings.Repeat("-", len(window)+2) + "+")
+		middle.WriteString(" " + window + " |")
+	}
+	return border.String() + "\n" + middle.String() + "\n" + border.String()
+}
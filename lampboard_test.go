@@ -0,0 +1,48 @@
+package enigma
+
+import "testing"
+
+func TestRenderLampboard(t *testing.T) {
+	tests := []struct {
+		name  string
+		lit   rune
+		style LampStyle
+		want  string
+	}{
+		{"none lit", 0, LampPlain, "" +
+			" Q  W  E  R  T  Z  U  I  O \n" +
+			"  A  S  D  F  G  H  J  K \n" +
+			" P  Y  X  C  V  B  N  M  L "},
+		{"plain", 'a', LampPlain, "" +
+			" Q  W  E  R  T  Z  U  I  O \n" +
+			" [A] S  D  F  G  H  J  K \n" +
+			" P  Y  X  C  V  B  N  M  L "},
+		{"terminal", 'Q', LampTerminal, "" +
+			"\x1b[1;7m Q \x1b[0m W  E  R  T  Z  U  I  O \n" +
+			"  A  S  D  F  G  H  J  K \n" +
+			" P  Y  X  C  V  B  N  M  L "},
+		{"terminal, none lit", 0, LampTerminal, "" +
+			" Q  W  E  R  T  Z  U  I  O \n" +
+			"  A  S  D  F  G  H  J  K \n" +
+			" P  Y  X  C  V  B  N  M  L "},
+	}
+	for _, tt := range tests {
+		if got := RenderLampboard(tt.lit, tt.style); got != tt.want {
+			t.Errorf("%s:\n%s\nexpected:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderRotorWindows(t *testing.T) {
+	tests := []struct {
+		positions, want string
+	}{
+		{"QDV", "+---+---+---+\n| Q | D | V |\n+---+---+---+"},
+		{"17 04 22", "+----+----+----+\n| 17 | 04 | 22 |\n+----+----+----+"},
+	}
+	for _, tt := range tests {
+		if got := RenderRotorWindows(tt.positions); got != tt.want {
+			t.Errorf("%s:\n%s\nexpected:\n%s", tt.positions, got, tt.want)
+		}
+	}
+}
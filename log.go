@@ -0,0 +1,74 @@
+package enigma
+
+import (
+	"context"
+	"log/slog"
+)
+
+// defaultLogger is what machines log to unless they have a logger of
+// their own; nil means no logging.
+var defaultLogger *slog.Logger
+
+// SetDefaultLogger sets the logger for all machines without a logger
+// of their own. Nothing is logged by default.
+func SetDefaultLogger(logger *slog.Logger) {
+	defaultLogger = logger
+}
+
+// SetLogger sets the logger of the machine. The machine logs what it
+// did (message sizes, sanitizer substitutions) and the configurations
+// that look like a mistake, but never the text itself, neither plain
+// nor encoded, nor the rotor positions.
+func (e *Enigma) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// log returns the logger to use, nil if there's none.
+func (e *Enigma) log() *slog.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return defaultLogger
+}
+
+// logEncode logs the summary of an encoded message.
+func (e *Enigma) logEncode(letters int) {
+	if logger := e.log(); logger != nil {
+		logger.LogAttrs(context.Background(), slog.LevelDebug, "enigma.encode",
+			slog.Int("letters", letters), slog.Int("keypresses", e.stats.Keypresses))
+	}
+}
+
+// logSanitize warns about the characters the sanitizer changed, by
+// kind, without saying which ones they were.
+func (e *Enigma) logSanitize(report SanitizeReport) {
+	logger := e.log()
+	if logger == nil || len(report.Changes) == 0 {
+		return
+	}
+	var counts [3]int
+	for _, change := range report.Changes {
+		if int(change.Kind) < len(counts) {
+			counts[change.Kind]++
+		}
+	}
+	if counts[Stripped]+counts[Substituted] == 0 {
+		return
+	}
+	logger.LogAttrs(context.Background(), slog.LevelWarn, "enigma.sanitize",
+		slog.Int("stripped", counts[Stripped]), slog.Int("substituted", counts[Substituted]),
+		slog.Int("uppercased", counts[Uppercased]))
+}
+
+// logConfig warns about configurations that work but are unlikely to
+// be intended.
+func (m *Model) logConfig(e *Enigma, config Config) {
+	logger := e.log()
+	if logger == nil {
+		return
+	}
+	if m.Plugboard && m.Slots != 0 && len(config.Plugboard) == 0 {
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "enigma.config",
+			slog.String("model", m.Name), slog.String("reason", "empty plugboard"))
+	}
+}
@@ -0,0 +1,98 @@
+package enigma
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recorder is a slog handler keeping the records, at every level.
+type recorder struct {
+	records []slog.Record
+}
+
+func (r *recorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (r *recorder) Handle(_ context.Context, record slog.Record) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *recorder) WithAttrs([]slog.Attr) slog.Handler { return r }
+func (r *recorder) WithGroup(string) slog.Handler      { return r }
+
+// attrs returns the attributes of a record as text.
+func attrs(record slog.Record) map[string]string {
+	values := map[string]string{}
+	record.Attrs(func(a slog.Attr) bool {
+		values[a.Key] = a.Value.String()
+		return true
+	})
+	return values
+}
+
+func TestLogging(t *testing.T) {
+	defaults := &recorder{}
+	SetDefaultLogger(slog.New(defaults))
+	defer SetDefaultLogger(nil)
+	e, err := M3.New(Config{Rotors: rotorsAt("I II III", "AAA"), Reflector: ReflectorConfig{ID: "B"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defaults.records) != 1 || defaults.records[0].Message != "enigma.config" || defaults.records[0].Level != slog.LevelWarn {
+		t.Fatalf("building an M3 without plugs logs %v", defaults.records)
+	}
+	if got := attrs(defaults.records[0]); got["model"] != "M3" || got["reason"] != "empty plugboard" {
+		t.Errorf("the configuration is logged with %v", got)
+	}
+
+	own := &recorder{}
+	e.SetLogger(slog.New(own))
+	plaintext := "Geheimer Befehl für 7 Uhr"
+	ciphertext, err := e.EncodeText(plaintext, NonAlphaSpaceToX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defaults.records) != 1 {
+		t.Error("the machine with a logger of its own logs to the default one")
+	}
+	want := []struct {
+		message string
+		level   slog.Level
+		attrs   map[string]string
+	}{
+		{"enigma.sanitize", slog.LevelWarn, map[string]string{"stripped": "1", "substituted": "5", "uppercased": "16"}},
+		{"enigma.encode", slog.LevelDebug, map[string]string{"letters": "25", "keypresses": "25"}},
+	}
+	if len(own.records) != len(want) {
+		t.Fatalf("encoding logs %d records, expected %d", len(own.records), len(want))
+	}
+	for i, w := range want {
+		record := own.records[i]
+		got := attrs(record)
+		if record.Message != w.message || record.Level != w.level || len(got) != len(w.attrs) {
+			t.Errorf("record %d is %s at %s with %v, expected %s at %s", i, record.Message, record.Level, got, w.message, w.level)
+			continue
+		}
+		for key, value := range w.attrs {
+			if got[key] != value {
+				t.Errorf("%s: %s is %s, expected %s", record.Message, key, got[key], value)
+			}
+		}
+	}
+
+	// Nothing of the texts, nor the rotor positions, is ever logged.
+	clean, _, _ := Sanitize(plaintext, NonAlphaSpaceToX)
+	for _, record := range append(defaults.records, own.records...) {
+		logged := record.Message
+		for key, value := range attrs(record) {
+			logged += " " + key + "=" + value
+		}
+		for _, secret := range []string{plaintext, clean, ciphertext, clean[:5], ciphertext[:5], e.Positions()} {
+			if strings.Contains(logged, secret) {
+				t.Errorf("%s is logged in %q", secret, logged)
+			}
+		}
+	}
+}
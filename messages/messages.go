@@ -0,0 +1,102 @@
+// Package messages is an archive of real intercepts, with what's known
+// of them: when they were sent, by whom, on which machine, and the
+// settings, for the ones that were broken, so that a famous message is
+// a call away from its plaintext:
+//
+//	message := messages.LookupByNetwork("Kriegsmarine")[0]
+//	plaintext, err := message.Decode()
+//
+// The messages and their keys are those of the testvectors package.
+package messages
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// ErrNotBroken is the error of decoding a message whose key was never
+// recovered.
+var ErrNotBroken = errors.New("the key of the message isn't known")
+
+// Message is an intercept: what it's known as, the date it was sent on
+// ("1941-07-07", or only as much of it as is known, e.g. "1945-05", or
+// nothing), the service or net it was sent on, the frequency it was
+// taken down on, if known, the ciphertext as it was sent, and the
+// settings of the machine at the message key, nil if it wasn't broken.
+type Message struct {
+	Name       string
+	Date       string
+	Network    string
+	Frequency  string
+	Ciphertext string
+	Settings   *enigma.Settings
+}
+
+// Decode decrypts the message with its settings.
+func (m Message) Decode() (string, error) {
+	if m.Settings == nil {
+		return "", ErrNotBroken
+	}
+	e, err := m.Settings.New()
+	if err != nil {
+		return "", err
+	}
+	return e.DecodeString(enigma.ParseGroups(m.Ciphertext, enigma.ClassicGroups)), nil
+}
+
+// Archive lists the messages, oldest first.
+var Archive = []Message{
+	{
+		Name:       testvectors.Barbarossa.Name,
+		Date:       "1941-07-07",
+		Network:    "Wehrmacht",
+		Ciphertext: testvectors.Barbarossa.Ciphertext,
+		Settings:   settings("I", testvectors.Barbarossa),
+	},
+	{
+		Name:       testvectors.Scharnhorst.Name,
+		Network:    "Kriegsmarine",
+		Ciphertext: testvectors.Scharnhorst.Ciphertext,
+		Settings:   settings("M3", testvectors.Scharnhorst),
+	},
+	{
+		Name:       testvectors.Doenitz.Name,
+		Date:       "1945-05",
+		Network:    "Kriegsmarine",
+		Ciphertext: testvectors.Doenitz.Ciphertext,
+		Settings:   settings("M4", testvectors.Doenitz),
+	},
+}
+
+// settings returns the settings of the vector on the model.
+func settings(model string, vector testvectors.Vector) *enigma.Settings {
+	return &enigma.Settings{Model: model, Config: vector.Config}
+}
+
+// LookupByDate returns the messages sent on the date, given as much of
+// it as is wanted: "1941-07-07", "1941-07", or "1941". Messages whose
+// date isn't known that closely aren't returned.
+func LookupByDate(date string) []Message {
+	var found []Message
+	for _, message := range Archive {
+		if date != "" && strings.HasPrefix(message.Date, date) {
+			found = append(found, message)
+		}
+	}
+	return found
+}
+
+// LookupByNetwork returns the messages sent on the network, in either
+// case, e.g. "Kriegsmarine".
+func LookupByNetwork(network string) []Message {
+	var found []Message
+	for _, message := range Archive {
+		if strings.EqualFold(message.Network, network) {
+			found = append(found, message)
+		}
+	}
+	return found
+}
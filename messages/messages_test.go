@@ -0,0 +1,58 @@
+package messages
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// Every message of the archive decodes to the plaintext of its vector,
+// on the machine it was sent on.
+func TestDecode(t *testing.T) {
+	plaintexts := make(map[string]string)
+	for _, vector := range testvectors.Vectors {
+		plaintexts[vector.Name] = vector.Plaintext
+	}
+	for _, message := range Archive {
+		plaintext, err := message.Decode()
+		if err != nil {
+			t.Errorf("%s: %v", message.Name, err)
+			continue
+		}
+		if want, ok := plaintexts[message.Name]; !ok || plaintext != want {
+			t.Errorf("%s decodes to %s, expected %s", message.Name, plaintext, want)
+		}
+	}
+	if _, err := (Message{Name: "unbroken", Ciphertext: "QWERT"}).Decode(); !errors.Is(err, ErrNotBroken) {
+		t.Errorf("the unbroken message decodes with %v", err)
+	}
+}
+
+// The messages are found by as much of the date as is given, but not
+// by more of it than is known, and by the network in either case.
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		lookup func() []Message
+		want   []string
+	}{
+		{func() []Message { return LookupByDate("1941-07-07") }, []string{testvectors.Barbarossa.Name}},
+		{func() []Message { return LookupByDate("1941") }, []string{testvectors.Barbarossa.Name}},
+		{func() []Message { return LookupByDate("1945-05") }, []string{testvectors.Doenitz.Name}},
+		{func() []Message { return LookupByDate("1945-05-08") }, nil},
+		{func() []Message { return LookupByDate("") }, nil},
+		{func() []Message { return LookupByNetwork("kriegsmarine") }, []string{testvectors.Scharnhorst.Name, testvectors.Doenitz.Name}},
+		{func() []Message { return LookupByNetwork("Wehrmacht") }, []string{testvectors.Barbarossa.Name}},
+		{func() []Message { return LookupByNetwork("Luftwaffe") }, nil},
+	}
+	for i, tt := range tests {
+		var got []string
+		for _, message := range tt.lookup() {
+			got = append(got, message.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookup %d finds %v, expected %v", i, got, tt.want)
+		}
+	}
+}
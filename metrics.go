@@ -0,0 +1,113 @@
+package enigma
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Collector takes the metrics of the machines: counters, and the time
+// things took. It's called from the goroutines of every machine, so it
+// should be safe for concurrent use. See the metrics package for one
+// backed by expvar.
+//
+// The names are:
+//
+//	enigma.encode.messages      strings encoded (EncodeString)
+//	enigma.encode.characters    letters encoded by them
+//	enigma.encode               the time it took (a duration)
+//	enigma.decode.messages      strings decoded (DecodeString)
+//	enigma.decode.characters    letters decoded by them
+//	enigma.decode               the time it took (a duration)
+//	enigma.stream.messages      streams encoded (EncodeStream)
+//	enigma.stream.characters    letters encoded by them
+//	enigma.stream.errors.<kind> the ones that went wrong, by kind
+//	enigma.stream               the time it took (a duration)
+//	enigma.batch.messages       messages decrypted (DecryptBatch)
+//	enigma.batch.characters     letters decrypted
+//	enigma.batch.errors.<kind>  the ones that went wrong, by kind
+//	enigma.batch                the time a batch took (a duration)
+//
+// The kinds of errors are those of the errors of the package, like
+// "garbled" for ErrGarbled or "plug_conflict" for ErrPlugConflict,
+// "canceled" for messages left when the context was done, and "other"
+// for the rest. The enigmad package counts its requests the same way.
+type Collector interface {
+	IncCounter(name string, n int64)
+	ObserveDuration(name string, d time.Duration)
+}
+
+// metrics is where the metrics go; nil means nowhere.
+var metrics Collector
+
+// SetMetricsCollector sets where the metrics of all machines go. There's
+// none by default, and then collecting them costs next to nothing. Set
+// it before the machines get to work, it's not safe to change while
+// they do.
+func SetMetricsCollector(collector Collector) {
+	metrics = collector
+}
+
+// MetricsCollector returns where the metrics of all machines go (see
+// SetMetricsCollector), nil if nowhere, so that the packages serving
+// them can add their own.
+func MetricsCollector() Collector {
+	return metrics
+}
+
+// errorKinds are the names of the errors in the metrics.
+var errorKinds = []struct {
+	err  error
+	name string
+}{
+	{ErrEmptyAfterSanitize, "empty_after_sanitize"},
+	{ErrBadIndicator, "bad_indicator"},
+	{ErrWrongKenngruppe, "wrong_kenngruppe"},
+	{ErrGarbled, "garbled"},
+	{ErrUnknownRotor, "unknown_rotor"},
+	{ErrUnknownReflector, "unknown_reflector"},
+	{ErrRingOutOfRange, "ring_out_of_range"},
+	{ErrPositionOutOfRange, "position_out_of_range"},
+	{ErrPlugConflict, "plug_conflict"},
+	{ErrRotorReflectorMismatch, "rotor_reflector_mismatch"},
+	{ErrNotInEra, "not_in_era"},
+}
+
+// errorKind returns the name of the kind of the error in the metrics.
+func errorKind(err error) string {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.name
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "canceled"
+	}
+	return "other"
+}
+
+// countError counts an error of the prefix (e.g. "enigma.batch").
+func countError(prefix string, err error) {
+	if metrics != nil && err != nil {
+		metrics.IncCounter(prefix+".errors."+errorKind(err), 1)
+	}
+}
+
+// metricsStart returns the time now, if there are metrics to collect.
+func metricsStart() time.Time {
+	if metrics == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observe counts the messages of the prefix and their letters, along with
+// the time it took since it started (see metricsStart).
+func observe(prefix string, messages, letters int, started time.Time) {
+	if metrics == nil {
+		return
+	}
+	metrics.IncCounter(prefix+".messages", int64(messages))
+	metrics.IncCounter(prefix+".characters", int64(letters))
+	metrics.ObserveDuration(prefix, time.Since(started))
+}
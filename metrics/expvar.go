@@ -0,0 +1,114 @@
+// Package metrics has collectors for the metrics of the enigma package
+// (see enigma.SetMetricsCollector) for those who don't run a metrics
+// system of their own.
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// samples is how many of the latest durations the quantiles are taken
+// from.
+const samples = 1024
+
+// Expvar is a collector publishing the metrics through expvar, which
+// serves them at /debug/vars along with the rest: the counters as they
+// are, and for every duration the count and the p50 and p99 of the
+// latest ones, in nanoseconds.
+type Expvar struct {
+	vars *expvar.Map
+	mu   sync.Mutex
+}
+
+// NewExpvar returns a collector publishing the metrics as a map of the
+// name. Just like expvar.Publish, it panics if the name is taken.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{vars: expvar.NewMap(name)}
+}
+
+// IncCounter adds n to the counter of the name.
+func (c *Expvar) IncCounter(name string, n int64) {
+	c.vars.Add(name, n)
+}
+
+// ObserveDuration adds a duration to those of the name.
+func (c *Expvar) ObserveDuration(name string, d time.Duration) {
+	w, ok := c.vars.Get(name).(*window)
+	if !ok {
+		c.mu.Lock()
+		if w, ok = c.vars.Get(name).(*window); !ok {
+			w = &window{}
+			c.vars.Set(name, w)
+		}
+		c.mu.Unlock()
+	}
+	w.observe(d)
+}
+
+// Counter returns the value of the counter of the name, 0 if nothing
+// was counted.
+func (c *Expvar) Counter(name string) int64 {
+	if v, ok := c.vars.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// Quantile returns the q quantile (0.5 for the median) of the latest
+// durations of the name, 0 if there are none.
+func (c *Expvar) Quantile(name string, q float64) time.Duration {
+	if w, ok := c.vars.Get(name).(*window); ok {
+		return w.quantile(q)
+	}
+	return 0
+}
+
+// window keeps the latest durations, and how many there were.
+type window struct {
+	mu     sync.Mutex
+	latest []time.Duration
+	count  int64
+}
+
+func (w *window) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.latest) < samples {
+		w.latest = append(w.latest, d)
+	} else {
+		w.latest[w.count%samples] = d
+	}
+	w.count++
+}
+
+func (w *window) quantile(q float64) time.Duration {
+	w.mu.Lock()
+	sorted := append([]time.Duration(nil), w.latest...)
+	w.mu.Unlock()
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(q * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// String implements expvar.Var.
+func (w *window) String() string {
+	w.mu.Lock()
+	count := w.count
+	w.mu.Unlock()
+	out, _ := json.Marshal(struct {
+		Count int64 `json:"count"`
+		P50   int64 `json:"p50"`
+		P99   int64 `json:"p99"`
+	}{count, int64(w.quantile(0.5)), int64(w.quantile(0.99))})
+	return string(out)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+// The counters add up, and the quantiles are those of the durations,
+// as published at /debug/vars.
+func TestExpvar(t *testing.T) {
+	c := NewExpvar("enigma-test")
+	c.IncCounter("enigma.encode.messages", 1)
+	c.IncCounter("enigma.encode.messages", 2)
+	for d := time.Duration(1); d <= 100; d++ {
+		c.ObserveDuration("enigma.encode", d*time.Millisecond)
+	}
+	if got := c.Counter("enigma.encode.messages"); got != 3 {
+		t.Errorf("the counter is %d, expected 3", got)
+	}
+	if got := c.Counter("enigma.batch.messages"); got != 0 {
+		t.Errorf("the counter never counted is %d", got)
+	}
+	if p50, p99 := c.Quantile("enigma.encode", 0.5), c.Quantile("enigma.encode", 0.99); p50 != 51*time.Millisecond || p99 != 100*time.Millisecond {
+		t.Errorf("the p50 is %v and the p99 %v", p50, p99)
+	}
+	if got := c.Quantile("enigma.batch", 0.5); got != 0 {
+		t.Errorf("the median of no durations is %v", got)
+	}
+	var published map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(expvar.Get("enigma-test").String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	var window struct{ Count, P50, P99 int64 }
+	if err := json.Unmarshal(published["enigma.encode"], &window); err != nil {
+		t.Fatal(err)
+	}
+	if window.Count != 100 || window.P50 != int64(51*time.Millisecond) || string(published["enigma.encode.messages"]) != "3" {
+		t.Errorf("the metrics are published as %s", expvar.Get("enigma-test"))
+	}
+}
+
+// Only the latest durations count toward the quantiles, and all of them
+// toward the count.
+func TestExpvarWindow(t *testing.T) {
+	w := &window{}
+	for i := 0; i < samples; i++ {
+		w.observe(time.Hour)
+	}
+	for i := 0; i < samples; i++ {
+		w.observe(time.Second)
+	}
+	if got := w.quantile(0.99); got != time.Second || w.count != 2*samples {
+		t.Errorf("the p99 is %v of %d durations", got, w.count)
+	}
+}
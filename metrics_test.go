@@ -0,0 +1,128 @@
+package enigma
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// metricsRecorder collects the metrics to be checked: the counters, and how
+// many durations there were of each name.
+type metricsRecorder struct {
+	mu        sync.Mutex
+	counters  map[string]int64
+	durations map[string]int
+}
+
+func newMetricsRecorder() *metricsRecorder {
+	return &metricsRecorder{counters: make(map[string]int64), durations: make(map[string]int)}
+}
+
+func (r *metricsRecorder) IncCounter(name string, n int64) {
+	r.mu.Lock()
+	r.counters[name] += n
+	r.mu.Unlock()
+}
+
+func (r *metricsRecorder) ObserveDuration(name string, d time.Duration) {
+	r.mu.Lock()
+	r.durations[name]++
+	r.mu.Unlock()
+}
+
+// collecting sets the recorder as the collector for the rest of the
+// test.
+func collecting(t *testing.T) *metricsRecorder {
+	r := newMetricsRecorder()
+	SetMetricsCollector(r)
+	t.Cleanup(func() { SetMetricsCollector(nil) })
+	return r
+}
+
+// After strings and streams encoded, a broken machine's stream turned
+// down, and a batch with a garbled message and a bad indicator, every
+// counter is what was done.
+func TestMetrics(t *testing.T) {
+	key := DailyKey{Config: classicConfig()}
+	messages := []ReceivedMessage{
+		batchMessage(t, key.Config, "WXC", "KCH", "", receiveText),
+		{"WXC", "ABCDE FGHIJ"},
+		batchMessage(t, Config{Rotors: rotorsAt("I II IV", "AAA"), Reflector: ReflectorConfig{ID: "B"}}, "UFW", "EMV", "", receiveText),
+	}
+	r := collecting(t)
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("WETTER")
+	e.EncodeString("BERICHT")
+	e.EncodeString("")
+	var out bytes.Buffer
+	if _, err := e.EncodeStream(strings.NewReader("WETTERBERICHT"), &out, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	broken := e.Clone()
+	broken.Plugboard[0], broken.Plugboard[1], broken.Plugboard[2] = 1, 2, 0
+	if _, err := broken.EncodeStream(strings.NewReader("WETTER"), &out, StreamOptions{}); err == nil {
+		t.Fatal("the broken machine encodes the stream")
+	}
+	results, err := DecryptBatch(context.Background(), key, messages, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptBatch(context.Background(), DailyKey{Config: Config{Rotors: rotorsAt("I II IX", "AAA"), Reflector: ReflectorConfig{ID: "B"}}}, messages, 1); err == nil {
+		t.Fatal("the batch is decrypted with rotor IX")
+	}
+	batched := 0
+	for _, result := range results {
+		batched += len(result.Plaintext)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, want := range map[string]int64{
+		"enigma.stream.messages":             1,
+		"enigma.stream.characters":           13,
+		"enigma.stream.errors.plug_conflict": 1,
+		"enigma.batch.messages":              3,
+		"enigma.batch.characters":            int64(batched),
+		"enigma.batch.errors.bad_indicator":  1,
+		"enigma.batch.errors.garbled":        1,
+		"enigma.batch.errors.unknown_rotor":  1,
+	} {
+		if got := r.counters[name]; got != want {
+			t.Errorf("%s is %d, expected %d", name, got, want)
+		}
+	}
+	// The batch encodes the indicators and the texts with EncodeString
+	// too, so those are counted after the two strings.
+	if got := r.counters["enigma.encode.messages"]; got < 2 {
+		t.Errorf("%d strings are counted, expected at least 2", got)
+	}
+	if got := r.counters["enigma.encode.characters"]; got < 13 {
+		t.Errorf("%d letters of strings are counted, expected at least 13", got)
+	}
+	if want := map[string]int{"enigma.encode": int(r.counters["enigma.encode.messages"]), "enigma.stream": 1, "enigma.batch": 1}; !reflect.DeepEqual(r.durations, want) {
+		t.Errorf("the durations observed are %v, expected %v", r.durations, want)
+	}
+}
+
+// With no collector, nothing is counted, and encoding doesn't allocate
+// any more than it does without metrics.
+func TestMetricsNone(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	without := testing.AllocsPerRun(100, func() { e.EncodeString(receiveText) })
+	collecting(t)
+	with := testing.AllocsPerRun(100, func() { e.EncodeString(receiveText) })
+	SetMetricsCollector(nil)
+	if again := testing.AllocsPerRun(100, func() { e.EncodeString(receiveText) }); again != without || with < without {
+		t.Errorf("encoding allocates %v times with no collector, %v before and %v with one", again, without, with)
+	}
+}
@@ -0,0 +1,623 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Model describes a particular Enigma machine as it was issued: the set
+// of rotors and reflectors it came with, the number of rotor slots, its
+// entry wheel, whether the reflector could be set to a position, and
+// whether it had a plugboard at all and how many cables it shipped with,
+// whether the Uhr could be plugged into it, and the name of its stepping
+// mechanism (see SteppingMechanisms), the lever one if not set.
+// RotatingReflector tells if the reflector steps too, like on the Enigma
+// G (see Enigma.RotatingReflector).
+//
+// FixedSlots lists the slots (counting from the left) holding rotors
+// that never step, and UKWD tells if the rewirable UKW-D can be used
+// in addition to the reflectors on the list.
+//
+// A zero Slots value allows any number of rotors and any slots to be
+// fixed, an empty EntryWheel lets the configuration choose one, and a
+// zero MaxPlugPairs doesn't limit the plugboard.
+type Model struct {
+	Name              string
+	Rotors            Rotors
+	Reflectors        Reflectors
+	EntryWheel        string
+	Slots             int
+	FixedSlots        []int
+	SettableReflector bool
+	UKWD              bool
+	Plugboard         bool
+	MaxPlugPairs      int
+	Uhr               bool
+	Stepping          string
+	RotatingReflector bool
+	Rules             []SelectionRule
+}
+
+// SelectionRule is a rule of procedure for picking the rotors, rather
+// than something the machine itself enforced: Allows tells if the rotor
+// order (the IDs from the left) keeps to it, and Description tells the
+// user what it is.
+type SelectionRule struct {
+	Name        string
+	Description string
+	Allows      func(rotors []string) bool
+}
+
+// NavalRotorRule is the Kriegsmarine rule of having at least one of the
+// naval rotors VI to VIII among the three, which army machines didn't
+// even have.
+var NavalRotorRule = SelectionRule{
+	Name:        "naval-rotor",
+	Description: "at least one of the rotors VI, VII, and VIII",
+	Allows: func(rotors []string) bool {
+		for _, id := range rotors {
+			if id == "VI" || id == "VII" || id == "VIII" {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// Generic is not a historical model at all: any number of rotors from
+// the military set, any reflector, any entry wheel, and a plugboard.
+// It's what NewMachine builds.
+var Generic = Model{
+	Name:              "generic",
+	Rotors:            historicRotors,
+	Reflectors:        historicReflectors,
+	SettableReflector: true,
+	UKWD:              true,
+	Plugboard:         true,
+	Uhr:               true,
+}
+
+// EnigmaI is the Wehrmacht Enigma I with rotors I to V and ten cables
+// for the plugboard, as used for most of the war, and with the Uhr the
+// Luftwaffe plugged in from 1944.
+var EnigmaI = Model{
+	Name:         "I",
+	Rotors:       historicRotors[:5],
+	Reflectors:   historicReflectors[:3],
+	Slots:        3,
+	Plugboard:    true,
+	MaxPlugPairs: 10,
+	Uhr:          true,
+}
+
+// EnigmaI1938 is the Enigma I as used before 1939: only rotors I to III
+// were available, and key sheets used six plugboard pairs.
+var EnigmaI1938 = Model{
+	Name:         "I-1938",
+	Rotors:       historicRotors[:3],
+	Reflectors:   historicReflectors[:2],
+	Slots:        3,
+	Plugboard:    true,
+	MaxPlugPairs: 6,
+}
+
+// M3 is the three-rotor naval Enigma, which added rotors VI to VIII to
+// the set of the Enigma I.
+var M3 = Model{
+	Name:         "M3",
+	Rotors:       historicRotors[:8],
+	Reflectors:   historicReflectors[1:3],
+	Slots:        3,
+	Plugboard:    true,
+	MaxPlugPairs: 10,
+}
+
+// M3Navy is the M3 as the Kriegsmarine nets used it: the same machine,
+// but one of the naval rotors always had to be in (see NavalRotorRule).
+var M3Navy = Model{
+	Name:         "M3-Navy",
+	Rotors:       historicRotors[:8],
+	Reflectors:   historicReflectors[1:3],
+	Slots:        3,
+	Plugboard:    true,
+	MaxPlugPairs: 10,
+	Rules:        []SelectionRule{NavalRotorRule},
+}
+
+// M4 is the four-rotor naval Enigma: the thin reflectors left room for
+// a fourth rotor on the left, which could be set but never stepped.
+var M4 = Model{
+	Name:         "M4",
+	Rotors:       historicRotors,
+	Reflectors:   historicReflectors[3:5],
+	Slots:        4,
+	FixedSlots:   []int{0},
+	Plugboard:    true,
+	MaxPlugPairs: 10,
+}
+
+// EnigmaT is the Tirpitz machine: any three of its eight rotors, the
+// Tirpitz entry wheel and settable reflector, and no plugboard.
+var EnigmaT = Model{
+	Name:              "T",
+	Rotors:            tirpitzRotors,
+	Reflectors:        tirpitzReflectors,
+	EntryWheel:        "T",
+	Slots:             3,
+	SettableReflector: true,
+}
+
+// EnigmaD is the commercial Enigma D: three rotors, the QWERTZ entry
+// wheel, a reflector that can be set to any position, and no plugboard.
+var EnigmaD = Model{
+	Name:              "D",
+	Rotors:            enigmaDRotors,
+	Reflectors:        enigmaDReflectors,
+	EntryWheel:        "QWERTZ",
+	Slots:             3,
+	SettableReflector: true,
+}
+
+// EnigmaK is the commercial Enigma K, the Swiss one: the same machine as
+// the Enigma D, with the QWERTZ entry wheel and no plugboard, but other
+// wirings.
+var EnigmaK = Model{
+	Name:              "K",
+	Rotors:            enigmaKRotors,
+	Reflectors:        enigmaKReflectors,
+	EntryWheel:        "QWERTZ",
+	Slots:             3,
+	SettableReflector: true,
+}
+
+// EnigmaKD is the commercial K chassis with nine-notch rotors and the
+// QWERTZ entry wheel, combined with the UKW-D, whose wiring is part of
+// the key. It has no plugboard.
+var EnigmaKD = Model{
+	Name:       "KD",
+	Rotors:     kdRotors,
+	EntryWheel: "QWERTZ",
+	Slots:      3,
+	UKWD:       true,
+}
+
+// EnigmaG312 is the Abwehr Enigma G-312: three gear-driven rotors with
+// many notches, the QWERTZ entry wheel, a reflector that is set like a
+// fourth rotor and steps along with the others, and no plugboard.
+var EnigmaG312 = Model{
+	Name:              "G312",
+	Rotors:            enigmaG312Rotors,
+	Reflectors:        enigmaG312Reflectors,
+	EntryWheel:        "QWERTZ",
+	Slots:             3,
+	SettableReflector: true,
+	Stepping:          "gear",
+	RotatingReflector: true,
+}
+
+// EnigmaG260 is the Enigma G-260, the same machine as the G-312 with
+// other wirings.
+var EnigmaG260 = Model{
+	Name:              "G260",
+	Rotors:            enigmaG260Rotors,
+	Reflectors:        enigmaG260Reflectors,
+	EntryWheel:        "QWERTZ",
+	Slots:             3,
+	SettableReflector: true,
+	Stepping:          "gear",
+	RotatingReflector: true,
+}
+
+// Models is a simple list of model pointers.
+type Models []*Model
+
+// GetByName takes a name of the model (e.g. "M4") and returns the
+// Model pointer.
+func (ms *Models) GetByName(name string) *Model {
+	for _, model := range *ms {
+		if model.Name == name {
+			return model
+		}
+	}
+	return nil
+}
+
+// KnownModels lists all the models with a preset, and the generic one.
+var KnownModels = Models{&Generic, &EnigmaI, &EnigmaI1938, &M3, &M3Navy, &M4, &EnigmaT, &EnigmaD, &EnigmaK, &EnigmaKD, &EnigmaG312, &EnigmaG260}
+
+// New builds a machine of the model, checking the configuration
+// against what the model actually supported.
+func (m *Model) New(config Config) (*Enigma, error) {
+	config = m.complete(config)
+	if err := m.Validate(config); err != nil {
+		return nil, err
+	}
+	reflector, _ := m.reflector(config.Reflector)
+	e := newEnigma(m.Rotors, config.Rotors, reflector,
+		historicEntryWheels.GetByID(m.entryWheel(config)), config.Plugboard)
+	if config.Reflector.Start != 0 {
+		e.Reflector.Position = CharToIndex(config.Reflector.Start)
+	}
+	e.reflectorStart = e.Reflector.Position
+	e.RotatingReflector = m.RotatingReflector
+	e.Display = config.Display
+	e.Groups = config.Groups
+	e.Procedure = config.Procedure
+	e.NoDoubleStep = config.NoDoubleStep
+	if config.Stepping != "" || m.Stepping != "" {
+		e.Stepping = SteppingMechanisms[m.steppingOf(config)]
+	}
+	e.model = m
+	m.logConfig(e, config)
+	if config.Keyboard != "" {
+		e.Keyboard, _ = NewKeyMap(config.Keyboard)
+	}
+	if config.Uhr != nil {
+		e.Uhr, _ = NewUhr(config.Plugboard, config.Uhr.Position)
+	}
+	e.makeStrict(config.Strict || strictDefault())
+	return e, nil
+}
+
+// NewWith builds a machine of the model from the options.
+func (m *Model) NewWith(options ...Option) (*Enigma, error) {
+	config, err := configure(options)
+	if err != nil {
+		return nil, err
+	}
+	return m.New(config)
+}
+
+// Validate checks that the rotors, the reflector, and the plugboard are
+// available on the model, and that the rotor settings are in range.
+// Every problem found is reported, not just the first one.
+func (m *Model) Validate(config Config) error {
+	config = m.complete(config)
+	var errs []error
+	if m.Slots != 0 && len(config.Rotors) != m.Slots {
+		errs = append(errs, fmt.Errorf("wrong number of rotors: Enigma %s takes %d, got %d",
+			m.Name, m.Slots, len(config.Rotors)))
+	}
+	if len(config.Rotors) == 0 {
+		errs = append(errs, fmt.Errorf("at least one rotor is required"))
+	}
+	used := make(map[string]bool)
+	for i, configuration := range config.Rotors {
+		if m.Slots != 0 && configuration.Fixed != m.isFixed(i) {
+			errs = append(errs, fmt.Errorf("slot %d of Enigma %s cannot be fixed", i+1, m.Name))
+		}
+		switch drivenBy := configuration.DrivenBy; {
+		case drivenBy == 0:
+		case m.Slots != 0 && !config.AllowNonHistorical:
+			errs = append(errs, fmt.Errorf("the stepping of Enigma %s cannot be changed", m.Name))
+		case drivenBy < 0 || drivenBy > len(config.Rotors) || drivenBy == i+1:
+			errs = append(errs, fmt.Errorf("slot %d cannot be driven by slot %d", i+1, drivenBy))
+		case config.Rotors[drivenBy-1].Fixed:
+			errs = append(errs, fmt.Errorf("slot %d cannot be driven by the fixed slot %d", i+1, drivenBy))
+		}
+		if configuration.Wiring != "" {
+			if err := validateRotorWiring(configuration); err != nil {
+				errs = append(errs, err)
+			} else if m.Slots != 0 {
+				errs = append(errs, fmt.Errorf(`rotor "%s" with a wiring of its own cannot go on Enigma %s`, configuration.ID, m.Name))
+			}
+		} else if rotor := m.Rotors.GetByID(configuration.ID); rotor == nil {
+			err := settingError(ErrUnknownRotor, `unknown rotor "%s" for Enigma %s`, configuration.ID, m.Name)
+			err.ID, err.Slot = configuration.ID, i+1
+			errs = append(errs, err)
+		} else if config.Era != 0 && rotor.Introduced > config.Era {
+			err := settingError(ErrNotInEra, `rotor "%s" was only issued in %d, not by %d`, configuration.ID, rotor.Introduced, config.Era)
+			err.ID, err.Slot, err.Value = configuration.ID, i+1, rotor.Introduced
+			errs = append(errs, err)
+		} else if m.Slots != 0 && i < m.Slots && !containsString(m.slotRotors(i), configuration.ID) {
+			errs = append(errs, fmt.Errorf(`rotor "%s" cannot go in slot %d of Enigma %s`, configuration.ID, i+1, m.Name))
+		} else if used[configuration.ID] {
+			errs = append(errs, fmt.Errorf(`rotor "%s" can only be used once`, configuration.ID))
+		}
+		used[configuration.ID] = true
+		if configuration.Start < 'A' || configuration.Start > 'Z' {
+			err := settingError(ErrPositionOutOfRange, `rotor position must be in the A-Z range, got "%c"`, configuration.Start)
+			err.ID, err.Slot, err.Letter = configuration.ID, i+1, configuration.Start
+			errs = append(errs, err)
+		}
+		if configuration.Ring < 1 || configuration.Ring > 26 {
+			err := settingError(ErrRingOutOfRange, "ring out of range: must be 1-26, got %d", configuration.Ring)
+			err.ID, err.Slot, err.Value = configuration.ID, i+1, configuration.Ring
+			errs = append(errs, err)
+		}
+	}
+	if rule, ok := m.brokenRule(config.Rotors); !ok {
+		errs = append(errs, fmt.Errorf(`rotors %s break the rule of Enigma %s: %s`,
+			strings.Join(configIDs(config.Rotors), " "), m.Name, rule.Description))
+	}
+	if _, err := m.reflector(config.Reflector); err != nil {
+		errs = append(errs, err)
+	}
+	if config.Reflector.Start != 0 {
+		if !m.SettableReflector {
+			errs = append(errs, fmt.Errorf("reflector position cannot be set on Enigma %s", m.Name))
+		} else if config.Reflector.Start < 'A' || config.Reflector.Start > 'Z' {
+			err := settingError(ErrPositionOutOfRange, `reflector position must be in the A-Z range, got "%c"`, config.Reflector.Start)
+			err.ID, err.Letter = config.Reflector.ID, config.Reflector.Start
+			errs = append(errs, err)
+		}
+	}
+	if config.EntryWheel != "" && config.EntryWheel != m.entryWheel(config) {
+		errs = append(errs, fmt.Errorf(`entry wheel "%s" cannot be used on Enigma %s`, config.EntryWheel, m.Name))
+	} else if historicEntryWheels.GetByID(m.entryWheel(config)) == nil {
+		errs = append(errs, fmt.Errorf(`unknown entry wheel "%s"`, config.EntryWheel))
+	}
+	if !m.Plugboard && len(config.Plugboard) > 0 {
+		errs = append(errs, fmt.Errorf("no plugboard on Enigma %s", m.Name))
+	}
+	if max := m.maxPlugPairs(config); max > 0 && len(config.Plugboard) > max {
+		errs = append(errs, fmt.Errorf("too many plugboard pairs: at most %d, got %d", max, len(config.Plugboard)))
+	}
+	if err := validatePlugs(config.Plugboard); err != nil {
+		errs = append(errs, err)
+	}
+	if config.Uhr != nil {
+		errs = append(errs, m.validateUhr(config)...)
+	}
+	if config.Procedure != SingleIndicator && config.Procedure != DoubledIndicator {
+		errs = append(errs, fmt.Errorf("unknown indicator procedure %d", int(config.Procedure)))
+	}
+	switch stepping := m.steppingOf(config); {
+	case SteppingMechanisms[stepping] == nil:
+		errs = append(errs, fmt.Errorf(`unknown stepping mechanism "%s"`, stepping))
+	case stepping != m.stepping() && m.Slots != 0 && !config.AllowNonHistorical:
+		errs = append(errs, fmt.Errorf("the %s stepping mechanism cannot be used on Enigma %s", stepping, m.Name))
+	}
+	if config.NoDoubleStep && m.Slots != 0 && !config.AllowNonHistorical {
+		errs = append(errs, fmt.Errorf("the double step of Enigma %s cannot be turned off", m.Name))
+	}
+	if config.Keyboard != "" {
+		if _, err := NewKeyMap(config.Keyboard); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// brokenRule returns the first of the rules of the model the rotors
+// don't keep to, if any.
+func (m *Model) brokenRule(rotors []RotorConfig) (SelectionRule, bool) {
+	ids := configIDs(rotors)
+	for _, rule := range m.Rules {
+		if !rule.Allows(ids) {
+			return rule, false
+		}
+	}
+	return SelectionRule{}, true
+}
+
+// configIDs returns the IDs of the configured rotors.
+func configIDs(rotors []RotorConfig) []string {
+	ids := make([]string, len(rotors))
+	for i, rotor := range rotors {
+		ids[i] = rotor.ID
+	}
+	return ids
+}
+
+// complete fills in the parts of the configuration that the model
+// doesn't leave a choice for, e.g. the reflector of a machine that
+// only ever had one.
+func (m *Model) complete(config Config) Config {
+	if config.Reflector.ID == "" {
+		switch {
+		case len(m.Reflectors) == 0 && m.UKWD:
+			config.Reflector.ID = UKWD
+		case len(m.Reflectors) == 1 && !m.UKWD:
+			config.Reflector.ID = m.Reflectors[0].ID
+		}
+	}
+	if len(m.FixedSlots) > 0 {
+		config.Rotors = append([]RotorConfig(nil), config.Rotors...)
+		for _, slot := range m.FixedSlots {
+			if slot < len(config.Rotors) {
+				config.Rotors[slot].Fixed = true
+			}
+		}
+	}
+	return config
+}
+
+// reflector returns the configured reflector: either one from the list,
+// a UKW-D wired with the configured pairs, or one wired as configured.
+func (m *Model) reflector(config ReflectorConfig) (*Reflector, error) {
+	if config.Wiring != "" {
+		switch err := validateReflectorWiring(config); {
+		case err != nil:
+			return nil, err
+		case m.Slots != 0:
+			return nil, fmt.Errorf(`reflector "%s" with a wiring of its own cannot go on Enigma %s`, config.ID, m.Name)
+		case len(config.Pairs) > 0:
+			return nil, fmt.Errorf(`reflector "%s" cannot be rewired`, config.ID)
+		}
+		return NewReflector(strings.ToUpper(config.Wiring), config.ID), nil
+	}
+	if config.ID == UKWD && m.UKWD {
+		return NewUKWD(config.Pairs)
+	}
+	if len(config.Pairs) > 0 {
+		return nil, fmt.Errorf(`reflector "%s" cannot be rewired`, config.ID)
+	}
+	if r := m.Reflectors.GetByID(config.ID); r != nil {
+		return r, nil
+	}
+	if knownReflector(config.ID) {
+		err := settingError(ErrRotorReflectorMismatch,
+			`reflector "%s" doesn't go with the rotors of Enigma %s`, config.ID, m.Name)
+		err.ID = config.ID
+		return nil, err
+	}
+	err := settingError(ErrUnknownReflector, `unknown reflector "%s" for Enigma %s`, config.ID, m.Name)
+	err.ID = config.ID
+	return nil, err
+}
+
+// knownReflector tells if the reflector exists on any of the models.
+func knownReflector(id string) bool {
+	for _, model := range KnownModels {
+		if (id == UKWD && model.UKWD) || model.Reflectors.GetByID(id) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isFixed tells if the rotor in the slot never steps on this model.
+func (m *Model) isFixed(slot int) bool {
+	for _, fixed := range m.FixedSlots {
+		if fixed == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// validateUhr checks that the Uhr can be plugged into the model, with
+// all of its ten plugs, and that the dial is in range.
+func (m *Model) validateUhr(config Config) []error {
+	var errs []error
+	if !m.Uhr {
+		errs = append(errs, fmt.Errorf("the Uhr cannot be used on Enigma %s", m.Name))
+	}
+	if config.Era != 0 && config.Era < UhrIntroduced {
+		errs = append(errs, settingError(ErrNotInEra, "the Uhr was only issued in %d, not by %d", UhrIntroduced, config.Era))
+	}
+	if len(config.Plugboard) != UhrPairs {
+		errs = append(errs, fmt.Errorf("the Uhr takes %d plugboard pairs, got %d", UhrPairs, len(config.Plugboard)))
+	}
+	if position := config.Uhr.Position; position < 0 || position >= len(UhrWiring) {
+		errs = append(errs, settingError(ErrPositionOutOfRange, "the Uhr position must be in the 0-39 range, got %d", position))
+	}
+	return errs
+}
+
+// stepping returns the name of the stepping mechanism of the model.
+func (m *Model) stepping() string {
+	if m.Stepping == "" {
+		return LeverStepping.Name()
+	}
+	return m.Stepping
+}
+
+// steppingOf returns the name of the stepping mechanism of the
+// configuration, the one of the model if not set.
+func (m *Model) steppingOf(config Config) string {
+	if config.Stepping == "" {
+		return m.stepping()
+	}
+	return config.Stepping
+}
+
+// maxPlugPairs returns the strictest of the plugboard limits set by the
+// model, by the configuration, and by the era, or zero if there is none.
+func (m *Model) maxPlugPairs(config Config) int {
+	max := m.MaxPlugPairs
+	if config.MaxPlugPairs > 0 && (max == 0 || config.MaxPlugPairs < max) {
+		max = config.MaxPlugPairs
+	}
+	if config.Era != 0 && config.Era < 1939 && m.Plugboard && (max == 0 || max > 6) {
+		max = 6
+	}
+	return max
+}
+
+// entryWheel returns the ID of the entry wheel for the configuration:
+// either the one the model was built with, or the configured one,
+// falling back to the alphabetical military wheel.
+func (m *Model) entryWheel(config Config) string {
+	switch {
+	case m.EntryWheel != "":
+		return m.EntryWheel
+	case config.EntryWheel != "":
+		return config.EntryWheel
+	}
+	return "ABC"
+}
+
+// NewEnigmaI is the Enigma I constructor. The options are checked
+// against the model: three of the rotors I to V, one of the reflectors
+// A, B, or C, and no more than ten plugboard pairs.
+func NewEnigmaI(options ...Option) (*Enigma, error) {
+	return EnigmaI.NewWith(options...)
+}
+
+// NewEnigmaM3 is the M3 constructor: three of the rotors I to VIII,
+// reflector B or C, and no more than ten plugboard pairs.
+func NewEnigmaM3(options ...Option) (*Enigma, error) {
+	return M3.NewWith(options...)
+}
+
+// NewEnigmaM3Navy is the constructor of the M3 of the naval nets: the
+// same as NewEnigmaM3, but one of the rotors VI to VIII has to be in.
+func NewEnigmaM3Navy(options ...Option) (*Enigma, error) {
+	return M3Navy.NewWith(options...)
+}
+
+// NewEnigmaM4 is the M4 constructor: four rotors, the leftmost of which
+// never steps, a thin reflector, and no more than ten plugboard pairs.
+func NewEnigmaM4(options ...Option) (*Enigma, error) {
+	return M4.NewWith(options...)
+}
+
+// NewEnigmaT is the Enigma T (Tirpitz) constructor, accepting three of
+// the eight Tirpitz rotors and, optionally, the reflector position.
+func NewEnigmaT(options ...Option) (*Enigma, error) {
+	return EnigmaT.NewWith(options...)
+}
+
+// NewEnigmaD is the Enigma D constructor, accepting its three rotors
+// and, optionally, the reflector position.
+func NewEnigmaD(options ...Option) (*Enigma, error) {
+	return EnigmaD.NewWith(options...)
+}
+
+// NewEnigmaK is the Enigma K constructor, accepting its three rotors
+// and, optionally, the reflector position.
+func NewEnigmaK(options ...Option) (*Enigma, error) {
+	return EnigmaK.NewWith(options...)
+}
+
+// NewEnigmaKD is the Enigma KD constructor, taking the UKW-D pairs
+// (see NewUKWD) along with the options for its three rotors.
+func NewEnigmaKD(ukwdPairs []string, options ...Option) (*Enigma, error) {
+	options = append(options[:len(options):len(options)],
+		WithReflector(UKWD), WithReflectorWiring(ukwdPairs...))
+	return EnigmaKD.NewWith(options...)
+}
+
+// NewEnigmaG312 is the Enigma G-312 constructor, accepting its three
+// rotors and, optionally, the reflector position.
+func NewEnigmaG312(options ...Option) (*Enigma, error) {
+	return EnigmaG312.NewWith(options...)
+}
+
+// NewEnigmaG260 is the Enigma G-260 constructor, accepting its three
+// rotors and, optionally, the reflector position.
+func NewEnigmaG260(options ...Option) (*Enigma, error) {
+	return EnigmaG260.NewWith(options...)
+}
+
+// validatePlugs checks that the plugboard pairs are made of two distinct
+// letters, and that no letter is plugged twice.
+func validatePlugs(plugs []string) error {
+	_, err := Latin.plugboard(plugs)
+	return err
+}
+
+// repeated returns the letter of the pair that is already in use, or
+// that is plugged into itself.
+func repeated(pair string, used []bool) (byte, bool) {
+	switch {
+	case used[CharToIndex(pair[0])]:
+		return pair[0], true
+	case used[CharToIndex(pair[1])], pair[0] == pair[1]:
+		return pair[1], true
+	}
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package enigma
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// elevenPairs is one cable more than the key sheets used.
+var elevenPairs = []string{"AB", "CD", "EF", "GH", "IJ", "KL", "MN", "OP", "QR", "ST", "UV"}
+
+func TestMaxPlugPairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   *Model
+		options []Option
+		pairs   int
+		ok      bool
+	}{
+		{"M3 with ten", &M3, nil, 10, true},
+		{"M3 with eleven", &M3, nil, 11, false},
+		{"generic with eleven", &Generic, nil, 11, true},
+		{"Enigma I of 1938 with six", &EnigmaI1938, nil, 6, true},
+		{"Enigma I of 1938 with seven", &EnigmaI1938, nil, 7, false},
+		{"generic limited to four", &Generic, []Option{WithMaxPlugPairs(4)}, 5, false},
+	}
+	for _, tt := range tests {
+		options := append([]Option{WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithPlugboard(elevenPairs[:tt.pairs]...)}, tt.options...)
+		_, err := tt.model.NewWith(options...)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: built %t (%v), expected %t", tt.name, ok, err, tt.ok)
+		}
+	}
+}
+
+// The settings generator never plugs in more cables than the model
+// had.
+func TestGenerateMaxPlugPairs(t *testing.T) {
+	for _, model := range KnownModels {
+		for seed := int64(0); seed < 20; seed++ {
+			settings, err := GenerateRandomConfig(model, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plugs := len(settings.Config.Plugboard)
+			if max := model.maxPlugPairs(settings.Config); max > 0 && plugs > max {
+				t.Errorf("%s: %d pairs generated, the limit is %d", model.Name, plugs, max)
+			}
+			if !model.Plugboard && plugs > 0 {
+				t.Errorf("%s: %d pairs generated for a machine without a plugboard", model.Name, plugs)
+			}
+		}
+	}
+}
+
+// A machine of 1937 has rotors I to III and six cables only, and one of
+// 1939 rotors IV and V and the ten cables too. Rotors of a model of
+// one's own can tell the year they were issued as well.
+func TestEra(t *testing.T) {
+	tests := []struct {
+		name   string
+		era    int
+		rotors string
+		pairs  int
+		ok     bool
+	}{
+		{"1937", 1937, "I II III", 6, true},
+		{"1937 with rotor IV", 1937, "I II IV", 6, false},
+		{"1937 with seven cables", 1937, "I II III", 7, false},
+		{"1939 with rotor IV", 1939, "I II IV", 10, true},
+		{"1939 with rotor V", 1939, "V II III", 10, true},
+		{"no era", 0, "I II IV", 10, true},
+	}
+	for _, tt := range tests {
+		_, err := NewEnigmaI(WithRotors(rotorsAt(tt.rotors, "AAA")...), WithReflector("B"), WithPlugboard(elevenPairs[:tt.pairs]...), WithEra(tt.era))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: built %t (%v), expected %t", tt.name, ok, err, tt.ok)
+		}
+		if tt.rotors == "I II IV" && !tt.ok && !errors.Is(err, ErrNotInEra) {
+			t.Errorf("%s: rotor IV is turned down with %v, expected ErrNotInEra", tt.name, err)
+		}
+	}
+	if _, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithUhr(4), WithPlugboard(elevenPairs[:10]...), WithEra(1943)); !errors.Is(err, ErrNotInEra) {
+		t.Errorf("the Uhr is taken in 1943 with %v", err)
+	}
+
+	rotors := Rotors{*NewRotor("EKMFLGDQVZNTOWYHXUSPAIBRCJ", "X1", "Q"), *NewRotor("AJDKSIRUXBLHWTMCQGZNPYFVOE", "X2", "E")}
+	rotors[1].Introduced = 1941
+	model := Model{Name: "X", Rotors: rotors, Reflectors: historicReflectors}
+	for _, tt := range []struct {
+		era int
+		ok  bool
+	}{{1940, false}, {1941, true}} {
+		_, err := model.NewWith(WithRotors(rotorsAt("X1 X2", "AA")...), WithReflector("B"), WithEra(tt.era))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("the rotor of 1941 is taken in %d: %t (%v), expected %t", tt.era, ok, err, tt.ok)
+		}
+	}
+}
+
+// The naval nets had one of VI to VIII in: I II III is turned down on the
+// M3 of the Kriegsmarine, naming the rule, and taken on the Enigma I and
+// the plain M3.
+func TestNavalRotorRule(t *testing.T) {
+	rotors := rotorsAt("I II III", "AAA")
+	_, err := NewEnigmaM3Navy(WithRotors(rotors...), WithReflector("B"))
+	if err == nil || !strings.Contains(err.Error(), NavalRotorRule.Description) {
+		t.Errorf("I II III is taken on the M3-Navy with %v", err)
+	}
+	for _, build := range []func(...Option) (*Enigma, error){NewEnigmaI, NewEnigmaM3} {
+		if _, err := build(WithRotors(rotors...), WithReflector("B")); err != nil {
+			t.Errorf("I II III is turned down: %v", err)
+		}
+	}
+	for _, ids := range []string{"I VI III", "VIII VII VI", "II IV VII"} {
+		if _, err := NewEnigmaM3Navy(WithRotors(rotorsAt(ids, "AAA")...), WithReflector("B")); err != nil {
+			t.Errorf("%s is turned down on the M3-Navy: %v", ids, err)
+		}
+	}
+	c, err := ModelCapabilities("M3-Navy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Rules, []string{NavalRotorRule.Description}) {
+		t.Errorf("the M3-Navy has the rules %v", c.Rules)
+	}
+	if c, _ := ModelCapabilities("M3"); len(c.Rules) != 0 {
+		t.Errorf("the M3 has the rules %v", c.Rules)
+	}
+}
+
+// Thousands of days of naval keys all have one of the naval rotors in,
+// from the key sheets and from the random configurations alike.
+func TestGenerateNavalRotors(t *testing.T) {
+	naval := func(rotors []RotorConfig) bool {
+		return NavalRotorRule.Allows(configIDs(rotors))
+	}
+	sheet, err := GenerateKeySheet(KeySheetOptions{Days: 3000, Model: &M3Navy, Rules: &KeySheetRules{}, Rand: rand.New(rand.NewSource(158))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, day := range sheet {
+		if !naval(day.Config.Rotors) {
+			t.Fatalf("the key of day %d has %v", i+1, configIDs(day.Config.Rotors))
+		}
+	}
+	rng := rand.New(rand.NewSource(158))
+	withoutNaval := 0
+	for i := 0; i < 3000; i++ {
+		settings, err := GenerateRandomConfig(&M3Navy, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !naval(settings.Config.Rotors) {
+			t.Fatalf("the random key %s has no naval rotor", settings)
+		}
+		army, _ := GenerateRandomConfig(&M3, rng)
+		if !naval(army.Config.Rotors) {
+			withoutNaval++
+		}
+	}
+	if withoutNaval == 0 {
+		t.Error("the plain M3 never goes without a naval rotor")
+	}
+}
@@ -0,0 +1,108 @@
+package enigma
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// NextStep tells what the next keypress does, all along 10,000 of them
+// on the two-notched rotors VI-VII-VIII, from a few random settings.
+func TestNextStepAgrees(t *testing.T) {
+	rng := rand.New(rand.NewSource(120))
+	for run := 0; run < 5; run++ {
+		config := Config{Reflector: ReflectorConfig{ID: "C"}}
+		for _, id := range []string{"VI", "VII", "VIII"} {
+			config.Rotors = append(config.Rotors, RotorConfig{ID: id, Ring: 1 + rng.Intn(26), Start: byte('A' + rng.Intn(26))})
+		}
+		e, err := M3Navy.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doubles := 0
+		for press := 1; press <= 10000; press++ {
+			before, stats := e.offsets(), e.Stats()
+			prediction := e.NextStep()
+			if e.Positions() != FormatPositions(before, e.Display) {
+				t.Fatalf("%s: NextStep moved the rotors", config)
+			}
+			e.EncodeChar(byte('A' + rng.Intn(26)))
+			after := e.offsets()
+			for i := range after {
+				if moved := after[i] != before[i]; moved != prediction.Moves[i] {
+					t.Fatalf("%s, keypress %d: rotor %d moved is %t, predicted %t", config, press, i+1, moved, prediction.Moves[i])
+				}
+			}
+			if double := e.Stats().DoubleSteps > stats.DoubleSteps; double != prediction.DoubleStep {
+				t.Fatalf("%s, keypress %d: double step is %t, predicted %t", config, press, double, prediction.DoubleStep)
+			}
+			if prediction.DoubleStep {
+				doubles++
+			}
+		}
+		// The middle rotor double steps twice on every turn of its own.
+		if doubles < 20 {
+			t.Errorf("%s: only %d double steps in 10,000 keypresses", config, doubles)
+		}
+	}
+}
+
+// StepSchedule is what NextStep tells keypress by keypress, and what
+// the rotors then do, on random long messages: on the two-notched
+// rotors, and on the M4, whose Greek wheel never moves.
+func TestStepSchedule(t *testing.T) {
+	rng := rand.New(rand.NewSource(149))
+	random := func(ids ...string) Config {
+		config := Config{Reflector: ReflectorConfig{ID: "C"}}
+		for _, id := range ids {
+			config.Rotors = append(config.Rotors, RotorConfig{ID: id, Ring: 1 + rng.Intn(26), Start: byte('A' + rng.Intn(26))})
+		}
+		return config
+	}
+	var configs []Config
+	for run := 0; run < 3; run++ {
+		configs = append(configs, random("VI", "VII", "VIII"), random("VIII", "II", "VI"))
+		m4 := random("Gamma", "VI", "I", "VII")
+		m4.Rotors[0].Fixed = true
+		m4.Reflector.ID = "C-thin"
+		configs = append(configs, m4)
+	}
+	for _, config := range configs {
+		n := 5000 + rng.Intn(5000)
+		schedule, err := StepSchedule(config, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(schedule) != n {
+			t.Fatalf("%s: the schedule has %d keypresses, expected %d", config, len(schedule), n)
+		}
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for press, step := range schedule {
+			if prediction := e.NextStep(); !reflect.DeepEqual(prediction, step) {
+				t.Fatalf("%s, keypress %d: the schedule has %+v, NextStep %+v", config, press+1, step, prediction)
+			}
+			before := e.offsets()
+			e.EncodeChar(byte('A' + rng.Intn(26)))
+			for i, offset := range e.offsets() {
+				if moved := offset != before[i]; moved != step.Moves[i] {
+					t.Fatalf("%s, keypress %d: rotor %d moved is %t, scheduled %t", config, press+1, i+1, moved, step.Moves[i])
+				}
+			}
+			if config.Rotors[0].Fixed && step.Moves[0] {
+				t.Fatalf("%s, keypress %d: the Greek wheel is scheduled to move", config, press+1)
+			}
+		}
+	}
+	if schedule, err := StepSchedule(classicConfig(), 0); err != nil || len(schedule) != 0 {
+		t.Errorf("no keypresses make the schedule %v (%v)", schedule, err)
+	}
+	if _, err := StepSchedule(Config{}, 1); err == nil {
+		t.Error("a machine of nothing has a schedule")
+	}
+	if _, err := StepSchedule(classicConfig(), -1); err == nil {
+		t.Error("-1 keypresses have a schedule")
+	}
+}
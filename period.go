@@ -0,0 +1,172 @@
+package enigma
+
+import "fmt"
+
+// Period returns the number of keypresses after which the rotors come
+// back to where they are. It's not simply 26 to the power of the number
+// of rotors: the double step skips some positions, and rotors with more
+// than one notch make the cycles shorter (and more than one of them).
+// Positions the rotors never come back to, like the middle rotor at its
+// notch right after being set there, get the period of the cycle they
+// lead into. A rotating reflector is left out: it's the rotors that
+// come back.
+//
+// The period is found by walking the rotors through their cycle a
+// keypress at a time, which is instant for three rotors but takes
+// seconds for five, with over ten million keypresses to the cycle. The
+// cycles of more stepping rotors are too long to walk, so for those the
+// period is 0, and the position index an error.
+func (e *Enigma) Period() int {
+	if e.tooLongToWalk() != nil {
+		return 0
+	}
+	period, _ := e.cycle()
+	return period
+}
+
+// maxWalkedRotors is the most stepping rotors whose cycle is walked
+// through (see Period).
+const maxWalkedRotors = 5
+
+// tooLongToWalk returns an error if the machine has more stepping rotors
+// than can be walked through.
+func (e *Enigma) tooLongToWalk() error {
+	stepping := 0
+	for _, rotor := range e.Rotors {
+		if !rotor.Fixed {
+			stepping++
+		}
+	}
+	if stepping > maxWalkedRotors {
+		return fmt.Errorf("the cycle of %d stepping rotors is too long to walk, at most %d can be", stepping, maxWalkedRotors)
+	}
+	return nil
+}
+
+// PositionIndex returns the number of keypresses from the first
+// position of the cycle the rotors are in (the one closest to all A's)
+// to the current one, from 0 to Period()-1. It's an error if the
+// rotors are at a position they never come back to.
+func (e *Enigma) PositionIndex() (int, error) {
+	if err := e.tooLongToWalk(); err != nil {
+		return 0, err
+	}
+	period, tail := e.cycle()
+	if tail > 0 {
+		return 0, fmt.Errorf("rotor positions %s are not on a cycle", e.Positions())
+	}
+	_, distance := e.cycleOrigin(period)
+	return (period - distance) % period, nil
+}
+
+// SetPositionIndex sets the rotors to the position index keypresses
+// from the first position of their cycle (see PositionIndex).
+func (e *Enigma) SetPositionIndex(index int) error {
+	if err := e.tooLongToWalk(); err != nil {
+		return err
+	}
+	period, tail := e.cycle()
+	if index < 0 || index >= period {
+		return fmt.Errorf("position index out of range: must be 0-%d, got %d", period-1, index)
+	}
+	c := e.Clone()
+	for i := 0; i < tail; i++ {
+		c.turn(nil)
+	}
+	origin, _ := c.cycleOrigin(period)
+	c.restore(origin)
+	for i := 0; i < index; i++ {
+		c.turn(nil)
+	}
+	e.restore(c.offsets())
+	e.moves, e.doubles = 0, nil
+	return nil
+}
+
+// cycle finds the period of the cycle the rotors end up in, and how
+// many keypresses it takes to get there (Brent's algorithm), leaving
+// the rotors where they are. The positions are compared in place, so
+// that a keypress doesn't allocate.
+func (e *Enigma) cycle() (period int, tail int) {
+	c := e.Clone()
+	power, period := 1, 1
+	slow := make([]int, len(e.Rotors))
+	c.saveOffsets(slow)
+	c.turn(nil)
+	for !c.atOffsets(slow) {
+		if power == period {
+			c.saveOffsets(slow)
+			power *= 2
+			period = 0
+		}
+		c.turn(nil)
+		period++
+	}
+	ahead := e.Clone()
+	for i := 0; i < period; i++ {
+		ahead.turn(nil)
+	}
+	behind := e.Clone()
+	for !behind.atRotorsOf(ahead) {
+		behind.turn(nil)
+		ahead.turn(nil)
+		tail++
+	}
+	return period, tail
+}
+
+// cycleOrigin returns the first position of the cycle the rotors are
+// on, i.e. the smallest one letter by letter, and how many keypresses
+// away from the current position it is.
+func (e *Enigma) cycleOrigin(period int) ([]int, int) {
+	c := e.Clone()
+	origin, distance := c.offsets(), 0
+	for i := 1; i < period; i++ {
+		c.turn(nil)
+		if c.beforeOffsets(origin) {
+			c.saveOffsets(origin)
+			distance = i
+		}
+	}
+	return origin, distance
+}
+
+// saveOffsets copies the offsets of the rotors into the slice, taken
+// around the alphabet like those of offsets.
+func (e *Enigma) saveOffsets(offsets []int) {
+	for i, rotor := range e.Rotors {
+		offsets[i] = mod26(rotor.Offset)
+	}
+}
+
+// atOffsets tells if the rotors are at the offsets.
+func (e *Enigma) atOffsets(offsets []int) bool {
+	for i, rotor := range e.Rotors {
+		if mod26(rotor.Offset) != offsets[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// atRotorsOf tells if the rotors are where those of the other machine
+// are.
+func (e *Enigma) atRotorsOf(other *Enigma) bool {
+	for i, rotor := range e.Rotors {
+		if mod26(rotor.Offset) != mod26(other.Rotors[i].Offset) {
+			return false
+		}
+	}
+	return true
+}
+
+// beforeOffsets tells if the rotors come before the offsets, letter by
+// letter from the left.
+func (e *Enigma) beforeOffsets(offsets []int) bool {
+	for i, rotor := range e.Rotors {
+		if offset := mod26(rotor.Offset); offset != offsets[i] {
+			return offset < offsets[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package enigma
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Walked through a whole period, rotors I-II-III come back to where
+// they started after 16900 keypresses, not 26³: the double step skips
+// a position of the middle rotor on every turn of it, and of the left
+// one. On the way, the position index counts the keypresses.
+func TestPeriodWalk(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := e.Period()
+	if period != 16900 {
+		t.Fatalf("the period is %d, expected 16900", period)
+	}
+	start, err := e.PositionIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for press := 1; ; press++ {
+		seen[e.Positions()] = true
+		e.EncodeChar('A')
+		if press%997 == 0 {
+			index, err := e.PositionIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if index != (start+press)%period {
+				t.Fatalf("after %d keypresses the position index is %d, expected %d", press, index, (start+press)%period)
+			}
+		}
+		if e.Positions() == "AAA" {
+			if press != period {
+				t.Errorf("the rotors came back after %d keypresses, expected %d", press, period)
+			}
+			break
+		}
+	}
+	if len(seen) != period {
+		t.Errorf("the rotors went through %d positions, expected %d", len(seen), period)
+	}
+
+	// The middle rotor at its notch with the fast one elsewhere can only
+	// be set by hand.
+	if err := e.ResetTo("AEA"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.PositionIndex(); err == nil {
+		t.Error("the rotors at AEA are taken to be on the cycle")
+	}
+	if err := e.SetPositionIndex(period); err == nil {
+		t.Error("the rotors are set past the end of the cycle")
+	}
+}
+
+// SetPositionIndex undoes PositionIndex, on rotors with two notches
+// too, whose cycles are shorter.
+func TestPositionIndexRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(130))
+	for _, ids := range []string{"I II III", "VI VII VIII", "VIII I VI", "Beta VI VII VIII"} {
+		config := Config{Rotors: rotorsAt(ids, "AAAA"), Reflector: ReflectorConfig{ID: "B"}}
+		if len(config.Rotors) == 4 {
+			config.Rotors[0].Fixed = true
+			config.Reflector.ID = "B-thin"
+		}
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		period := e.Period()
+		for i := 0; i < 10; i++ {
+			e.FastForward(rng.Intn(period))
+			index, err := e.PositionIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			positions := e.Positions()
+			want := e.Clone().EncodeString("WETTERBERICHT")
+			if err := e.SetPositionIndex(index); err != nil {
+				t.Fatal(err)
+			}
+			if e.Positions() != positions || e.Clone().EncodeString("WETTERBERICHT") != want {
+				t.Errorf("%s: set to index %d, the rotors are at %s, expected %s", ids, index, e.Positions(), positions)
+			}
+			other := rng.Intn(period)
+			if err := e.SetPositionIndex(other); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := e.PositionIndex(); err != nil || got != other {
+				t.Errorf("%s: set to index %d, the index is %d (%v)", ids, other, got, err)
+			}
+		}
+	}
+}
+
+// The cycle of seven stepping rotors is too long to walk: there's no
+// period, and no position index, but it's told at once.
+func TestPeriodTooLong(t *testing.T) {
+	e, err := Generic.New(Config{Rotors: rotorsAt("VIII VII VI V IV III II", "AAAAAAA"), Reflector: ReflectorConfig{ID: "B"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if period := e.Period(); period != 0 {
+		t.Errorf("the period is %d, expected 0", period)
+	}
+	if _, err := e.PositionIndex(); err == nil || err.Error() != "the cycle of 7 stepping rotors is too long to walk, at most 5 can be" {
+		t.Errorf("the position index is taken with %v", err)
+	}
+	if err := e.SetPositionIndex(0); err == nil {
+		t.Error("the position index is set")
+	}
+
+	// With four of them fixed, the other three are walked as usual.
+	e.Rotors[0].Fixed, e.Rotors[1].Fixed = true, true
+	e.Rotors[2].Fixed, e.Rotors[3].Fixed = true, true
+	if period := e.Period(); period != 16900 {
+		t.Errorf("with four rotors fixed, the period is %d, expected 16900", period)
+	}
+}
@@ -0,0 +1,240 @@
+package enigma
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Permutation is a substitution of the alphabet, kept the way the
+// wirings are: the index of the letter every letter goes to. Rotors,
+// reflectors, the plugboard and the machine as a whole are all
+// permutations at any one setting, and the algebra of them is what
+// Rejewski broke the Enigma with.
+type Permutation [26]int
+
+// IdentityPermutation leaves every letter where it is.
+func IdentityPermutation() Permutation {
+	var p Permutation
+	for i := range p {
+		p[i] = i
+	}
+	return p
+}
+
+// NewPermutation reads a permutation written as the letters A to Z go
+// to, e.g. "EKMFLGDQVZNTOWYHXUSPAIBRCJ", in either case. Every letter
+// has to be there exactly once.
+func NewPermutation(mapping string) (Permutation, error) {
+	mapping = strings.ToUpper(mapping)
+	if len(mapping) != 26 {
+		return Permutation{}, fmt.Errorf("a permutation should have 26 letters, got %d", len(mapping))
+	}
+	var p Permutation
+	var used [26]bool
+	for i := 0; i < 26; i++ {
+		letter := mapping[i]
+		if letter < 'A' || letter > 'Z' {
+			return Permutation{}, fmt.Errorf(`a permutation should be letters, got "%c"`, letter)
+		}
+		if used[CharToIndex(letter)] {
+			return Permutation{}, fmt.Errorf(`letter "%c" is in the permutation twice`, letter)
+		}
+		used[CharToIndex(letter)] = true
+		p[i] = CharToIndex(letter)
+	}
+	return p, nil
+}
+
+// String returns the letters A to Z go to.
+func (p Permutation) String() string {
+	letters := make([]byte, 26)
+	for i, j := range p {
+		letters[i] = IndexToChar(j)
+	}
+	return string(letters)
+}
+
+// Valid tells if every letter goes to a different letter, as it does
+// in a permutation from NewPermutation, but not always in one put
+// together by hand, such as a wiring changed after it was built.
+func (p Permutation) Valid() bool {
+	return permutes(p[:], len(p))
+}
+
+// Inverse returns the permutation the other way round. It has to be
+// valid.
+func (p Permutation) Inverse() Permutation {
+	var inverse Permutation
+	invert(inverse[:], p[:])
+	return inverse
+}
+
+// Then returns the permutation of p followed by q, the way the current
+// goes through one part of the machine and then the next.
+func (p Permutation) Then(q Permutation) Permutation {
+	var r Permutation
+	for i, j := range p {
+		r[i] = q[j]
+	}
+	return r
+}
+
+// IsInvolution tells if the permutation swaps letters in pairs (or
+// leaves them be), the way reflectors and the plugboard do, and so
+// undoes itself.
+func (p Permutation) IsInvolution() bool {
+	return inverts(p[:], p[:])
+}
+
+// FixedPoints returns the letters the permutation leaves where they
+// are, in order: the letters without a cable on a plugboard, the
+// self-steckered ones.
+func (p Permutation) FixedPoints() string {
+	var letters []byte
+	for _, i := range fixedContacts(p[:]) {
+		letters = append(letters, IndexToChar(i))
+	}
+	return string(letters)
+}
+
+// HasFixedPoint tells if the permutation leaves any letter where it is,
+// which no reflector does.
+func (p Permutation) HasFixedPoint() bool {
+	return len(fixedContacts(p[:])) > 0
+}
+
+// Cycles returns the cycles of the permutation, each from its first
+// letter in the alphabet, in the order of those, e.g. "AELTPHQXRU" for A
+// going to E, E to L, and so on until U goes back to A. A fixed point is
+// a cycle of one letter.
+func (p Permutation) Cycles() []string {
+	var cycles []string
+	var seen [26]bool
+	for start := range p {
+		if seen[start] {
+			continue
+		}
+		var cycle []byte
+		for i := start; !seen[i]; i = p[i] {
+			seen[i] = true
+			cycle = append(cycle, IndexToChar(i))
+		}
+		cycles = append(cycles, string(cycle))
+	}
+	return cycles
+}
+
+// CycleType returns the lengths of the cycles, longest first: what
+// Rejewski's catalogue of the characteristics was ordered by, as it
+// doesn't depend on the plugboard.
+func (p Permutation) CycleType() []int {
+	cycles := p.Cycles()
+	lengths := make([]int, len(cycles))
+	for i, cycle := range cycles {
+		lengths[i] = len(cycle)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+	return lengths
+}
+
+// permutes tells if the mapping takes each of the n contacts to a
+// different one. The checks here work on the wirings of any size, the
+// ones over an alphabet too (see Alphabet), and Permutation has them
+// for the 26 letters.
+func permutes(mapping []int, n int) bool {
+	if len(mapping) != n {
+		return false
+	}
+	seen := make([]bool, n)
+	for _, j := range mapping {
+		if j < 0 || j >= n || seen[j] {
+			return false
+		}
+		seen[j] = true
+	}
+	return true
+}
+
+// inverts tells if the inverse undoes the mapping, which has to be a
+// permutation. A mapping that inverts itself swaps contacts in pairs.
+func inverts(inverse, mapping []int) bool {
+	if len(inverse) != len(mapping) {
+		return false
+	}
+	for i, j := range mapping {
+		if inverse[j] != i {
+			return false
+		}
+	}
+	return true
+}
+
+// invert writes the inverse of the mapping, which has to be a
+// permutation, into inverse, as long as it.
+func invert(inverse, mapping []int) {
+	for i, j := range mapping {
+		inverse[j] = i
+	}
+}
+
+// unpaired returns the first contact the mapping doesn't swap with
+// another one, or -1 if it swaps them all in pairs, the way reflectors
+// are wired. The mapping has to be a permutation.
+func unpaired(mapping []int) int {
+	for i, j := range mapping {
+		if i == j || mapping[j] != i {
+			return i
+		}
+	}
+	return -1
+}
+
+// fixedContacts returns the contacts the mapping takes to themselves,
+// in order.
+func fixedContacts(mapping []int) []int {
+	var fixed []int
+	for i, j := range mapping {
+		if i == j {
+			fixed = append(fixed, i)
+		}
+	}
+	return fixed
+}
+
+// Permutation returns the wiring of the rotor, at A with the ring at 1.
+func (r *Rotor) Permutation() Permutation {
+	return r.StraightSeq
+}
+
+// PermutationAt returns what the rotor substitutes on the way in with
+// its ring, turned to the offset (0 is A).
+func (r *Rotor) PermutationAt(offset int) Permutation {
+	turned := *r
+	turned.Offset = offset
+	var p Permutation
+	for i := range p {
+		p[i] = turned.Step(i, false)
+	}
+	return p
+}
+
+// Permutation returns the wiring of the reflector, at its A position.
+func (r *Reflector) Permutation() Permutation {
+	return r.Sequence
+}
+
+// Permutation returns the letters the plugboard swaps.
+func (p *Plugboard) Permutation() Permutation {
+	return Permutation(*p)
+}
+
+// Permutation returns what the machine substitutes with the rotors where
+// they are, without stepping them first, unlike a keypress.
+func (e *Enigma) Permutation() Permutation {
+	var p Permutation
+	for i := range p {
+		p[i] = e.substitute(i)
+	}
+	return p
+}
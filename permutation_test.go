@@ -0,0 +1,126 @@
+package enigma
+
+import (
+	"reflect"
+	"testing"
+)
+
+// rotorI is the wiring of rotor I.
+const rotorI = "EKMFLGDQVZNTOWYHXUSPAIBRCJ"
+
+// The cycles of rotor I start from their first letter, a fixed point is
+// a cycle of its own, and the cycle type has the lengths longest first.
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		mapping string
+		cycles  []string
+		lengths []int
+	}{
+		{rotorI, []string{"AELTPHQXRU", "BKNW", "CMOY", "DFG", "IV", "JZ", "S"}, []int{10, 4, 4, 3, 2, 2, 1}},
+		{"BCDEFGHIJKLMNOPQRSTUVWXYZA", []string{"ABCDEFGHIJKLMNOPQRSTUVWXYZ"}, []int{26}},
+		{"YRUHQSLDPXNGOKMIEBFZCWVJAT", []string{"AY", "BR", "CU", "DH", "EQ", "FS", "GL", "IP", "JX", "KN", "MO", "TZ", "VW"},
+			[]int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}},
+	}
+	for _, tt := range tests {
+		p, err := NewPermutation(tt.mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cycles := p.Cycles(); !reflect.DeepEqual(cycles, tt.cycles) {
+			t.Errorf("%s has the cycles %v, expected %v", tt.mapping, cycles, tt.cycles)
+		}
+		if lengths := p.CycleType(); !reflect.DeepEqual(lengths, tt.lengths) {
+			t.Errorf("%s has the cycle type %v, expected %v", tt.mapping, lengths, tt.lengths)
+		}
+	}
+	cycles := IdentityPermutation().Cycles()
+	if len(cycles) != 26 || cycles[0] != "A" || cycles[25] != "Z" {
+		t.Errorf("the identity has the cycles %v", cycles)
+	}
+}
+
+// The letters a plugboard leaves without a cable are its fixed points,
+// the self-steckered letters; a reflector has none.
+func TestFixedPoints(t *testing.T) {
+	e, err := Generic.New(Config{
+		Rotors:    rotorsAt("I II III", "AAA"),
+		Reflector: ReflectorConfig{ID: "B"},
+		Plugboard: []string{"AT", "BL", "DF", "GJ", "HM", "NW", "OP", "QY", "RZ", "VX"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugboard := e.Plugboard.Permutation()
+	if got := plugboard.FixedPoints(); got != "CEIKSU" || !plugboard.HasFixedPoint() {
+		t.Errorf("the self-steckered letters are %s, expected CEIKSU", got)
+	}
+	if lengths := plugboard.CycleType(); !reflect.DeepEqual(lengths, []int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1}) {
+		t.Errorf("the plugboard has the cycle type %v", lengths)
+	}
+	if p := e.Reflector.Permutation(); p.HasFixedPoint() || p.FixedPoints() != "" || !p.IsInvolution() {
+		t.Errorf("the reflector leaves %s in place", p.FixedPoints())
+	}
+	if got := IdentityPermutation().FixedPoints(); got != "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		t.Errorf("the identity leaves %s in place", got)
+	}
+}
+
+// Rejewski's theorem: the product of two permutations swapping letters
+// in pairs has its cycles in pairs of the same length, whatever the
+// plugboard. The machine at two settings is two such permutations.
+func TestCycleTypeInPairs(t *testing.T) {
+	for _, plugs := range [][]string{nil, {"AT", "BL", "DF", "GJ", "HM", "NW"}} {
+		config := classicConfig()
+		config.Plugboard = plugs
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.FastForward(1)
+		first := e.Permutation()
+		e.FastForward(3)
+		fourth := e.Permutation()
+		lengths := first.Then(fourth).CycleType()
+		if len(lengths)%2 != 0 {
+			t.Fatalf("%v: the cycle type %v has an odd number of cycles", plugs, lengths)
+		}
+		for i := 0; i < len(lengths); i += 2 {
+			if lengths[i] != lengths[i+1] {
+				t.Errorf("%v: the cycle type %v isn't in pairs", plugs, lengths)
+				break
+			}
+		}
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	p, err := NewPermutation(rotorI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Inverse().String(); got != "UWYGADFPVZBECKMTHXSLRINQOJ" {
+		t.Errorf("rotor I is inverted to %s", got)
+	}
+	if p.Then(p.Inverse()) != IdentityPermutation() || p.Inverse().Then(p) != IdentityPermutation() {
+		t.Error("rotor I isn't undone by its inverse")
+	}
+	if !p.Valid() || p.IsInvolution() || p.FixedPoints() != "S" {
+		t.Errorf("rotor I is valid %t, an involution %t, and leaves %s in place", p.Valid(), p.IsInvolution(), p.FixedPoints())
+	}
+	if lower, err := NewPermutation("ekmflgdqvzntowyhxuspaibrcj"); err != nil || lower != p {
+		t.Errorf("rotor I in small letters is %s (%v)", lower, err)
+	}
+	broken := p
+	broken[0] = broken[1]
+	if broken.Valid() {
+		t.Error("a letter twice is valid")
+	}
+	if broken[0] = 26; broken.Valid() {
+		t.Error("a contact out of range is valid")
+	}
+	for _, mapping := range []string{"ABC", "AACDEFGHIJKLMNOPQRSTUVWXYZ", "ABCDEFGHIJKLMNOPQRSTUVWXY1"} {
+		if _, err := NewPermutation(mapping); err == nil {
+			t.Errorf("%s is a permutation", mapping)
+		}
+	}
+}
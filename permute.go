@@ -0,0 +1,121 @@
+package enigma
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PermuteAt returns the lamp the letter lights with the rotors at the
+// positions (see ParsePositions), without stepping them first, unlike a
+// keypress. The machine itself doesn't change.
+func (e *Enigma) PermuteAt(positions string, letter rune) (rune, error) {
+	key, err := keyOf(letter, -1, "encoded")
+	if err != nil {
+		return letter, err
+	}
+	table, err := e.PermutationTableAt(positions)
+	if err != nil {
+		return letter, err
+	}
+	return table[CharToIndex(key)], nil
+}
+
+// PermutationTableAt returns the lamps lit by the keys from A to Z with
+// the rotors at the positions, the same way as PermuteAt. On machines
+// with a reflector, it's always a set of swapped pairs with no letter
+// left in place.
+func (e *Enigma) PermutationTableAt(positions string) ([26]rune, error) {
+	c := e.Clone()
+	if err := c.ResetTo(positions); err != nil {
+		return [26]rune{}, err
+	}
+	return c.permutationTable(), nil
+}
+
+// permutationTable returns the lamps lit by the keys from A to Z with
+// the rotors where they are.
+func (e *Enigma) permutationTable() [26]rune {
+	var table [26]rune
+	for i := range table {
+		table[i] = rune(IndexToChar(e.substitute(i)))
+	}
+	return table
+}
+
+// SubstitutionSeries returns the alphabets a machine of the
+// configuration (see NewMachine) substitutes with for each of the first
+// n keypresses: row i has the lamps the keys from A to Z light once the
+// rotors moved for keypress i, so that the i-th letter of any text
+// encodes to its letter in row i.
+func SubstitutionSeries(cfg Config, n int) ([][26]rune, error) {
+	if err := checkKeypresses(n); err != nil {
+		return nil, err
+	}
+	e, err := Generic.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return e.NextSubstitutions(n), nil
+}
+
+// checkKeypresses tells if there can be that many keypresses, i.e. it
+// isn't negative.
+func checkKeypresses(n int) error {
+	if n < 0 {
+		return fmt.Errorf("the number of keypresses cannot be negative, got %d", n)
+	}
+	return nil
+}
+
+// NextSubstitutions returns the alphabets the machine substitutes with
+// for each of its next n keypresses, the same way as SubstitutionSeries
+// but from where the rotors are, which don't move. There are none for a
+// negative n, the same as for 0.
+func (e *Enigma) NextSubstitutions(n int) [][26]rune {
+	if n < 0 {
+		n = 0
+	}
+	c := e.Clone()
+	series := make([][26]rune, n)
+	for i := range series {
+		c.moveRotors()
+		series[i] = c.permutationTable()
+	}
+	return series
+}
+
+// WriteSubstitutionSeries writes a series of SubstitutionSeries as
+// "csv" (with a header row, a keypress, counting from 1, and its lamps
+// for the keys from A to Z on each row) or as "json" (an array of the
+// alphabets, as strings).
+func WriteSubstitutionSeries(w io.Writer, series [][26]rune, format string) error {
+	switch format {
+	case "json":
+		rows := make([]string, len(series))
+		for i, row := range series {
+			rows[i] = string(row[:])
+		}
+		return json.NewEncoder(w).Encode(rows)
+	case "csv":
+		writer := csv.NewWriter(w)
+		record := make([]string, 27)
+		record[0] = "keypress"
+		for i := 0; i < 26; i++ {
+			record[i+1] = string(IndexToChar(i))
+		}
+		writer.Write(record)
+		for i, row := range series {
+			record[0] = strconv.Itoa(i + 1)
+			for j, lamp := range row {
+				record[j+1] = string(lamp)
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf(`unknown series format "%s", use "csv" or "json"`, format)
+}
@@ -0,0 +1,196 @@
+package enigma
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// At many random positions, PermuteAt lights the lamp a keypress does
+// once the rotors stepped there, the table is a set of swapped pairs,
+// and the machine stays where it was.
+func TestPermuteAt(t *testing.T) {
+	rng := rand.New(rand.NewSource(140))
+	rotors := rotorsAt("IV II V", "AAA")
+	for i := range rotors {
+		rotors[i].Ring = 7 * (i + 1)
+	}
+	e, err := NewEnigmaI(WithRotors(rotors...), WithReflector("B"), WithPlugboard("AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		positions := string([]byte{byte('A' + rng.Intn(26)), byte('A' + rng.Intn(26)), byte('A' + rng.Intn(26))})
+		letter := rune('A' + rng.Intn(26))
+		if err := e.ResetTo(positions); err != nil {
+			t.Fatal(err)
+		}
+		c := e.Clone()
+		want, err := c.EncodeRune(letter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.PermuteAt(c.Positions(), letter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("at %s, %c permutes to %c, expected %c as keyed from %s", c.Positions(), letter, got, want, positions)
+		}
+		table, err := e.PermutationTableAt(positions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, lamp := range table {
+			if lamp == rune('A'+j) || table[lamp-'A'] != rune('A'+j) {
+				t.Fatalf("at %s, the table %s isn't a set of swapped pairs", positions, string(table[:]))
+			}
+		}
+		if e.Positions() != positions {
+			t.Fatalf("permuting at %s moves the rotors to %s", positions, e.Positions())
+		}
+	}
+}
+
+// Lowercase letters permute like capitals, and anything else, or
+// positions the machine hasn't got, are errors.
+func TestPermuteAtErrors(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper, _ := e.PermuteAt("QDV", 'W')
+	if lower, err := e.PermuteAt("QDV", 'w'); err != nil || lower != upper {
+		t.Errorf("w permutes to %c (%v), expected %c", lower, err, upper)
+	}
+	if _, err := e.PermuteAt("QDV", '1'); err == nil {
+		t.Error("1 is permuted")
+	}
+	if _, err := e.PermutationTableAt("QD"); err == nil {
+		t.Error("two positions are taken for three rotors")
+	}
+	table, err := e.PermutationTableAt("AAA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := SubstitutionSeries(classicConfig(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := e.PermutationTableAt("AAB"); !reflect.DeepEqual(series[0], next) || reflect.DeepEqual(table, next) {
+		t.Errorf("the first keypress substitutes with %s, expected %s", string(series[0][:]), string(next[:]))
+	}
+}
+
+// Row i of the series, applied to the i-th letter of a text, encodes it
+// the way EncodeString does, on an Enigma I, an M3 through the double
+// step, and an M4; and each series is built afresh, whatever came
+// before it.
+func TestSubstitutionSeries(t *testing.T) {
+	m3 := Config{Rotors: rotorsAt("VI VII VIII", "ZDZ"), Reflector: ReflectorConfig{ID: "C"}, Plugboard: []string{"AQ", "EP"}}
+	for i := range m3.Rotors {
+		m3.Rotors[i].Ring = 5 + i
+	}
+	configs := []struct {
+		name   string
+		model  *Model
+		config Config
+	}{
+		{"Enigma I", &EnigmaI, barbarossa.config},
+		{"M3", &M3, m3},
+		{"M4", &M4, Config{Rotors: rotorsAt("Beta II IV I", "VJNA"), Reflector: ReflectorConfig{ID: "B-thin"}, Plugboard: []string{"AT", "BL", "DF"}}},
+	}
+	plaintext := strings.Repeat(receiveText, 3)
+	for _, c := range configs {
+		series, err := SubstitutionSeries(c.config, len(plaintext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := c.model.New(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := e.EncodeString(plaintext)
+		got := make([]byte, len(plaintext))
+		for i := range plaintext {
+			got[i] = byte(series[i][plaintext[i]-'A'])
+		}
+		if string(got) != want {
+			t.Errorf("%s: the series encodes to %s, expected %s", c.name, got, want)
+		}
+		again, err := SubstitutionSeries(c.config, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(again, series[:10]) {
+			t.Errorf("%s: the series starts differently the second time", c.name)
+		}
+	}
+	if series, err := SubstitutionSeries(classicConfig(), 0); err != nil || len(series) != 0 {
+		t.Errorf("the series of no keypresses is %v (%v)", series, err)
+	}
+	if _, err := SubstitutionSeries(classicConfig(), -1); err == nil {
+		t.Error("a series of -1 keypresses is built")
+	}
+	if _, err := SubstitutionSeries(Config{Rotors: rotorsAt("I II IX", "AAA")}, 1); err == nil {
+		t.Error("a series is built with rotor IX")
+	}
+}
+
+// The series of a machine starts where its rotors are, and leaves them
+// there.
+func TestNextSubstitutions(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("WETTER")
+	series := e.NextSubstitutions(5)
+	if e.Positions() != "AAG" {
+		t.Errorf("the rotors moved to %s", e.Positions())
+	}
+	want, err := e.PermutationTableAt("AAH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series[0] != want {
+		t.Errorf("the series starts with %s, expected %s", string(series[0][:]), string(want[:]))
+	}
+	if series := e.NextSubstitutions(-1); len(series) != 0 {
+		t.Errorf("-1 keypresses have the series %v", series)
+	}
+	if _, err := SubstitutionSeries(classicConfig(), -1); err == nil {
+		t.Error("-1 keypresses have a series")
+	}
+}
+
+// The series is written as CSV, a header and a row a keypress, or as an
+// array of alphabets in JSON.
+func TestWriteSubstitutionSeries(t *testing.T) {
+	series, err := SubstitutionSeries(classicConfig(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := string(series[0][:]), string(series[1][:])
+	var out bytes.Buffer
+	if err := WriteSubstitutionSeries(&out, series, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `["`+first+`","`+second+`"]`+"\n"; got != want {
+		t.Errorf("the JSON is %s, expected %s", got, want)
+	}
+	out.Reset()
+	if err := WriteSubstitutionSeries(&out, series, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || lines[0] != "keypress,"+strings.Join(strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", ""), ",") ||
+		lines[2] != "2,"+strings.Join(strings.Split(second, ""), ",") {
+		t.Errorf("the CSV is %s", out.String())
+	}
+	if err := WriteSubstitutionSeries(&out, series, "xml"); err == nil {
+		t.Error("the series is written as XML")
+	}
+}
@@ -0,0 +1,135 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errClosed is the error of writing to a closed encoding writer.
+var errClosed = errors.New("enigma: write to a closed writer")
+
+// encoderWriter encodes the letters on their way to the writer.
+type encoderWriter struct {
+	e      *Enigma
+	w      io.Writer
+	policy NonAlphaPolicy
+	buf    []byte
+	n      int64
+	closed bool
+}
+
+// NewEncoderWriter returns a writer encoding everything written to it
+// with the machine, letters of either case, and writing the result to w,
+// so that the machine can go in any io pipeline. The bytes that aren't
+// letters are handled by the policy: NonAlphaStrip leaves them out,
+// NonAlphaPreserve passes them through as they are (punctuation and line
+// endings stay where they were, the machine doesn't move for them), and
+// NonAlphaReject stops with a CharacterError. The other policies are
+// an error. An empty write does nothing, not even an empty write to w.
+// Closing the writer doesn't close w.
+func NewEncoderWriter(machine *Enigma, w io.Writer, policy NonAlphaPolicy) (io.WriteCloser, error) {
+	if err := checkPipePolicy(policy); err != nil {
+		return nil, err
+	}
+	return &encoderWriter{e: machine, w: w, policy: policy}, nil
+}
+
+// checkPipePolicy tells if the policy is one the bytes going through a
+// pipe can be handled by.
+func checkPipePolicy(policy NonAlphaPolicy) error {
+	switch policy {
+	case NonAlphaStrip, NonAlphaPreserve, NonAlphaReject:
+		return nil
+	}
+	return fmt.Errorf("policy %d is not for pipes: expected NonAlphaStrip, NonAlphaPreserve or NonAlphaReject", policy)
+}
+
+func (c *encoderWriter) Write(p []byte) (int, error) {
+	if c.closed {
+		return 0, errClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	c.buf = c.buf[:0]
+	n, err := c.e.pipeBytes(p, &c.buf, c.policy, c.n, c.e.EncodeChar, "encoded")
+	c.n += int64(n)
+	if _, werr := c.w.Write(c.buf); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+func (c *encoderWriter) Close() error {
+	c.closed = true
+	return nil
+}
+
+// decoderReader decodes the letters read from the reader.
+type decoderReader struct {
+	e      *Enigma
+	r      io.Reader
+	policy NonAlphaPolicy
+	buf    []byte
+	out    []byte
+	n      int64
+	err    error
+}
+
+// NewDecoderReader returns a reader decoding everything read from r
+// with the machine (see DecodeChar), the counterpart of
+// NewEncoderWriter, with the same policy for the bytes that aren't
+// letters.
+func NewDecoderReader(machine *Enigma, r io.Reader, policy NonAlphaPolicy) (io.Reader, error) {
+	if err := checkPipePolicy(policy); err != nil {
+		return nil, err
+	}
+	return &decoderReader{e: machine, r: r, policy: policy}, nil
+}
+
+func (d *decoderReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if cap(d.buf) < len(p) {
+			d.buf = make([]byte, len(p))
+		}
+		n, err := d.r.Read(d.buf[:len(p)])
+		d.out = d.out[:0]
+		piped, perr := d.e.pipeBytes(d.buf[:n], &d.out, d.policy, d.n, d.e.DecodeChar, "decoded")
+		d.n += int64(piped)
+		switch {
+		case perr != nil:
+			d.err = perr
+		case err != nil:
+			d.err = err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// pipeBytes sends the letters of the bytes through the machine, adding
+// the result to out, and returns how many bytes were taken. Offset is
+// where the bytes start in the whole stream, for the errors.
+func (e *Enigma) pipeBytes(p []byte, out *[]byte, policy NonAlphaPolicy, offset int64, key func(byte) byte, verb string) (int, error) {
+	for i, b := range p {
+		switch {
+		case b >= 'a' && b <= 'z':
+			*out = append(*out, key(b-('a'-'A')))
+		case b >= 'A' && b <= 'Z':
+			*out = append(*out, key(b))
+		case policy == NonAlphaPreserve:
+			*out = append(*out, b)
+		case policy == NonAlphaReject:
+			return i, &CharacterError{Rune: rune(b), Index: int(offset) + i, verb: verb}
+		}
+	}
+	return len(p), nil
+}
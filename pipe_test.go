@@ -0,0 +1,113 @@
+package enigma
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// pipeText is letters of both cases mixed with what isn't a letter.
+const pipeText = "Wetter, 12 Uhr!\n"
+
+// Through the writer, what isn't a letter is left out, kept where it
+// was, or stops the encoding there, and the machine only moves for the
+// letters; the reader decodes it back the same way.
+func TestPipePolicies(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	letters := e.Clone().EncodeString("WETTERUHR")
+	preserved := letters[:6] + ", 12 " + letters[6:] + "!\n"
+	tests := []struct {
+		policy    NonAlphaPolicy
+		want      string
+		decrypt   string
+		positions string
+	}{
+		{NonAlphaStrip, letters, "WETTERUHR", "AAJ"},
+		{NonAlphaPreserve, preserved, "WETTER, 12 UHR!\n", "AAJ"},
+		{NonAlphaReject, letters[:6], "WETTER", "AAG"},
+	}
+	for _, tt := range tests {
+		e, _ := Generic.New(classicConfig())
+		var out bytes.Buffer
+		w, err := NewEncoderWriter(e, &out, tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := w.Write([]byte(pipeText))
+		if tt.policy == NonAlphaReject {
+			var charErr *CharacterError
+			if !errors.As(err, &charErr) || charErr.Rune != ',' || charErr.Index != 6 || n != 6 {
+				t.Errorf("policy %d: the text is written as %d bytes with %v", tt.policy, n, err)
+			}
+		} else if err != nil || n != len(pipeText) {
+			t.Errorf("policy %d: the text is written as %d bytes with %v", tt.policy, n, err)
+		}
+		if out.String() != tt.want || e.Positions() != tt.positions {
+			t.Errorf("policy %d: the text encodes to %q with the rotors at %s, expected %q at %s",
+				tt.policy, out.String(), e.Positions(), tt.want, tt.positions)
+		}
+
+		e, _ = Generic.New(classicConfig())
+		input := tt.want
+		if tt.policy == NonAlphaReject {
+			input = preserved
+		}
+		r, err := NewDecoderReader(e, iotest.OneByteReader(strings.NewReader(input)), tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypt, err := ioutil.ReadAll(r)
+		if string(decrypt) != tt.decrypt || (tt.policy == NonAlphaReject) != errors.Is(err, ErrInvalidCharacter) {
+			t.Errorf("policy %d: %q decodes to %q with %v, expected %q", tt.policy, input, decrypt, err, tt.decrypt)
+		}
+	}
+}
+
+// A read into an empty buffer returns at once, without reading or
+// decoding anything.
+func TestDecoderReaderEmptyRead(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := e.Clone().EncodeString("WETTER")
+	r, err := NewDecoderReader(e, strings.NewReader(ciphertext), NonAlphaStrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][]byte{nil, {}} {
+		if n, err := r.Read(p); n != 0 || err != nil {
+			t.Errorf("an empty read returns %d, %v", n, err)
+		}
+	}
+	if e.Positions() != "AAA" {
+		t.Errorf("an empty read moves the rotors to %s", e.Positions())
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil || string(got) != "WETTER" {
+		t.Errorf("after the empty reads, %s is decoded to %s (%v)", ciphertext, got, err)
+	}
+}
+
+// Policies that make no sense for bytes going through, and ones that
+// don't exist, are refused up front.
+func TestPipePolicyUnknown(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, policy := range []NonAlphaPolicy{NonAlphaSpaceToX, NonAlphaSubstitute, NonAlphaPolicy(7), NonAlphaPolicy(-1)} {
+		if _, err := NewEncoderWriter(e, ioutil.Discard, policy); err == nil {
+			t.Errorf("the writer takes policy %d", policy)
+		}
+		if _, err := NewDecoderReader(e, strings.NewReader("QWERT"), policy); err == nil {
+			t.Errorf("the reader takes policy %d", policy)
+		}
+	}
+}
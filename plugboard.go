@@ -1,5 +1,7 @@
 package enigma
 
+import "strings"
+
 // Plugboard is a two-way mapping between characters modifying the
 // encoding procedure of the Enigma machine.
 type Plugboard [26]int
@@ -21,3 +23,41 @@ func NewPlugboard(pairs []string) *Plugboard {
 	}
 	return &p
 }
+
+// ParsePlugboard is the plugboard constructor for the pairs the way key
+// sheets have them, e.g. "AB CD EF", in either case. A letter can only
+// be plugged once, and never into itself.
+func ParsePlugboard(pairs string) (*Plugboard, error) {
+	fields := strings.Fields(strings.ToUpper(pairs))
+	if err := validatePlugs(fields); err != nil {
+		return nil, err
+	}
+	return NewPlugboard(fields), nil
+}
+
+// Table returns the plugboard as a table of the letters from 0 to 25,
+// for pipelines of letter indexes of their own; the machine looks the
+// letters up in the plugboard itself.
+func (p *Plugboard) Table() [26]int8 {
+	return table8(*p)
+}
+
+// table8 returns the mapping of the letters with the indexes in bytes.
+func table8(mapping [26]int) [26]int8 {
+	var table [26]int8
+	for i, j := range mapping {
+		table[i] = int8(j)
+	}
+	return table
+}
+
+// Pairs returns the plugged letter pairs in alphabetical order.
+func (p *Plugboard) Pairs() []string {
+	var pairs []string
+	for i, j := range p {
+		if i < j {
+			pairs = append(pairs, string([]byte{IndexToChar(i), IndexToChar(j)}))
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,88 @@
+package enigma
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The pairs are read the way key sheets have them, in either case, and
+// each plug swaps its two letters both ways.
+func TestParsePlugboard(t *testing.T) {
+	p, err := ParsePlugboard("av BS cg  dl\tfu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Pairs(), []string{"AV", "BS", "CG", "DL", "FU"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the plugboard has %v, expected %v", got, want)
+	}
+	for i, j := range p {
+		if p[j] != i {
+			t.Errorf("%c is plugged into %c, which is plugged into %c", IndexToChar(i), IndexToChar(j), IndexToChar(p[j]))
+		}
+	}
+	if p, err := ParsePlugboard(""); err != nil || len(p.Pairs()) != 0 {
+		t.Errorf("no pairs make a plugboard of %v (%v)", p, err)
+	}
+}
+
+// A letter plugged twice, or into itself, is a plug conflict telling
+// the letter; anything that isn't a pair of letters can't be read.
+func TestParsePlugboardErrors(t *testing.T) {
+	tests := []struct {
+		pairs    string
+		conflict byte
+	}{
+		{"AB CA", 'A'},
+		{"AB CB", 'B'},
+		{"AB CC", 'C'},
+		{"ab Ba", 'B'},
+		{"ABC", 0},
+		{"AB C", 0},
+		{"A1", 0},
+	}
+	for _, tt := range tests {
+		_, err := ParsePlugboard(tt.pairs)
+		if err == nil {
+			t.Errorf("%q makes a plugboard", tt.pairs)
+			continue
+		}
+		var setting *SettingError
+		if tt.conflict == 0 {
+			if errors.Is(err, ErrPlugConflict) {
+				t.Errorf("%q is a plug conflict: %v", tt.pairs, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrPlugConflict) || !errors.As(err, &setting) || setting.Letter != tt.conflict {
+			t.Errorf("%q is turned down with %v, expected a conflict over %c", tt.pairs, err, tt.conflict)
+		}
+	}
+}
+
+// The plugboard swaps the letters on the way in and again on the way
+// out: the machine with it encodes every letter like the one without,
+// with the letter swapped first and the lamp swapped after.
+func TestPlugboardEntryAndExit(t *testing.T) {
+	p, err := ParsePlugboard("AV BS CG DL FU HZ IN KM OW RX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := classicConfig()
+	config.Plugboard = p.Pairs()
+	plugged, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bare, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(receiveText); i++ {
+		in := CharToIndex(receiveText[i])
+		want := IndexToChar(p[CharToIndex(bare.EncodeChar(IndexToChar(p[in])))])
+		if got := plugged.EncodeChar(receiveText[i]); got != want {
+			t.Fatalf("letter %d, %c, encodes to %c, expected %c", i, receiveText[i], got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package enigma
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlugboardPresetInfo is a named plugboard setting: the pairs and the
+// message it comes from, so that it can be checked.
+type PlugboardPresetInfo struct {
+	Pairs  []string
+	Source string
+}
+
+// PlugboardPresets are the named plugboard settings, as used with
+// WithPlugboard("@name").
+var PlugboardPresets = map[string]PlugboardPresetInfo{
+	"barbarossa-1941-07-07": {
+		Pairs: []string{"AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX"},
+		Source: "Operation Barbarossa message of 7 July 1941, Enigma I with rotors II IV V, " +
+			"rings 02 21 12, reflector B, indicator WXC KCH",
+	},
+	"u534-1945-05-05": {
+		Pairs: []string{"AT", "BL", "DF", "GJ", "HM", "NW", "OP", "QY", "RZ", "VX"},
+		Source: "U-534 message of 5 May 1945, M4 with rotors Beta II IV I, " +
+			"rings 01 01 01 22, reflector B-thin, message key VJNA",
+	},
+}
+
+// RegisterPlugboardPreset adds a named plugboard setting, checking the
+// pairs first. Names already taken can't be registered again.
+func RegisterPlugboardPreset(name string, pairs ...string) error {
+	if _, ok := PlugboardPresets[name]; ok {
+		return fmt.Errorf(`plugboard preset "%s" is already registered`, name)
+	}
+	if err := validatePlugs(pairs); err != nil {
+		return err
+	}
+	PlugboardPresets[name] = PlugboardPresetInfo{Pairs: pairs}
+	return nil
+}
+
+// PlugboardPreset returns the plugboard with the named setting.
+func PlugboardPreset(name string) (*Plugboard, error) {
+	info, ok := PlugboardPresets[name]
+	if !ok {
+		return nil, unknownPreset(name)
+	}
+	return NewPlugboard(info.Pairs), nil
+}
+
+// ResolvePlugboard returns the plugboard pairs, replacing a single
+// "@name" with the pairs of the named preset.
+func ResolvePlugboard(pairs []string) ([]string, error) {
+	if len(pairs) != 1 || !strings.HasPrefix(pairs[0], "@") {
+		return pairs, nil
+	}
+	info, ok := PlugboardPresets[pairs[0][1:]]
+	if !ok {
+		return nil, unknownPreset(pairs[0][1:])
+	}
+	return info.Pairs, nil
+}
+
+// PresetError is returned for a preset that doesn't exist, listing the
+// ones that do.
+type PresetError struct {
+	Name      string
+	Available []string
+}
+
+// Error implements the error interface.
+func (e *PresetError) Error() string {
+	return fmt.Sprintf(`unknown preset "%s", available: %s`, e.Name, strings.Join(e.Available, ", "))
+}
+
+// Unwrap returns ErrUnknownPreset, so that errors.Is works.
+func (e *PresetError) Unwrap() error {
+	return ErrUnknownPreset
+}
+
+// unknownPreset makes a PresetError for the plugboard presets.
+func unknownPreset(name string) *PresetError {
+	e := &PresetError{Name: name}
+	for preset := range PlugboardPresets {
+		e.Available = append(e.Available, preset)
+	}
+	sort.Strings(e.Available)
+	return e
+}
@@ -0,0 +1,74 @@
+package enigma_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// presetVectors are the messages the shipped presets come from.
+var presetVectors = map[string]testvectors.Vector{
+	"barbarossa-1941-07-07": testvectors.Barbarossa,
+	"u534-1945-05-05":       testvectors.Doenitz,
+}
+
+// Every shipped preset, referenced by name, decrypts the message it
+// comes from.
+func TestPlugboardPresetsDecrypt(t *testing.T) {
+	for name, info := range enigma.PlugboardPresets {
+		vector, ok := presetVectors[name]
+		if !ok {
+			t.Errorf("%s: no message to check it against (%s)", name, info.Source)
+			continue
+		}
+		e, err := enigma.NewMachine(
+			enigma.WithRotors(vector.Config.Rotors...),
+			enigma.WithReflector(vector.Config.Reflector.ID),
+			enigma.WithPlugboard("@"+name),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.DecodeString(enigma.ParseGroups(vector.Ciphertext, enigma.ClassicGroups)); got != vector.Plaintext {
+			t.Errorf("%s: %s decrypts to %s, expected %s", name, vector.Name, got, vector.Plaintext)
+		}
+		plugboard, err := enigma.PlugboardPreset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(plugboard.Pairs(), " "); got != strings.Join(vector.Config.Plugboard, " ") {
+			t.Errorf("%s: the plugboard has %s, expected %s", name, got, strings.Join(vector.Config.Plugboard, " "))
+		}
+	}
+}
+
+func TestPlugboardPresetErrors(t *testing.T) {
+	_, err := enigma.NewMachine(enigma.WithRotors(enigma.RotorConfig{ID: "I", Start: 'A', Ring: 1}), enigma.WithReflector("B"), enigma.WithPlugboard("@barbarossa"))
+	var preset *enigma.PresetError
+	if !errors.As(err, &preset) || !errors.Is(err, enigma.ErrUnknownPreset) {
+		t.Fatalf("an unknown preset is %v", err)
+	}
+	if got := strings.Join(preset.Available, " "); preset.Name != "barbarossa" || got != "barbarossa-1941-07-07 u534-1945-05-05" {
+		t.Errorf("the error tells %s and %s", preset.Name, got)
+	}
+	if _, err := enigma.PlugboardPreset("nope"); !errors.Is(err, enigma.ErrUnknownPreset) {
+		t.Errorf("an unknown preset is %v", err)
+	}
+
+	defer delete(enigma.PlugboardPresets, "classroom")
+	if err := enigma.RegisterPlugboardPreset("classroom", "AB", "CD"); err != nil {
+		t.Fatal(err)
+	}
+	if plugboard, err := enigma.PlugboardPreset("classroom"); err != nil || strings.Join(plugboard.Pairs(), " ") != "AB CD" {
+		t.Errorf("the registered preset is %v (%v)", plugboard, err)
+	}
+	if err := enigma.RegisterPlugboardPreset("classroom", "EF"); err == nil {
+		t.Error("a preset is registered twice")
+	}
+	if err := enigma.RegisterPlugboardPreset("broken", "AB", "BC"); err == nil {
+		t.Error("a preset with a letter plugged twice is registered")
+	}
+}
@@ -0,0 +1,191 @@
+package enigma
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DisplayMode tells how rotor positions are shown in the windows:
+// as letters ("QDV") or, like on the machines with numbered rings,
+// as two-digit numbers ("17 04 22"). Either way 01 is A.
+type DisplayMode int
+
+// Display modes.
+const (
+	DisplayLetters DisplayMode = iota
+	DisplayNumbers
+)
+
+// FormatPositions renders rotor offsets in the display mode.
+func FormatPositions(offsets []int, mode DisplayMode) string {
+	if mode == DisplayNumbers {
+		numbers := make([]string, len(offsets))
+		for i, offset := range offsets {
+			numbers[i] = fmt.Sprintf("%02d", offset+1)
+		}
+		return strings.Join(numbers, " ")
+	}
+	letters := make([]byte, len(offsets))
+	for i, offset := range offsets {
+		letters[i] = IndexToChar(offset)
+	}
+	return string(letters)
+}
+
+// ParsePositions reads rotor positions given either as letters ("QDV",
+// "Q D V") or as numbers from 1 to 26 ("17 04 22"), and returns the
+// rotor offsets. Letters and numbers can be mixed.
+func ParsePositions(positions string) ([]int, error) {
+	return parseSettings(positions, ErrPositionOutOfRange, "rotor position")
+}
+
+// ParseRings reads ring settings given either as letters ("BUL", "B U L")
+// or as numbers ("02 21 12"), the two ways key sheets had them, and
+// returns the ring numbers from 1 to 26; A is 1, the same as in the
+// rotor windows. Letters and numbers can be mixed.
+func ParseRings(rings string) ([]int, error) {
+	indexes, err := parseSettings(rings, ErrRingOutOfRange, "ring")
+	for i := range indexes {
+		indexes[i]++
+	}
+	return indexes, err
+}
+
+// parseSettings reads letters or numbers from 1 to 26 into alphabet
+// indexes, reporting errors of the kind about the setting.
+func parseSettings(settings string, kind error, setting string) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.Fields(strings.ToUpper(settings)) {
+		if number, err := strconv.Atoi(field); err == nil {
+			if number < 1 || number > 26 {
+				err := settingError(kind, "%s out of range: must be 01-26, got %s", setting, field)
+				err.Slot, err.Value = len(indexes)+1, number
+				return nil, err
+			}
+			indexes = append(indexes, number-1)
+			continue
+		}
+		for i := range field {
+			if field[i] < 'A' || field[i] > 'Z' {
+				err := settingError(kind, `%ss should be letters or numbers, got "%s"`, setting, field)
+				err.Slot, err.Letter = len(indexes)+1, field[i]
+				return nil, err
+			}
+			indexes = append(indexes, CharToIndex(field[i]))
+		}
+	}
+	return indexes, nil
+}
+
+// Positions returns what the rotor windows show, from left to right,
+// in the display mode of the machine. A rotating reflector has a window
+// too, to the left of the rotors.
+func (e *Enigma) Positions() string {
+	offsets := make([]int, 0, len(e.Rotors)+1)
+	if e.RotatingReflector {
+		offsets = append(offsets, mod26(e.Reflector.Position))
+	}
+	for _, rotor := range e.Rotors {
+		offsets = append(offsets, mod26(rotor.Offset))
+	}
+	return FormatPositions(offsets, e.Display)
+}
+
+// Windows returns the letters in the rotor windows, from left to right,
+// whatever the display mode.
+func (e *Enigma) Windows() []rune {
+	windows := make([]rune, len(e.Rotors))
+	for i, rotor := range e.Rotors {
+		windows[i] = rune(IndexToChar(mod26(rotor.Offset)))
+	}
+	return windows
+}
+
+// SetPositions turns the rotors to the letters, one for each rotor from
+// the left, the way an operator would mid-message (see ResetTo).
+func (e *Enigma) SetPositions(windows []rune) error {
+	letters := make([]byte, len(windows))
+	for i, r := range windows {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if r < 'A' || r > 'Z' {
+			err := settingError(ErrPositionOutOfRange, `rotor positions should be letters, got "%c"`, r)
+			err.Slot = i + 1
+			return err
+		}
+		letters[i] = byte(r)
+	}
+	if len(letters) != len(e.Rotors) {
+		return fmt.Errorf("expected %d rotor positions, got %d", len(e.Rotors), len(letters))
+	}
+	return e.ResetTo(string(letters))
+}
+
+// RingSettings returns the ring settings of the rotors from the left,
+// from 1 to 26, the way RotorConfig has them.
+func (e *Enigma) RingSettings() []int {
+	rings := make([]int, len(e.Rotors))
+	for i, rotor := range e.Rotors {
+		rings[i] = mod26(rotor.Ring) + 1
+	}
+	return rings
+}
+
+// RotorIDs returns the IDs of the rotors from the left.
+func (e *Enigma) RotorIDs() []string {
+	ids := make([]string, len(e.Rotors))
+	for i, rotor := range e.Rotors {
+		ids[i] = rotor.ID
+	}
+	return ids
+}
+
+// ResetTo sets the rotors to new positions, given as letters or numbers
+// (see ParsePositions), one for each rotor. A rotating reflector can be
+// given a position too, before the ones of the rotors, and goes back to
+// where the machine was built with if it isn't.
+func (e *Enigma) ResetTo(positions string) error {
+	offsets, err := ParsePositions(positions)
+	if err != nil {
+		return err
+	}
+	reflector := e.reflectorStart
+	if e.RotatingReflector && len(offsets) == len(e.Rotors)+1 {
+		reflector, offsets = offsets[0], offsets[1:]
+	}
+	if len(offsets) != len(e.Rotors) {
+		return fmt.Errorf("expected %d rotor positions, got %d", len(e.Rotors), len(offsets))
+	}
+	if e.RotatingReflector {
+		e.Reflector.Position = reflector
+	}
+	for i, rotor := range e.Rotors {
+		rotor.Offset = offsets[i]
+	}
+	e.moves, e.doubles = 0, nil
+	e.sealOffsets()
+	return nil
+}
+
+// FastForward steps the rotors as if n keys were pressed.
+func (e *Enigma) FastForward(n int) {
+	for i := 0; i < n; i++ {
+		e.moveRotors()
+	}
+}
+
+// Reset sets the rotors back to the positions the machine was built with,
+// and clears the statistics.
+func (e *Enigma) Reset() {
+	e.stats = MachineStats{}
+	e.moves, e.doubles = 0, nil
+	for i, rotor := range e.Rotors {
+		rotor.Offset = e.start[i]
+	}
+	if e.RotatingReflector {
+		e.Reflector.Position = e.reflectorStart
+	}
+	e.sealOffsets()
+}
@@ -0,0 +1,48 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// Positions set as numbers are the same as set as letters, 01 being A,
+// and the numbered display shows them as they were set.
+func TestNumericPositions(t *testing.T) {
+	byLetter, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithPositions("QDV"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byNumber, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithPositions("17 04 22"), WithDisplay(DisplayNumbers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := byNumber.Positions(); got != "17 04 22" {
+		t.Errorf("the numbered windows show %s, expected 17 04 22", got)
+	}
+	if got := byLetter.Positions(); got != "QDV" {
+		t.Errorf("the lettered windows show %s, expected QDV", got)
+	}
+	if a, b := byLetter.EncodeString("WETTERBERICHT"), byNumber.EncodeString("WETTERBERICHT"); a != b {
+		t.Errorf("set as QDV the machine encodes to %s, and set as 17 04 22 to %s", a, b)
+	}
+	for _, positions := range []string{"QDV", "17 04 22", "Q 04 V", "qdv"} {
+		if err := byNumber.ResetTo(positions); err != nil {
+			t.Fatal(err)
+		}
+		if got := byNumber.Positions(); got != "17 04 22" {
+			t.Errorf("set to %s, the windows show %s, expected 17 04 22", positions, got)
+		}
+	}
+}
+
+func TestParsePositionsErrors(t *testing.T) {
+	for _, positions := range []string{"00 01 02", "27", "A1B", "Q-V"} {
+		_, err := ParsePositions(positions)
+		if !errors.Is(err, ErrPositionOutOfRange) {
+			t.Errorf("%s: %v, expected ErrPositionOutOfRange", positions, err)
+		}
+	}
+	if got := FormatPositions([]int{0, 25}, DisplayNumbers); got != "01 26" {
+		t.Errorf("A and Z are shown as %s, expected 01 26", got)
+	}
+}
@@ -1,27 +1,173 @@
 package enigma
 
-// HistoricRotors match the original Enigma configurations, including the
-// notches. "Beta" and "Gamma" are additional rotors used in M4
-// at the leftmost position.
-var HistoricRotors = Rotors{
-	*NewRotor("EKMFLGDQVZNTOWYHXUSPAIBRCJ", "I", "Q"),
-	*NewRotor("AJDKSIRUXBLHWTMCQGZNPYFVOE", "II", "E"),
-	*NewRotor("BDFHJLCPRTXVZNYEIWGAKMUSQO", "III", "V"),
-	*NewRotor("ESOVPZJAYQUIRHXLNFTGKDCMWB", "IV", "J"),
-	*NewRotor("VZBRGITYUPSDNHLXAWMJQOFECK", "V", "Z"),
-	*NewRotor("JPGVOUMFYQBENHZRDKASXLICTW", "VI", "ZM"),
-	*NewRotor("NZJHGRCXMYSWBOUFAIVLPEKQDT", "VII", "ZM"),
-	*NewRotor("FKQHTLXOCBJSPDZRAMEWNIUYGV", "VIII", "ZM"),
-	*NewRotor("LEYJVCNIXWPBQMDRTAKZGFUHOS", "Beta", ""),
-	*NewRotor("FSOKANUERHMBTIYCWLQPZXVGJD", "Gamma", ""),
-}
-
-// HistoricReflectors in the list are pre-loaded with historically accurate data
-// from Enigma machines. Use "B-Thin" and "C-Thin" with M4 (4 rotors).
-var HistoricReflectors = Reflectors{
+// HistoricRotors returns a copy of the rotors matching the original
+// Enigma configurations, including the notches and the year they were
+// issued. "Beta" and "Gamma" are additional rotors used in M4 at the
+// leftmost position.
+func HistoricRotors() Rotors {
+	return historicRotors.Copy()
+}
+
+var historicRotors = Rotors{
+	*introduced(1930, NewRotor("EKMFLGDQVZNTOWYHXUSPAIBRCJ", "I", "Q")),
+	*introduced(1930, NewRotor("AJDKSIRUXBLHWTMCQGZNPYFVOE", "II", "E")),
+	*introduced(1930, NewRotor("BDFHJLCPRTXVZNYEIWGAKMUSQO", "III", "V")),
+	*introduced(1938, NewRotor("ESOVPZJAYQUIRHXLNFTGKDCMWB", "IV", "J")),
+	*introduced(1938, NewRotor("VZBRGITYUPSDNHLXAWMJQOFECK", "V", "Z")),
+	*introduced(1939, NewRotor("JPGVOUMFYQBENHZRDKASXLICTW", "VI", "ZM")),
+	*introduced(1939, NewRotor("NZJHGRCXMYSWBOUFAIVLPEKQDT", "VII", "ZM")),
+	*introduced(1940, NewRotor("FKQHTLXOCBJSPDZRAMEWNIUYGV", "VIII", "ZM")),
+	*introduced(1942, NewRotor("LEYJVCNIXWPBQMDRTAKZGFUHOS", "Beta", "")),
+	*introduced(1943, NewRotor("FSOKANUERHMBTIYCWLQPZXVGJD", "Gamma", "")),
+}
+
+// HistoricReflectors returns a copy of the reflectors pre-loaded with
+// historically accurate data from Enigma machines. Use "B-Thin" and
+// "C-Thin" with M4 (4 rotors).
+func HistoricReflectors() Reflectors {
+	return historicReflectors.Copy()
+}
+
+var historicReflectors = Reflectors{
 	*NewReflector("EJMZALYXVBWFCRQUONTSPIKHGD", "A"),
 	*NewReflector("YRUHQSLDPXNGOKMIEBFZCWVJAT", "B"),
 	*NewReflector("FVPJIAOYEDRZXWGCTKUQSBNMHL", "C"),
 	*NewReflector("ENKQAUYWJICOPBLMDXZVFTHRGS", "B-thin"),
 	*NewReflector("RDOBJNTKVEHMLFCWZAXGYIPSUQ", "C-thin"),
 }
+
+// HistoricEntryWheels returns a copy of the known entry wheel wirings:
+// the alphabetical one used in military machines, the keyboard-ordered
+// one used in commercial models, and the Tirpitz one.
+func HistoricEntryWheels() EntryWheels {
+	return historicEntryWheels.Copy()
+}
+
+var historicEntryWheels = EntryWheels{
+	*NewEntryWheel("ABCDEFGHIJKLMNOPQRSTUVWXYZ", "ABC"),
+	*NewEntryWheel("QWERTZUIOASDFGHJKPYXCVBNML", "QWERTZ"),
+	*NewEntryWheel("KZROUQHYAIGBLWVSTDXFPNMCJE", "T"),
+}
+
+// TirpitzRotors returns the eight rotors of the Enigma T (Tirpitz) used
+// for the German–Japanese naval liaison. Each of them has five notches.
+func TirpitzRotors() Rotors {
+	return tirpitzRotors.Copy()
+}
+
+var tirpitzRotors = Rotors{
+	*NewRotor("KPTYUELOCVGRFQDANJMBSWHZXI", "I", "WZEKQ"),
+	*NewRotor("UPHZLWEQMTDJXCAKSOIGVBYFNR", "II", "WZFLR"),
+	*NewRotor("QUDLYRFEKONVZAXWHMGPJBSICT", "III", "WZEKQ"),
+	*NewRotor("CIWTBKXNRESPFLYDAGVHQUOJZM", "IV", "WZFLR"),
+	*NewRotor("UAXGISNJBVERDYLFZWTPCKOHMQ", "V", "YCFKR"),
+	*NewRotor("XFUZGALVHCNYSEWQTDMRBKPIOJ", "VI", "XEIMQ"),
+	*NewRotor("BJVFTXPLNAYOZIKWGDQERUCHSM", "VII", "YCFKR"),
+	*NewRotor("YMTPNZHWKODAJXELUQVGCBISFR", "VIII", "XEIMQ"),
+}
+
+// TirpitzReflectors returns the only reflector of the Enigma T.
+func TirpitzReflectors() Reflectors {
+	return tirpitzReflectors.Copy()
+}
+
+var tirpitzReflectors = Reflectors{
+	*NewReflector("GEKPBTAUMOCNILJDXZYFHWVQSR", "T"),
+}
+
+// EnigmaDRotors returns the rotors of the commercial Enigma D.
+func EnigmaDRotors() Rotors {
+	return enigmaDRotors.Copy()
+}
+
+var enigmaDRotors = Rotors{
+	*NewRotor("LPGSZMHAEOQKVXRFYBUTNICJDW", "I", "Y"),
+	*NewRotor("SLVGBTFXJQOHEWIRZYAMKPCNDU", "II", "E"),
+	*NewRotor("CJGDPSHKTURAWZXFMYNQOBVLIE", "III", "N"),
+}
+
+// EnigmaDReflectors returns the settable reflector of the Enigma D.
+func EnigmaDReflectors() Reflectors {
+	return enigmaDReflectors.Copy()
+}
+
+var enigmaDReflectors = Reflectors{
+	*NewReflector("IMETCGFRAYSQBZXWLHKDVUPOJN", "D"),
+}
+
+// EnigmaG312Rotors returns the rotors of the Abwehr Enigma G-312, with
+// 17, 15, and 11 notches, driven by gears rather than levers.
+func EnigmaG312Rotors() Rotors {
+	return enigmaG312Rotors.Copy()
+}
+
+var enigmaG312Rotors = Rotors{
+	*NewRotor("DMTWSILRUYQNKFEJCAZBPGXOHV", "I", "SUVWZABCEFGIKLOPQ"),
+	*NewRotor("HQZGPJTMOBLNCIFDYAWVEUSRKX", "II", "STVYZACDFGHKMNQ"),
+	*NewRotor("UQNTLSZFMREHDPXKIBVYGJCWOA", "III", "UWXAEFHKMNR"),
+}
+
+// EnigmaG312Reflectors returns the turning reflector of the G-312.
+func EnigmaG312Reflectors() Reflectors {
+	return enigmaG312Reflectors.Copy()
+}
+
+var enigmaG312Reflectors = Reflectors{
+	*NewReflector("RULQMZJSYGOCETKWDAHNBXPVIF", "UKW"),
+}
+
+// EnigmaG260Rotors returns the rotors of the Enigma G-260, notched like
+// the ones of the G-312 but wired differently.
+func EnigmaG260Rotors() Rotors {
+	return enigmaG260Rotors.Copy()
+}
+
+var enigmaG260Rotors = Rotors{
+	*NewRotor("RCSPBLKQAUMHWYTIFZVGOJNEXD", "I", "SUVWZABCEFGIKLOPQ"),
+	*NewRotor("WCMIBVPJXAROSGNDLZKEYHUFQT", "II", "STVYZACDFGHKMNQ"),
+	*NewRotor("FVDHZELSQMAXOKYIWPGCBUJTNR", "III", "UWXAEFHKMNR"),
+}
+
+// EnigmaG260Reflectors returns the turning reflector of the G-260,
+// wired like the one of the Enigma D.
+func EnigmaG260Reflectors() Reflectors {
+	return enigmaG260Reflectors.Copy()
+}
+
+var enigmaG260Reflectors = Reflectors{
+	*NewReflector("IMETCGFRAYSQBZXWLHKDVUPOJN", "UKW"),
+}
+
+// EnigmaKRotors returns the rotors of the commercial Enigma K, wired
+// the way the Swiss Army had them rewired in 1939.
+func EnigmaKRotors() Rotors {
+	return enigmaKRotors.Copy()
+}
+
+var enigmaKRotors = Rotors{
+	*NewRotor("PEZUOHXSCVFMTBGLRINQJWAYDK", "I", "Y"),
+	*NewRotor("ZOUESYDKFWPCIQXHMVBLGNJRAT", "II", "E"),
+	*NewRotor("EHRVXGAOBQUSIMZFLYNWKTPDJC", "III", "N"),
+}
+
+// EnigmaKReflectors returns the settable reflector of the Enigma K,
+// wired like the one of the Enigma D.
+func EnigmaKReflectors() Reflectors {
+	return enigmaKReflectors.Copy()
+}
+
+var enigmaKReflectors = Reflectors{
+	*NewReflector("IMETCGFRAYSQBZXWLHKDVUPOJN", "K"),
+}
+
+// KDRotors returns the rotors of the Enigma KD, a commercial K machine
+// with nine-notch rotors and the rewirable UKW-D.
+func KDRotors() Rotors {
+	return kdRotors.Copy()
+}
+
+var kdRotors = Rotors{
+	*NewRotor("VEZIOJCXKYDUNTWAPLQGBHSFMR", "I", "SUYAEHLNQ"),
+	*NewRotor("HGRBSJZETDLVPMQYCXAOKINFUW", "II", "SUYAEHLNQ"),
+	*NewRotor("NWLHXGRBYOJSAZDVTPKFQMEUIC", "III", "SUYAEHLNQ"),
+}
@@ -0,0 +1,84 @@
+package enigma
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// receiveText is a report written the way operators did, with X for the
+// spaces and the full stops.
+const receiveText = "" +
+	"VONXKOMMANDANTXANXALLEXEINHEITENXDERXFEINDXHATXSICHXINXDERXNACHTX" +
+	"NACHXNORDENXZURUECKGEZOGENXDIEXBRUECKEXUEBERXDENXFLUSSXISTXUNBESCH" +
+	"AEDIGTXWIRXERWARTENXWEITEREXBEFEHLEXBISXMORGENXFRUEHXENDE"
+
+// A message decrypted with the day's key checks out; decrypted with the
+// key of the next day, it's flagged, both by its Kenngruppe and by
+// looking like noise.
+func TestReceiveMessage(t *testing.T) {
+	sheet, err := GenerateKeySheet(KeySheetOptions{Days: 2, Rand: rand.New(rand.NewSource(124))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today, tomorrow := sheet[0], sheet[1]
+	transmission, err := BuildTransmission(today.Config, receiveText, TransmissionOptions{
+		Time:       "0715",
+		Rand:       rand.New(rand.NewSource(1)),
+		Kenngruppe: today.Kenngruppen[2],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, result, err := ReceiveMessage(today.Config, transmission, today.Kenngruppen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != receiveText || !result.KenngruppeOK || !result.CountOK || len(result.Warnings) != 0 {
+		t.Errorf("with the day's key, %s is received with %+v", plaintext, result)
+	}
+	if result.GarbleScore > garbledThreshold {
+		t.Errorf("the plaintext scores %.2f as garbled", result.GarbleScore)
+	}
+
+	plaintext, result, err = ReceiveMessage(tomorrow.Config, transmission, tomorrow.Kenngruppen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.KenngruppeOK || result.GarbleScore <= garbledThreshold || len(result.Warnings) != 2 {
+		t.Errorf("with the wrong day's key, %s is received with %+v", plaintext, result)
+	}
+
+	// The Kenngruppe is in the clear, so only the score gives away a
+	// wrong key when the groups are the same.
+	_, result, err = ReceiveMessage(tomorrow.Config, transmission, today.Kenngruppen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.KenngruppeOK || result.GarbleScore <= garbledThreshold {
+		t.Errorf("with the wrong key and the right groups, the message is received with %+v", result)
+	}
+}
+
+// A part with fewer letters than its preamble says is flagged.
+func TestReceiveMessageCount(t *testing.T) {
+	config := classicConfig()
+	transmission, err := BuildTransmission(config, receiveText, TransmissionOptions{Kenngruppe: "QEW"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := strings.SplitN(transmission, "\n", 2)[0]
+	count := strings.Fields(header)[0]
+	tampered := strings.Replace(transmission, count+" =", "999 =", 1)
+	_, result, err := ReceiveMessage(config, tampered, []string{"QEW"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CountOK || !result.KenngruppeOK || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "999") {
+		t.Errorf("the miscounted message is received with %+v", result)
+	}
+	if _, _, err := ReceiveMessage(config, transmission, nil); err == nil {
+		t.Error("a message is received without the groups of the day")
+	}
+}
@@ -1,11 +1,19 @@
 package enigma
 
+import "fmt"
+
+// UKWD is the ID of the field-rewirable reflector D (Umkehrwalze D).
+const UKWD = "UKW-D"
+
 // Reflector is used to reverse a signal inside the Enigma: the current
 // goes from the keys through the rotors to the reflector, then it is
 // reversed and goes through the rotors again in the opposite direction.
+// Some models allowed the reflector to be set to any of 26 positions
+// (though it never moved during encoding), which is what Position is for.
 type Reflector struct {
 	ID       string
 	Sequence [26]int
+	Position int
 }
 
 // NewReflector is a constuctor, taking a reflector mapping and
@@ -15,7 +23,59 @@ func NewReflector(mapping string, id string) *Reflector {
 	for i, value := range mapping {
 		seq[i] = CharToIndex(byte(value))
 	}
-	return &Reflector{id, seq}
+	return &Reflector{ID: id, Sequence: seq}
+}
+
+// NewUKWD is the constructor for the rewirable reflector D, taking the
+// twelve letter pairs it's plugged with. Bletchley Park notation is
+// used, where the B–O pair is wired permanently, so the pairs have to
+// cover the remaining 24 letters.
+func NewUKWD(pairs []string) (*Reflector, error) {
+	if len(pairs) != 12 {
+		return nil, fmt.Errorf("UKW-D takes 12 letter pairs, got %d", len(pairs))
+	}
+	r := &Reflector{ID: UKWD}
+	r.Sequence[CharToIndex('B')] = CharToIndex('O')
+	r.Sequence[CharToIndex('O')] = CharToIndex('B')
+	var used [26]bool
+	used[CharToIndex('B')], used[CharToIndex('O')] = true, true
+	for _, pair := range pairs {
+		if len(pair) != 2 || pair[0] < 'A' || pair[0] > 'Z' || pair[1] < 'A' || pair[1] > 'Z' {
+			return nil, fmt.Errorf(`UKW-D should be wired by letter pairs ("AC DE"), got "%s"`, pair)
+		}
+		if letter, ok := repeated(pair, used[:]); ok {
+			err := settingError(ErrPlugConflict, `letters cannot repeat across the UKW-D (B–O is fixed), check "%s"`, pair)
+			err.ID, err.Letter = UKWD, letter
+			return nil, err
+		}
+		first, second := CharToIndex(pair[0]), CharToIndex(pair[1])
+		used[first], used[second] = true, true
+		r.Sequence[first] = second
+		r.Sequence[second] = first
+	}
+	return r, nil
+}
+
+// Pairs returns the letter pairs a UKW-D is plugged with, leaving out
+// the fixed B–O pair. For other reflectors it returns nil.
+func (r *Reflector) Pairs() []string {
+	if r.ID != UKWD {
+		return nil
+	}
+	var pairs []string
+	for i, j := range r.Sequence {
+		if i < j && !(i == CharToIndex('B') && j == CharToIndex('O')) {
+			pairs = append(pairs, string([]byte{IndexToChar(i), IndexToChar(j)}))
+		}
+	}
+	return pairs
+}
+
+// Reflect sends the signal back, taking the reflector position
+// into account.
+func (r *Reflector) Reflect(letter int) int {
+	letter = r.Sequence[mod26(letter+r.Position)]
+	return mod26(letter - r.Position)
 }
 
 // Reflectors is a simple list of reflector pointers.
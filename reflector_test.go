@@ -0,0 +1,94 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// enigmaD is the Enigma D with rotors I II III at AAA and the reflector
+// at its position.
+func enigmaD(t *testing.T, reflector byte) *Enigma {
+	t.Helper()
+	e, err := NewEnigmaD(WithRotors(
+		RotorConfig{ID: "I", Start: 'A', Ring: 1},
+		RotorConfig{ID: "II", Start: 'A', Ring: 1},
+		RotorConfig{ID: "III", Start: 'A', Ring: 1},
+	), WithReflectorPosition(reflector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// Set to any position, the reflector still swaps the letters in pairs,
+// none with itself.
+func TestReflectorPositions(t *testing.T) {
+	r := *enigmaDReflectors.GetByID("D")
+	for position := 0; position < 26; position++ {
+		r.Position = position
+		for letter := 0; letter < 26; letter++ {
+			back := r.Reflect(letter)
+			if back == letter || r.Reflect(back) != letter {
+				t.Fatalf("at %c, the reflector takes %c to %c and back to %c",
+					IndexToChar(position), IndexToChar(letter), IndexToChar(back), IndexToChar(r.Reflect(back)))
+			}
+		}
+	}
+}
+
+// The position of the reflector is part of the key: it changes the
+// ciphertext, a machine set the same deciphers it, and the reflector
+// doesn't step while typing.
+func TestEnigmaDReflectorPosition(t *testing.T) {
+	plaintext := "HANDELSGESELLSCHAFTXBERLIN"
+	at := enigmaD(t, 'A').EncodeString(plaintext)
+	seen := map[string]byte{at: 'A'}
+	for position := byte('B'); position <= 'Z'; position++ {
+		e := enigmaD(t, position)
+		ciphertext := e.EncodeString(plaintext)
+		if other, ok := seen[ciphertext]; ok {
+			t.Errorf("the reflector at %c encodes the same as at %c: %s", position, other, ciphertext)
+		}
+		seen[ciphertext] = position
+		if e.Reflector.Position != CharToIndex(position) {
+			t.Errorf("the reflector set at %c moved to %c", position, IndexToChar(e.Reflector.Position))
+		}
+		if got := enigmaD(t, position).EncodeString(ciphertext); got != plaintext {
+			t.Errorf("at %c, %s deciphers to %s, expected %s", position, ciphertext, got, plaintext)
+		}
+	}
+}
+
+// kdPairs is a wiring of the UKW-D, in Bletchley Park notation.
+var kdPairs = []string{"AZ", "CX", "DV", "EU", "FT", "GS", "HR", "IQ", "JP", "KN", "LM", "WY"}
+
+// The known answer was worked out on a machine written apart from this
+// package: the KD rotors, the QWERTZ entry wheel, and the UKW-D with
+// B–O fixed.
+func TestEnigmaKD(t *testing.T) {
+	rotors := []RotorConfig{{ID: "III", Ring: 5, Start: 'R'}, {ID: "I", Ring: 12, Start: 'T'}, {ID: "II", Ring: 20, Start: 'X'}}
+	plaintext, ciphertext := "GEHEIMEKOMMANDOSACHEXSCHWEIZ", "VHNVTTWAJSTPVCVNYZOYOELLTSDP"
+	e, err := NewEnigmaKD(kdPairs, WithRotors(rotors...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.EncodeString(plaintext); got != ciphertext {
+		t.Errorf("%s encodes to %s, expected %s", plaintext, got, ciphertext)
+	}
+	e.Reset()
+	if got := e.EncodeString(ciphertext); got != plaintext {
+		t.Errorf("%s decodes to %s, expected %s", ciphertext, got, plaintext)
+	}
+	if got := e.Config().Reflector.Pairs; len(got) != 12 {
+		t.Errorf("the UKW-D is saved with the pairs %v", got)
+	}
+	if _, err := NewEnigmaKD(nil, WithRotors(rotors...)); err == nil {
+		t.Error("the KD is built without the UKW-D pairs")
+	}
+	if _, err := NewEnigmaKD(append(kdPairs[:11:11], "BW"), WithRotors(rotors...)); !errors.Is(err, ErrPlugConflict) {
+		t.Errorf("the fixed B–O pair is rewired: %v", err)
+	}
+	if _, err := NewEnigmaKD(kdPairs, WithRotors(rotors...), WithPlugboard("AB")); err == nil {
+		t.Error("the KD is built with a plugboard")
+	}
+}
@@ -0,0 +1,93 @@
+package enigma
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// REPLOptions set up RunREPL: whether the lampboard is drawn after
+// every keypress, and in which style.
+type REPLOptions struct {
+	Lampboard bool
+	Style     LampStyle
+}
+
+// replHelp lists the commands of RunREPL.
+const replHelp = `:set positions ABC  set the rotors
+:reset              set the rotors back to where they started
+:state              show the machine state
+:trace on|off       show every keypress
+:undo               take back the last keypress
+:quit               leave
+`
+
+// RunREPL runs an interactive session: the prompt shows the rotor
+// windows, every line typed is pressed key by key, and lines starting
+// with a colon are commands (see :help). It returns when the input
+// ends or on :quit; errors of the commands are shown, not returned.
+func RunREPL(rw io.ReadWriter, e *Enigma, options REPLOptions) error {
+	scanner := bufio.NewScanner(rw)
+	trace := false
+	for {
+		if _, err := fmt.Fprintf(rw, "%s> ", e.Positions()); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(rw)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		var out string
+		switch fields := strings.Fields(line); {
+		case len(fields) == 0:
+			continue
+		case !strings.HasPrefix(line, ":"):
+			out = replType(e, line, trace, options)
+		case fields[0] == ":quit" || fields[0] == ":q":
+			return nil
+		case fields[0] == ":help":
+			out = replHelp
+		case fields[0] == ":reset":
+			e.Reset()
+		case fields[0] == ":state":
+			out = e.DebugString()
+		case fields[0] == ":undo":
+			if err := e.UndoRune(); err != nil {
+				out = err.Error() + "\n"
+			}
+		case fields[0] == ":trace" && len(fields) == 2 && (fields[1] == "on" || fields[1] == "off"):
+			trace = fields[1] == "on"
+		case fields[0] == ":set" && len(fields) >= 3 && fields[1] == "positions":
+			if err := e.ResetTo(strings.Join(fields[2:], " ")); err != nil {
+				out = err.Error() + "\n"
+			}
+		default:
+			out = fmt.Sprintf("unknown command %q, see :help\n", line)
+		}
+		if _, err := io.WriteString(rw, out); err != nil {
+			return err
+		}
+	}
+}
+
+// replType presses the keys of the line, returning what the session
+// shows for them.
+func replType(e *Enigma, line string, trace bool, options REPLOptions) string {
+	clean, _, _ := Sanitize(line, NonAlphaStrip)
+	var b strings.Builder
+	lamps := make([]byte, len(clean))
+	for i := range clean {
+		lamps[i] = e.EncodeChar(clean[i])
+		if trace {
+			fmt.Fprintf(&b, "%c -> %c  %s\n", clean[i], lamps[i], e.Positions())
+		}
+		if options.Lampboard {
+			b.WriteString(RenderLampboard(rune(lamps[i]), options.Style) + "\n\n")
+		}
+	}
+	b.Write(lamps)
+	b.WriteByte('\n')
+	return b.String()
+}
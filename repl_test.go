@@ -0,0 +1,105 @@
+package enigma
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// script is a session typed in advance, with what it prints kept.
+type script struct {
+	in  io.Reader
+	out bytes.Buffer
+}
+
+func (s *script) Read(p []byte) (int, error)  { return s.in.Read(p) }
+func (s *script) Write(p []byte) (int, error) { return s.out.Write(p) }
+
+// A scripted session shows what the same calls on a machine of its own
+// do, keypress by keypress, with the trace on and an undo in between.
+func TestRunREPL(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &script{in: strings.NewReader("" +
+		"Hallo\n" +
+		":trace on\n" +
+		"ab\n" +
+		":undo\n" +
+		"\n" +
+		":state\n" +
+		":trace off\n" +
+		":set positions QEV\n" +
+		":set positions Q1V\n" +
+		"A\n" +
+		":reset\n" +
+		":bogus\n" +
+		":quit\n" +
+		"never typed\n")}
+	if err := RunREPL(session, e, REPLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	prompt := func() { fmt.Fprintf(&want, "%s> ", m.Positions()) }
+	prompt()
+	want.WriteString(m.EncodeString("HALLO") + "\n")
+	prompt()
+	prompt()
+	a := m.EncodeChar('A')
+	fmt.Fprintf(&want, "A -> %c  %s\n", a, m.Positions())
+	b := m.EncodeChar('B')
+	fmt.Fprintf(&want, "B -> %c  %s\n", b, m.Positions())
+	fmt.Fprintf(&want, "%c%c\n", a, b)
+	prompt()
+	if err := m.UndoRune(); err != nil {
+		t.Fatal(err)
+	}
+	prompt()
+	prompt()
+	want.WriteString(m.DebugString())
+	prompt()
+	prompt()
+	if err := m.ResetTo("QEV"); err != nil {
+		t.Fatal(err)
+	}
+	prompt()
+	want.WriteString(m.ResetTo("Q1V").Error() + "\n")
+	prompt()
+	want.WriteString(m.EncodeString("A") + "\n")
+	prompt()
+	m.Reset()
+	prompt()
+	want.WriteString("unknown command \":bogus\", see :help\n")
+	prompt()
+
+	if got := session.out.String(); got != want.String() {
+		t.Errorf("the session shows\n%s\nexpected\n%s", got, want.String())
+	}
+	if e.Positions() != "AAA" || e.Stats().Keypresses != 0 {
+		t.Errorf("after :reset the machine is at %s with %d keypresses", e.Positions(), e.Stats().Keypresses)
+	}
+}
+
+// The session ends with the input, and can draw the lampboard.
+func TestRunREPLLampboard(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &script{in: strings.NewReader("A")}
+	if err := RunREPL(session, e, REPLOptions{Lampboard: true, Style: LampPlain}); err != nil {
+		t.Fatal(err)
+	}
+	want := "AAA> " + RenderLampboard('B', LampPlain) + "\n\nB\nAAB> \n"
+	if got := session.out.String(); got != want {
+		t.Errorf("the session shows\n%q\nexpected\n%q", got, want)
+	}
+}
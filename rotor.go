@@ -6,14 +6,25 @@ package enigma
 // process, following the machine configuration. As a result, there
 // are millions of possible combinations, making brute-forcing attacks
 // on Enigma unfeasible (and even more so when the plugboard is used).
+//
+// Offset and Ring don't have to be between 0 and 25: any value is taken
+// around the alphabet, so an Offset of 27 is B, and of -3 is X.
+//
+// A Fixed rotor can be set to any position but never steps, like the
+// fourth rotor of the M4. DrivenBy, if set, is the slot of the rotor
+// driving this one, counting from 1 on the left. Introduced is the year
+// the rotor was issued, if known, for machines of an era (see WithEra).
 type Rotor struct {
 	ID          string
 	StraightSeq [26]int
 	ReverseSeq  [26]int
 	Turnover    []int
+	Introduced  int
 
-	Offset int
-	Ring   int
+	Offset   int
+	Ring     int
+	Fixed    bool
+	DrivenBy int
 }
 
 // NewRotor is a constructor for rotors, taking a mapping string
@@ -32,32 +43,50 @@ func NewRotor(mapping string, id string, turnovers string) *Rotor {
 	return r
 }
 
+// introduced sets the year the rotor was issued.
+func introduced(year int, r *Rotor) *Rotor {
+	r.Introduced = year
+	return r
+}
+
 // Move the rotor, shifting the offset by a given number.
 func (r *Rotor) move(offset int) {
-	r.Offset = (r.Offset + offset) % 26
+	r.Offset = mod26(r.Offset + offset)
 }
 
 // ShouldTurnOver checks if the current rotor position corresponds
 // to a notch that is supposed to move the next rotor.
 func (r *Rotor) ShouldTurnOver() bool {
 	for _, turnover := range r.Turnover {
-		if r.Offset == turnover {
+		if mod26(r.Offset) == turnover {
 			return true
 		}
 	}
 	return false
 }
 
+// TurnoverLetters returns the window letters at which the rotor drives
+// its neighbour, e.g. Q for rotor I ("Royal Flags Wave Kings Above").
+// The notches are on the ring, so the letters don't change with the
+// ring setting.
+func (r *Rotor) TurnoverLetters() []rune {
+	letters := make([]rune, len(r.Turnover))
+	for i, turnover := range r.Turnover {
+		letters[i] = rune(IndexToChar(turnover))
+	}
+	return letters
+}
+
 // Step through the rotor, performing the letter substitution depending
 // on the offset and direction.
 func (r *Rotor) Step(letter int, invert bool) int {
-	letter = (letter - r.Ring + r.Offset + 26) % 26
+	letter = mod26(letter - r.Ring + r.Offset)
 	if invert {
 		letter = r.ReverseSeq[letter]
 	} else {
 		letter = r.StraightSeq[letter]
 	}
-	letter = (letter + r.Ring - r.Offset + 26) % 26
+	letter = mod26(letter + r.Ring - r.Offset)
 	return letter
 }
 
@@ -65,10 +94,12 @@ func (r *Rotor) Step(letter int, invert bool) int {
 type Rotors []Rotor
 
 // GetByID takes a "name" of the rotor (e.g. "III") and returns the
-// Rotor pointer.
+// Rotor pointer. It's a copy, notches included, so changing it doesn't
+// change the list.
 func (rs *Rotors) GetByID(id string) *Rotor {
 	for _, rotor := range *rs {
 		if rotor.ID == id {
+			rotor.Turnover = append([]int(nil), rotor.Turnover...)
 			return &rotor
 		}
 	}
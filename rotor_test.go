@@ -0,0 +1,220 @@
+package enigma
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Offsets set out of the alphabet by hand go around it, so the machine
+// encodes as if they had been set in it.
+func TestOffsetsOutOfRange(t *testing.T) {
+	tests := []struct {
+		offset, same int
+	}{
+		{27, 1},
+		{-3, 23},
+		{260, 0},
+		{-26, 0},
+	}
+	for _, tt := range tests {
+		mutated, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference := mutated.Clone()
+		for i := range mutated.Rotors {
+			mutated.Rotors[i].Offset = tt.offset
+			reference.Rotors[i].Offset = tt.same
+		}
+		want := reference.EncodeString("ANGRIFFIMMORGENGRAUENXANGRIFFIMMORGENGRAUEN")
+		if got := mutated.EncodeString("ANGRIFFIMMORGENGRAUENXANGRIFFIMMORGENGRAUEN"); got != want {
+			t.Errorf("offset %d encodes to %s, expected %s as with offset %d", tt.offset, got, want, tt.same)
+		}
+		if mutated.Positions() != reference.Positions() {
+			t.Errorf("offset %d leaves the rotors at %s, expected %s", tt.offset, mutated.Positions(), reference.Positions())
+		}
+		for i := range mutated.Rotors {
+			mutated.Rotors[i].Offset = tt.offset
+		}
+		if got := mutated.DecodeString(want); got != "ANGRIFFIMMORGENGRAUENXANGRIFFIMMORGENGRAUEN" {
+			t.Errorf("offset %d decodes %s to %s", tt.offset, want, got)
+		}
+	}
+}
+
+// So does a reflector position set out of the alphabet by hand, which
+// is saved in the alphabet, too.
+func TestReflectorPositionOutOfRange(t *testing.T) {
+	for _, tt := range []struct{ position, same int }{{27, 1}, {-3, 23}, {260, 0}} {
+		mutated, reference := enigmaD(t, 'A'), enigmaD(t, 'A')
+		mutated.Reflector.Position, reference.Reflector.Position = tt.position, tt.same
+		if got, want := mutated.EncodeString("WETTERBERICHT"), reference.EncodeString("WETTERBERICHT"); got != want {
+			t.Errorf("reflector position %d encodes to %s, expected %s as at %d", tt.position, got, want, tt.same)
+		}
+		config := mutated.Config()
+		if want := reference.Config().Reflector.Start; config.Reflector.Start != want {
+			t.Errorf("reflector position %d is saved as %q, expected %q", tt.position, config.Reflector.Start, want)
+		}
+		rebuilt, err := EnigmaD.New(config)
+		if err != nil {
+			t.Fatalf("reflector position %d: the saved configuration %s doesn't build: %v", tt.position, config, err)
+		}
+		if got, want := rebuilt.EncodeString("WETTERBERICHT"), mutated.EncodeString("WETTERBERICHT"); got != want {
+			t.Errorf("reflector position %d: the rebuilt machine encodes to %s, expected %s", tt.position, got, want)
+		}
+	}
+}
+
+// A ring set out of range by hand is saved in range, so that the saved
+// configuration builds the same machine again.
+func TestRingOutOfRangeConfig(t *testing.T) {
+	for _, ring := range []int{27, -1, 52} {
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Rotors[2].Ring = ring
+		config := e.Config()
+		if got, want := config.Rotors[2].Ring, mod26(ring)+1; got != want {
+			t.Errorf("ring %d is saved as %d, expected %d", ring, got, want)
+		}
+		rebuilt, err := Generic.New(config)
+		if err != nil {
+			t.Fatalf("ring %d: the saved configuration doesn't build: %v", ring, err)
+		}
+		if got, want := rebuilt.EncodeString("WETTERBERICHT"), e.EncodeString("WETTERBERICHT"); got != want {
+			t.Errorf("ring %d: the rebuilt machine encodes to %s, expected %s", ring, got, want)
+		}
+	}
+}
+
+// A machine with every rotor fixed never steps, with either mechanism:
+// it's a single substitution, the same for every keypress.
+func TestAllRotorsFixed(t *testing.T) {
+	for _, stepping := range []string{"lever", "gear"} {
+		rotors := rotorsAt("I II III", "QEV")
+		for i := range rotors {
+			rotors[i].Fixed = true
+		}
+		e, err := NewMachine(WithRotors(rotors...), WithReflector("B"), WithStepping(stepping))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period := e.Period(); period != 1 {
+			t.Errorf("%s: the period is %d, expected 1", stepping, period)
+		}
+		first := e.EncodeChar('A')
+		for i := 0; i < 100; i++ {
+			if lamp := e.EncodeChar('A'); lamp != first {
+				t.Fatalf("%s: A lights %c on keypress %d, and %c on the first", stepping, lamp, i+2, first)
+			}
+		}
+		if e.Positions() != "QEV" {
+			t.Errorf("%s: the fixed rotors moved to %s", stepping, e.Positions())
+		}
+	}
+}
+
+// Fixed slots are saved, read back, and shown in brackets.
+func TestFixedRotorsConfig(t *testing.T) {
+	rotors := rotorsAt("I II III IV", "AAAA")
+	rotors[0].Fixed, rotors[1].Fixed = true, true
+	e, err := NewMachine(WithRotors(rotors...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(e.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	for i, rotor := range config.Rotors {
+		if rotor.Fixed != (i < 2) {
+			t.Errorf("slot %d is read back fixed %t from %s", i+1, rotor.Fixed, data)
+		}
+	}
+	if got, want := config.String(), "B [I] [II] III IV 01 01 01 01 AAAA"; got != want {
+		t.Errorf("the configuration is %s, expected %s", got, want)
+	}
+	m4, err := NewEnigmaM4(WithRotors(rotorsAt("Beta II IV I", "AAAA")...), WithReflector("B-thin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m4.Rotors[0].Fixed || m4.Rotors[1].Fixed {
+		t.Error("the M4 doesn't have just its thin rotor fixed")
+	}
+}
+
+// A Beta that steps makes the M4 an odometer of four rotors. Rotor I is
+// then in the middle and double steps too, skipping a position of every
+// turn of its own, so the period grows 25 times rather than 26; without
+// the double step, it's the full 26.
+func TestSteppingBeta(t *testing.T) {
+	m4 := Config{Rotors: rotorsAt("Beta I II III", "AAAA"), Reflector: ReflectorConfig{ID: "B-thin"}}
+	standard, err := M4.New(m4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		drivenBy     int
+		noDoubleStep bool
+		times        int
+	}{
+		{"driven by its neighbour", 0, false, 25},
+		{"driven by slot 2", 2, false, 25},
+		{"no double step", 0, true, 26},
+	}
+	for _, tt := range tests {
+		config := m4
+		config.Rotors = rotorsAt("Beta I II III", "AAAA")
+		config.Rotors[0].DrivenBy = tt.drivenBy
+		config.NoDoubleStep = tt.noDoubleStep
+		e, err := Generic.New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := standard.Period()
+		if tt.noDoubleStep {
+			config.Rotors[0].Fixed = true
+			odometer, err := Generic.New(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			base = odometer.Period()
+		}
+		if period := e.Period(); period != tt.times*base {
+			t.Errorf("%s: the period is %d, expected %d times %d", tt.name, period, tt.times, base)
+		}
+		data, err := json.Marshal(e.Config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Config
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatal(err)
+		}
+		if back.Rotors[0].Fixed || back.Rotors[0].DrivenBy != tt.drivenBy || back.NoDoubleStep != tt.noDoubleStep {
+			t.Errorf("%s: the stepping is read back from %s as %+v", tt.name, data, back)
+		}
+	}
+
+	// The M4 itself keeps Beta fixed, and its stepping can't be changed
+	// but on purpose.
+	config := m4
+	config.Rotors = rotorsAt("Beta I II III", "AAAA")
+	e, err := M4.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Rotors[0].Fixed || e.Period() != 16900 {
+		t.Errorf("the M4 steps its Beta, with a period of %d", e.Period())
+	}
+	config.Rotors[0].DrivenBy = 2
+	if _, err := M4.New(config); err == nil {
+		t.Error("the stepping of the M4 is changed without AllowNonHistorical")
+	}
+}
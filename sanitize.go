@@ -0,0 +1,180 @@
+package enigma
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// NonAlphaPolicy tells what Sanitize does with everything that isn't
+// a letter.
+type NonAlphaPolicy int
+
+// Policies for characters other than letters: NonAlphaSpaceToX writes
+// spaces as X, the way operators did, and strips the rest, as well as
+// any leading and trailing whitespace. NonAlphaStrip strips them all,
+// and NonAlphaReject doesn't accept them at all. NonAlphaPreserve and
+// NonAlphaSubstitute are only for the machines over an alphabet (see
+// AlphabetMachine.EncodeText): the first passes the characters the
+// alphabet hasn't got through as they are, the other has its filler
+// encoded instead. The pipes take NonAlphaPreserve too (see
+// NewEncoderWriter).
+const (
+	NonAlphaSpaceToX NonAlphaPolicy = iota
+	NonAlphaStrip
+	NonAlphaReject
+	NonAlphaPreserve
+	NonAlphaSubstitute
+)
+
+// ChangeKind tells what Sanitize did to a character.
+type ChangeKind int
+
+// Kinds of changes.
+const (
+	Stripped ChangeKind = iota
+	Substituted
+	Uppercased
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Stripped:
+		return "stripped"
+	case Substituted:
+		return "substituted"
+	case Uppercased:
+		return "uppercased"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a character Sanitize changed: its byte index in the
+// original text, the character itself, and what it was replaced with
+// (nothing, if it was stripped).
+type Change struct {
+	Index       int
+	Rune        rune
+	Kind        ChangeKind
+	Replacement string
+}
+
+// SanitizeReport lists the changes made by Sanitize, in order.
+type SanitizeReport struct {
+	Changes []Change
+}
+
+// umlauts are written out the way German operators did.
+var umlauts = map[rune]string{
+	'Ä': "AE", 'Ö': "OE", 'Ü': "UE", 'ä': "AE", 'ö': "OE", 'ü': "UE", 'ß': "SS",
+}
+
+// Sanitize prepares a text to be encoded: letters are uppercased,
+// umlauts are written out (Ä is AE, ß is SS), and everything else is
+// dealt with according to the policy. The report tells what was done
+// to which characters, so it can be shown before anything is encoded.
+func Sanitize(s string, policy NonAlphaPolicy) (string, SanitizeReport, error) {
+	var clean strings.Builder
+	z := sanitizer{policy: policy, out: &clean}
+	for i, r := range s {
+		if err := z.feed(i, r); err != nil {
+			return "", z.report, err
+		}
+	}
+	z.finish()
+	return clean.String(), z.report, nil
+}
+
+// sanitizer does the work of Sanitize a character at a time, so that
+// streams can be sanitized the same way. Whitespace is held back until
+// it's known not to be trailing.
+type sanitizer struct {
+	policy  NonAlphaPolicy
+	out     io.ByteWriter
+	report  SanitizeReport
+	started bool
+	pending []Change
+}
+
+// feed sanitizes the character at the index of the original text.
+func (z *sanitizer) feed(i int, r rune) error {
+	switch {
+	case z.policy < NonAlphaSpaceToX || z.policy > NonAlphaSubstitute:
+		return fmt.Errorf("unknown policy %d", z.policy)
+	case z.policy > NonAlphaReject:
+		return fmt.Errorf("policy %d is for the machines over an alphabet", z.policy)
+	case z.policy == NonAlphaReject && !isLetter(r):
+		return &CharacterError{Rune: r, Index: i}
+	case unicode.IsSpace(r):
+		if z.started && z.policy == NonAlphaSpaceToX {
+			z.pending = append(z.pending, Change{Index: i, Rune: r})
+		} else {
+			z.change(i, r, Stripped, "")
+		}
+		return nil
+	}
+	z.flush(true)
+	z.started = true
+	switch {
+	case r >= 'A' && r <= 'Z':
+		z.out.WriteByte(byte(r))
+	case r >= 'a' && r <= 'z':
+		z.out.WriteByte(byte(unicode.ToUpper(r)))
+		z.change(i, r, Uppercased, string(unicode.ToUpper(r)))
+	case umlauts[r] != "":
+		for j := 0; j < len(umlauts[r]); j++ {
+			z.out.WriteByte(umlauts[r][j])
+		}
+		z.change(i, r, Substituted, umlauts[r])
+	default:
+		z.change(i, r, Stripped, "")
+	}
+	return nil
+}
+
+// finish strips the trailing whitespace.
+func (z *sanitizer) finish() {
+	z.flush(false)
+}
+
+// flush deals with the whitespace held back: spaces are written as X
+// if it's followed by something, everything else is stripped.
+func (z *sanitizer) flush(followed bool) {
+	for _, c := range z.pending {
+		if followed && c.Rune == ' ' {
+			z.out.WriteByte('X')
+			z.change(c.Index, c.Rune, Substituted, "X")
+		} else {
+			z.change(c.Index, c.Rune, Stripped, "")
+		}
+	}
+	z.pending = z.pending[:0]
+}
+
+func (z *sanitizer) change(i int, r rune, kind ChangeKind, replacement string) {
+	z.report.Changes = append(z.report.Changes, Change{i, r, kind, replacement})
+}
+
+// isLetter tells if the character is kept by every policy.
+func isLetter(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || umlauts[r] != ""
+}
+
+// EncodeText sanitizes the text (see Sanitize) and encodes it. An empty
+// text encodes to nothing; a text with nothing left after sanitizing is
+// ErrEmptyAfterSanitize. The rotors don't move in either case.
+func (e *Enigma) EncodeText(text string, policy NonAlphaPolicy) (string, error) {
+	if err := e.checkSeal(); err != nil {
+		return "", err
+	}
+	clean, report, err := Sanitize(text, policy)
+	if err != nil {
+		return "", err
+	}
+	e.logSanitize(report)
+	if clean == "" && text != "" {
+		return "", ErrEmptyAfterSanitize
+	}
+	return e.EncodeString(clean), nil
+}
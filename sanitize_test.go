@@ -0,0 +1,192 @@
+package enigma
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The report tells what happened to every character that didn't make
+// it into the text as it was, at its byte index.
+func TestSanitizeReport(t *testing.T) {
+	text := "Grüße\r\n2 Schiffe 🚢 Ä\r\n"
+	clean, report, err := Sanitize(text, NonAlphaSpaceToX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "GRUESSEXSCHIFFEXXAE"; clean != want {
+		t.Errorf("%q is sanitized to %s, expected %s", text, clean, want)
+	}
+	want := []Change{
+		{1, 'r', Uppercased, "R"},
+		{2, 'ü', Substituted, "UE"},
+		{4, 'ß', Substituted, "SS"},
+		{6, 'e', Uppercased, "E"},
+		{7, '\r', Stripped, ""},
+		{8, '\n', Stripped, ""},
+		{9, '2', Stripped, ""},
+		{10, ' ', Substituted, "X"},
+		{12, 'c', Uppercased, "C"},
+		{13, 'h', Uppercased, "H"},
+		{14, 'i', Uppercased, "I"},
+		{15, 'f', Uppercased, "F"},
+		{16, 'f', Uppercased, "F"},
+		{17, 'e', Uppercased, "E"},
+		{18, ' ', Substituted, "X"},
+		{19, '🚢', Stripped, ""},
+		{23, ' ', Substituted, "X"},
+		{24, 'Ä', Substituted, "AE"},
+		{26, '\r', Stripped, ""},
+		{27, '\n', Stripped, ""},
+	}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Errorf("the report is\n%v\nexpected\n%v", report.Changes, want)
+	}
+}
+
+func TestSanitizePolicies(t *testing.T) {
+	tests := []struct {
+		text   string
+		policy NonAlphaPolicy
+		want   string
+	}{
+		{"  Zug 7 fährt\r\n", NonAlphaSpaceToX, "ZUGXXFAEHRT"},
+		{"  Zug 7 fährt\r\n", NonAlphaStrip, "ZUGFAEHRT"},
+		{"Zeile eins\r\nZeile zwei", NonAlphaSpaceToX, "ZEILEXEINSZEILEXZWEI"},
+		{"Ölfeld", NonAlphaReject, "OELFELD"},
+	}
+	for _, tt := range tests {
+		got, _, err := Sanitize(tt.text, tt.policy)
+		if err != nil || got != tt.want {
+			t.Errorf("%q is sanitized to %s (%v), expected %s", tt.text, got, err, tt.want)
+		}
+	}
+	_, _, err := Sanitize("Grüße 🚢", NonAlphaReject)
+	var character *CharacterError
+	if !errors.As(err, &character) || character.Rune != ' ' || character.Index != 7 {
+		t.Errorf("the space is rejected with %v", err)
+	}
+	// The policies of the machines over an alphabet are told from the
+	// ones that don't exist.
+	for _, tt := range []struct {
+		policy NonAlphaPolicy
+		want   string
+	}{
+		{NonAlphaPreserve, "policy 3 is for the machines over an alphabet"},
+		{NonAlphaSubstitute, "policy 4 is for the machines over an alphabet"},
+		{NonAlphaPolicy(42), "unknown policy 42"},
+		{NonAlphaPolicy(-1), "unknown policy -1"},
+	} {
+		if _, _, err := Sanitize("Zug", tt.policy); err == nil || err.Error() != tt.want {
+			t.Errorf("policy %d is refused with %v, expected %s", tt.policy, err, tt.want)
+		}
+	}
+}
+
+// Encoding goes through Sanitize, so the preview is what gets encoded.
+func TestEncodeTextSanitizes(t *testing.T) {
+	text := "Grüße\r\n2 Schiffe 🚢 Ä\r\n"
+	clean, _, err := Sanitize(text, NonAlphaSpaceToX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := e.Clone().EncodeString(clean)
+	if got, err := e.EncodeText(text, NonAlphaSpaceToX); err != nil || got != want {
+		t.Errorf("%q encodes to %s (%v), expected %s", text, got, err, want)
+	}
+	if _, err := e.EncodeText(" 7🚢\r\n", NonAlphaSpaceToX); err != ErrEmptyAfterSanitize {
+		t.Errorf("a text of no letters is encoded with %v", err)
+	}
+}
+
+// Empty input encodes to nothing, and whitespace alone to nothing with
+// ErrEmptyAfterSanitize (or a rejection), whichever way it's encoded,
+// and the rotors don't move.
+func TestEmptyInput(t *testing.T) {
+	tests := []struct {
+		text   string
+		policy NonAlphaPolicy
+		want   error
+	}{
+		{"", NonAlphaSpaceToX, nil},
+		{"", NonAlphaStrip, nil},
+		{"", NonAlphaReject, nil},
+		{"   \n  ", NonAlphaSpaceToX, ErrEmptyAfterSanitize},
+		{"   \n  ", NonAlphaStrip, ErrEmptyAfterSanitize},
+		{"   \n  ", NonAlphaReject, &CharacterError{}},
+	}
+	check := func(how string, text string, policy NonAlphaPolicy, got string, err, want error) {
+		t.Helper()
+		var character *CharacterError
+		switch {
+		case got != "":
+			t.Errorf("%s: %q encodes to %s with policy %d", how, text, got, policy)
+		case want == nil && err != nil, want == ErrEmptyAfterSanitize && err != want:
+			t.Errorf("%s: %q encodes with %v with policy %d, expected %v", how, text, err, policy, want)
+		case want != nil && want != ErrEmptyAfterSanitize && !errors.As(err, &character):
+			t.Errorf("%s: %q encodes with %v with policy %d, expected a rejection", how, text, err, policy)
+		}
+	}
+	for _, tt := range tests {
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.EncodeText(tt.text, tt.policy)
+		check("EncodeText", tt.text, tt.policy, got, err, tt.want)
+		var out bytes.Buffer
+		_, err = e.EncodeStream(strings.NewReader(tt.text), &out, StreamOptions{Policy: tt.policy})
+		check("EncodeStream", tt.text, tt.policy, out.String(), err, tt.want)
+		if tt.text == "" {
+			check("EncodeString", tt.text, tt.policy, e.EncodeString(tt.text), nil, nil)
+		}
+		if e.Positions() != "AAA" {
+			t.Errorf("%q with policy %d moves the rotors to %s", tt.text, tt.policy, e.Positions())
+		}
+	}
+}
+
+// A zero-length write to the encoding writer writes nothing, not even
+// an empty write, and a whitespace-only one skipped writes no letters.
+func TestEncoderWriterEmpty(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var writes [][]byte
+	w, err := NewEncoderWriter(e, writerFunc(func(p []byte) (int, error) {
+		writes = append(writes, append([]byte(nil), p...))
+		return len(p), nil
+	}), NonAlphaStrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := w.Write(nil); n != 0 || err != nil {
+		t.Errorf("the empty write writes %d bytes with %v", n, err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("the empty write goes through as %q", writes)
+	}
+	if n, err := w.Write([]byte("   \n  ")); n != 6 || err != nil {
+		t.Errorf("the whitespace is written as %d bytes with %v", n, err)
+	}
+	for _, p := range writes {
+		if len(p) != 0 {
+			t.Errorf("the whitespace encodes to %q", p)
+		}
+	}
+	if e.Positions() != "AAA" {
+		t.Errorf("the empty writes move the rotors to %s", e.Positions())
+	}
+}
+
+// writerFunc turns a function into an io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
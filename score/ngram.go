@@ -0,0 +1,149 @@
+package score
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxN is the longest n-gram a table can have: 26^4 log-probabilities
+// are still small enough to keep in a slice.
+const maxN = 4
+
+// NGrams rates a text by the n-grams of a language: the average log
+// probability (base 10) of the n-grams of its letters, so that texts of
+// any length compare. N-grams the table doesn't have get the floor, a
+// tenth of the least likely one it does. Anything but capital letters
+// is skipped.
+type NGrams struct {
+	name  string
+	n     int
+	logs  []float64
+	floor float64
+}
+
+// NewNGrams builds a scorer from the counts (or frequencies, it doesn't
+// matter which) of the n-grams, all of the same length, from 1 to 4.
+func NewNGrams(name string, counts map[string]float64) (*NGrams, error) {
+	n, total := 0, 0.0
+	for gram, count := range counts {
+		if n == 0 {
+			n = len(gram)
+		}
+		if len(gram) != n || n > maxN {
+			return nil, fmt.Errorf(`n-grams should all be 1 to %d letters long, with %d, got "%s"`, maxN, n, gram)
+		}
+		if count <= 0 {
+			return nil, fmt.Errorf(`n-gram "%s" should have a positive count, got %g`, gram, count)
+		}
+		total += count
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("the table of %s is empty", name)
+	}
+	g := &NGrams{name: name, n: n, logs: make([]float64, power(26, n)), floor: math.Inf(1)}
+	// An n-gram given in small letters and in capitals counts as one.
+	summed := make(map[int]float64, len(counts))
+	for gram, count := range counts {
+		index, ok := g.index([]byte(strings.ToUpper(gram)))
+		if !ok {
+			return nil, fmt.Errorf(`n-grams should be letters, got "%s"`, gram)
+		}
+		summed[index] += count
+	}
+	for _, count := range summed {
+		g.floor = math.Min(g.floor, math.Log10(count/total)-1)
+	}
+	for i := range g.logs {
+		g.logs[i] = g.floor
+	}
+	for index, count := range summed {
+		g.logs[index] = math.Log10(count / total)
+	}
+	return g, nil
+}
+
+// LoadNGrams reads a table of n-grams from a text with an n-gram and its
+// count on every line, e.g. "TION 13168375", the way the quadgram tables
+// of English and German going around are written.
+func LoadNGrams(name string, r io.Reader) (*NGrams, error) {
+	counts := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`line %d should be an n-gram and its count, got "%s"`, line, scanner.Text())
+		}
+		count, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		counts[fields[0]] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewNGrams(name, counts)
+}
+
+// mustNGrams is NewNGrams for the tables known to be right.
+func mustNGrams(name string, counts map[string]float64) *NGrams {
+	g, err := NewNGrams(name, counts)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Name returns the name of the table.
+func (g *NGrams) Name() string { return g.name }
+
+// N returns the length of the n-grams.
+func (g *NGrams) N() int { return g.n }
+
+// Score returns the average log probability of the n-grams of the text,
+// or minus infinity if it's too short to have any.
+func (g *NGrams) Score(text []byte) float64 {
+	letters := make([]byte, 0, len(text))
+	for _, letter := range text {
+		if letter >= 'A' && letter <= 'Z' {
+			letters = append(letters, letter)
+		}
+	}
+	if len(letters) < g.n {
+		return math.Inf(-1)
+	}
+	sum := 0.0
+	for i := 0; i+g.n <= len(letters); i++ {
+		index, _ := g.index(letters[i : i+g.n])
+		sum += g.logs[index]
+	}
+	return sum / float64(len(letters)-g.n+1)
+}
+
+// index returns the place of the n-gram in the table.
+func (g *NGrams) index(gram []byte) (int, bool) {
+	index := 0
+	for _, letter := range gram {
+		if letter < 'A' || letter > 'Z' {
+			return 0, false
+		}
+		index = index*26 + int(letter-'A')
+	}
+	return index, true
+}
+
+// power returns base to the power of n.
+func power(base, n int) int {
+	p := 1
+	for i := 0; i < n; i++ {
+		p *= base
+	}
+	return p
+}
@@ -0,0 +1,120 @@
+// Package score rates candidate decrypts by how much they look like
+// language rather than noise, so that searches over the key space can
+// tell the right settings from the rest. Higher is better, always.
+package score
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Scorer rates a text. Name tells which scorer it is, e.g. in reports.
+type Scorer interface {
+	Score(text []byte) float64
+	Name() string
+}
+
+// funcScorer is a Scorer made of a function.
+type funcScorer struct {
+	name string
+	f    func(text []byte) float64
+}
+
+func (s *funcScorer) Score(text []byte) float64 { return s.f(text) }
+func (s *funcScorer) Name() string              { return s.name }
+
+// Func turns a function into a Scorer.
+func Func(name string, f func(text []byte) float64) Scorer {
+	return &funcScorer{name, f}
+}
+
+// FromString turns a function taking a string, the way text is handled
+// in the enigma package, into a Scorer.
+func FromString(name string, f func(text string) float64) Scorer {
+	return Func(name, func(text []byte) float64 { return f(string(text)) })
+}
+
+// IoC rates the text by its index of coincidence: the chance that two
+// letters picked from it at random are the same, about 0.076 for German
+// and 0.038 for noise. It's cheap and doesn't care which language or
+// letters it is, which makes it good for a coarse search.
+var IoC = Func("ioc", func(text []byte) float64 {
+	var counts [26]int
+	n := 0
+	for _, letter := range text {
+		if letter >= 'A' && letter <= 'Z' {
+			counts[letter-'A']++
+			n++
+		}
+	}
+	if n < 2 {
+		return 0
+	}
+	sum := 0
+	for _, count := range counts {
+		sum += count * (count - 1)
+	}
+	return float64(sum) / float64(n*(n-1))
+})
+
+// Weighted adds up the scores of the scorers, each times its weight.
+func Weighted(weights map[Scorer]float64) Scorer {
+	return Func("weighted", func(text []byte) float64 {
+		total := 0.0
+		for s, weight := range weights {
+			total += weight * s.Score(text)
+		}
+		return total
+	})
+}
+
+// Staged rates the text with the fine scorer, but only if it scores at
+// least the threshold with the coarse one; anything else is minus
+// infinity. With a cheap coarse scorer, most candidates of a search
+// never get to the expensive one.
+func Staged(coarse, fine Scorer, threshold float64) Scorer {
+	return Func("staged("+coarse.Name()+", "+fine.Name()+")", func(text []byte) float64 {
+		if coarse.Score(text) < threshold {
+			return math.Inf(-1)
+		}
+		return fine.Score(text)
+	})
+}
+
+// cacheSize is how many scores Cached remembers before starting over.
+const cacheSize = 1 << 16
+
+// cachedScorer remembers the scores by the hash of the text.
+type cachedScorer struct {
+	Scorer
+	mu     sync.Mutex
+	scores map[uint64]float64
+}
+
+// Cached remembers the scores of the last texts it was given, for hill
+// climbing, which rates the same texts over and over. Texts are told
+// apart by a 64-bit hash; it's safe to use from several goroutines.
+func Cached(s Scorer) Scorer {
+	return &cachedScorer{Scorer: s, scores: make(map[uint64]float64)}
+}
+
+func (c *cachedScorer) Score(text []byte) float64 {
+	h := fnv.New64a()
+	h.Write(text)
+	key := h.Sum64()
+	c.mu.Lock()
+	score, ok := c.scores[key]
+	c.mu.Unlock()
+	if ok {
+		return score
+	}
+	score = c.Scorer.Score(text)
+	c.mu.Lock()
+	if len(c.scores) >= cacheSize {
+		c.scores = make(map[uint64]float64)
+	}
+	c.scores[key] = score
+	c.mu.Unlock()
+	return score
+}
@@ -0,0 +1,129 @@
+package score
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// constant is a scorer giving every text the same score, counting the
+// texts it was given.
+type constant struct {
+	score float64
+	calls int
+}
+
+func (c *constant) Score([]byte) float64 { c.calls++; return c.score }
+func (c *constant) Name() string         { return "constant" }
+
+func TestIoC(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"AABB", 4.0 / 12},
+		{"AA BB, aa", 4.0 / 12},
+		{"ABCD", 0},
+		{"A", 0},
+		{strings.Repeat("E", 10), 1},
+	}
+	for _, tt := range tests {
+		if got := IoC.Score([]byte(tt.text)); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%q scores %g, expected %g", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	two, three := &constant{score: 2}, &constant{score: 3}
+	s := Weighted(map[Scorer]float64{two: 0.5, three: -1})
+	if got := s.Score([]byte("TEXT")); got != -2 {
+		t.Errorf("0.5×2 - 1×3 is %g", got)
+	}
+	if got := Weighted(nil).Score([]byte("TEXT")); got != 0 {
+		t.Errorf("no scorers score %g", got)
+	}
+}
+
+// The fine scorer only sees the texts passing the coarse one.
+func TestStaged(t *testing.T) {
+	length := FromString("length", func(text string) float64 { return float64(len(text)) })
+	fine := &constant{score: 7}
+	s := Staged(length, fine, 5)
+	if got := s.Score([]byte("VIER")); !math.IsInf(got, -1) || fine.calls != 0 {
+		t.Errorf("below the threshold, the text scores %g with %d calls of the fine scorer", got, fine.calls)
+	}
+	if got := s.Score([]byte("FUENF")); got != 7 || fine.calls != 1 {
+		t.Errorf("at the threshold, the text scores %g with %d calls of the fine scorer", got, fine.calls)
+	}
+	if got := s.Name(); got != "staged(length, constant)" {
+		t.Errorf("the staged scorer is named %s", got)
+	}
+}
+
+// Cached asks the scorer once per text, from any number of goroutines.
+func TestCached(t *testing.T) {
+	counting := &constant{score: 1.5}
+	var mu sync.Mutex
+	s := Cached(Func("counting", func(text []byte) float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return counting.Score(text) + float64(len(text))
+	}))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if got := s.Score([]byte("WETTER")); got != 7.5 {
+					t.Errorf("WETTER scores %g", got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if counting.calls > 8 {
+		t.Errorf("WETTER was scored %d times", counting.calls)
+	}
+	calls := counting.calls
+	if got := s.Score([]byte("BERICHT")); got != 8.5 || counting.calls != calls+1 {
+		t.Errorf("BERICHT scores %g after %d calls", got, counting.calls-calls)
+	}
+	if s.Name() != "counting" {
+		t.Errorf("the cached scorer is named %s", s.Name())
+	}
+}
+
+func TestNGrams(t *testing.T) {
+	g, err := LoadNGrams("test", strings.NewReader("AB 3\n\nBA 1\nab 4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab, ba := math.Log10(7.0/8), math.Log10(1.0/8)
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"ABA", (ab + ba) / 2},
+		{"A-B", ab},
+		{"ABC", (ab + ba - 1) / 2},
+	}
+	for _, tt := range tests {
+		if got := g.Score([]byte(tt.text)); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s scores %g, expected %g", tt.text, got, tt.want)
+		}
+	}
+	if got := g.Score([]byte("A")); !math.IsInf(got, -1) {
+		t.Errorf("a text with no bigrams scores %g", got)
+	}
+	if g.N() != 2 || g.Name() != "test" {
+		t.Errorf("the table is %s of %d-grams", g.Name(), g.N())
+	}
+	for _, table := range []string{"AB 1\nABC 1", "AB 0", "", "A1 2", "ABCDE 1", "AB", "AB many"} {
+		if _, err := LoadNGrams("broken", strings.NewReader(table)); err == nil {
+			t.Errorf("%q is loaded", table)
+		}
+	}
+}
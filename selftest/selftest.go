@@ -0,0 +1,208 @@
+// Package selftest checks machines against what holds for any of them:
+// the ciphertext deciphers back to the plaintext, no letter is ever
+// enciphered to itself, and the fast paths of the enigma package
+// encode the same as the plain one. The checks take any settings and
+// any text, so they make fuzz targets as they are:
+//
+//	func FuzzEncode(f *testing.F) {
+//		f.Add([]byte("key"), "Angriff im Morgengrauen")
+//		f.Fuzz(func(t *testing.T, seed []byte, msg string) {
+//			settings, err := selftest.SeededSettings(&enigma.M4, seed)
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			if err := selftest.RoundTrip(settings, msg); err != nil {
+//				t.Fatal(err)
+//			}
+//			if err := selftest.Differential(settings, msg); err != nil {
+//				t.Fatal(err)
+//			}
+//		})
+//	}
+package selftest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/enigmatest"
+)
+
+// bulkLetters is how long Differential makes the text, so that
+// EncodeBytes looks the rotors up in its tables.
+const bulkLetters = 256
+
+// RoundTrip checks that the message, sanitized (see
+// enigma.SanitizePlaintext), comes back the same from a second machine
+// of the settings: enciphered again, as the reciprocal machines do, or
+// decoded on the others. No letter may be enciphered to itself, unless
+// the reflector has a letter wired to itself.
+func RoundTrip(settings enigma.Settings, msg string) error {
+	sender, err := settings.New()
+	if err != nil {
+		return err
+	}
+	receiver, err := settings.New()
+	if err != nil {
+		return err
+	}
+	plaintext := enigma.SanitizePlaintext(msg)
+	ciphertext := sender.EncodeString(plaintext)
+	var deciphered string
+	if receiver.IsReciprocal() {
+		deciphered = receiver.EncodeString(ciphertext)
+	} else {
+		deciphered = receiver.DecodeString(ciphertext)
+	}
+	if i := enigmatest.Divergence(plaintext, deciphered); i >= 0 {
+		return fmt.Errorf("the ciphertext deciphers differently at %d (%s)", i, settings)
+	}
+	if !receiver.IsReciprocal() || receiver.Reflector.Permutation().FixedPoints() != "" {
+		return nil
+	}
+	for i := range plaintext {
+		if plaintext[i] == ciphertext[i] {
+			return fmt.Errorf(`"%c" at %d is enciphered to itself (%s)`, plaintext[i], i, settings)
+		}
+	}
+	return nil
+}
+
+// Differential encodes the message, sanitized and repeated to be long
+// enough for the tables, on two machines of the settings: on one with
+// EncodeBytes, and on the other a key at a time, checking every step of
+// the rotors against the rules of the lever and gear mechanisms as
+// written down here. Any difference is an error.
+func Differential(settings enigma.Settings, msg string) error {
+	plaintext := []byte(enigma.SanitizePlaintext(msg))
+	if len(plaintext) == 0 {
+		return nil
+	}
+	for len(plaintext) < bulkLetters {
+		plaintext = append(plaintext, plaintext...)
+	}
+	fast, err := settings.New()
+	if err != nil {
+		return err
+	}
+	reference, err := settings.New()
+	if err != nil {
+		return err
+	}
+	want := make([]byte, len(plaintext))
+	for i, letter := range plaintext {
+		moves, known := referenceMoves(reference)
+		before := reference.Windows()
+		want[i] = reference.EncodeChar(letter)
+		after := reference.Windows()
+		for slot := range before {
+			if moved := before[slot] != after[slot]; known && moved != moves[slot] {
+				return fmt.Errorf("rotor %d at %c moved: %t, expected %t, on keypress %d (%s)",
+					slot+1, before[slot], moved, moves[slot], i+1, settings)
+			}
+		}
+	}
+	got := make([]byte, len(plaintext))
+	if n := fast.EncodeBytes(got, plaintext); n != len(plaintext) {
+		return fmt.Errorf("EncodeBytes encoded %d letters of %d (%s)", n, len(plaintext), settings)
+	}
+	if i := enigmatest.Divergence(string(want), string(got)); i >= 0 {
+		return fmt.Errorf(`EncodeBytes encodes keypress %d to "%c", expected "%c" (%s)`, i+1, got[i], want[i], settings)
+	}
+	if fast.Positions() != reference.Positions() {
+		return fmt.Errorf("EncodeBytes left the rotors at %s, expected %s (%s)", fast.Positions(), reference.Positions(), settings)
+	}
+	return nil
+}
+
+// SeededSettings picks random settings for the model (see
+// enigma.GenerateRandomConfig), the same ones for the same seed, so
+// that a fuzzer can explore them.
+func SeededSettings(model *enigma.Model, seed []byte) (enigma.Settings, error) {
+	return enigma.GenerateRandomConfig(model, &seeded{seed: sha256.Sum256(seed)})
+}
+
+// seeded is an endless stream of bytes drawn from a seed: the SHA-256
+// of the seed and a counter, one block after another.
+type seeded struct {
+	seed    [sha256.Size]byte
+	counter uint64
+	block   []byte
+}
+
+func (s *seeded) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		if len(s.block) == 0 {
+			var input [sha256.Size + 8]byte
+			copy(input[:], s.seed[:])
+			binary.BigEndian.PutUint64(input[sha256.Size:], s.counter)
+			s.counter++
+			block := sha256.Sum256(input[:])
+			s.block = block[:]
+		}
+		copied := copy(p[n:], s.block)
+		s.block = s.block[copied:]
+		n += copied
+	}
+	return len(p), nil
+}
+
+// referenceMoves tells which rotors move on the next keypress by the
+// rules of the lever and gear mechanisms, and false if the machine
+// steps some other way. A rotor is driven by the one its DrivenBy says,
+// or else by the closest stepping rotor to its right, and the rightmost
+// one moves on every keypress. With levers, a rotor moves when its
+// driver is at a notch, and also, unless the double step is turned off,
+// when it drives another rotor and is at a notch itself. With gears, a
+// rotor moves when its driver moves past a notch.
+func referenceMoves(e *enigma.Enigma) ([]bool, bool) {
+	gear := e.Stepping == enigma.GearStepping
+	if e.Stepping != nil && e.Stepping != enigma.LeverStepping && !gear {
+		return nil, false
+	}
+	driver := func(slot int) int {
+		if drivenBy := e.Rotors[slot].DrivenBy; drivenBy > 0 {
+			return drivenBy - 1
+		}
+		for i := slot + 1; i < len(e.Rotors); i++ {
+			if !e.Rotors[i].Fixed {
+				return i
+			}
+		}
+		return -1
+	}
+	drives := func(slot int) bool {
+		for i, rotor := range e.Rotors {
+			if i != slot && !rotor.Fixed && driver(i) == slot {
+				return true
+			}
+		}
+		return false
+	}
+	moves := make([]bool, len(e.Rotors))
+	decided := make([]bool, len(e.Rotors))
+	var decide func(slot int) bool
+	decide = func(slot int) bool {
+		if decided[slot] || e.Rotors[slot].Fixed {
+			return moves[slot]
+		}
+		decided[slot] = true
+		d := driver(slot)
+		switch {
+		case d < 0:
+			moves[slot] = true
+		case gear:
+			moves[slot] = decide(d) && e.Rotors[d].ShouldTurnOver()
+		default:
+			moves[slot] = e.Rotors[d].ShouldTurnOver() ||
+				(!e.NoDoubleStep && e.Rotors[slot].ShouldTurnOver() && drives(slot))
+		}
+		return moves[slot]
+	}
+	for slot := range e.Rotors {
+		decide(slot)
+	}
+	return moves, true
+}
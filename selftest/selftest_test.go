@@ -0,0 +1,82 @@
+package selftest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// FuzzEncode checks random settings of every model, picked with the
+// seed, against any message: it comes back the same, and the tables of
+// EncodeBytes agree with the plain keypresses.
+func FuzzEncode(f *testing.F) {
+	for i := range enigma.KnownModels {
+		f.Add(uint8(i), []byte("key"), "Angriff im Morgengrauen")
+		f.Add(uint8(i), []byte{byte(i)}, testvectors.Barbarossa.Plaintext)
+	}
+	f.Fuzz(func(t *testing.T, model uint8, seed []byte, msg string) {
+		m := enigma.KnownModels[int(model)%len(enigma.KnownModels)]
+		settings, err := SeededSettings(m, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RoundTrip(settings, msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := Differential(settings, msg); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// The real messages pass both checks, and settings that don't build
+// pass neither.
+func TestChecks(t *testing.T) {
+	for _, vector := range testvectors.Vectors {
+		settings := enigma.Settings{Config: vector.Config}
+		if err := RoundTrip(settings, vector.Plaintext); err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+		}
+		if err := Differential(settings, vector.Plaintext); err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+		}
+	}
+	broken := enigma.Settings{Config: enigma.Config{Rotors: []enigma.RotorConfig{{ID: "IX", Start: 'A', Ring: 1}}, Reflector: enigma.ReflectorConfig{ID: "B"}}}
+	if err := RoundTrip(broken, "WETTER"); err == nil {
+		t.Error("rotor IX goes round")
+	}
+	if err := Differential(broken, "WETTER"); err == nil {
+		t.Error("rotor IX encodes the same both ways")
+	}
+	if err := Differential(enigma.Settings{Config: testvectors.Barbarossa.Config}, "1940"); err != nil {
+		t.Errorf("a message of no letters differs: %v", err)
+	}
+}
+
+// The same seed gives the same settings, another seed others, and the
+// stream of a seed doesn't depend on how it's read.
+func TestSeededSettings(t *testing.T) {
+	a, err := SeededSettings(&enigma.M4, []byte("seed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := SeededSettings(&enigma.M4, []byte("seed"))
+	c, _ := SeededSettings(&enigma.M4, []byte("other"))
+	if a.String() != b.String() || a.String() == c.String() {
+		t.Errorf("the seeds give %s, %s, and %s", a, b, c)
+	}
+	whole, pieces := &seeded{}, &seeded{}
+	all := make([]byte, 100)
+	whole.Read(all)
+	var read []byte
+	for _, n := range []int{1, 31, 33, 7, 28} {
+		p := make([]byte, n)
+		pieces.Read(p)
+		read = append(read, p...)
+	}
+	if !bytes.Equal(all, read) {
+		t.Error("the stream read in pieces differs")
+	}
+}
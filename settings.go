@@ -0,0 +1,194 @@
+package enigma
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Settings is the saved form of a machine: the name of the model it was
+// built as, if any (see KnownModels), and its configuration with the
+// rotors where they are now (see Config), in the JSON form of Config
+// (MarshalSettings indents it more):
+//
+//	{
+//	  "model": "M4",
+//	  "config": {
+//	    "rotors": [
+//	      {"id": "Beta", "start": "V", "ring": 1, "fixed": true},
+//	      {"id": "II", "start": "J", "ring": 1},
+//	      {"id": "IV", "start": "N", "ring": 1},
+//	      {"id": "I", "start": "A", "ring": 22}
+//	    ],
+//	    "reflector": {"id": "B-thin"},
+//	    "entryWheel": "ABC",
+//	    "plugboard": ["AT", "BL", "DF", "GJ", "HM", "NW", "OP", "QY", "RZ", "VX"]
+//	  }
+//	}
+type Settings struct {
+	Model  string `json:"model,omitempty"`
+	Config Config `json:"config"`
+}
+
+// MarshalSettings saves the machine as it is now (see Settings), e.g.
+// to pick up a message where it was left, or to share a daily key.
+func (e *Enigma) MarshalSettings() ([]byte, error) {
+	return json.MarshalIndent(e.settings(), "", "  ")
+}
+
+// settings returns the machine as it is now, the way MarshalSettings
+// saves it.
+func (e *Enigma) settings() Settings {
+	settings := Settings{Config: e.Config()}
+	if e.model != nil && e.model != &Generic {
+		settings.Model = e.model.Name
+	}
+	return settings
+}
+
+// LoadSettings builds a machine saved by MarshalSettings, checking it
+// against its model again; without a model, it's built the way
+// NewMachine builds them.
+func LoadSettings(data []byte) (*Enigma, error) {
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return settings.New()
+}
+
+// New builds the machine of the settings, the way LoadSettings does.
+func (s Settings) New() (*Enigma, error) {
+	model := &Generic
+	if s.Model != "" {
+		if model = KnownModels.GetByName(s.Model); model == nil {
+			return nil, fmt.Errorf(`unknown model "%s"`, s.Model)
+		}
+	}
+	return model.New(s.Config)
+}
+
+// String returns the settings on a single line, the way Config.String
+// has them, after the name of the model and a colon if there's one,
+// e.g. "M4: B-thin [Beta] II IV I 01 01 01 22 VJNA AT BL". Without a
+// model, it's the line other simulators take, e.g. "B II IV V 01 01 01
+// AAA AB CD".
+func (s Settings) String() string {
+	if s.Model == "" {
+		return s.Config.String()
+	}
+	return s.Model + ": " + s.Config.String()
+}
+
+// ParseSettingsString reads settings written on a single line, the way
+// Settings.String writes them or other simulators do: the reflector, the
+// rotors from the left, a ring for each, as a number ("1", "01") or a
+// letter, the starting positions, and the plugboard pairs, e.g. "B II IV
+// V 1 1 1 AAA AB CD". The name of the model with a colon can come first,
+// the reflector can have a position (":V") and UKW-D pairs in
+// parentheses, fixed rotors can be in brackets, and "Uhr 27" at the end
+// plugs the Uhr in at the dial position. The settings are only read,
+// not checked against the model (see Model.Validate).
+func ParseSettingsString(line string) (Settings, error) {
+	var settings Settings
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+		settings.Model = strings.TrimSuffix(fields[0], ":")
+		if KnownModels.GetByName(settings.Model) == nil {
+			return Settings{}, fmt.Errorf(`unknown model "%s"`, settings.Model)
+		}
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return Settings{}, fmt.Errorf("settings should start with the reflector, got nothing")
+	}
+	reflector, fields, err := parseReflectorField(fields)
+	if err != nil {
+		return Settings{}, err
+	}
+	settings.Config.Reflector = reflector
+	if n := len(fields); n >= 2 && strings.EqualFold(fields[n-2], "Uhr") {
+		position, err := strconv.Atoi(fields[n-1])
+		if err != nil {
+			return Settings{}, fmt.Errorf(`the Uhr should be set to a number, got "%s"`, fields[n-1])
+		}
+		settings.Config.Uhr = &UhrConfig{Position: position}
+		fields = fields[:n-2]
+	}
+	for slots := 1; 2*slots < len(fields); slots++ {
+		if rotors, ok := parseRotorFields(fields, slots); ok {
+			settings.Config.Rotors = rotors
+			for _, pair := range fields[2*slots+1:] {
+				settings.Config.Plugboard = append(settings.Config.Plugboard, strings.ToUpper(pair))
+			}
+			if err := validatePlugs(settings.Config.Plugboard); err != nil {
+				return Settings{}, err
+			}
+			return settings, nil
+		}
+	}
+	return Settings{}, fmt.Errorf(`settings should have the rotors, their rings, and their positions after the reflector, got "%s"`, strings.Join(fields, " "))
+}
+
+// parseReflectorField reads the reflector off the fields, with its
+// position and UKW-D pairs if any, and returns the fields left.
+func parseReflectorField(fields []string) (ReflectorConfig, []string, error) {
+	field := fields[0]
+	fields = fields[1:]
+	var reflector ReflectorConfig
+	if open := strings.Index(field, "("); open >= 0 {
+		pairs := []string{field[open+1:]}
+		for !strings.HasSuffix(pairs[len(pairs)-1], ")") {
+			if len(fields) == 0 {
+				return ReflectorConfig{}, nil, fmt.Errorf(`reflector pairs should end with ")", got "%s"`, strings.Join(pairs, " "))
+			}
+			pairs, fields = append(pairs, fields[0]), fields[1:]
+		}
+		pairs[len(pairs)-1] = strings.TrimSuffix(pairs[len(pairs)-1], ")")
+		for _, pair := range pairs {
+			if pair != "" {
+				reflector.Pairs = append(reflector.Pairs, strings.ToUpper(pair))
+			}
+		}
+		field = field[:open]
+	}
+	if colon := strings.Index(field, ":"); colon >= 0 {
+		start := strings.ToUpper(field[colon+1:])
+		if len(start) != 1 || start[0] < 'A' || start[0] > 'Z' {
+			return ReflectorConfig{}, nil, fmt.Errorf(`reflector position should be a letter, got "%s"`, field[colon+1:])
+		}
+		reflector.Start = start[0]
+		field = field[:colon]
+	}
+	reflector.ID = field
+	return reflector, fields, nil
+}
+
+// parseRotorFields reads the rotors off the fields if there are that
+// many of them: their IDs, their rings, and a field with their starting
+// positions, followed by letter pairs only.
+func parseRotorFields(fields []string, slots int) ([]RotorConfig, bool) {
+	positions := strings.ToUpper(fields[2*slots])
+	if len(positions) != slots {
+		return nil, false
+	}
+	for _, pair := range fields[2*slots+1:] {
+		if len(pair) != 2 {
+			return nil, false
+		}
+	}
+	rotors := make([]RotorConfig, slots)
+	for i := range rotors {
+		id := fields[i]
+		if strings.HasPrefix(id, "[") && strings.HasSuffix(id, "]") {
+			id, rotors[i].Fixed = id[1:len(id)-1], true
+		}
+		rings, err := ParseRings(fields[slots+i])
+		if err != nil || len(rings) != 1 || positions[i] < 'A' || positions[i] > 'Z' {
+			return nil, false
+		}
+		rotors[i].ID, rotors[i].Ring, rotors[i].Start = id, rings[0], positions[i]
+	}
+	return rotors, true
+}
@@ -0,0 +1,109 @@
+package enigma
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// The lines other simulators take are read as the settings they stand
+// for, with the model, the reflector's position and pairs, the fixed
+// rotors, and the Uhr where they're there.
+func TestParseSettingsString(t *testing.T) {
+	ukwd := []string{"AC", "DE", "FG", "HI", "JK", "LM", "NP", "QR", "ST", "UV", "WX", "YZ"}
+	tests := []struct {
+		line string
+		want Settings
+	}{
+		{"B II IV V 1 1 1 AAA AB CD", Settings{Config: Config{
+			Reflector: ReflectorConfig{ID: "B"},
+			Rotors:    rotorsAt("II IV V", "AAA"),
+			Plugboard: []string{"AB", "CD"},
+		}}},
+		{"B II IV V 02 21 12 BLA av bs", Settings{Config: Config{
+			Reflector: ReflectorConfig{ID: "B"},
+			Rotors:    []RotorConfig{{ID: "II", Start: 'B', Ring: 2}, {ID: "IV", Start: 'L', Ring: 21}, {ID: "V", Start: 'A', Ring: 12}},
+			Plugboard: []string{"AV", "BS"},
+		}}},
+		{"M4: B-thin [Beta] II IV I A A A V vjna AT", Settings{Model: "M4", Config: Config{
+			Reflector: ReflectorConfig{ID: "B-thin"},
+			Rotors:    []RotorConfig{{ID: "Beta", Start: 'V', Ring: 1, Fixed: true}, {ID: "II", Start: 'J', Ring: 1}, {ID: "IV", Start: 'N', Ring: 1}, {ID: "I", Start: 'A', Ring: 22}},
+			Plugboard: []string{"AT"},
+		}}},
+		{"UKW-D(AC DE FG HI JK LM NP QR ST UV WX YZ) I II III 01 01 01 AAA Uhr 27", Settings{Config: Config{
+			Reflector: ReflectorConfig{ID: UKWD, Pairs: ukwd},
+			Rotors:    rotorsAt("I II III", "AAA"),
+			Uhr:       &UhrConfig{Position: 27},
+		}}},
+		{"T:V I II III 01 01 01 AAA", Settings{Config: Config{
+			Reflector: ReflectorConfig{ID: "T", Start: 'V'},
+			Rotors:    rotorsAt("I II III", "AAA"),
+		}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSettingsString(tt.line)
+		if err != nil {
+			t.Errorf("%s: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s is read as %+v, expected %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// What String writes, ParseSettingsString reads back, for random keys
+// of every model, and the machine built is the same.
+func TestSettingsStringRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(280))
+	for _, model := range KnownModels {
+		for i := 0; i < 20; i++ {
+			settings, err := GenerateRandomConfig(model, rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := settings.String()
+			read, err := ParseSettingsString(line)
+			if err != nil {
+				t.Errorf("%s: %v", line, err)
+				continue
+			}
+			if got := read.String(); got != line {
+				t.Errorf("%s is written back as %s", line, got)
+			}
+			a, err := settings.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := read.New()
+			if err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if got, want := b.EncodeString(receiveText), a.EncodeString(receiveText); got != want {
+				t.Errorf("%s: the machine read back encodes to %s, expected %s", line, got, want)
+			}
+		}
+	}
+	if got, want := (Settings{Config: classicConfig()}).String(), "B I II III 01 01 01 AAA"; got != want {
+		t.Errorf("the settings are written as %s, expected %s", got, want)
+	}
+}
+
+func TestParseSettingsStringErrors(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"Z3: B I II III 01 01 01 AAA",
+		"B I II III 01 01 01",
+		"B I II III 01 01 01 AA",
+		"B I II III 01 01 27 AAA",
+		"B I II III 01 01 01 AAA AB BC",
+		"B I II III 01 01 01 AAA ABC",
+		"B:1 I II III 01 01 01 AAA",
+		"UKW-D(AC DE I II III 01 01 01 AAA",
+		"B I II III 01 01 01 AAA Uhr XX",
+	} {
+		if settings, err := ParseSettingsString(line); err == nil {
+			t.Errorf("%q is read as %+v", line, settings)
+		}
+	}
+}
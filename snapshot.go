@@ -0,0 +1,67 @@
+package enigma
+
+// Snapshot is the state of a machine at some point, rotors and all,
+// that nothing can change. Any number of goroutines can fork machines
+// off the same one at once, each getting one of its own.
+type Snapshot struct {
+	e *Enigma
+}
+
+// Snapshot takes a snapshot of the machine as it is now. Hooks aren't
+// taken along (see Clone).
+func (e *Enigma) Snapshot() Snapshot {
+	return Snapshot{e: e.Clone()}
+}
+
+// Machine returns a new machine in the state of the snapshot.
+func (s Snapshot) Machine() *Enigma {
+	if s.e == nil {
+		return nil
+	}
+	return s.e.Clone()
+}
+
+// Config returns the configuration of the machine in the snapshot (see
+// Enigma.Config).
+func (s Snapshot) Config() Config {
+	if s.e == nil {
+		return Config{}
+	}
+	return s.Machine().Config()
+}
+
+// Positions returns what the rotor windows show in the snapshot (see
+// Enigma.Positions).
+func (s Snapshot) Positions() string {
+	if s.e == nil {
+		return ""
+	}
+	return s.e.Positions()
+}
+
+// Copy returns a copy of the rotors that shares nothing with the list,
+// the notches included.
+func (rs Rotors) Copy() Rotors {
+	c := make(Rotors, len(rs))
+	for i, rotor := range rs {
+		rotor.Turnover = append([]int(nil), rotor.Turnover...)
+		c[i] = rotor
+	}
+	return c
+}
+
+// Copy returns a copy of the reflectors.
+func (refs Reflectors) Copy() Reflectors {
+	return append(Reflectors(nil), refs...)
+}
+
+// RotorSet returns a copy of the rotors of the family (see
+// RotorFamilies), to be read or changed without changing the registry,
+// or nil if there's no such family.
+func RotorSet(family string) Rotors {
+	rotors := rotorFamilies[family]
+	if rotors == nil {
+		return nil
+	}
+	return rotors.Copy()
+}
@@ -0,0 +1,48 @@
+package enigma
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The zero Snapshot is of no machine: there's nothing to fork, and
+// nothing in the windows.
+func TestSnapshotZero(t *testing.T) {
+	var s Snapshot
+	if e := s.Machine(); e != nil {
+		t.Errorf("the zero snapshot forks a machine at %s, expected none", e.Positions())
+	}
+	if got := s.Positions(); got != "" {
+		t.Errorf("the zero snapshot is at %q, expected nothing", got)
+	}
+	if got := s.Config(); !reflect.DeepEqual(got, Config{}) {
+		t.Errorf("the zero snapshot is configured as %+v, expected the zero Config", got)
+	}
+}
+
+// The tables are handed out as copies, so changing one changes neither
+// the table nor the machines built from it.
+func TestTablesAreCopies(t *testing.T) {
+	want := EnigmaI.Rotors.GetByID("I").Turnover[0]
+	rotors := HistoricRotors()
+	rotors.GetByID("I").Turnover[0] = want + 1
+	rotors[0].ID = "X"
+	RotorFamilies()["historic"][0].ID = "X"
+	RotorSet("historic")[0].ID = "X"
+	if got := HistoricRotors(); got.GetByID("I") == nil || got.GetByID("I").Turnover[0] != want {
+		t.Errorf("rotor I of HistoricRotors is changed along with its copy")
+	}
+	if got := EnigmaI.Rotors.GetByID("I"); got == nil || got.Turnover[0] != want {
+		t.Errorf("rotor I of the Enigma I is changed along with a copy of HistoricRotors")
+	}
+	reflectors := HistoricReflectors()
+	reflectors[0].ID = "X"
+	if got := HistoricReflectors(); got.GetByID("A") == nil {
+		t.Errorf("reflector A of HistoricReflectors is changed along with its copy")
+	}
+	wheels := HistoricEntryWheels()
+	wheels[0].ID = "X"
+	if got := HistoricEntryWheels(); got.GetByID("ABC") == nil {
+		t.Errorf("entry wheel ABC of HistoricEntryWheels is changed along with its copy")
+	}
+}
@@ -0,0 +1,105 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MachineStats counts what the machine went through since it was built
+// or reset: the keys pressed, the letters encoded or decoded by them
+// (FastForward presses keys without any), the steps of every rotor (from
+// left to right, like the rotors themselves), and the double steps, i.e.
+// the times a rotor moved only because it was at its own notch. Stages
+// is the time the keypresses spent in every part of the machine, when
+// they're timed (see TimeStages).
+type MachineStats struct {
+	Keypresses  int
+	Letters     int
+	Steps       []int
+	DoubleSteps int
+	Stages      StageTimes
+}
+
+// StageTimes is the time spent stepping the rotors, in the plugboard
+// (with the keyboard, the Uhr and the entry wheel, both ways), in the
+// rotors (both ways too), and in the reflector.
+type StageTimes struct {
+	Stepping  time.Duration
+	Plugboard time.Duration
+	Rotors    time.Duration
+	Reflector time.Duration
+}
+
+// Total returns the time spent in all the stages.
+func (t StageTimes) Total() time.Duration {
+	return t.Stepping + t.Plugboard + t.Rotors + t.Reflector
+}
+
+// TimeStages turns the timing of the stages of every keypress on or off
+// (see MachineStats.Stages). The clock is read between the stages, which
+// takes longer than most of them, so the times are for telling which
+// stage is slow rather than how fast the machine is.
+func (e *Enigma) TimeStages(on bool) {
+	e.timeStages = on
+}
+
+// Stats returns the statistics of the machine.
+func (e *Enigma) Stats() MachineStats {
+	stats := e.stats
+	stats.Steps = make([]int, len(e.Rotors))
+	copy(stats.Steps, e.stats.Steps)
+	return stats
+}
+
+// DebugString describes the machine in detail: the configuration, the
+// current rotor positions, the turnovers (see TurnoverTable), and the
+// statistics.
+func (e *Enigma) DebugString() string {
+	stats := e.Stats()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-14s%s\n", "config:", e.Config())
+	fmt.Fprintf(&b, "%-14s%s\n", "positions:", e.Positions())
+	for _, turnover := range e.TurnoverTable() {
+		fmt.Fprintf(&b, "%-14s%s\n", fmt.Sprintf("slot %d:", turnover.Slot), turnover)
+	}
+	fmt.Fprintf(&b, "%-14s%d\n", "keypresses:", stats.Keypresses)
+	fmt.Fprintf(&b, "%-14s%d\n", "letters:", stats.Letters)
+	for i, rotor := range e.Rotors {
+		fmt.Fprintf(&b, "%-14s%d steps\n", fmt.Sprintf("rotor %s:", rotor.ID), stats.Steps[i])
+	}
+	fmt.Fprintf(&b, "%-14s%d\n", "double steps:", stats.DoubleSteps)
+	if stages := stats.Stages; stages.Total() > 0 {
+		fmt.Fprintf(&b, "%-14sstepping %s, plugboard %s, rotors %s, reflector %s\n", "stages:",
+			stages.Stepping, stages.Plugboard, stages.Rotors, stages.Reflector)
+	}
+	return b.String()
+}
+
+// stageTimer times the stages of a keypress: the stepping of the
+// rotors, then every part of the machine as signal tells its hook.
+type stageTimer struct {
+	stages  *StageTimes
+	started time.Time
+}
+
+// stepped adds the time since the keypress started to the stepping.
+func (t *stageTimer) stepped() {
+	now := time.Now()
+	t.stages.Stepping += now.Sub(t.started)
+	t.started = now
+}
+
+// lap adds the time since the last part to the stage of this one.
+func (t *stageTimer) lap(p part, _ int) {
+	now := time.Now()
+	switch spent := now.Sub(t.started); p {
+	case partRotor:
+		t.stages.Rotors += spent
+	case partReflector:
+		t.stages.Reflector += spent
+	default:
+		t.stages.Plugboard += spent
+	}
+	t.started = now
+}
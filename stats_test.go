@@ -0,0 +1,158 @@
+package enigma
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString(strings.Repeat("A", 100))
+	e.FastForward(5)
+	stats := e.Stats()
+	if stats.Keypresses != 105 || stats.Letters != 100 {
+		t.Errorf("%d keypresses and %d letters, expected 105 and 100", stats.Keypresses, stats.Letters)
+	}
+	// The middle rotor steps as the right one goes from V to W, at the
+	// keypresses 22, 48, 74 and 100, which leaves it at its notch, E; at
+	// 101 it steps again, and the left one with it.
+	if want := []int{1, 5, 105}; len(stats.Steps) != 3 || stats.Steps[0] != want[0] || stats.Steps[1] != want[1] || stats.Steps[2] != want[2] {
+		t.Errorf("the rotors stepped %v times, expected %v", stats.Steps, want)
+	}
+	if stats.DoubleSteps != 1 {
+		t.Errorf("%d double steps, expected 1", stats.DoubleSteps)
+	}
+	if stats.Stages.Total() != 0 {
+		t.Errorf("the stages took %s without being timed", stats.Stages.Total())
+	}
+}
+
+// Timing the stages times every way of pressing the keys, and doesn't
+// change what they encode to.
+func TestTimeStages(t *testing.T) {
+	plaintext := strings.Repeat("WETTERBERICHT", 100)
+	tests := []struct {
+		name  string
+		press func(e *Enigma) string
+	}{
+		{"EncodeString", func(e *Enigma) string { return e.EncodeString(plaintext) }},
+		{"EncodeBytes", func(e *Enigma) string {
+			dst := make([]byte, len(plaintext))
+			e.EncodeBytes(dst, []byte(plaintext))
+			return string(dst)
+		}},
+		{"DecodeString", func(e *Enigma) string {
+			e.Reflector.Sequence = rotation(1)
+			return e.DecodeString(plaintext)
+		}},
+	}
+	for _, tt := range tests {
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tt.press(e.Clone())
+		e.TimeStages(true)
+		if got := tt.press(e); got != want {
+			t.Errorf("%s: timed, %s is %s, expected %s", tt.name, plaintext, got, want)
+		}
+		stages := e.Stats().Stages
+		if stages.Stepping <= 0 || stages.Plugboard <= 0 || stages.Rotors <= 0 || stages.Reflector <= 0 {
+			t.Errorf("%s: a stage wasn't timed: %+v", tt.name, stages)
+		}
+	}
+}
+
+// Over 26×26×26 keypresses from AAA, the fast rotor steps on every
+// one, the middle one on each of the 676 turns of the fast one and on
+// each of its double steps, and the slow one only with those: every
+// time the middle rotor comes to its notch, E, which it does once in
+// 26 of its steps, so 27 times in its 703.
+func TestStatsFullCycle(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString(strings.Repeat("A", 26*26*26))
+	stats := e.Stats()
+	if want := []int{27, 703, 17576}; len(stats.Steps) != 3 || stats.Steps[0] != want[0] || stats.Steps[1] != want[1] || stats.Steps[2] != want[2] {
+		t.Errorf("the rotors stepped %v times, expected %v", stats.Steps, want)
+	}
+	if stats.DoubleSteps != stats.Steps[0] {
+		t.Errorf("%d double steps, expected %d, one for every step of the slow rotor", stats.DoubleSteps, stats.Steps[0])
+	}
+	if e.Positions() != "BBA" {
+		t.Errorf("the rotors are at %s, expected BBA", e.Positions())
+	}
+	for _, want := range []string{"keypresses:   17576", "rotor I:      27 steps", "double steps: 27"} {
+		if !strings.Contains(e.DebugString(), want) {
+			t.Errorf("the debug string doesn't have %q:\n%s", want, e.DebugString())
+		}
+	}
+	e.Reset()
+	if stats := e.Stats(); stats.Keypresses != 0 || stats.Steps[0] != 0 || stats.DoubleSteps != 0 {
+		t.Errorf("Reset leaves the statistics at %+v", stats)
+	}
+}
+
+// Every way of pressing the keys counts its letters and steps the same,
+// the statistics returned are the caller's own, and turning the timing
+// off stops it.
+func TestStatsEveryPath(t *testing.T) {
+	plaintext := strings.Repeat("WETTERBERICHT", 30)
+	tests := []struct {
+		name  string
+		press func(e *Enigma)
+	}{
+		{"EncodeString", func(e *Enigma) { e.EncodeString(plaintext) }},
+		{"EncodeChar", func(e *Enigma) {
+			for i := range plaintext {
+				e.EncodeChar(plaintext[i])
+			}
+		}},
+		{"EncodeRune", func(e *Enigma) {
+			for _, r := range plaintext {
+				e.EncodeRune(r)
+			}
+		}},
+		{"EncodeBytes", func(e *Enigma) { e.EncodeBytes(make([]byte, len(plaintext)), []byte(plaintext)) }},
+		{"DecodeString", func(e *Enigma) { e.DecodeString(plaintext) }},
+	}
+	var want MachineStats
+	for i, tt := range tests {
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.press(e)
+		stats := e.Stats()
+		if i == 0 {
+			want = stats
+		}
+		if stats.Letters != len(plaintext) || stats.Keypresses != want.Keypresses || stats.DoubleSteps != want.DoubleSteps || !reflect.DeepEqual(stats.Steps, want.Steps) {
+			t.Errorf("%s: the statistics are %+v, expected %+v", tt.name, stats, want)
+		}
+	}
+
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("WETTER")
+	e.Stats().Steps[2] = 1000
+	if got := e.Stats().Steps[2]; got != 6 {
+		t.Errorf("the fast rotor stepped %d times after the statistics were changed", got)
+	}
+	e.TimeStages(true)
+	e.EncodeString(plaintext)
+	e.TimeStages(false)
+	timed := e.Stats().Stages
+	e.EncodeString(plaintext)
+	if e.Stats().Stages != timed || timed.Total() <= 0 {
+		t.Errorf("the stages are timed at %+v, then %+v with the timing off", timed, e.Stats().Stages)
+	}
+}
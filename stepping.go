@@ -0,0 +1,231 @@
+package enigma
+
+import "fmt"
+
+// SteppingMechanism is what moves the rotors on a keypress. Next tells
+// which rotors move on the next one, from the left, and which of those
+// moves are double steps, without moving anything; the machine moves
+// them, by one letter each, and leaves the fixed ones where they are.
+// Name is what configurations call it by (see SteppingMechanisms).
+type SteppingMechanism interface {
+	Next(e *Enigma) (moves, doubles []bool)
+	Name() string
+}
+
+// LeverStepping is the lever and pawl mechanism of the military
+// machines, with its double step (see Enigma.NoDoubleStep). It's the
+// one a machine steps with unless set otherwise.
+var LeverStepping SteppingMechanism = leverStepping{}
+
+// GearStepping is the cog-wheel drive of the Zählwerk machines and the
+// Enigma G: like an odometer, a rotor moves only when the one driving
+// it moves past a notch, so there's no double step.
+var GearStepping SteppingMechanism = gearStepping{}
+
+// SteppingMechanisms lists the mechanisms by the name configurations
+// have them under (see Config.Stepping). New ones can be added.
+var SteppingMechanisms = map[string]SteppingMechanism{
+	LeverStepping.Name(): LeverStepping,
+	GearStepping.Name():  GearStepping,
+}
+
+// stepFiller is a mechanism telling how the rotors move into slices of
+// the machine, so that a keypress doesn't allocate any (see next). It
+// steps the rotors of an AlphabetMachine just as well: all it needs of
+// a machine is its train.
+type stepFiller interface {
+	fill(t train, moves, doubles []bool)
+}
+
+// train is the row of rotors of a machine, from the left, the way the
+// stepping mechanisms of this package see it: how many slots there
+// are, if the rotor in a slot is fixed, the slot driving it (set by
+// hand, counting from 1, or 0 for the closest stepping rotor to the
+// right), if it's at a notch, and if a rotor at its own notch moves
+// with the one it drives.
+type train interface {
+	slots() int
+	fixed(slot int) bool
+	drivenBy(slot int) int
+	atNotch(slot int) bool
+	doubleSteps() bool
+}
+
+func (e *Enigma) slots() int            { return len(e.Rotors) }
+func (e *Enigma) fixed(slot int) bool   { return e.Rotors[slot].Fixed }
+func (e *Enigma) drivenBy(slot int) int { return e.Rotors[slot].DrivenBy }
+func (e *Enigma) atNotch(slot int) bool { return e.Rotors[slot].ShouldTurnOver() }
+func (e *Enigma) doubleSteps() bool     { return !e.NoDoubleStep }
+
+// willMove tells if the stepping rotor in the slot moves on the next
+// keypress with the lever mechanism: the fast rotor always moves, and
+// every other rotor moves when its driver, its right neighbour, is at a
+// notch. A rotor at its own notch moves too, together with its left
+// neighbour (the double step), unless it's the leftmost stepping rotor.
+// Fixed rotors are left out altogether, so their neighbours are driven
+// as if they were next to each other. It also tells if that's a double
+// step, i.e. the rotor moves only because it's at its own notch while
+// driving another rotor.
+func willMove(t train, slot int) (moves bool, double bool) {
+	driver := driverOf(t, slot)
+	if driver < 0 || t.atNotch(driver) {
+		return true, false
+	}
+	if t.doubleSteps() && t.atNotch(slot) && drives(t, slot) {
+		return true, true
+	}
+	return false, false
+}
+
+// driverOf returns the slot of the rotor driving the one in the slot:
+// the one set by DrivenBy or else the closest stepping rotor to the
+// right, or -1 for the fast rotor.
+func driverOf(t train, slot int) int {
+	if drivenBy := t.drivenBy(slot); drivenBy > 0 {
+		return drivenBy - 1
+	}
+	for i := slot + 1; i < t.slots(); i++ {
+		if !t.fixed(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// drives tells if the rotor in the slot drives any stepping rotor.
+func drives(t train, slot int) bool {
+	for i := 0; i < t.slots(); i++ {
+		if i != slot && !t.fixed(i) && driverOf(t, i) == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// leverStepping moves a rotor when its driver is at a notch, and one at
+// its own notch together with the rotor it drives.
+type leverStepping struct{}
+
+func (leverStepping) Name() string { return "lever" }
+
+func (s leverStepping) Next(e *Enigma) (moves, doubles []bool) {
+	moves, doubles = make([]bool, len(e.Rotors)), make([]bool, len(e.Rotors))
+	s.fill(e, moves, doubles)
+	return moves, doubles
+}
+
+func (leverStepping) fill(t train, moves, doubles []bool) {
+	n := t.slots()
+	for slot := 0; slot < n; slot++ {
+		if t.drivenBy(slot) != 0 {
+			for i := 0; i < n; i++ {
+				if !t.fixed(i) {
+					moves[i], doubles[i] = willMove(t, i)
+				}
+			}
+			return
+		}
+	}
+	// With every rotor driven by its neighbour, it's what willMove tells
+	// in a single pass from the right: a rotor drives another one if
+	// there's a stepping rotor to its left.
+	leftmost := -1
+	for i := 0; i < n; i++ {
+		if !t.fixed(i) {
+			leftmost = i
+			break
+		}
+	}
+	driver := -1
+	for i := n - 1; i >= 0; i-- {
+		if t.fixed(i) {
+			continue
+		}
+		switch {
+		case driver < 0 || t.atNotch(driver):
+			moves[i] = true
+		case t.doubleSteps() && i != leftmost && t.atNotch(i):
+			moves[i], doubles[i] = true, true
+		}
+		driver = i
+	}
+}
+
+// gearStepping moves a rotor when its driver moves, and is at a notch
+// when it does.
+type gearStepping struct{}
+
+func (gearStepping) Name() string { return "gear" }
+
+func (s gearStepping) Next(e *Enigma) (moves, doubles []bool) {
+	moves, doubles = make([]bool, len(e.Rotors)), make([]bool, len(e.Rotors))
+	s.fill(e, moves, doubles)
+	return moves, doubles
+}
+
+// fill tells the moves, using doubles, which stay false, to remember
+// the slots already decided.
+func (s gearStepping) fill(t train, moves, doubles []bool) {
+	for i := 0; i < t.slots(); i++ {
+		if !t.fixed(i) {
+			s.decide(t, i, moves, doubles)
+		}
+	}
+	for i := range doubles {
+		doubles[i] = false
+	}
+}
+
+// decide tells if the rotor in the slot moves, deciding its driver
+// first. A rotor driving itself through a loop of DrivenBy is taken as
+// not moving.
+func (s gearStepping) decide(t train, slot int, moves, decided []bool) bool {
+	if !decided[slot] {
+		decided[slot] = true
+		driver := driverOf(t, slot)
+		moves[slot] = driver < 0 || (s.decide(t, driver, moves, decided) && t.atNotch(driver))
+	}
+	return moves[slot]
+}
+
+// next tells how the rotors move on the next keypress, like the Next of
+// the mechanism. The slices are the machine's own, reused on every
+// keypress, for the mechanisms of this package.
+func (e *Enigma) next() (moves, doubles []bool) {
+	mechanism := e.stepping()
+	filler, ok := mechanism.(stepFiller)
+	if !ok {
+		return mechanism.Next(e)
+	}
+	n := len(e.Rotors)
+	if cap(e.moving) < n {
+		e.moving, e.doubling = make([]bool, n), make([]bool, n)
+	}
+	moves, doubles = e.moving[:n], e.doubling[:n]
+	for i := range moves {
+		moves[i], doubles[i] = false, false
+	}
+	filler.fill(e, moves, doubles)
+	return moves, doubles
+}
+
+// stepping returns the mechanism of the machine.
+func (e *Enigma) stepping() SteppingMechanism {
+	if e.Stepping == nil {
+		return LeverStepping
+	}
+	return e.Stepping
+}
+
+// WithStepping sets the stepping mechanism by its name (see
+// SteppingMechanisms). The models only take one they didn't step with
+// with AllowNonHistorical.
+func WithStepping(name string) Option {
+	return func(c *Config) error {
+		if SteppingMechanisms[name] == nil {
+			return fmt.Errorf(`unknown stepping mechanism "%s"`, name)
+		}
+		c.Stepping = name
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package enigma_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/enigmatest"
+)
+
+// harness builds a machine of the rotors from the left at the
+// positions, with the rings at 1 and reflector B.
+func harness(t *testing.T, ids, positions string) *enigmatest.Harness {
+	t.Helper()
+	var rotors []enigma.RotorConfig
+	for i, id := range strings.Fields(ids) {
+		rotors = append(rotors, enigma.RotorConfig{ID: id, Start: positions[i], Ring: 1})
+	}
+	e, err := enigma.NewMachine(enigma.WithRotors(rotors...), enigma.WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enigmatest.New(e)
+}
+
+// The middle rotor steps along with the left one when it's at its own
+// notch: ADU ADV AEW BFX.
+func TestDoubleStep(t *testing.T) {
+	h := harness(t, "I II III", "ADU")
+	for _, step := range []struct {
+		positions string
+		next      enigmatest.StepPrediction
+	}{
+		{"ADU", enigmatest.StepPrediction{Moves: []bool{false, false, true}}},
+		{"ADV", enigmatest.StepPrediction{Moves: []bool{false, true, true}}},
+		{"AEW", enigmatest.StepPrediction{Moves: []bool{true, true, true}, DoubleStep: true}},
+		{"BFX", enigmatest.StepPrediction{Moves: []bool{false, false, true}}},
+	} {
+		h.ExpectPositions(t, step.positions)
+		h.ExpectNextStep(t, step.next)
+		h.StepN(1)
+	}
+}
+
+// Every rotor drives its left neighbour, and every one in the middle
+// double steps, however many there are.
+func TestNewMachineStepping(t *testing.T) {
+	tests := []struct {
+		rotors, start string
+		want          []string
+	}{
+		// The carry runs through the five rotors a keypress at a time,
+		// every rotor it passes double stepping.
+		{"I II III IV V", "ADUIZ", []string{"ADUIZ", "ADUJA", "ADVKB", "AEWKC", "BFWKD", "BFWKE"}},
+		// The left one of two never double steps.
+		{"I III", "PU", []string{"PU", "PV", "QW", "QX", "QY"}},
+		{"V", "Y", []string{"Y", "Z", "A", "B"}},
+	}
+	for _, tt := range tests {
+		h := harness(t, tt.rotors, tt.start)
+		h.StepN(len(tt.want) - 1)
+		if got := strings.Join(h.History, " "); got != strings.Join(tt.want, " ") {
+			t.Errorf("%s at %s: the rotors went through %s, expected %s", tt.rotors, tt.start, got, strings.Join(tt.want, " "))
+		}
+	}
+}
+
+// On the M4 the fourth rotor never moves, even with the carry running
+// through the three on its right.
+func TestM4Stepping(t *testing.T) {
+	e, err := enigma.NewEnigmaM4(
+		enigma.WithRotors(
+			enigma.RotorConfig{ID: "Beta", Start: 'Z', Ring: 1},
+			enigma.RotorConfig{ID: "I", Start: 'A', Ring: 1},
+			enigma.RotorConfig{ID: "II", Start: 'D', Ring: 1},
+			enigma.RotorConfig{ID: "III", Start: 'U', Ring: 1},
+		),
+		enigma.WithReflector("B-thin"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := enigmatest.New(e)
+	h.StepN(2)
+	h.ExpectNextStep(t, enigmatest.StepPrediction{Moves: []bool{false, true, true, true}, DoubleStep: true})
+	h.StepN(1)
+	h.ExpectPositions(t, "ZBFX")
+	if got := strings.Join(h.History, " "); got != "ZADU ZADV ZAEW ZBFX" {
+		t.Errorf("the rotors went through %s", got)
+	}
+}
+
+// Without the double step, a machine goes the same way as the real one
+// until the middle rotor reaches its notch; on the keypress after that,
+// only the real one steps the middle rotor again.
+func TestWithoutDoubleStep(t *testing.T) {
+	plaintext := strings.Repeat("A", 60)
+	for _, tt := range []struct {
+		start    string
+		diverges int
+	}{
+		{"ADU", 3},
+		{"KDR", 6},
+	} {
+		var rotors []enigma.RotorConfig
+		for i, id := range []string{"I", "II", "III"} {
+			rotors = append(rotors, enigma.RotorConfig{ID: id, Start: tt.start[i], Ring: 1})
+		}
+		lever, err := enigma.NewEnigmaI(enigma.WithRotors(rotors...), enigma.WithReflector("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		simple, err := enigma.NewEnigmaI(enigma.WithRotors(rotors...), enigma.WithReflector("B"), enigma.WithoutDoubleStep(), enigma.AllowNonHistorical())
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, b := lever.EncodeString(plaintext), simple.EncodeString(plaintext)
+		first := 0
+		for first < len(a) && a[first] == b[first] {
+			first++
+		}
+		if first+1 != tt.diverges {
+			t.Errorf("%s: the ciphertexts diverge on keypress %d, expected %d:\n%s\n%s", tt.start, first+1, tt.diverges, a, b)
+		}
+		if _, err := enigma.NewEnigmaI(enigma.WithRotors(rotors...), enigma.WithReflector("B"), enigma.WithoutDoubleStep()); err == nil {
+			t.Errorf("%s: the Enigma I is built without the double step", tt.start)
+		}
+	}
+}
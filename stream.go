@@ -0,0 +1,198 @@
+package enigma
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultProgressInterval is how often, in bytes read, EncodeStream
+// reports progress if no interval is set.
+const DefaultProgressInterval = 1 << 20
+
+// StreamOptions set up EncodeStream: the policy for characters other
+// than letters (see Sanitize), and an optional Progress callback called
+// every Interval bytes read (DefaultProgressInterval if not set) and
+// once at the end. The total is -1 if the size of the source isn't
+// known.
+//
+// TransmissionFormat reads the source as an intercept is written down
+// instead: the lines matching Preamble (the preamble of BuildTransmission
+// if not set) are left out, and so is everything in the others but the
+// letters, which are taken in either case, whatever the policy.
+type StreamOptions struct {
+	Policy   NonAlphaPolicy
+	Progress func(processed, total int64)
+	Interval int64
+
+	TransmissionFormat bool
+	Preamble           *regexp.Regexp
+}
+
+// StreamSummary tells what EncodeStream did: the bytes read and
+// written, the characters stripped by the policy, the bytes left out
+// (the ones of the characters stripped, and of the preambles in the
+// transmission format), and the time it took.
+type StreamSummary struct {
+	BytesIn  int64
+	BytesOut int64
+	Stripped int
+	Skipped  int64
+	Elapsed  time.Duration
+}
+
+// preambleFilter drops the preamble lines from the source.
+type preambleFilter struct {
+	in      *bufio.Reader
+	pattern *regexp.Regexp
+	line    []byte
+	err     error
+	skipped int64
+}
+
+func (f *preambleFilter) Read(p []byte) (int, error) {
+	for len(f.line) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		f.line, f.err = f.in.ReadBytes('\n')
+		if f.pattern.Match(bytes.TrimSpace(f.line)) {
+			f.skipped += int64(len(f.line))
+			f.line = nil
+		}
+	}
+	n := copy(p, f.line)
+	f.line = f.line[n:]
+	return n, nil
+}
+
+// Skipped returns the bytes of the preambles dropped so far.
+func (f *preambleFilter) Skipped() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.skipped
+}
+
+// countingWriter encodes the letters on their way to the writer.
+type countingWriter struct {
+	e *Enigma
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) WriteByte(b byte) error {
+	c.n++
+	return c.w.WriteByte(c.e.EncodeChar(b))
+}
+
+// EncodeStream sanitizes and encodes everything from r into w, going
+// through the same steps as Sanitize and EncodeString, just without
+// holding the whole text in memory. An empty source writes nothing, and
+// one with nothing left after sanitizing is ErrEmptyAfterSanitize, just
+// like for EncodeText. The machine is checked first (see Validate), so
+// that a long stream isn't encoded by a broken one. It's checked on
+// every call: the exported fields can change without the machine
+// knowing, so there's nothing to remember a check by, and it costs a
+// few hundred lookups.
+func (e *Enigma) EncodeStream(r io.Reader, w io.Writer, options StreamOptions) (StreamSummary, error) {
+	err := e.Validate()
+	if err == nil {
+		err = e.checkSeal()
+	}
+	if err != nil {
+		countError("enigma.stream", err)
+		return StreamSummary{}, err
+	}
+	started := time.Now()
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	total := streamSize(r)
+	var filter *preambleFilter
+	if options.TransmissionFormat {
+		filter = &preambleFilter{in: bufio.NewReader(r), pattern: options.Preamble}
+		if filter.pattern == nil {
+			filter.pattern = preamblePattern
+		}
+		r, options.Policy = filter, NonAlphaStrip
+	}
+	out := &countingWriter{e: e, w: bufio.NewWriter(w)}
+	z := sanitizer{policy: options.Policy, out: out}
+	in := bufio.NewReader(r)
+	var summary StreamSummary
+	next := interval
+	for {
+		var rn rune
+		var size int
+		rn, size, err = in.ReadRune()
+		if err != nil {
+			break
+		}
+		if err = z.feed(int(summary.BytesIn), rn); err != nil {
+			break
+		}
+		summary.BytesIn += int64(size)
+		if read := summary.BytesIn + filter.Skipped(); options.Progress != nil && read >= next {
+			options.Progress(read, total)
+			next += interval
+		}
+		if len(z.report.Changes) > 1024 {
+			summary.tally(z.report.Changes)
+			e.logSanitize(z.report)
+			z.report.Changes = z.report.Changes[:0]
+		}
+	}
+	if err == io.EOF {
+		err = nil
+		z.finish()
+	}
+	summary.tally(z.report.Changes)
+	e.logSanitize(z.report)
+	summary.BytesIn += filter.Skipped()
+	summary.Skipped += filter.Skipped()
+	if flushErr := out.w.Flush(); err == nil {
+		err = flushErr
+	}
+	summary.BytesOut = out.n
+	if err == nil && summary.BytesIn > 0 && summary.BytesOut == 0 {
+		err = ErrEmptyAfterSanitize
+	}
+	summary.Elapsed = time.Since(started)
+	e.logEncode(int(summary.BytesOut))
+	observe("enigma.stream", 1, int(summary.BytesOut), started)
+	countError("enigma.stream", err)
+	if options.Progress != nil {
+		options.Progress(summary.BytesIn, total)
+	}
+	return summary, err
+}
+
+// streamSize returns the size of the source, if it can tell, or -1.
+func streamSize(r io.Reader) int64 {
+	switch source := r.(type) {
+	case interface{ Len() int }:
+		return int64(source.Len())
+	case *os.File:
+		if info, err := source.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// tally counts the stripped characters among the changes, and their
+// bytes.
+func (s *StreamSummary) tally(changes []Change) {
+	for _, change := range changes {
+		if change.Kind == Stripped {
+			s.Stripped++
+			s.Skipped += int64(utf8.RuneLen(change.Rune))
+		}
+	}
+}
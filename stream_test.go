@@ -0,0 +1,144 @@
+package enigma
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// slowReader hands the source out a few bytes at a time, and doesn't
+// tell its size.
+type slowReader struct {
+	r *strings.Reader
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	if len(p) > 7 {
+		p = p[:7]
+	}
+	return s.r.Read(p)
+}
+
+// Progress is reported every interval and once at the end, with the
+// total only if the size of the source is known.
+func TestEncodeStreamProgress(t *testing.T) {
+	text := strings.Repeat("ANGRIFF UM 5 UHR. ", 555)[:9990]
+	for _, tt := range []struct {
+		name   string
+		source func() (r io.Reader, total int64)
+	}{
+		{"slow", func() (io.Reader, int64) {
+			return &slowReader{r: strings.NewReader(text)}, -1
+		}},
+		{"sized", func() (io.Reader, int64) {
+			return strings.NewReader(text), int64(len(text))
+		}},
+	} {
+		e, err := Generic.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		source, total := tt.source()
+		var calls [][2]int64
+		options := StreamOptions{Interval: 1000, Progress: func(processed, total int64) {
+			calls = append(calls, [2]int64{processed, total})
+		}}
+		if _, err := e.EncodeStream(source, &bytes.Buffer{}, options); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != 10 {
+			t.Fatalf("%s: progress reported %d times, expected 10: %v", tt.name, len(calls), calls)
+		}
+		for i, call := range calls {
+			want := int64(1000 * (i + 1))
+			if i == len(calls)-1 {
+				want = int64(len(text))
+			}
+			if call[0] != want || call[1] != total {
+				t.Errorf("%s: progress %d is %v, expected %d of %d", tt.name, i+1, call, want, total)
+			}
+		}
+	}
+}
+
+// The summary tells what happened to the text, the same as Sanitize
+// does, and the encoded text is what EncodeText makes of it.
+func TestEncodeStreamSummary(t *testing.T) {
+	text := strings.Repeat("Grüße an alle, 7 Uhr! 🚢\r\n", 300)
+	clean, report, err := Sanitize(text, NonAlphaSpaceToX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripped, skipped := 0, int64(0)
+	for _, change := range report.Changes {
+		if change.Kind == Stripped {
+			stripped++
+			skipped += int64(len(string(change.Rune)))
+		}
+	}
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := e.Clone().EncodeText(text, NonAlphaSpaceToX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	summary, err := e.EncodeStream(iotest.OneByteReader(strings.NewReader(text)), &out, StreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Error("the stream encodes to something else than the text")
+	}
+	if summary.BytesIn != int64(len(text)) || summary.BytesOut != int64(len(clean)) || summary.Stripped != stripped || summary.Skipped != skipped {
+		t.Errorf("the summary is %+v, expected %d bytes in, %d out, %d characters of %d bytes stripped",
+			summary, len(text), len(clean), stripped, skipped)
+	}
+	if summary.Elapsed <= 0 {
+		t.Error("the stream took no time")
+	}
+}
+
+// An intercept as it was written down, with its preambles, groups, line
+// breaks, and letters in either case, reads as the letters alone, and
+// the summary counts every byte left out.
+func TestEncodeStreamTransmission(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := e.Clone().EncodeString(receiveText)
+	lines := strings.Split(FormatGroups(ciphertext, ClassicGroups), "\n")
+	intercept := "1840 = 2tl 1tl = 90 = WXC KCH =\r\n" +
+		strings.Join(lines[:2], "\r\n") + "\r\n\r\n" +
+		"1840 = 2tl 2tl = 91 = EMV UFW =\r\n" +
+		strings.ToLower(strings.Join(lines[2:], "\n")) + "\n"
+	var out bytes.Buffer
+	summary, err := e.Clone().EncodeStream(strings.NewReader(intercept), &out, StreamOptions{TransmissionFormat: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != receiveText {
+		t.Errorf("the intercept decrypts to %s", out.String())
+	}
+	if summary.BytesIn != int64(len(intercept)) || summary.BytesOut != int64(len(receiveText)) || summary.Skipped != int64(len(intercept)-len(ciphertext)) {
+		t.Errorf("the summary is %+v, expected %d bytes in, %d out, %d skipped", summary, len(intercept), len(receiveText), len(intercept)-len(ciphertext))
+	}
+
+	// A preamble of one's own is left out instead.
+	summary, err = e.Clone().EncodeStream(strings.NewReader("VON FLOTTE 17\n"+lines[0]), &out, StreamOptions{TransmissionFormat: true, Preamble: regexp.MustCompile(`^VON \w+ \d+$`)})
+	if err != nil || summary.BytesOut != 25 {
+		t.Errorf("with a preamble of its own, the intercept decrypts to %d letters (%v)", summary.BytesOut, err)
+	}
+
+	// Without the transmission format, the preamble is text like any.
+	out.Reset()
+	if _, err := e.Clone().EncodeStream(strings.NewReader(intercept), &out, StreamOptions{Policy: NonAlphaStrip}); err != nil || strings.Contains(out.String(), receiveText[:20]) {
+		t.Errorf("without the transmission format, the intercept decrypts to %s (%v)", out.String(), err)
+	}
+}
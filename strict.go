@@ -0,0 +1,195 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrExternallyMutated is the error of a strict machine (see WithStrict)
+// that was changed by something other than its own methods, wrapped in
+// a SettingError telling what changed.
+var ErrExternallyMutated = errors.New("changed from the outside")
+
+// strictMode is 1 if machines are strict unless configured otherwise,
+// read and written atomically, since machines can be built on any
+// goroutine.
+var strictMode int32
+
+// SetStrictMode makes all machines built from then on strict, as if
+// they were configured WithStrict, e.g. for a test run to find the code
+// that sets the fields of the machines directly. It's safe to call
+// while machines are being built, which are strict or not as the mode
+// was when they were.
+func SetStrictMode(strict bool) {
+	var mode int32
+	if strict {
+		mode = 1
+	}
+	atomic.StoreInt32(&strictMode, mode)
+}
+
+// strictDefault tells if machines are strict unless configured
+// otherwise.
+func strictDefault() bool {
+	return atomic.LoadInt32(&strictMode) == 1
+}
+
+// WithStrict makes the machine strict: it remembers its settings and
+// where its rotors are, and refuses to encode if any of them changed
+// since, unless its own methods (ResetTo, ReplaceRotor, the keypresses
+// themselves) changed them. Setting Rotor.Offset to move a rotor, or
+// rewiring the plugboard through Enigma.Plugboard, works on other
+// machines, but bypasses every check. The methods returning an error
+// return an ErrExternallyMutated, and the ones that can't, like
+// EncodeString and EncodeChar, panic with it.
+func WithStrict() Option {
+	return func(c *Config) error {
+		c.Strict = true
+		return nil
+	}
+}
+
+// seal is what a strict machine remembers of itself: the checksums of
+// its parts, and the offsets of the rotors.
+type seal struct {
+	parts   []uint64
+	offsets []int
+}
+
+// sealParts names the parts of the machine the checksums are of, after
+// the rotors, one for each slot.
+var sealParts = []string{"reflector", "plugboard", "entry wheel", "keyboard", "stepping"}
+
+// checksums returns the checksums of the rotors, from left to right,
+// and of the other parts (see sealParts).
+func (e *Enigma) checksums() []uint64 {
+	sums := make([]uint64, 0, len(e.Rotors)+len(sealParts))
+	for _, rotor := range e.Rotors {
+		if rotor == nil {
+			sums = append(sums, 0)
+			continue
+		}
+		sum := checksum(fnvOffset, rotor.StraightSeq[:]...)
+		sum = checksum(sum, rotor.ReverseSeq[:]...)
+		sum = checksum(sum, rotor.Turnover...)
+		sum = checksum(sum, rotor.Ring, bit(rotor.Fixed), rotor.DrivenBy, len(rotor.Turnover))
+		sums = append(sums, checksumString(sum, rotor.ID))
+	}
+	reflector := checksum(fnvOffset, e.Reflector.Sequence[:]...)
+	plugboard := checksum(fnvOffset, e.Plugboard[:]...)
+	if e.Uhr != nil {
+		plugboard = checksum(checksum(plugboard, e.Uhr.in[:]...), e.Uhr.out[:]...)
+	}
+	if !e.RotatingReflector {
+		reflector = checksum(reflector, e.Reflector.Position)
+	}
+	sums = append(sums, checksumString(reflector, e.Reflector.ID),
+		plugboard,
+		checksum(checksum(fnvOffset, e.EntryWheel.StraightSeq[:]...), e.EntryWheel.ReverseSeq[:]...))
+	keyboard := uint64(fnvOffset)
+	if e.Keyboard != nil {
+		keyboard = checksum(checksum(keyboard, e.Keyboard.Keys[:]...), e.Keyboard.Lamps[:]...)
+	}
+	stepping := checksum(fnvOffset, bit(e.NoDoubleStep), len(e.Rotors))
+	return append(sums, keyboard, checksumString(stepping, e.stepping().Name()))
+}
+
+// makeStrict makes a newly built machine strict, if it should be.
+func (e *Enigma) makeStrict(strict bool) {
+	if strict {
+		e.seal = &seal{}
+		e.sealSettings()
+	}
+}
+
+// sealSettings makes a strict machine remember its settings as they are
+// now, along with the rotor positions.
+func (e *Enigma) sealSettings() {
+	if e.seal == nil {
+		return
+	}
+	e.seal.parts = e.checksums()
+	e.sealOffsets()
+}
+
+// sealOffsets makes a strict machine remember where the rotors are now.
+func (e *Enigma) sealOffsets() {
+	if e.seal == nil {
+		return
+	}
+	if len(e.seal.offsets) != len(e.Rotors) {
+		e.seal.offsets = make([]int, len(e.Rotors))
+	}
+	for i, rotor := range e.Rotors {
+		if rotor != nil {
+			e.seal.offsets[i] = rotor.Offset
+		}
+	}
+}
+
+// checkSeal returns an ErrExternallyMutated if a strict machine was
+// changed from the outside, telling the first part that was.
+func (e *Enigma) checkSeal() error {
+	if e.seal == nil {
+		return nil
+	}
+	sums := e.checksums()
+	if len(sums) != len(e.seal.parts) || len(e.Rotors) != len(e.seal.offsets) {
+		return settingError(ErrExternallyMutated, "the rotors were changed from the outside")
+	}
+	for i, sum := range sums {
+		if sum == e.seal.parts[i] {
+			continue
+		}
+		if i < len(e.Rotors) {
+			err := settingError(ErrExternallyMutated, "the rotor in slot %d was changed from the outside", i+1)
+			err.Slot = i + 1
+			if e.Rotors[i] != nil {
+				err.ID = e.Rotors[i].ID
+			}
+			return err
+		}
+		return settingError(ErrExternallyMutated, "the %s was changed from the outside", sealParts[i-len(e.Rotors)])
+	}
+	for i, rotor := range e.Rotors {
+		if rotor != nil && rotor.Offset != e.seal.offsets[i] {
+			err := settingError(ErrExternallyMutated, "the rotor in slot %d was turned from the outside", i+1)
+			err.ID, err.Slot = rotor.ID, i+1
+			return err
+		}
+	}
+	return nil
+}
+
+// mustBeSealed panics if a strict machine was changed from the outside,
+// for the methods that can't return an error.
+func (e *Enigma) mustBeSealed() {
+	if err := e.checkSeal(); err != nil {
+		panic(fmt.Errorf("enigma: %w", err))
+	}
+}
+
+// FNV-1a, over ints.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// checksum adds the values to the checksum.
+func checksum(sum uint64, values ...int) uint64 {
+	for _, v := range values {
+		sum ^= uint64(v)
+		sum *= fnvPrime
+	}
+	return sum
+}
+
+// checksumString adds the bytes of the string to the checksum.
+func checksumString(sum uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		sum ^= uint64(s[i])
+		sum *= fnvPrime
+	}
+	return sum
+}
@@ -0,0 +1,215 @@
+package enigma
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mutations change each exported field of a machine from the outside.
+var mutations = []struct {
+	name   string
+	mutate func(e *Enigma)
+}{
+	{"rotor offset", func(e *Enigma) { e.Rotors[2].Offset = 5 }},
+	{"rotor wiring", func(e *Enigma) {
+		e.Rotors[1].StraightSeq[0], e.Rotors[1].StraightSeq[1] = e.Rotors[1].StraightSeq[1], e.Rotors[1].StraightSeq[0]
+	}},
+	{"rotor reverse wiring", func(e *Enigma) {
+		e.Rotors[1].ReverseSeq[0], e.Rotors[1].ReverseSeq[1] = e.Rotors[1].ReverseSeq[1], e.Rotors[1].ReverseSeq[0]
+	}},
+	{"rotor notch", func(e *Enigma) { e.Rotors[2].Turnover[0] = 3 }},
+	{"rotor notches", func(e *Enigma) { e.Rotors[2].Turnover = append(e.Rotors[2].Turnover, 3) }},
+	{"rotor ring", func(e *Enigma) { e.Rotors[0].Ring = 4 }},
+	{"rotor fixed", func(e *Enigma) { e.Rotors[0].Fixed = true }},
+	{"rotor drive", func(e *Enigma) { e.Rotors[0].DrivenBy = 3 }},
+	{"rotor ID", func(e *Enigma) { e.Rotors[0].ID = "IV" }},
+	{"rotor replaced", func(e *Enigma) { e.Rotors[0] = e.Rotors[1] }},
+	{"rotor added", func(e *Enigma) { e.Rotors = append([]*Rotor{e.Rotors[0]}, e.Rotors...) }},
+	{"reflector wiring", func(e *Enigma) {
+		a, b := 0, e.Reflector.Sequence[0]
+		c, d := 1, e.Reflector.Sequence[1]
+		if b == 1 {
+			c, d = 2, e.Reflector.Sequence[2]
+		}
+		e.Reflector.Sequence[a], e.Reflector.Sequence[c] = d, b
+		e.Reflector.Sequence[d], e.Reflector.Sequence[b] = a, c
+	}},
+	{"reflector ID", func(e *Enigma) { e.Reflector.ID = "C" }},
+	{"reflector position", func(e *Enigma) { e.Reflector.Position = 1 }},
+	{"plugboard", func(e *Enigma) { e.Plugboard[0], e.Plugboard[1] = 1, 0 }},
+	{"Uhr", func(e *Enigma) { e.Uhr = &Uhr{} }},
+	{"entry wheel", func(e *Enigma) {
+		e.EntryWheel.StraightSeq[0], e.EntryWheel.StraightSeq[1] = e.EntryWheel.StraightSeq[1], e.EntryWheel.StraightSeq[0]
+	}},
+	{"keyboard", func(e *Enigma) {
+		keys, _ := NewKeyMap("QWERTY-positional")
+		e.Keyboard = keys
+	}},
+	{"double step", func(e *Enigma) { e.NoDoubleStep = true }},
+}
+
+// encodes tells if the machine encodes a letter, with the error if it
+// doesn't, and if EncodeString panics with it too.
+func encodes(e *Enigma) (err error, panicked bool) {
+	func() {
+		defer func() { panicked = recover() != nil }()
+		e.Clone().EncodeString("WETTER")
+	}()
+	_, err = e.EncodeRune('W')
+	return err, panicked
+}
+
+// A strict machine refuses to encode after any of its fields was
+// changed from the outside, telling what was; a machine that isn't
+// strict encodes as it did before there were strict ones.
+func TestStrictMutations(t *testing.T) {
+	for _, m := range mutations {
+		strict, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithStrict())
+		if err != nil {
+			t.Fatal(err)
+		}
+		loose, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err, panicked := encodes(strict); err != nil || panicked {
+			t.Fatalf("the strict machine as built doesn't encode: %v", err)
+		}
+		m.mutate(strict)
+		m.mutate(loose)
+		err, panicked := encodes(strict)
+		var setting *SettingError
+		if !errors.Is(err, ErrExternallyMutated) || !errors.As(err, &setting) || !panicked {
+			t.Errorf("%s: the strict machine encodes with %v (panicking: %v)", m.name, err, panicked)
+		}
+		if err, panicked := encodes(loose); err != nil || panicked {
+			t.Errorf("%s: the machine that isn't strict doesn't encode: %v", m.name, err)
+		}
+	}
+}
+
+// The error tells the slot of a rotor that was changed, and what part
+// of the machine it was otherwise.
+func TestStrictError(t *testing.T) {
+	e, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"), WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Rotors[2].Offset = 5
+	_, err = e.EncodeRune('A')
+	var setting *SettingError
+	if !errors.As(err, &setting) || setting.Slot != 3 || setting.ID != "III" || !strings.Contains(err.Error(), "turned from the outside") {
+		t.Errorf("the turned rotor is reported as %v", err)
+	}
+	e.Rotors[2].Offset = 0
+	e.Plugboard[0], e.Plugboard[1] = 1, 0
+	if _, err := e.EncodeRune('A'); err == nil || !strings.Contains(err.Error(), "the plugboard was changed") {
+		t.Errorf("the rewired plugboard is reported as %v", err)
+	}
+}
+
+// The machine's own methods may change it: a strict machine still
+// encodes like one built that way afterwards.
+func TestStrictSanctioned(t *testing.T) {
+	e, err := NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector(UKWD), WithReflectorWiring(strings.Fields("AC DE FG HI JK LM NP QR ST UV WX YZ")...), WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"keypresses", func() error { e.EncodeString("WETTERBERICHT"); return nil }},
+		{"ResetTo", func() error { return e.ResetTo("QEV") }},
+		{"StepBack", e.StepBack},
+		{"ReplaceRotor", func() error { return e.ReplaceRotor(1, RotorConfig{ID: "V", Start: 'K', Ring: 3}) }},
+		{"SwapRotors", func() error { return e.SwapRotors(1, 3) }},
+		{"RewireReflector", func() error {
+			return e.RewireReflector(strings.Fields("AZ CX DY EW FV GU HT IS JR KQ LP MN"))
+		}},
+		{"Reset", func() error { e.Reset(); return nil }},
+		{"Clone", func() error { e = e.Clone(); return nil }},
+	}
+	for _, c := range changes {
+		if err := c.change(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if err, panicked := encodes(e); err != nil || panicked {
+			t.Errorf("after %s, the strict machine doesn't encode: %v", c.name, err)
+			continue
+		}
+		built, err := Generic.New(e.Config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := e.Clone().EncodeString(receiveText), built.EncodeString(receiveText); got != want {
+			t.Errorf("after %s, the strict machine encodes to %s, expected %s", c.name, got, want)
+		}
+	}
+	if !e.Config().Strict {
+		t.Error("the strict machine's configuration isn't strict")
+	}
+}
+
+// In strict mode, every machine built is strict, the ones built before
+// aren't, and the mode can be turned off again.
+func TestStrictMode(t *testing.T) {
+	before, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetStrictMode(true)
+	defer SetStrictMode(false)
+	for _, build := range []func() (*Enigma, error){
+		func() (*Enigma, error) { return Generic.New(classicConfig()) },
+		func() (*Enigma, error) {
+			return NewMachine(WithRotors(rotorsAt("I II III", "AAA")...), WithReflector("B"))
+		},
+	} {
+		e, err := build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Rotors[0].Offset = 1
+		if _, err := e.EncodeRune('A'); !errors.Is(err, ErrExternallyMutated) {
+			t.Errorf("the machine built in strict mode encodes with %v", err)
+		}
+	}
+	before.Rotors[0].Offset = 1
+	if _, err := before.EncodeRune('A'); err != nil {
+		t.Errorf("the machine built before strict mode doesn't encode: %v", err)
+	}
+	SetStrictMode(false)
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Config().Strict {
+		t.Error("the machine built after strict mode is strict")
+	}
+}
+
+// The mode can be switched while machines are being built on other
+// goroutines (run with -race to see it's no data race).
+func TestStrictModeConcurrent(t *testing.T) {
+	defer SetStrictMode(false)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := Generic.New(classicConfig()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		SetStrictMode(j%2 == 0)
+	}
+	wg.Wait()
+}
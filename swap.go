@@ -0,0 +1,122 @@
+package enigma
+
+import "fmt"
+
+// ReplaceRotor puts another rotor in the slot (counting from 1 on the
+// left), set to its ring and starting position, e.g. when the rotor is
+// dragged into a slot of a machine on the screen. Whether it's fixed
+// and which slot drives it belong to the slot, so they stay as they
+// were. The change is checked against the model the machine was built
+// as (see Model.Validate), e.g. the leftmost slot of an M4 only takes
+// Beta and Gamma; if it's not allowed, the machine is left as it is.
+// The other rotors, the plugboard, and everything else are untouched,
+// but StepBack can't go back past the change.
+func (e *Enigma) ReplaceRotor(slot int, cfg RotorConfig) error {
+	if slot < 1 || slot > len(e.Rotors) {
+		return fmt.Errorf("no slot %d on a machine with %d rotors", slot, len(e.Rotors))
+	}
+	if err := e.checkSeal(); err != nil {
+		return err
+	}
+	cfg.Fixed, cfg.DrivenBy = e.Rotors[slot-1].Fixed, e.Rotors[slot-1].DrivenBy
+	config := e.Config()
+	config.Rotors[slot-1] = cfg
+	model, err := e.checkRotors(config)
+	if err != nil {
+		return err
+	}
+	rotor := cfg.rotor(model.Rotors)
+	rotor.Offset = CharToIndex(upper(cfg.Start))
+	rotor.Ring = cfg.Ring - 1
+	rotor.Fixed, rotor.DrivenBy = cfg.Fixed, cfg.DrivenBy
+	e.Rotors[slot-1] = rotor
+	e.start[slot-1] = rotor.Offset
+	e.moves, e.doubles = 0, nil
+	e.sealSettings()
+	return nil
+}
+
+// SwapRotors swaps the rotors in the two slots, each with its ring, and
+// sets them to their starting positions, so that the machine is the
+// same as one built with the rotors the other way round. As with
+// ReplaceRotor, the slots keep whether they're fixed and what drives
+// them, the change is checked against the model, and nothing else is
+// touched.
+func (e *Enigma) SwapRotors(slotA, slotB int) error {
+	for _, slot := range []int{slotA, slotB} {
+		if slot < 1 || slot > len(e.Rotors) {
+			return fmt.Errorf("no slot %d on a machine with %d rotors", slot, len(e.Rotors))
+		}
+	}
+	if err := e.checkSeal(); err != nil {
+		return err
+	}
+	a, b := slotA-1, slotB-1
+	config := e.Config()
+	config.Rotors[a], config.Rotors[b] = config.Rotors[b], config.Rotors[a]
+	config.Rotors[a].Fixed, config.Rotors[b].Fixed = config.Rotors[b].Fixed, config.Rotors[a].Fixed
+	config.Rotors[a].DrivenBy, config.Rotors[b].DrivenBy = config.Rotors[b].DrivenBy, config.Rotors[a].DrivenBy
+	if _, err := e.checkRotors(config); err != nil {
+		return err
+	}
+	e.Rotors[a], e.Rotors[b] = e.Rotors[b], e.Rotors[a]
+	e.Rotors[a].Fixed, e.Rotors[b].Fixed = e.Rotors[b].Fixed, e.Rotors[a].Fixed
+	e.Rotors[a].DrivenBy, e.Rotors[b].DrivenBy = e.Rotors[b].DrivenBy, e.Rotors[a].DrivenBy
+	e.start[a], e.start[b] = e.start[b], e.start[a]
+	e.Rotors[a].Offset, e.Rotors[b].Offset = e.start[a], e.start[b]
+	e.moves, e.doubles = 0, nil
+	e.sealSettings()
+	return nil
+}
+
+// RewireReflector plugs the UKW-D of the machine with other pairs (see
+// NewUKWD), the way it was rewired in the field, keeping its position.
+// Other reflectors can't be rewired, and pairs that aren't allowed
+// leave the machine as it is.
+func (e *Enigma) RewireReflector(pairs []string) error {
+	if e.Reflector.ID != UKWD {
+		return fmt.Errorf(`reflector "%s" cannot be rewired, only the %s can`, e.Reflector.ID, UKWD)
+	}
+	if err := e.checkSeal(); err != nil {
+		return err
+	}
+	reflector, err := NewUKWD(pairs)
+	if err != nil {
+		return err
+	}
+	reflector.Position = e.Reflector.Position
+	e.Reflector = *reflector
+	e.sealSettings()
+	return nil
+}
+
+// SetReflectorPosition turns a settable reflector, like the ones of the
+// Enigma D and the Tirpitz machine, to the position (A to Z). It's
+// checked against the model, like Config.Reflector.Start is.
+func (e *Enigma) SetReflectorPosition(position byte) error {
+	if err := e.checkSeal(); err != nil {
+		return err
+	}
+	config := e.Config()
+	config.Reflector.Start = upper(position)
+	if _, err := e.checkRotors(config); err != nil {
+		return err
+	}
+	e.Reflector.Position = CharToIndex(config.Reflector.Start)
+	e.reflectorStart = e.Reflector.Position
+	e.sealSettings()
+	return nil
+}
+
+// checkRotors checks the configuration of the machine with its rotors
+// changed against its model, the generic one if it wasn't built from a
+// model. What the machine was built with was allowed, so only the
+// rotors (or the reflector) are in question.
+func (e *Enigma) checkRotors(config Config) (*Model, error) {
+	model := e.model
+	if model == nil {
+		model = &Generic
+	}
+	config.AllowNonHistorical = true
+	return model, model.Validate(config)
+}
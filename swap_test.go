@@ -0,0 +1,104 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+// swapPlaintext is what the machines are compared on.
+var swapPlaintext = strings.Repeat("FUNKSPRUCHANOBERKOMMANDO", 20)
+
+// Swapped, the rotors are the ones of a machine built the other way
+// round, at their starting positions, the one between them goes on from
+// where it was, and the plugboard is still in.
+func TestSwapRotors(t *testing.T) {
+	config := Config{Reflector: ReflectorConfig{ID: "B"}, Plugboard: []string{"AV", "BS", "CG"}}
+	for i, id := range []string{"IV", "II", "V"} {
+		config.Rotors = append(config.Rotors, RotorConfig{ID: id, Start: "QMX"[i], Ring: 3 * (i + 1)})
+	}
+	e, err := EnigmaI.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("VORAUSABTEILUNG")
+	middle := e.Positions()[1]
+	if err := e.SwapRotors(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	config.Rotors[0], config.Rotors[2] = config.Rotors[2], config.Rotors[0]
+	config.Rotors[1].Start = middle
+	fresh, err := EnigmaI.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Positions() != "X"+string(middle)+"Q" || e.Config().String() != fresh.Config().String() {
+		t.Errorf("swapped, the machine is %s at %s, expected %s", e.Config(), e.Positions(), fresh.Config())
+	}
+	if got, want := e.EncodeString(swapPlaintext), fresh.EncodeString(swapPlaintext); got != want {
+		t.Errorf("the swapped machine encodes to\n%s\nexpected\n%s", got, want)
+	}
+}
+
+// A rotor put in a slot starts at its own position, and the others go
+// on from where they were. StepBack can't go back past the change.
+func TestReplaceRotor(t *testing.T) {
+	e, err := EnigmaI.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeString("VORAUSABTEILUNG")
+	before := e.Positions()
+	if err := e.ReplaceRotor(2, RotorConfig{ID: "V", Start: 'K', Ring: 5}); err != nil {
+		t.Fatal(err)
+	}
+	config := classicConfig()
+	config.Rotors[0].Start, config.Rotors[2].Start = before[0], before[2]
+	config.Rotors[1] = RotorConfig{ID: "V", Start: 'K', Ring: 5}
+	fresh, err := EnigmaI.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := before[:1] + "K" + before[2:]; e.Positions() != want {
+		t.Errorf("the rotors are at %s, expected %s", e.Positions(), want)
+	}
+	if got, want := e.EncodeString(swapPlaintext), fresh.EncodeString(swapPlaintext); got != want {
+		t.Errorf("the machine encodes to\n%s\nexpected\n%s", got, want)
+	}
+	e.ReplaceRotor(1, RotorConfig{ID: "I", Start: 'A', Ring: 1})
+	if err := e.StepBack(); err == nil {
+		t.Error("the machine steps back past the new rotor")
+	}
+}
+
+// Slots the machine hasn't got, and rotors its model doesn't take in
+// them, are turned down, and the machine is left as it was.
+func TestSwapRotorsInvalid(t *testing.T) {
+	e, err := NewEnigmaM4(WithRotors(rotorsAt("Beta II IV I", "VJNA")...), WithReflector("B-thin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := e.Config().String()
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{"slot 0", func() error { return e.SwapRotors(0, 2) }},
+		{"slot 5", func() error { return e.SwapRotors(2, 5) }},
+		{"Beta into a stepping slot", func() error { return e.SwapRotors(1, 2) }},
+		{"I into the fixed slot", func() error { return e.ReplaceRotor(1, RotorConfig{ID: "I", Start: 'A', Ring: 1}) }},
+		{"Gamma into a stepping slot", func() error { return e.ReplaceRotor(3, RotorConfig{ID: "Gamma", Start: 'A', Ring: 1}) }},
+		{"IV twice", func() error { return e.ReplaceRotor(2, RotorConfig{ID: "IV", Start: 'A', Ring: 1}) }},
+		{"slot 9", func() error { return e.ReplaceRotor(9, RotorConfig{ID: "V", Start: 'A', Ring: 1}) }},
+	}
+	for _, tt := range tests {
+		if err := tt.change(); err == nil {
+			t.Errorf("%s: the change is made", tt.name)
+		}
+		if got := e.Config().String(); got != config || e.Positions() != "VJNA" {
+			t.Errorf("%s: the machine is %s at %s, expected %s", tt.name, got, e.Positions(), config)
+		}
+	}
+	if err := e.ReplaceRotor(1, RotorConfig{ID: "Gamma", Start: 'A', Ring: 1}); err != nil || e.Positions() != "AJNA" {
+		t.Errorf("Gamma goes into the fixed slot at %s (%v)", e.Positions(), err)
+	}
+}
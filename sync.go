@@ -0,0 +1,89 @@
+package enigma
+
+import "sync"
+
+// SyncEnigma is a machine that can be shared between goroutines: every
+// method takes the lock, so keypresses from different goroutines are
+// encoded one after another, as if typed by a single operator. The
+// read-only ones (Config, Positions, Stats) take it too, so that they
+// never see the rotors halfway through a step. What the machine keeps
+// from one keypress to the next is where its rotors are, so ResetTo is
+// what sets its state; there's no SetState besides it.
+//
+// Most of the time it's simpler to give every goroutine a machine of
+// its own.
+type SyncEnigma struct {
+	mu sync.Mutex
+	e  *Enigma
+}
+
+// NewSynchronized wraps the machine. The machine shouldn't be used
+// directly afterwards.
+func NewSynchronized(e *Enigma) *SyncEnigma {
+	return &SyncEnigma{e: e}
+}
+
+// EncodeRune encodes a single letter (see Enigma.EncodeRune).
+func (s *SyncEnigma) EncodeRune(r rune) (rune, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.EncodeRune(r)
+}
+
+// EncodeString encodes a string in one go, with no other keypresses
+// in between.
+func (s *SyncEnigma) EncodeString(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.EncodeString(text)
+}
+
+// Reset sets the rotors back to the starting positions.
+func (s *SyncEnigma) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.e.Reset()
+}
+
+// ResetTo sets the rotors to new positions (see Enigma.ResetTo).
+func (s *SyncEnigma) ResetTo(positions string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.ResetTo(positions)
+}
+
+// FastForward steps the rotors as if n keys were pressed.
+func (s *SyncEnigma) FastForward(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.e.FastForward(n)
+}
+
+// Config returns the current configuration of the machine.
+func (s *SyncEnigma) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.Config()
+}
+
+// Positions returns what the rotor windows show.
+func (s *SyncEnigma) Positions() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.Positions()
+}
+
+// Stats returns the statistics of the machine.
+func (s *SyncEnigma) Stats() MachineStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.e.Stats()
+}
+
+// Do runs f with the lock held, for anything the methods above don't
+// cover, e.g. several keypresses that have to stay together.
+func (s *SyncEnigma) Do(f func(e *Enigma)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.e)
+}
@@ -0,0 +1,115 @@
+package enigma
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Keypresses from 32 goroutines are encoded as if by a single operator:
+// together they light the same lamps as the same keypresses typed one
+// after another, and each goroutine sees its share of that stream in
+// order. Run with -race.
+func TestSynchronized(t *testing.T) {
+	const goroutines, presses = 32, 200
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSynchronized(e)
+	lamps := make([][]rune, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < presses; i++ {
+				lamp, err := s.EncodeRune('A')
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				lamps[g] = append(lamps[g], lamp)
+				if i%50 == 0 {
+					s.Positions()
+					s.Stats()
+					s.Config()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	sequential, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := []rune(sequential.EncodeString(strings.Repeat("A", goroutines*presses)))
+	if got, want := s.Positions(), sequential.Positions(); got != want {
+		t.Errorf("the rotors are at %s, expected %s", got, want)
+	}
+	if got := s.Stats().Keypresses; got != goroutines*presses {
+		t.Errorf("%d keypresses were counted, expected %d", got, goroutines*presses)
+	}
+	var all []rune
+	for g, own := range lamps {
+		if !subsequence(own, stream) {
+			t.Errorf("goroutine %d saw its lamps out of the order of the stream", g)
+		}
+		all = append(all, own...)
+	}
+	if got, want := sortRunes(all), sortRunes(stream); string(got) != string(want) {
+		t.Error("the goroutines lit other lamps than the stream typed in one go")
+	}
+}
+
+// Do keeps several keypresses together, so the order in which they are
+// logged under the lock is the order in which they were encoded.
+func TestSynchronizedDo(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSynchronized(e)
+	var typed, lit []byte
+	var wg sync.WaitGroup
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func(key byte) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				s.Do(func(e *Enigma) {
+					typed = append(typed, key, key)
+					lit = append(lit, e.EncodeChar(key), e.EncodeChar(key))
+				})
+			}
+		}(byte('A' + g%26))
+	}
+	wg.Wait()
+	sequential, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(lit), sequential.EncodeString(string(typed)); got != want {
+		t.Errorf("the logged keypresses encode to\n%s\nexpected\n%s", got, want)
+	}
+}
+
+// subsequence tells whether the runes appear in the stream in the same
+// order, not necessarily next to each other.
+func subsequence(runes, stream []rune) bool {
+	i := 0
+	for _, r := range stream {
+		if i < len(runes) && runes[i] == r {
+			i++
+		}
+	}
+	return i == len(runes)
+}
+
+func sortRunes(runes []rune) []rune {
+	sorted := append([]rune(nil), runes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
@@ -0,0 +1,149 @@
+// Package testvectors holds real wartime messages along with the keys
+// they were sent with and what they decrypt to, for checking that a
+// configuration builds the machine the operators had. Only messages
+// whose keys were recovered are on the list: a vector that can't be
+// checked isn't much of a vector.
+package testvectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emedvedev/enigma"
+)
+
+// Vector is a message: what it's known as, where it comes from, the
+// configuration of the machine at the message key, the ciphertext as it
+// was sent (in groups, without the indicator), and the plaintext.
+type Vector struct {
+	Name        string
+	Description string
+	Config      enigma.Config
+	Ciphertext  string
+	Plaintext   string
+}
+
+// Verify checks the vector (see Verify).
+func (v Vector) Verify() error {
+	return Verify(v.Config, v.Ciphertext, v.Plaintext)
+}
+
+// Vectors lists the known messages, oldest first.
+var Vectors = []Vector{
+	Instruction,
+	Barbarossa,
+	Scharnhorst,
+	Doenitz,
+}
+
+// Instruction is the one every Enigma implementation starts with: five
+// A's on rotors I, II, and III at AAA, with the rings at 01 and no plugs.
+var Instruction = Vector{
+	Name:        "Instruction manual",
+	Description: "AAAAA on I II III at AAA, reflector B, as in the operating instructions",
+	Config:      config("B", "I II III", []int{1, 1, 1}, "AAA", nil),
+	Ciphertext:  "BDZGO",
+	Plaintext:   "AAAAA",
+}
+
+// Barbarossa is the first part of a Wehrmacht message of 7 July 1941,
+// from the time the invasion of the Soviet Union began.
+var Barbarossa = Vector{
+	Name:        "Operation Barbarossa",
+	Description: "Enigma I message of 7 July 1941, part 1, message key BLA",
+	Config: config("B", "II IV V", []int{2, 21, 12}, "BLA",
+		[]string{"AV", "BS", "CG", "DL", "FU", "HZ", "IN", "KM", "OW", "RX"}),
+	Ciphertext: "EDPUD NRGYS ZRCXN UYTPO MRMBO FKTBZ REZKM LXLVE FGUEY SIOZV " +
+		"EQMIK UBPMM YLKLT TDEIS MDICA GYKUA CTCDO MOHWX MUUIA UBSTS " +
+		"LRNBZ SZWNR FXWFY SSXJZ VIJHI DISHP RKLKA YUPAD TXQSP INQMA " +
+		"TLPIF SVKDA SCTAC DPBOP VHJK",
+	Plaintext: "AUFKLXABTEILUNGXVONXKURTINOWAXKURTINOWAXNORDWESTLXSEBEZXSEBEZX" +
+		"UAFFLIEGERSTRASZERIQTUNGXDUBROWKIXDUBROWKIXOPOTSCHKAXOPOTSCHKAX" +
+		"UMXEINSAQTDREINULLXUHRANGETRETENXANGRIFFXINFXRGTX",
+}
+
+// Scharnhorst is a signal of the battleship Scharnhorst, sent on an M3
+// with two of the naval rotors in.
+var Scharnhorst = Vector{
+	Name:        "Scharnhorst",
+	Description: "M3 signal of the Scharnhorst, message key UZV",
+	Config: config("B", "III VI VIII", []int{1, 8, 13}, "UZV",
+		[]string{"AN", "EZ", "HK", "IJ", "LR", "MQ", "OT", "PV", "SW", "UX"}),
+	Ciphertext: "YKAEN ZAPMS CHZBF OCUVM RMDPY COFHA DZIZM EFXTH FLOLP ZLFGG " +
+		"BOTGO XGRET DWTJI QHLMX VJWKZ UASTR",
+	Plaintext: "STEUEREJTANAFJORDJANSTANDORTQUAAACCCVIERNEUNNEUNZWOFAHRTZWONUL" +
+		"SMXXSCHARNHORSTHCO",
+}
+
+// Doenitz is the M4 message to U-534 of May 1945, from the last days of
+// the war, signed by Dönitz.
+var Doenitz = Vector{
+	Name:        "Dönitz message",
+	Description: "M4 message to U-534, May 1945, message key VJNA",
+	Config: config("B-thin", "Beta II IV I", []int{1, 1, 1, 22}, "VJNA",
+		[]string{"AT", "BL", "DF", "GJ", "HM", "NW", "OP", "QY", "RZ", "VX"}),
+	Ciphertext: "NCZW VUSX PNYM INHZ XMQX SFWX WLKJ AHSH NMCO CCAK UQPM KCSM " +
+		"HKSE INJU SBLK IOSX CKUB HMLL XCSJ USRR DVKO HULX WCCB GVLI " +
+		"YXEO AHXR HKKF VDRE WEZL XOBA FGYU JQUK GRTV UKAM EURB VEKS " +
+		"UHHV OYHA BCJW MAKL FKLM YFVN RIZR VVRT KOFD ANJM OLBG FFLE " +
+		"OPRG TFLV RHOW OPBE KVWM UQFM PWPA RMFH AGKX IIBG",
+	Plaintext: "VONVONJLOOKSJHFFTTTEINSEINSDREIZWOYYQNNSNEUNINHALTXXBEIANGRIFF" +
+		"UNTERWASSERGEDRUECKTYWABOSXLETZTERGEGNERSTANDNULACHTDREINULUHR" +
+		"MARQUANTONJOTANEUNACHTSEYHSDREIYZWOZWONULGRADYACHTSMYSTOSSENACH" +
+		"XEKNSVIERMBFAELLTYNNNNNNOOOVIERYSICHTEINSNULL",
+}
+
+// config builds the configuration of a vector: with four rotors, the
+// leftmost one is the fixed thin rotor of the M4.
+func config(reflector, rotors string, rings []int, positions string, plugs []string) enigma.Config {
+	ids := strings.Fields(rotors)
+	c := enigma.Config{Reflector: enigma.ReflectorConfig{ID: reflector}, Plugboard: plugs}
+	for i, id := range ids {
+		c.Rotors = append(c.Rotors, enigma.RotorConfig{
+			ID:    id,
+			Start: positions[i],
+			Ring:  rings[i],
+			Fixed: len(ids) == 4 && i == 0,
+		})
+	}
+	return c
+}
+
+// Verify decrypts the ciphertext, in groups or not, on a machine of the
+// configuration (see enigma.NewMachine), and returns an error telling
+// where it first differs from the expected plaintext, if it does.
+func Verify(config enigma.Config, ciphertext, expectedPlaintext string) error {
+	e, err := enigma.Generic.New(config)
+	if err != nil {
+		return err
+	}
+	plaintext := e.DecodeString(enigma.ParseGroups(ciphertext, enigma.ClassicGroups))
+	for i := 0; i < len(plaintext) || i < len(expectedPlaintext); i++ {
+		switch {
+		case i >= len(plaintext):
+			return fmt.Errorf("the plaintext is %d letters short of the expected %d", len(expectedPlaintext)-len(plaintext), len(expectedPlaintext))
+		case i >= len(expectedPlaintext):
+			return fmt.Errorf("the plaintext is %d letters longer than the expected %d", len(plaintext)-len(expectedPlaintext), len(expectedPlaintext))
+		case plaintext[i] != expectedPlaintext[i]:
+			return fmt.Errorf(`the plaintext differs at %d: expected "%c", got "%c" (%s)`, i, expectedPlaintext[i], plaintext[i], config)
+		}
+	}
+	return nil
+}
+
+// Roundtrip checks what holds for any machine and any text: the
+// ciphertext decrypts back to the plaintext, and no letter of it is
+// encrypted to itself. The plaintext is capital letters only.
+func Roundtrip(config enigma.Config, plaintext string) error {
+	e, err := enigma.Generic.New(config)
+	if err != nil {
+		return err
+	}
+	ciphertext := e.EncodeString(plaintext)
+	for i := 0; i < len(plaintext) && i < len(ciphertext); i++ {
+		if plaintext[i] == ciphertext[i] {
+			return fmt.Errorf(`"%c" at %d is encrypted to itself (%s)`, plaintext[i], i, config)
+		}
+	}
+	return Verify(config, ciphertext, plaintext)
+}
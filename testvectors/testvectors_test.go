@@ -0,0 +1,114 @@
+package testvectors_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/emedvedev/enigma"
+	"github.com/emedvedev/enigma/enigmatest"
+	"github.com/emedvedev/enigma/testvectors"
+)
+
+// Every message encrypts to what was sent, on a machine built from its
+// configuration, and a failure says where it went wrong and where the
+// rotors were (see enigmatest.DiffCiphertext).
+func TestVectors(t *testing.T) {
+	for _, vector := range testvectors.Vectors {
+		e, err := enigma.Generic.New(vector.Config)
+		if err != nil {
+			t.Fatalf("%s: %v", vector.Name, err)
+		}
+		enigmatest.New(e).ExpectCiphertext(t, vector.Plaintext, enigma.ParseGroups(vector.Ciphertext, enigma.ClassicGroups))
+		if err := vector.Verify(); err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+		}
+		if err := testvectors.Roundtrip(vector.Config, vector.Plaintext); err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+		}
+	}
+}
+
+// A ring off by one shows where the ciphertext goes wrong, and Verify
+// tells the same letter of the plaintext.
+func TestVectorsDiverge(t *testing.T) {
+	vector := testvectors.Barbarossa
+	config := vector.Config
+	config.Rotors = append([]enigma.RotorConfig(nil), config.Rotors...)
+	config.Rotors[2].Ring++
+	e, err := enigma.Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := enigmatest.New(e)
+	ciphertext := enigma.ParseGroups(vector.Ciphertext, enigma.ClassicGroups)
+	diff := h.DiffCiphertext(ciphertext, h.Machine.Clone().EncodeString(vector.Plaintext))
+	if !strings.HasPrefix(diff, "ciphertext differs at 0 ") || !strings.HasSuffix(diff, "rotors at BLB on that keypress") {
+		t.Errorf("the wrong ring is reported as\n%s", diff)
+	}
+	if err := testvectors.Verify(config, vector.Ciphertext, vector.Plaintext); err == nil || !strings.Contains(err.Error(), "differs at 0:") {
+		t.Errorf("the wrong ring is verified with %v", err)
+	}
+}
+
+// Verify takes the ciphertext in groups or not, and tells a plaintext
+// too short, too long, or a configuration that can't be built.
+func TestVerify(t *testing.T) {
+	vector := testvectors.Barbarossa
+	if err := testvectors.Verify(vector.Config, strings.Replace(vector.Ciphertext, " ", "", -1), vector.Plaintext); err != nil {
+		t.Errorf("the ciphertext out of its groups is verified with %v", err)
+	}
+	tests := []struct {
+		name      string
+		config    enigma.Config
+		plaintext string
+		want      string
+	}{
+		{"short", vector.Config, vector.Plaintext + "X", "1 letters short of the expected"},
+		{"long", vector.Config, vector.Plaintext[:len(vector.Plaintext)-2], "2 letters longer than the expected"},
+		{"letter", vector.Config, "B" + vector.Plaintext[1:], `differs at 0: expected "B", got "A"`},
+		{"config", enigma.Config{Rotors: []enigma.RotorConfig{{ID: "IX", Start: 'A', Ring: 1}}, Reflector: enigma.ReflectorConfig{ID: "B"}}, vector.Plaintext, "IX"},
+	}
+	for _, tt := range tests {
+		if err := testvectors.Verify(tt.config, vector.Ciphertext, tt.plaintext); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: verified with %v, expected %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+// The Dönitz, Scharnhorst, and Barbarossa messages are on the list,
+// each with a name and a description.
+func TestVectorsListed(t *testing.T) {
+	listed := make(map[string]bool)
+	for _, vector := range testvectors.Vectors {
+		if vector.Name == "" || vector.Description == "" || vector.Ciphertext == "" {
+			t.Errorf("the vector %+v is missing something", vector)
+		}
+		listed[vector.Name] = true
+	}
+	for _, vector := range []testvectors.Vector{testvectors.Doenitz, testvectors.Scharnhorst, testvectors.Barbarossa} {
+		if !listed[vector.Name] {
+			t.Errorf("%s isn't listed", vector.Name)
+		}
+	}
+}
+
+// Roundtrip holds on any machine: random configurations of the
+// military models, with a plaintext of every vector. The commercial
+// ones have entry wheels of their own, which the generic machine of
+// Verify doesn't.
+func TestRoundtripRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(269))
+	for _, model := range []*enigma.Model{&enigma.EnigmaI, &enigma.M3, &enigma.M3Navy, &enigma.M4} {
+		for i := 0; i < 20; i++ {
+			settings, err := enigma.GenerateRandomConfig(model, rng)
+			if err != nil {
+				t.Fatalf("Enigma %s: %v", model.Name, err)
+			}
+			vector := testvectors.Vectors[i%len(testvectors.Vectors)]
+			if err := testvectors.Roundtrip(settings.Config, vector.Plaintext); err != nil {
+				t.Errorf("Enigma %s: %v", model.Name, err)
+			}
+		}
+	}
+}
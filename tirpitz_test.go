@@ -0,0 +1,77 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+// tirpitzConfig is the Enigma T with the rotors from the left at the
+// rings and positions, and the reflector at its position.
+func tirpitzConfig(rotors string, rings []int, positions string, reflector byte) Config {
+	config := Config{Reflector: ReflectorConfig{ID: "T", Start: reflector}}
+	for i, id := range strings.Fields(rotors) {
+		config.Rotors = append(config.Rotors, RotorConfig{ID: id, Ring: rings[i], Start: positions[i]})
+	}
+	return config
+}
+
+// The known answers were worked out on a machine written apart from
+// this package, from the same wirings: the Tirpitz entry wheel and
+// reflector, and the five notches of every rotor.
+func TestEnigmaTKnownAnswer(t *testing.T) {
+	tests := []struct {
+		config                Config
+		plaintext, ciphertext string
+	}{
+		{tirpitzConfig("I II III", []int{1, 1, 1}, "AAA", 'A'), "AAAAAAAAAAAAAAAAAAAA", "FWRYLQCQGZRGSVNOZEPV"},
+		{tirpitzConfig("VI II V", []int{3, 17, 9}, "QDV", 'F'), "DIEMARINEISTEINSATZBEREITXNACHTOKIO", "TUADLBFERDJKZWJWQXGTVDXBUHONLDXIUAJ"},
+	}
+	for _, tt := range tests {
+		e, err := NewEnigmaT(WithRotors(tt.config.Rotors...), WithReflectorPosition(tt.config.Reflector.Start))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.EncodeString(tt.ciphertext); got != tt.plaintext {
+			t.Errorf("%s: %s decrypts to %s, expected %s", tt.config, tt.ciphertext, got, tt.plaintext)
+		}
+	}
+}
+
+// Five notches on every rotor make the middle one step, and double
+// step, five times as often as on the military machines: the rotors
+// have to be where they're expected all along a long sequence.
+func TestEnigmaTLongStepping(t *testing.T) {
+	e, err := EnigmaT.New(tirpitzConfig("I II III", []int{1, 1, 1}, "AAA", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := map[int]string{1: "AAB", 26: "AFA", 100: "DVW", 676: "EEA", 1000: "TDM", 5000: "UTI"}
+	for press := 1; press <= 5000; press++ {
+		e.EncodeChar('A')
+		if want, ok := checkpoints[press]; ok && e.Positions() != want {
+			t.Errorf("after %d keypresses the rotors are at %s, expected %s", press, e.Positions(), want)
+		}
+	}
+	stats := e.Stats()
+	if want := []int{228, 1189, 5000}; len(stats.Steps) != 3 || stats.Steps[0] != want[0] || stats.Steps[1] != want[1] || stats.Steps[2] != want[2] {
+		t.Errorf("the rotors stepped %v times, expected %v", stats.Steps, want)
+	}
+	if stats.DoubleSteps != 228 {
+		t.Errorf("%d double steps, expected 228", stats.DoubleSteps)
+	}
+}
+
+// The Enigma T had no plugboard, and takes any three of its rotors.
+func TestEnigmaTRules(t *testing.T) {
+	if _, err := NewEnigmaT(WithRotors(tirpitzConfig("VIII VII I", []int{1, 1, 1}, "AAA", 0).Rotors...)); err != nil {
+		t.Errorf("rotors VIII VII I are turned down: %v", err)
+	}
+	config := tirpitzConfig("I II III", []int{1, 1, 1}, "AAA", 0)
+	config.Plugboard = []string{"AB"}
+	if _, err := EnigmaT.New(config); err == nil {
+		t.Error("a plugboard is accepted on the Enigma T")
+	}
+	if _, err := EnigmaT.New(tirpitzConfig("I II III IV", []int{1, 1, 1, 1}, "AAAA", 0)); err == nil {
+		t.Error("four rotors are accepted on the Enigma T")
+	}
+}
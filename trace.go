@@ -0,0 +1,52 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceStep is a part of the machine the current went through on a
+// keypress: the part (see SignalStep), the letter it came in at and the
+// one it left at, and for the rotors, the slot (counting from 1, zero
+// for the other parts) and the offset the rotor was at, 0 being A in
+// the window.
+type TraceStep struct {
+	Part    string
+	In, Out byte
+	Slot    int
+	Offset  int
+}
+
+// String describes the step, e.g. "rotor III in slot 3 at B: A -> C".
+func (s TraceStep) String() string {
+	if s.Slot != 0 {
+		part, back := strings.CutSuffix(s.Part, ", back")
+		part += fmt.Sprintf(" at %c", IndexToChar(mod26(s.Offset)))
+		if back {
+			part += ", back"
+		}
+		return fmt.Sprintf("%s: %c -> %c", part, s.In, s.Out)
+	}
+	return fmt.Sprintf("%s: %c -> %c", s.Part, s.In, s.Out)
+}
+
+// EncodeWithTrace encodes a letter like EncodeRune, and returns the way
+// the current went, a step per part, from the key to the lamp: the
+// keyboard map and the plugboard (or the Uhr), the entry wheel, the
+// rotors from the right, the reflector, and back.
+func (e *Enigma) EncodeWithTrace(r rune) (rune, []TraceStep, error) {
+	lamp, err := e.EncodeRune(r)
+	if err != nil {
+		return lamp, nil, err
+	}
+	path := e.signalPath(CharToIndex(upper(byte(r))))
+	steps := make([]TraceStep, 0, len(path)-1)
+	for i := 1; i < len(path); i++ {
+		step := TraceStep{Part: path[i].Part, In: path[i-1].Letter, Out: path[i].Letter, Slot: path[i].Slot}
+		if step.Slot != 0 {
+			step.Offset = e.Rotors[step.Slot-1].Offset
+		}
+		steps = append(steps, step)
+	}
+	return lamp, steps, nil
+}
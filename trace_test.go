@@ -0,0 +1,61 @@
+package enigma
+
+import "testing"
+
+func TestEncodeWithTrace(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lamp, steps, err := e.EncodeWithTrace('A')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lamp != 'B' {
+		t.Errorf("A encodes to %c, expected B", lamp)
+	}
+	want := []string{
+		"plugboard: A -> A",
+		"entry wheel ABC: A -> A",
+		"rotor III in slot 3 at B: A -> C",
+		"rotor II in slot 2 at A: C -> D",
+		"rotor I in slot 1 at A: D -> F",
+		"reflector B: F -> S",
+		"rotor I in slot 1 at A, back: S -> S",
+		"rotor II in slot 2 at A, back: S -> E",
+		"rotor III in slot 3 at B, back: E -> B",
+		"entry wheel ABC, back: B -> B",
+		"plugboard, back: B -> B",
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("the trace has %d steps, expected %d: %v", len(steps), len(want), steps)
+	}
+	for i, step := range steps {
+		if step.String() != want[i] {
+			t.Errorf("step %d is %q, expected %q", i+1, step, want[i])
+		}
+	}
+}
+
+// The trace ends at the lamp the keypress lights, whatever the machine
+// has on the way.
+func TestTraceEndsAtLamp(t *testing.T) {
+	config := classicConfig()
+	config.Plugboard = uhrPairs
+	config.Uhr = &UhrConfig{Position: 3}
+	config.Keyboard = "QWERTZ"
+	e, err := Generic.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference := e.Clone()
+	for _, key := range "WETTERBERICHT" {
+		lamp, steps, err := e.EncodeWithTrace(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := rune(reference.EncodeChar(byte(key))); lamp != want || rune(steps[len(steps)-1].Out) != want {
+			t.Errorf("%c lights %c and the trace ends at %c, expected %c", key, lamp, steps[len(steps)-1].Out, want)
+		}
+	}
+}
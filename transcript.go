@@ -0,0 +1,133 @@
+package enigma
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Keypress is a single entry of a transcript: the key pressed, the lamp
+// it lit, and the rotor positions shown while the key was held down.
+type Keypress struct {
+	Key       byte
+	Lamp      byte
+	Positions string
+}
+
+// Transcript is the record of a session, one keypress after another.
+type Transcript []Keypress
+
+// keypressJSON is the JSON form of Keypress, with letters as strings.
+type keypressJSON struct {
+	Key       string `json:"key"`
+	Lamp      string `json:"lamp"`
+	Positions string `json:"positions"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (k Keypress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keypressJSON{string(k.Key), string(k.Lamp), k.Positions})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (k *Keypress) UnmarshalJSON(data []byte) error {
+	var v keypressJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Key) != 1 || len(v.Lamp) != 1 {
+		return fmt.Errorf(`key and lamp should be single letters, got "%s" and "%s"`, v.Key, v.Lamp)
+	}
+	*k = Keypress{Key: v.Key[0], Lamp: v.Lamp[0], Positions: v.Positions}
+	return nil
+}
+
+// JSONLines returns the transcript as JSON lines, a keypress per line.
+func (t Transcript) JSONLines() ([]byte, error) {
+	var b bytes.Buffer
+	for _, keypress := range t {
+		line, err := json.Marshal(keypress)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// ParseTranscript reads a transcript from JSON lines.
+func ParseTranscript(data []byte) (Transcript, error) {
+	var t Transcript
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var keypress Keypress
+		if err := json.Unmarshal(scanner.Bytes(), &keypress); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %w", line, err)
+		}
+		t = append(t, keypress)
+	}
+	return t, scanner.Err()
+}
+
+// StartRecording makes the machine record every keypress from now on.
+// Keep the configuration at this point (see Config) to replay the
+// transcript later.
+func (e *Enigma) StartRecording() {
+	e.transcript = Transcript{}
+}
+
+// StopRecording stops the recording and returns the transcript.
+func (e *Enigma) StopRecording() (Transcript, error) {
+	if e.transcript == nil {
+		return nil, fmt.Errorf("the machine is not recording")
+	}
+	t := e.transcript
+	e.transcript = nil
+	return t, nil
+}
+
+// DivergenceError tells where a replayed transcript went differently:
+// the index of the keypress, the lamps, and the rotor positions.
+type DivergenceError struct {
+	Index             int
+	Key               byte
+	Expected, Got     byte
+	ExpectedPositions string
+	Positions         string
+}
+
+// Error implements the error interface.
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("keypress %d: %c lit %c instead of %c (rotors at %s, expected %s)",
+		e.Index, e.Key, e.Got, e.Expected, e.Positions, e.ExpectedPositions)
+}
+
+// Replay presses the keys of the transcript on a fresh machine with
+// the configuration, and checks that the same lamps light up with the
+// rotors at the same positions. The first keypress that differs is
+// returned as a DivergenceError.
+func Replay(config Config, t Transcript) error {
+	e, err := Generic.New(config)
+	if err != nil {
+		return err
+	}
+	for i, keypress := range t {
+		lamp := e.EncodeChar(keypress.Key)
+		if lamp != keypress.Lamp || e.Positions() != keypress.Positions {
+			return &DivergenceError{
+				Index:             i,
+				Key:               keypress.Key,
+				Expected:          keypress.Lamp,
+				Got:               lamp,
+				ExpectedPositions: keypress.Positions,
+				Positions:         e.Positions(),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package enigma
+
+import (
+	"errors"
+	"testing"
+)
+
+// A recorded session replays as it was, through JSON lines too, and a
+// corrupted keypress is pinpointed.
+func TestTranscriptReplay(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := e.Config()
+	e.StartRecording()
+	e.EncodeString("WETTERVORHERSAGE")
+	transcript, err := e.StopRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript) != 16 || transcript[0].Key != 'W' || transcript[0].Positions != "AAB" {
+		t.Fatalf("the transcript starts with %+v, of %d keypresses", transcript[0], len(transcript))
+	}
+	data, err := transcript.JSONLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseTranscript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Replay(config, parsed); err != nil {
+		t.Fatalf("the session doesn't replay: %v", err)
+	}
+
+	corrupted := append(Transcript(nil), parsed...)
+	corrupted[7].Lamp = corrupted[7].Key
+	err = Replay(config, corrupted)
+	var divergence *DivergenceError
+	if !errors.As(err, &divergence) {
+		t.Fatalf("the corrupted session replays with %v", err)
+	}
+	if divergence.Index != 7 || divergence.Got != parsed[7].Lamp || divergence.Expected != parsed[7].Key || divergence.Positions != parsed[7].Positions {
+		t.Errorf("the divergence is %+v, expected keypress 7 lighting %c", divergence, parsed[7].Lamp)
+	}
+
+	corrupted = append(Transcript(nil), parsed...)
+	corrupted[3].Positions = "ZZZ"
+	if err := Replay(config, corrupted); !errors.As(err, &divergence) || divergence.Index != 3 {
+		t.Errorf("the corrupted positions replay with %v", err)
+	}
+	if _, err := e.StopRecording(); err == nil {
+		t.Error("the recording is stopped twice")
+	}
+}
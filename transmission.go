@@ -0,0 +1,381 @@
+package enigma
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessageLimit is the longest message procedure allowed, in letters.
+// Longer texts were sent in parts, each with a message key of its own.
+const MessageLimit = 250
+
+// MessageTokens are the words operators wrote for numbers and
+// punctuation, the Army and the Navy way (see the format package), that
+// SplitMessage doesn't break between parts unless told otherwise.
+var MessageTokens = []string{
+	"NULL", "EINS", "ZWO", "DREI", "VIER", "FUENF", "SECHS", "SEQS",
+	"SIEBEN", "ACHT", "AQT", "NEUN", "UD",
+}
+
+// SplitMessage splits the text into parts of at most limit letters
+// (MessageLimit if not set). Parts are cut after an X, the way words
+// were separated, if there's one among the last 25 letters, so that
+// words don't get broken between parts; and never in the middle of one
+// of the tokens, MessageTokens if none are given, so that a FUENF isn't
+// sent as FU and ENF.
+func SplitMessage(plaintext string, limit int, tokens ...string) []string {
+	if limit <= 0 {
+		limit = MessageLimit
+	}
+	if len(tokens) == 0 {
+		tokens = MessageTokens
+	}
+	var parts []string
+	for len(plaintext) > limit {
+		cut := splitPoint(plaintext, limit, tokens)
+		parts = append(parts, plaintext[:cut])
+		plaintext = plaintext[cut:]
+	}
+	if len(plaintext) > 0 {
+		parts = append(parts, plaintext)
+	}
+	return parts
+}
+
+// splitPoint returns where to cut the first part of the text: after the
+// last X among the last 25 letters of the limit that isn't in a token,
+// or else after the last token or letter that fits. A token longer than
+// the limit is cut anyway.
+func splitPoint(text string, limit int, tokens []string) int {
+	cut, afterX := 0, 0
+	for i := 0; i < limit; {
+		next := i + 1
+		for _, token := range tokens {
+			if len(token) > next-i && strings.HasPrefix(text[i:], token) {
+				next = i + len(token)
+			}
+		}
+		if next > limit {
+			break
+		}
+		if next == i+1 && text[i] == 'X' && i > 0 && limit-i <= 25 {
+			afterX = next
+		}
+		cut, i = next, next
+	}
+	switch {
+	case afterX > 0:
+		return afterX
+	case cut > 0:
+		return cut
+	}
+	return limit
+}
+
+// TransmissionOptions are the parts of a transmission that aren't all
+// given by the key sheet: the time of the message, the longest part
+// (MessageLimit if not set), where the random indicators come from
+// (crypto/rand if not set), the tokens the parts aren't cut in
+// (MessageTokens if not set, see SplitMessage), and the Kenngruppe, the
+// three letters from the key sheet telling which key the message is in.
+// Keys are picked by the operator instead, for as many parts as given;
+// with RejectWeakKeys, a lazy one (see QualityOf) is a WeakKeyError.
+// Random keys are never lazy.
+type TransmissionOptions struct {
+	Time           string
+	Limit          int
+	Rand           io.Reader
+	Tokens         []string
+	Kenngruppe     string
+	Keys           []MessageKey
+	RejectWeakKeys bool
+}
+
+// MessageKey is the ground setting and the message key of a part.
+type MessageKey struct {
+	Ground, Key string
+}
+
+// BuildTransmission encrypts the message the way it was done from 1940
+// on: for every part, the operator picks a random ground setting and a
+// random message key, encrypts the message key at the ground setting,
+// and the text at the message key. Each part starts with the preamble,
+// e.g. "1920 = 2tl 1tl = 250 = WXC KCH =", with the number of parts
+// and the part ("tl" for Teil), the number of letters, the ground
+// setting, and the encrypted message key, followed by the text in the
+// classic groups. With a Kenngruppe, the text starts with a group of two
+// random letters and the Kenngruppe, in the clear. Parts are separated
+// by blank lines. The plaintext is sanitized with NonAlphaReject, so
+// anything but letters is a *CharacterError, and nothing is encrypted.
+func BuildTransmission(config Config, plaintext string, options TransmissionOptions) (string, error) {
+	e, err := Generic.New(config)
+	if err != nil {
+		return "", err
+	}
+	if plaintext, _, err = Sanitize(plaintext, NonAlphaReject); err != nil {
+		return "", err
+	}
+	if options.Rand == nil {
+		options.Rand = rand.Reader
+	}
+	parts := SplitMessage(plaintext, options.Limit, options.Tokens...)
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		var ground, key string
+		if i < len(options.Keys) {
+			if ground, err = positionLetters(options.Keys[i].Ground); err != nil {
+				return "", fmt.Errorf("part %d: ground setting: %w", i+1, err)
+			}
+			if key, err = positionLetters(options.Keys[i].Key); err != nil {
+				return "", fmt.Errorf("part %d: message key: %w", i+1, err)
+			}
+			if report := QualityOf(key, ground); options.RejectWeakKeys && report.Weak() {
+				return "", &WeakKeyError{Part: i + 1, Report: report}
+			}
+		} else if ground, key, err = randomKeys(options.Rand, len(e.Rotors)); err != nil {
+			return "", err
+		}
+		if err := e.ResetTo(key); err != nil {
+			return "", err
+		}
+		if err := e.ResetTo(ground); err != nil {
+			return "", err
+		}
+		indicator := e.EncodeString(key)
+		e.ResetTo(key)
+		var preamble []string
+		if options.Time != "" {
+			preamble = append(preamble, options.Time)
+		}
+		if len(parts) > 1 {
+			preamble = append(preamble, fmt.Sprintf("%dtl %dtl", len(parts), i+1))
+		}
+		body := e.EncodeString(part)
+		if options.Kenngruppe != "" {
+			filler, err := randomLetters(options.Rand, 2)
+			if err != nil {
+				return "", err
+			}
+			body = filler + options.Kenngruppe + body
+		}
+		preamble = append(preamble, strconv.Itoa(len(body)), ground+" "+indicator)
+		texts[i] = strings.Join(preamble, " = ") + " =\n" + FormatGroups(body, ClassicGroups)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// positionLetters writes the positions in capitals, the way they go in
+// the preamble, whether they were given as letters or numbers (see
+// ParsePositions).
+func positionLetters(positions string) (string, error) {
+	offsets, err := ParsePositions(positions)
+	if err != nil {
+		return "", err
+	}
+	return FormatPositions(offsets, DisplayLetters), nil
+}
+
+// preamblePattern matches the preamble of a part: the time, the part
+// numbers, the number of letters, the ground setting, and the message
+// key, all but the last three optional.
+var preamblePattern = regexp.MustCompile(
+	`^(?:(\d{4}) = )?(?:(\d+)tl (\d+)tl = )?(\d+) = ([A-Z]+) ([A-Z]+) =$`)
+
+// ParseTransmission decrypts a transmission made by BuildTransmission,
+// putting the parts back together in order. A missing part, or a part
+// with a different number of letters than the preamble says, is an
+// error.
+func ParseTransmission(config Config, transmission string) (string, error) {
+	plaintext, result, err := receive(config, transmission, nil)
+	if err == nil && !result.CountOK {
+		err = errors.New(result.Warnings[0])
+	}
+	return plaintext, err
+}
+
+// ValidationResult tells if a received message seems to belong to the
+// key it was decrypted with: the Kenngruppe is one of the day, the
+// letter counts match the preambles, and the decrypt doesn't look like
+// noise (see GarbleScore). Warnings explain what didn't check out.
+type ValidationResult struct {
+	KenngruppeOK bool
+	CountOK      bool
+	GarbleScore  float64
+	Warnings     []string
+}
+
+// ReceiveMessage decrypts a transmission with a Kenngruppe (see
+// TransmissionOptions), checking it against the groups allowed on the
+// day, and reports what looks wrong instead of failing, so that a
+// message decrypted with the wrong day's key is flagged rather than
+// silently garbled. Only a malformed transmission is an error.
+func ReceiveMessage(config Config, transmission string, kenngruppen []string) (string, ValidationResult, error) {
+	if len(kenngruppen) == 0 {
+		return "", ValidationResult{}, fmt.Errorf("at least one Kenngruppe is required")
+	}
+	return receive(config, transmission, kenngruppen)
+}
+
+// garbledThreshold is the score above which a decrypt is flagged. Real
+// messages, short and full of X, score up to about 0.6 (the first part
+// of the Barbarossa message is 0.59), noise close to 1.
+const garbledThreshold = 0.75
+
+// receive decrypts the transmission; if kenngruppen are given, every
+// part starts with the Kenngruppe.
+func receive(config Config, transmission string, kenngruppen []string) (string, ValidationResult, error) {
+	result := ValidationResult{KenngruppeOK: len(kenngruppen) > 0, CountOK: true}
+	e, err := Generic.New(config)
+	if err != nil {
+		return "", result, err
+	}
+	type part struct {
+		number int
+		text   string
+	}
+	var parts []part
+	total := 1
+	lines := strings.Split(strings.Replace(transmission, "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		match := preamblePattern.FindStringSubmatch(line)
+		if match == nil {
+			return "", result, fmt.Errorf(`transmission line %d should be a preamble, got "%s"`, i+1, line)
+		}
+		number := 1
+		if match[2] != "" {
+			total, _ = strconv.Atoi(match[2])
+			number, _ = strconv.Atoi(match[3])
+		}
+		var body strings.Builder
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			i++
+			body.WriteString(lines[i])
+		}
+		text, err := ciphertextLetters(ParseGroups(body.String(), ClassicGroups))
+		if err != nil {
+			return "", result, fmt.Errorf("part %d: %w", number, err)
+		}
+		if count, _ := strconv.Atoi(match[4]); count != len(text) {
+			result.CountOK = false
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("part %d should have %d letters, got %d", number, count, len(text)))
+		}
+		if len(kenngruppen) > 0 {
+			if len(text) < 5 || !containsString(kenngruppen, text[2:5]) {
+				result.KenngruppeOK = false
+				result.Warnings = append(result.Warnings, fmt.Sprintf("part %d has no Kenngruppe of the day", number))
+			}
+			if len(text) >= 5 {
+				text = text[5:]
+			}
+		}
+		if err := e.ResetTo(match[5]); err != nil {
+			return "", result, fmt.Errorf("part %d: %w", number, err)
+		}
+		key := e.EncodeString(match[6])
+		if err := e.ResetTo(key); err != nil {
+			return "", result, fmt.Errorf("part %d: %w", number, err)
+		}
+		parts = append(parts, part{number, e.EncodeString(text)})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].number < parts[j].number })
+	var plaintext strings.Builder
+	for i, p := range parts {
+		if p.number != i+1 {
+			return "", result, fmt.Errorf("part %d of %d is missing", i+1, total)
+		}
+		plaintext.WriteString(p.text)
+	}
+	if len(parts) != total {
+		return "", result, fmt.Errorf("part %d of %d is missing", len(parts)+1, total)
+	}
+	result.GarbleScore = GarbleScore(plaintext.String())
+	if result.GarbleScore > garbledThreshold {
+		result.Warnings = append(result.Warnings, "the text looks garbled, was the right key used?")
+	}
+	return plaintext.String(), result, nil
+}
+
+// Index of coincidence of German text and of random letters.
+const (
+	germanIoC = 0.0762
+	randomIoC = 1.0 / 26
+)
+
+// GarbleScore tells how much the text looks like random letters rather
+// than German, from 0 (German) to 1 (noise), by its index of
+// coincidence. Short texts are unreliable either way.
+func GarbleScore(text string) float64 {
+	var counts [26]int
+	n := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 'A' && text[i] <= 'Z' {
+			counts[CharToIndex(text[i])]++
+			n++
+		}
+	}
+	if n < 2 {
+		return 1
+	}
+	sum := 0
+	for _, count := range counts {
+		sum += count * (count - 1)
+	}
+	ioc := float64(sum) / float64(n*(n-1))
+	score := (germanIoC - ioc) / (germanIoC - randomIoC)
+	switch {
+	case score < 0:
+		return 0
+	case score > 1:
+		return 1
+	}
+	return score
+}
+
+// containsString tells if the list has the string.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// randomKeys picks a random ground setting and a message key that isn't
+// lazy for it.
+func randomKeys(rng io.Reader, n int) (ground, key string, err error) {
+	if ground, err = randomLetters(rng, n); err != nil {
+		return "", "", err
+	}
+	for {
+		if key, err = randomLetters(rng, n); err != nil || !QualityOf(key, ground).Weak() {
+			return ground, key, err
+		}
+	}
+}
+
+// randomLetters returns n random letters.
+func randomLetters(rng io.Reader, n int) (string, error) {
+	letters := make([]byte, 0, n)
+	buf := make([]byte, 1)
+	for len(letters) < n {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return "", err
+		}
+		if buf[0] < 234 {
+			letters = append(letters, IndexToChar(int(buf[0]%26)))
+		}
+	}
+	return string(letters), nil
+}
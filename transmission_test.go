@@ -0,0 +1,116 @@
+package enigma
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		tokens    []string
+		want      []int
+	}{
+		{"short", strings.Repeat("A", 250), nil, []int{250}},
+		{"no X", strings.Repeat("A", 700), nil, []int{250, 250, 200}},
+		{"after an X", strings.Repeat("A", 230) + "X" + strings.Repeat("A", 100), nil, []int{231, 100}},
+		{"X too far back", strings.Repeat("A", 200) + "X" + strings.Repeat("A", 100), nil, []int{250, 51}},
+		{"token", strings.Repeat("A", 247) + "FUENF" + strings.Repeat("A", 10), nil, []int{247, 15}},
+		{"token after an X", strings.Repeat("A", 240) + "X" + "SIEBEN" + "XAAAA", nil, []int{248, 4}},
+		{"X in a token", strings.Repeat("A", 240) + "XYZ" + strings.Repeat("A", 20), []string{"XYZ"}, []int{250, 13}},
+		{"tokens given", strings.Repeat("A", 248) + "QQQ", []string{"QQQ"}, []int{248, 3}},
+	}
+	for _, tt := range tests {
+		parts := SplitMessage(tt.plaintext, 0, tt.tokens...)
+		var lengths []int
+		for _, part := range parts {
+			lengths = append(lengths, len(part))
+		}
+		if strings.Join(parts, "") != tt.plaintext {
+			t.Errorf("%s: the parts don't make up the text", tt.name)
+		}
+		if len(lengths) != len(tt.want) {
+			t.Errorf("%s: parts of %v letters, expected %v", tt.name, lengths, tt.want)
+			continue
+		}
+		for i := range lengths {
+			if lengths[i] != tt.want[i] {
+				t.Errorf("%s: parts of %v letters, expected %v", tt.name, lengths, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// A text too long for one message goes in parts, and comes back whole.
+func TestTransmissionParts(t *testing.T) {
+	plaintext := strings.Repeat("ANGRIFFXUMXFUENFXUHRXZWOXNULLXSTOP", 21)[:700]
+	transmission, err := BuildTransmission(classicConfig(), plaintext, TransmissionOptions{Time: "1920"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(transmission, "\n\n")
+	if len(parts) != 3 {
+		t.Fatalf("the transmission has %d parts, expected 3", len(parts))
+	}
+	for i, part := range parts {
+		if marker := []string{"3tl 1tl", "3tl 2tl", "3tl 3tl"}[i]; !strings.Contains(part, marker) {
+			t.Errorf("part %d doesn't have %s in its preamble", i+1, marker)
+		}
+	}
+	got, err := ParseTransmission(classicConfig(), transmission)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != plaintext {
+		t.Errorf("the transmission decrypts to %s, expected %s", got, plaintext)
+	}
+
+	shuffled := strings.Join([]string{parts[2], parts[0], parts[1]}, "\n\n")
+	if got, err := ParseTransmission(classicConfig(), shuffled); err != nil || got != plaintext {
+		t.Errorf("the parts out of order decrypt to %s (%v), expected %s", got, err, plaintext)
+	}
+	if _, err := ParseTransmission(classicConfig(), parts[0]+"\n\n"+parts[2]); err == nil {
+		t.Error("the transmission without its second part decrypts without an error")
+	}
+}
+
+// Keys and plaintexts the machine can't take are errors, not panics;
+// keys in small letters or numbers are written in capitals.
+func TestTransmissionInvalid(t *testing.T) {
+	tests := []struct {
+		plaintext string
+		keys      []MessageKey
+	}{
+		{"hello world", nil},
+		{"ANGRIFF", []MessageKey{{"ABC", "AB?"}}},
+		{"ANGRIFF", []MessageKey{{"ABC", "ABCD"}}},
+		{"ANGRIFF", []MessageKey{{"27 01 01", "ABC"}}},
+	}
+	for _, tt := range tests {
+		if transmission, err := BuildTransmission(classicConfig(), tt.plaintext, TransmissionOptions{Keys: tt.keys}); err == nil {
+			t.Errorf("%q with the keys %v is sent as %q", tt.plaintext, tt.keys, transmission)
+		}
+	}
+	var charErr *CharacterError
+	if _, err := BuildTransmission(classicConfig(), "hello world", TransmissionOptions{}); !errors.As(err, &charErr) || charErr.Index != 5 {
+		t.Errorf("the space is sent with %v", err)
+	}
+
+	transmission, err := BuildTransmission(classicConfig(), "angriff", TransmissionOptions{Keys: []MessageKey{{"wxc", "01 12 03"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := BuildTransmission(classicConfig(), "ANGRIFF", TransmissionOptions{Keys: []MessageKey{{"WXC", "ALC"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transmission != want {
+		t.Errorf("the transmission is %q, expected %q", transmission, want)
+	}
+	if got, err := ParseTransmission(classicConfig(), transmission); err != nil || got != "ANGRIFF" {
+		t.Errorf("the transmission decrypts to %s (%v)", got, err)
+	}
+}
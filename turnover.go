@@ -0,0 +1,42 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Turnover tells when the stepping rotor in a slot (counting from 1 on
+// the left) is driven: when the rotor in the Driver slot moves on from
+// one of the window letters.
+type Turnover struct {
+	Slot    int
+	Driver  int
+	Rotor   string
+	Letters []rune
+}
+
+// String describes the turnover, e.g. "moves when I in slot 3 leaves Q"
+// or, for the rotors with two notches, "leaves Z or M".
+func (t Turnover) String() string {
+	if len(t.Letters) == 0 {
+		return fmt.Sprintf("never moves, %s in slot %d has no notch", t.Rotor, t.Driver)
+	}
+	return fmt.Sprintf("moves when %s in slot %d leaves %s", t.Rotor, t.Driver, strings.Join(strings.Split(string(t.Letters), ""), " or "))
+}
+
+// TurnoverTable tells, for the rotors in their current order, at which
+// window letters every stepping rotor other than the fast one is
+// driven, from left to right. The double step isn't in the table: a
+// rotor at its own notch also moves with the one it drives.
+func (e *Enigma) TurnoverTable() []Turnover {
+	var table []Turnover
+	for i, rotor := range e.Rotors {
+		slot := driverOf(e, i)
+		if rotor.Fixed || slot < 0 {
+			continue
+		}
+		driver := e.Rotors[slot]
+		table = append(table, Turnover{Slot: i + 1, Driver: slot + 1, Rotor: driver.ID, Letters: driver.TurnoverLetters()})
+	}
+	return table
+}
@@ -0,0 +1,66 @@
+package enigma
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Royal Flags Wave Kings Above: Q E V J Z for I to V, and Z and M for
+// the naval rotors, whatever the rings.
+func TestTurnoverLetters(t *testing.T) {
+	tests := []struct {
+		id      string
+		letters string
+	}{
+		{"I", "Q"}, {"II", "E"}, {"III", "V"}, {"IV", "J"}, {"V", "Z"},
+		{"VI", "ZM"}, {"VII", "ZM"}, {"VIII", "ZM"}, {"Beta", ""},
+	}
+	for _, tt := range tests {
+		for _, ring := range []int{1, 13, 26} {
+			e, err := NewMachine(WithRotors(RotorConfig{ID: tt.id, Start: 'A', Ring: ring}), WithReflector("B"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(e.Rotors[0].TurnoverLetters()); got != tt.letters {
+				t.Errorf("%s with the ring at %d turns over at %q, expected %q", tt.id, ring, got, tt.letters)
+			}
+		}
+	}
+}
+
+// The table follows the rotors around: swapped, the slots are driven at
+// the letters of the rotors that came in, and the fixed rotor of the M4
+// isn't in it.
+func TestTurnoverTable(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Turnover{{Slot: 1, Driver: 2, Rotor: "II", Letters: []rune("E")}, {Slot: 2, Driver: 3, Rotor: "III", Letters: []rune("V")}}
+	if got := e.TurnoverTable(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the table is %v, expected %v", got, want)
+	}
+	if err := e.SwapRotors(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	want = []Turnover{{Slot: 1, Driver: 2, Rotor: "II", Letters: []rune("E")}, {Slot: 2, Driver: 3, Rotor: "I", Letters: []rune("Q")}}
+	if got := e.TurnoverTable(); !reflect.DeepEqual(got, want) {
+		t.Errorf("swapped, the table is %v, expected %v", got, want)
+	}
+	if got := want[1].String(); got != "moves when I in slot 3 leaves Q" {
+		t.Errorf("the turnover reads %q", got)
+	}
+	if !strings.Contains(e.DebugString(), "slot 2:       moves when I in slot 3 leaves Q\n") {
+		t.Errorf("the state display is\n%s", e.DebugString())
+	}
+
+	m4, err := NewEnigmaM4(WithRotors(rotorsAt("Beta VI II VIII", "AAAA")...), WithReflector("B-thin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := m4.TurnoverTable()
+	if len(table) != 2 || table[0].Slot != 2 || table[1].String() != "moves when VIII in slot 4 leaves Z or M" {
+		t.Errorf("the M4 table is %v", table)
+	}
+}
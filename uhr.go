@@ -0,0 +1,149 @@
+package enigma
+
+import "fmt"
+
+// UhrWiring is the wiring of the disc inside the Uhr: the contact on
+// one face, from 0 to 39, connected to the one on the other.
+var UhrWiring = [40]int{
+	6, 31, 4, 29, 18, 39, 16, 25, 30, 23,
+	28, 1, 38, 11, 36, 37, 26, 27, 24, 21,
+	14, 3, 12, 17, 2, 7, 0, 33, 10, 35,
+	8, 5, 22, 19, 20, 13, 34, 15, 32, 9,
+}
+
+// UhrPairs is how many cables the Uhr is plugged in with: all ten of
+// its plugs, no more and no less. UhrIntroduced is the year it was.
+const (
+	UhrPairs      = 10
+	UhrIntroduced = 1944
+)
+
+// Uhr is the box plugged in instead of the plugboard cables, turning
+// the ten pairs into a scrambler set by a dial from 0 to 39. Each pair
+// has the letter of the red a-plug first and the one of the black
+// b-plug second, and the pairs are the plugs 1 to 10, in order. The
+// current goes in at the thick pin of a plug and comes out at the thin
+// pin of another one. Unlike the cables, the Uhr doesn't swap the
+// letters in pairs but at every fourth position, 0 being the cables
+// again; the way back undoes the way in, though, so the machine still
+// decodes what it encodes (see IsReciprocal).
+type Uhr struct {
+	pairs    []string
+	position int
+	// in and out are the substitution at the dial position, the tables
+	// the circuit looks the letters up in (see SetPosition and Table).
+	in, out [26]int
+}
+
+// UhrConfig is the setting of the Uhr: the dial position. The pairs it's
+// plugged in with are the plugboard ones of the configuration.
+type UhrConfig struct {
+	Position int `json:"position"`
+}
+
+// WithUhr plugs the Uhr in with the plugboard pairs, a-plug first, and
+// sets its dial.
+func WithUhr(position int) Option {
+	return func(c *Config) error {
+		c.Uhr = &UhrConfig{Position: position}
+		return nil
+	}
+}
+
+// SetUhrPosition turns the dial of the Uhr plugged into the machine.
+func (e *Enigma) SetUhrPosition(position int) error {
+	if e.Uhr == nil {
+		return fmt.Errorf("no Uhr is plugged in")
+	}
+	if err := e.checkSeal(); err != nil {
+		return err
+	}
+	if err := e.Uhr.SetPosition(position); err != nil {
+		return err
+	}
+	e.sealSettings()
+	return nil
+}
+
+// NewUhr is the Uhr constructor, taking the ten pairs it's plugged in
+// with and the dial position.
+func NewUhr(pairs []string, position int) (*Uhr, error) {
+	if len(pairs) != UhrPairs {
+		return nil, fmt.Errorf("the Uhr takes %d plugboard pairs, got %d", UhrPairs, len(pairs))
+	}
+	if err := validatePlugs(pairs); err != nil {
+		return nil, err
+	}
+	u := &Uhr{pairs: append([]string(nil), pairs...)}
+	for i, pair := range u.pairs {
+		u.pairs[i] = string([]byte{upper(pair[0]), upper(pair[1])})
+	}
+	return u, u.SetPosition(position)
+}
+
+// Pairs returns the pairs the Uhr is plugged in with, a-plug first.
+func (u *Uhr) Pairs() []string {
+	return append([]string(nil), u.pairs...)
+}
+
+// Position returns the dial position.
+func (u *Uhr) Position() int {
+	return u.position
+}
+
+// SetPosition turns the dial, from 0 to 39.
+func (u *Uhr) SetPosition(position int) error {
+	if position < 0 || position >= len(UhrWiring) {
+		return settingError(ErrPositionOutOfRange, "the Uhr position must be in the 0-39 range, got %d", position)
+	}
+	u.position = position
+	u.wire()
+	return nil
+}
+
+// Table returns the substitution of the Uhr at the dial position, on the
+// way in and on the way out, like Plugboard.Table. The dial makes it
+// work out the tables again whenever it turns (see SetPosition), so the
+// ones returned are only good until then.
+func (u *Uhr) Table() (in, out [26]int8) {
+	return table8(u.in), table8(u.out)
+}
+
+// wire works out the substitution at the current position. The current
+// from the keyboard goes in at the thick pin of a plug and comes out at
+// the thin pin of another one, on the other face of the disc. A-plug i
+// (counting from 0) has its thick pin at the contact 4i and its thin
+// pin at 4i+2, and b-plug i its thin pin at the contact the disc takes
+// 4i to at 0 and its thick pin at the one it takes 4i+2 to, so that at
+// 0 the Uhr is the plugboard cables again. The wiring takes the
+// contacts 4i to 4i+2 on the other face whatever the position, so an
+// a-plug always goes to a b-plug and the other way round, and every
+// fourth position is reciprocal too. Letters without a plug go straight
+// through.
+func (u *Uhr) wire() {
+	var back [40]int
+	for contact, other := range UhrWiring {
+		back[other] = contact
+	}
+	// across and across back take a contact to the other face at the
+	// current position.
+	across := func(contact int) int {
+		return (UhrWiring[(contact+u.position)%40] - u.position + 40) % 40
+	}
+	acrossBack := func(contact int) int {
+		return (back[(contact+u.position)%40] - u.position + 40) % 40
+	}
+	var thinA, thinB [40]int
+	for i, pair := range u.pairs {
+		thinA[4*i+2] = CharToIndex(pair[0])
+		thinB[UhrWiring[4*i]] = CharToIndex(pair[1])
+	}
+	for i := range u.in {
+		u.in[i] = i
+	}
+	for i, pair := range u.pairs {
+		u.in[CharToIndex(pair[0])] = thinB[across(4*i)]
+		u.in[CharToIndex(pair[1])] = thinA[acrossBack(UhrWiring[4*i+2])]
+	}
+	u.out = Permutation(u.in).Inverse()
+}
@@ -0,0 +1,138 @@
+package enigma
+
+import "testing"
+
+var uhrPairs = []string{"AB", "CD", "EF", "GH", "IJ", "KL", "MN", "OP", "QR", "ST"}
+
+// substitution returns the letters the Uhr takes A to Z to, on the way
+// in.
+func (u *Uhr) substitution() string {
+	letters := make([]byte, len(u.in))
+	for i, letter := range u.in {
+		letters[i] = IndexToChar(letter)
+	}
+	return string(letters)
+}
+
+func TestUhrWiring(t *testing.T) {
+	tests := []struct {
+		position int
+		want     string
+	}{
+		// At 0, every a-plug goes to its own b-plug, like a cable.
+		{0, "BADCFEHGJILKNMPORQTSUVWXYZ"},
+		{4, "LMJOTQRKPCHABSDIFGNEUVWXYZ"},
+		{3, "JKROHSTADGLQFINEPCBMUVWXYZ"},
+		{27, "BGDQJERKNMHILOPATSFCUVWXYZ"},
+	}
+	for _, tt := range tests {
+		u, err := NewUhr(uhrPairs, tt.position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.substitution(); got != tt.want {
+			t.Errorf("position %d substitutes %s, expected %s", tt.position, got, tt.want)
+		}
+	}
+}
+
+// Every fourth position swaps the letters in pairs, like the cables do;
+// the others don't.
+func TestUhrReciprocalPositions(t *testing.T) {
+	for position := range UhrWiring {
+		u, err := NewUhr(uhrPairs, position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reciprocal := true
+		for letter, other := range u.in {
+			if u.in[other] != letter {
+				reciprocal = false
+			}
+		}
+		if want := position%4 == 0; reciprocal != want {
+			t.Errorf("position %d is reciprocal: %t, expected %t", position, reciprocal, want)
+		}
+	}
+}
+
+func TestUhrAtZeroIsPlugboard(t *testing.T) {
+	cables := classicConfig()
+	cables.Plugboard = uhrPairs
+	uhr := cables
+	uhr.Uhr = &UhrConfig{Position: 0}
+	plugged, err := Generic.New(cables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withUhr, err := Generic.New(uhr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := "QRSTUVWXYZANGRIFFIMMORGENGRAUENABCDEFGHIJKLMNOP"
+	if got, want := withUhr.EncodeString(plaintext), plugged.EncodeString(plaintext); got != want {
+		t.Errorf("the Uhr at 0 encodes to %s, expected %s as with the cables", got, want)
+	}
+}
+
+// uhrLetter follows the current of a letter through the plugs and the
+// disc of the Uhr at the position, a contact at a time, the way wire
+// works it out for all the letters at once.
+func uhrLetter(pairs []string, position int, letter byte) byte {
+	across := func(contact int, wiring [40]int) int {
+		return (wiring[(contact+position)%40] - position + 40) % 40
+	}
+	var back [40]int
+	for contact, other := range UhrWiring {
+		back[other] = contact
+	}
+	for i, pair := range pairs {
+		switch letter {
+		case pair[0]:
+			// In at the thick pin of a-plug i, out at the thin pin of
+			// the b-plug across the disc.
+			contact := across(4*i, UhrWiring)
+			for j := range pairs {
+				if UhrWiring[4*j] == contact {
+					return pairs[j][1]
+				}
+			}
+		case pair[1]:
+			contact := across(UhrWiring[4*i+2], back)
+			for j := range pairs {
+				if 4*j+2 == contact {
+					return pairs[j][0]
+				}
+			}
+		}
+	}
+	return letter
+}
+
+// The tables of the Uhr are what every letter goes to through the plugs
+// and the disc, at every dial position, the way back undoing the way in;
+// at 0, they're those of the cables.
+func TestUhrTable(t *testing.T) {
+	u, err := NewUhr(uhrPairs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in, out := u.Table(); in != NewPlugboard(uhrPairs).Table() || out != in {
+		t.Errorf("at 0, the Uhr is %v, expected the cables %v", in, NewPlugboard(uhrPairs).Table())
+	}
+	for position := range UhrWiring {
+		if err := u.SetPosition(position); err != nil {
+			t.Fatal(err)
+		}
+		in, out := u.Table()
+		for letter := 0; letter < 26; letter++ {
+			want := uhrLetter(uhrPairs, position, IndexToChar(letter))
+			if got := IndexToChar(int(in[letter])); got != want {
+				t.Errorf("at %d, %c goes to %c, expected %c", position, IndexToChar(letter), got, want)
+			}
+			if int(out[in[letter]]) != letter {
+				t.Errorf("at %d, the way back takes %c to %c", position, IndexToChar(int(in[letter])), IndexToChar(int(out[in[letter]])))
+			}
+		}
+	}
+}
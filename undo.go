@@ -0,0 +1,151 @@
+package enigma
+
+import "fmt"
+
+// StepBack turns the rotors back by one keypress. The lever mechanism
+// only ever moves a rotor forward by one, so every stepping rotor was
+// either where it is now or one letter behind; StepBack tries all of
+// these positions and picks the one that steps to the current one.
+//
+// Because of the double step, two positions can step to the same one
+// (e.g. DEW and EFW both go to EFX on rotors I-II-III), so the machine
+// remembers the keypresses that made a double step. A rotating reflector
+// is turned back too, if the leftmost rotor drove it. Past the point the
+// rotors were last set, or if the position can't be stepped to at all,
+// an error is returned and the rotors are left as they are.
+func (e *Enigma) StepBack() error {
+	previous, err := e.previousOffsets()
+	if err != nil {
+		return err
+	}
+	e.restore(previous)
+	if e.reflectorMoves() {
+		e.Reflector.Position = mod26(e.Reflector.Position - 1)
+	}
+	for len(e.doubles) > 0 && e.doubles[len(e.doubles)-1] == e.moves {
+		e.doubles = e.doubles[:len(e.doubles)-1]
+	}
+	e.moves--
+	return nil
+}
+
+// UndoRune takes back the last keypress: the rotors are stepped back
+// and the keypress is taken out of the statistics and the transcript,
+// along with the letter it typed.
+func (e *Enigma) UndoRune() error {
+	previous, err := e.previousOffsets()
+	if err != nil {
+		return err
+	}
+	if len(e.transcript) > 0 {
+		e.transcript = e.transcript[:len(e.transcript)-1]
+	}
+	if e.stats.Keypresses > 0 {
+		e.restore(previous)
+		undone := MachineStats{Steps: make([]int, len(e.Rotors))}
+		e.turn(&undone)
+		e.stats.Keypresses--
+		if e.stats.Letters > 0 {
+			e.stats.Letters--
+		}
+		e.stats.DoubleSteps -= undone.DoubleSteps
+		for i := range e.stats.Steps {
+			e.stats.Steps[i] -= undone.Steps[i]
+		}
+	}
+	return e.StepBack()
+}
+
+// previousOffsets finds the rotor offsets one keypress back, leaving
+// the rotors where they are.
+func (e *Enigma) previousOffsets() ([]int, error) {
+	current := e.offsets()
+	if e.moves == 0 {
+		return nil, fmt.Errorf("rotor positions %s are where the rotors were set",
+			FormatPositions(current, e.Display))
+	}
+	candidates := e.predecessors(current)
+	if len(candidates) > 1 {
+		doubles := 0
+		for i := len(e.doubles) - 1; i >= 0 && e.doubles[i] == e.moves; i-- {
+			doubles++
+		}
+		var matching [][]int
+		for _, candidate := range candidates {
+			e.restore(candidate)
+			if e.turn(nil) == doubles {
+				matching = append(matching, candidate)
+			}
+		}
+		e.restore(current)
+		candidates = matching
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("rotor positions %s cannot be reached by stepping",
+			FormatPositions(current, e.Display))
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, fmt.Errorf("rotor positions %s can be reached from both %s and %s",
+		FormatPositions(current, e.Display), FormatPositions(candidates[0], e.Display),
+		FormatPositions(candidates[1], e.Display))
+}
+
+// predecessors returns all the rotor offsets that step to the given
+// ones, leaving the rotors where they are.
+func (e *Enigma) predecessors(offsets []int) [][]int {
+	saved := e.offsets()
+	defer e.restore(saved)
+	var stepping []int
+	for i, rotor := range e.Rotors {
+		if !rotor.Fixed {
+			stepping = append(stepping, i)
+		}
+	}
+	var found [][]int
+	for mask := 0; mask < 1<<len(stepping); mask++ {
+		e.restore(offsets)
+		for bit, slot := range stepping {
+			if mask&(1<<bit) != 0 {
+				e.Rotors[slot].move(25)
+			}
+		}
+		candidate := e.offsets()
+		e.turn(nil)
+		if equalOffsets(e.offsets(), offsets) {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// offsets returns the rotor offsets from left to right.
+func (e *Enigma) offsets() []int {
+	offsets := make([]int, len(e.Rotors))
+	for i, rotor := range e.Rotors {
+		offsets[i] = mod26(rotor.Offset)
+	}
+	return offsets
+}
+
+// restore sets the rotors to the offsets.
+func (e *Enigma) restore(offsets []int) {
+	for i, rotor := range e.Rotors {
+		rotor.Offset = offsets[i]
+	}
+	e.sealOffsets()
+}
+
+// equalOffsets tells if both sets of rotors are at the same positions.
+func equalOffsets(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package enigma
+
+import "testing"
+
+// Stepping back, keypress by keypress, takes the machine through the
+// snapshots taken before each of them, across the double step too.
+func TestStepBack(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() (*Enigma, error)
+	}{
+		// ADU ADV AEW BFX: the middle rotor double steps from E.
+		{"double step", func() (*Enigma, error) {
+			return NewEnigmaI(WithRotors(rotorsAt("I II III", "ADU")...), WithReflector("B"))
+		}},
+		// Beta never steps, the other three do as on the M3.
+		{"M4", func() (*Enigma, error) {
+			return NewEnigmaM4(WithRotors(rotorsAt("Beta I II III", "ZADU")...), WithReflector("B-thin"))
+		}},
+	}
+	for _, tt := range tests {
+		e, err := tt.build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var snapshots []Snapshot
+		for i := 0; i < 30; i++ {
+			snapshots = append(snapshots, e.Snapshot())
+			e.EncodeChar('A')
+		}
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			if err := e.StepBack(); err != nil {
+				t.Fatalf("%s: keypress %d can't be stepped back: %v", tt.name, i+1, err)
+			}
+			want := snapshots[i]
+			if e.Positions() != want.Positions() {
+				t.Fatalf("%s: stepped back before keypress %d, the rotors are at %s, expected %s", tt.name, i+1, e.Positions(), want.Positions())
+			}
+			if got, want := e.Clone().EncodeString("WETTER"), want.Machine().EncodeString("WETTER"); got != want {
+				t.Fatalf("%s: stepped back before keypress %d, the machine encodes to %s, expected %s", tt.name, i+1, got, want)
+			}
+		}
+		if err := e.StepBack(); err == nil {
+			t.Errorf("%s: stepped back past where the rotors were set", tt.name)
+		}
+	}
+}
+
+// UndoRune takes the keypress out of the statistics and the transcript
+// too.
+func TestUndoRune(t *testing.T) {
+	e, err := NewEnigmaI(WithRotors(rotorsAt("I II III", "ADU")...), WithReflector("B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.StartRecording()
+	e.EncodeString("AAA")
+	before := e.Stats()
+	e.EncodeChar('A')
+	if err := e.UndoRune(); err != nil {
+		t.Fatal(err)
+	}
+	stats := e.Stats()
+	if stats.Keypresses != before.Keypresses || stats.Steps[0] != before.Steps[0] || stats.Steps[1] != before.Steps[1] || stats.DoubleSteps != before.DoubleSteps {
+		t.Errorf("the statistics after the undo are %+v, expected %+v", stats, before)
+	}
+	if e.Positions() != "BFX" {
+		t.Errorf("the rotors are at %s, expected BFX", e.Positions())
+	}
+	transcript, err := e.StopRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript) != 3 {
+		t.Errorf("the transcript has %d keypresses, expected 3", len(transcript))
+	}
+}
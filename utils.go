@@ -1,10 +1,5 @@
 package enigma
 
-import (
-	"regexp"
-	"strings"
-)
-
 // CharToIndex returns the alphabet index of a given letter.
 func CharToIndex(char byte) int {
 	return int(char - 'A')
@@ -15,13 +10,38 @@ func IndexToChar(index int) byte {
 	return byte('A' + index)
 }
 
+// mod26 returns the index wrapped around the alphabet, for negative
+// indexes too.
+func mod26(index int) int {
+	// Most indexes are at most two turns around the alphabet off, and
+	// telling them apart in branches, or dividing, is what encoding
+	// would spend its time on.
+	if uint(index+wrapOffset) < uint(len(wrap26)) {
+		return int(wrap26[index+wrapOffset])
+	}
+	if index %= 26; index < 0 {
+		index += 26
+	}
+	return index
+}
+
+// wrap26 holds mod26 of the indexes from -wrapOffset on.
+var wrap26 [5 * 26]uint8
+
+// wrapOffset is the index of 0 in wrap26.
+const wrapOffset = 2 * 26
+
+func init() {
+	for i := range wrap26 {
+		wrap26[i] = uint8(i % 26)
+	}
+}
+
 // SanitizePlaintext will prepare a string to be encoded
 // in the Enigma machine: everything except A-Z will be
-// stripped, spaces will be replaced with "X".
+// stripped, spaces will be replaced with "X", and umlauts
+// will be written out (see Sanitize).
 func SanitizePlaintext(plaintext string) string {
-	plaintext = strings.TrimSpace(plaintext)
-	plaintext = strings.ToUpper(plaintext)
-	plaintext = strings.Replace(plaintext, " ", "X", -1)
-	plaintext = regexp.MustCompile(`[^A-Z]`).ReplaceAllString(plaintext, "")
-	return plaintext
+	clean, _, _ := Sanitize(plaintext, NonAlphaSpaceToX)
+	return clean
 }
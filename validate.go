@@ -0,0 +1,103 @@
+package enigma
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks the machine as it is now, exported fields and all,
+// e.g. after they were changed by hand: the wirings of the rotors are
+// still permutations, the reflector and the plugboard swap letters in
+// pairs, the entry wheel and the keyboard map undo, and, for machines of
+// a historical model, the rotors, the reflector, and the fixed slots are
+// the ones the model had. Every problem found is reported, each
+// with where it is. Offsets and rings are taken around the alphabet
+// (see Rotor), so any of them are fine. The rotor IDs are only checked
+// against a historical model: Generic takes rotors wired by the
+// configuration under any ID (see RotorConfig.Wiring), so there it's
+// their wirings that are checked, not what they're called.
+func (e *Enigma) Validate() error {
+	var errs []error
+	if len(e.Rotors) == 0 {
+		errs = append(errs, fmt.Errorf("at least one rotor is required"))
+	}
+	for i, rotor := range e.Rotors {
+		if rotor == nil {
+			errs = append(errs, fmt.Errorf("slot %d: no rotor", i+1))
+			continue
+		}
+		errs = append(errs, e.validateRotor(i, rotor)...)
+	}
+	reflector, plugboard := Permutation(e.Reflector.Sequence), Permutation(e.Plugboard)
+	switch {
+	case !reflector.Valid():
+		errs = append(errs, fmt.Errorf(`reflector "%s": wiring is not a permutation`, e.Reflector.ID))
+	case !reflector.IsInvolution() || reflector.HasFixedPoint():
+		errs = append(errs, fmt.Errorf(`reflector "%s": letters are not swapped in pairs`, e.Reflector.ID))
+	}
+	switch {
+	case !plugboard.Valid():
+		errs = append(errs, fmt.Errorf("plugboard: not a permutation"))
+	case !plugboard.IsInvolution():
+		err := settingError(ErrPlugConflict, "plugboard: letters are not plugged in pairs")
+		err.ID = "plugboard"
+		errs = append(errs, err)
+	}
+	if !Permutation(e.EntryWheel.StraightSeq).Valid() || e.EntryWheel.ReverseSeq != Permutation(e.EntryWheel.StraightSeq).Inverse() {
+		errs = append(errs, fmt.Errorf(`entry wheel "%s": wiring is not a permutation`, e.EntryWheel.ID))
+	}
+	if e.Keyboard != nil && (!Permutation(e.Keyboard.Keys).Valid() || e.Keyboard.Lamps != Permutation(e.Keyboard.Keys).Inverse()) {
+		errs = append(errs, fmt.Errorf(`keyboard "%s": the lamps don't undo the keys`, e.Keyboard.Layout))
+	}
+	if e.model != nil && e.model.Slots != 0 {
+		errs = append(errs, e.validateModel()...)
+	}
+	return errors.Join(errs...)
+}
+
+// validateRotor checks the rotor in the slot.
+func (e *Enigma) validateRotor(slot int, rotor *Rotor) []error {
+	var errs []error
+	if !Permutation(rotor.StraightSeq).Valid() || rotor.ReverseSeq != Permutation(rotor.StraightSeq).Inverse() {
+		errs = append(errs, fmt.Errorf(`slot %d: wiring of rotor "%s" is not a permutation`, slot+1, rotor.ID))
+	}
+	for _, turnover := range rotor.Turnover {
+		if turnover < 0 || turnover > 25 {
+			errs = append(errs, fmt.Errorf(`slot %d: notch of rotor "%s" out of range: %d`, slot+1, rotor.ID, turnover))
+		}
+	}
+	if drivenBy := rotor.DrivenBy; drivenBy < 0 || drivenBy > len(e.Rotors) || drivenBy == slot+1 {
+		errs = append(errs, fmt.Errorf("slot %d cannot be driven by slot %d", slot+1, drivenBy))
+	}
+	return errs
+}
+
+// validateModel checks the machine against its historical model.
+func (e *Enigma) validateModel() []error {
+	m := e.model
+	var errs []error
+	if len(e.Rotors) != m.Slots {
+		errs = append(errs, fmt.Errorf("wrong number of rotors: Enigma %s takes %d, got %d",
+			m.Name, m.Slots, len(e.Rotors)))
+	}
+	for i, rotor := range e.Rotors {
+		if rotor == nil {
+			continue
+		}
+		if m.Rotors.GetByID(rotor.ID) == nil {
+			err := settingError(ErrUnknownRotor, `slot %d: unknown rotor "%s" for Enigma %s`, i+1, rotor.ID, m.Name)
+			err.ID, err.Slot = rotor.ID, i+1
+			errs = append(errs, err)
+		}
+		if rotor.Fixed != m.isFixed(i) {
+			errs = append(errs, fmt.Errorf("slot %d of Enigma %s cannot be fixed", i+1, m.Name))
+		}
+	}
+	if id := e.Reflector.ID; m.Reflectors.GetByID(id) == nil && !(id == UKWD && m.UKWD) {
+		err := settingError(ErrRotorReflectorMismatch,
+			`reflector "%s" doesn't go with the rotors of Enigma %s`, id, m.Name)
+		err.ID = id
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,106 @@
+package enigma
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A machine built right is valid, and each exported field broken after
+// it was built is caught, with where it was broken.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(e *Enigma)
+		want    string
+	}{
+		{"rotor wiring", func(e *Enigma) { e.Rotors[1].StraightSeq[0] = e.Rotors[1].StraightSeq[1] }, `slot 2: wiring of rotor "II" is not a permutation`},
+		{"rotor reverse wiring", func(e *Enigma) {
+			e.Rotors[0].ReverseSeq[0], e.Rotors[0].ReverseSeq[1] = e.Rotors[0].ReverseSeq[1], e.Rotors[0].ReverseSeq[0]
+		}, `slot 1: wiring of rotor "I" is not a permutation`},
+		{"notch", func(e *Enigma) { e.Rotors[2].Turnover = []int{26} }, `slot 3: notch of rotor "III" out of range: 26`},
+		{"missing rotor", func(e *Enigma) { e.Rotors[2] = nil }, "slot 3: no rotor"},
+		{"drive", func(e *Enigma) { e.Rotors[0].DrivenBy = 1 }, "slot 1 cannot be driven by slot 1"},
+		{"reflector fixed point", func(e *Enigma) {
+			e.Reflector.Sequence[0], e.Reflector.Sequence[e.Reflector.Sequence[0]] = 0, e.Reflector.Sequence[0]
+		}, `reflector "B": letters are not swapped in pairs`},
+		{"reflector wiring", func(e *Enigma) { e.Reflector.Sequence[0] = 26 }, `reflector "B": wiring is not a permutation`},
+		{"plugboard", func(e *Enigma) { e.Plugboard[0] = 1 }, "plugboard: not a permutation"},
+		{"plugboard pairs", func(e *Enigma) { e.Plugboard[0], e.Plugboard[1], e.Plugboard[2] = 1, 2, 0 }, "plugboard: letters are not plugged in pairs"},
+		{"entry wheel", func(e *Enigma) { e.EntryWheel.ReverseSeq[4] = 5 }, `entry wheel "ABC": wiring is not a permutation`},
+		{"model rotor count", func(e *Enigma) { e.Rotors = e.Rotors[:2] }, "wrong number of rotors: Enigma I takes 3, got 2"},
+		{"model rotor", func(e *Enigma) { e.Rotors[1].ID = "VIII" }, `slot 2: unknown rotor "VIII" for Enigma I`},
+		{"model fixed slot", func(e *Enigma) { e.Rotors[2].Fixed = true }, "slot 3 of Enigma I cannot be fixed"},
+		{"model reflector", func(e *Enigma) { e.Reflector.ID = "B-thin" }, `reflector "B-thin" doesn't go with the rotors of Enigma I`},
+	}
+	for _, tt := range tests {
+		e, err := EnigmaI.New(classicConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Validate(); err != nil {
+			t.Fatalf("the machine as built is invalid: %v", err)
+		}
+		tt.corrupt(e)
+		err = e.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: the machine is validated with %v, expected %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Generic takes rotors wired by the configuration under any ID, so a
+// rotor renamed by hand is still valid there, but its wiring isn't.
+func TestValidateGeneric(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Rotors[1].ID = "K-II"
+	if err := e.Validate(); err != nil {
+		t.Errorf("the renamed rotor is validated with %v", err)
+	}
+	e.Rotors[1].StraightSeq[0] = e.Rotors[1].StraightSeq[1]
+	if err := e.Validate(); err == nil || !strings.Contains(err.Error(), `slot 2: wiring of rotor "K-II" is not a permutation`) {
+		t.Errorf("the rewired rotor is validated with %v", err)
+	}
+}
+
+// Every problem is listed, not just the first, and the ones of a kind
+// can be told by errors.Is.
+func TestValidateEveryProblem(t *testing.T) {
+	e, err := EnigmaI.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Rotors[0].StraightSeq[0] = e.Rotors[0].StraightSeq[1]
+	e.Rotors[2].ID = "Beta"
+	e.Plugboard[0], e.Plugboard[1], e.Plugboard[2] = 1, 2, 0
+	err = e.Validate()
+	if err == nil {
+		t.Fatal("the broken machine is valid")
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 3 {
+		t.Errorf("%d problems are listed, expected 3: %v", len(lines), err)
+	}
+	if !errors.Is(err, ErrUnknownRotor) || !errors.Is(err, ErrPlugConflict) {
+		t.Errorf("the problems aren't told apart: %v", err)
+	}
+}
+
+// A broken machine doesn't get to encode a stream.
+func TestEncodeStreamValidates(t *testing.T) {
+	e, err := Generic.New(classicConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Reflector.Sequence[0] = 26
+	var out bytes.Buffer
+	if _, err := e.EncodeStream(strings.NewReader("WETTERBERICHT"), &out, StreamOptions{}); err == nil || out.Len() != 0 {
+		t.Errorf("the broken machine encodes the stream to %q (%v)", out.String(), err)
+	}
+	if e.Positions() != "AAA" {
+		t.Errorf("the broken machine stepped to %s", e.Positions())
+	}
+}
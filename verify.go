@@ -0,0 +1,245 @@
+package enigma
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IssueKind tells what VerifyTranscription found wrong.
+type IssueKind int
+
+// Kinds of issues: the letter count isn't the one of the preamble, a
+// group isn't five letters long (or, for the last one, is longer), a
+// short group next to a long one (a letter written down in the wrong
+// group), a group written down twice, a group missing, copies of a part
+// reading differently, and something that isn't a preamble or a group
+// of letters at all.
+const (
+	CountMismatch IssueKind = iota
+	WrongGroupLength
+	BoundarySlip
+	DuplicatedGroup
+	MissingGroup
+	CopiesDiffer
+	Unreadable
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case CountMismatch:
+		return "count mismatch"
+	case WrongGroupLength:
+		return "wrong group length"
+	case BoundarySlip:
+		return "boundary slip"
+	case DuplicatedGroup:
+		return "duplicated group"
+	case MissingGroup:
+		return "missing group"
+	case CopiesDiffer:
+		return "copies differ"
+	case Unreadable:
+		return "unreadable"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(k))
+}
+
+// TranscriptionIssue is something that looks wrong in a transcription:
+// the part (counting the preambles from 1) and the group (counting from
+// 1, 0 if it's about the part as a whole), what's wrong, and the likely
+// fix, if there's one to suggest.
+type TranscriptionIssue struct {
+	Part    int
+	Group   int
+	Kind    IssueKind
+	Message string
+	Fix     string
+}
+
+func (i TranscriptionIssue) String() string {
+	where := fmt.Sprintf("part %d", i.Part)
+	if i.Group > 0 {
+		where += fmt.Sprintf(", group %d", i.Group)
+	}
+	s := fmt.Sprintf("%s: %s: %s", where, i.Kind, i.Message)
+	if i.Fix != "" {
+		s += " (" + i.Fix + ")"
+	}
+	return s
+}
+
+// transcribedPart is a part of a transcription as it was written down.
+type transcribedPart struct {
+	index     int
+	count     int
+	indicator string
+	groups    []string
+}
+
+// VerifyTranscription checks a transcription of a transmission (see
+// BuildTransmission) before it's decrypted: the letter count of every
+// part against its preamble, and the groups, which should all be five
+// letters long but the last one. Parts taken down more than once (the
+// same part number and indicator), as messages were often sent twice or
+// taken by two stations, are compared group by group, which tells which
+// group went missing or got in twice. No issues means the transcription
+// looks fine, not that it decrypts. It takes the text, not a parsed
+// transmission: transmissions are text in this package (BuildTransmission
+// writes it and ParseTransmission reads it), and the transcriptions worth
+// checking are the ones that wouldn't parse.
+func VerifyTranscription(transmission string) []TranscriptionIssue {
+	var issues []TranscriptionIssue
+	var parts []transcribedPart
+	index := 0
+	lines := strings.Split(strings.Replace(transmission, "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		index++
+		part := transcribedPart{index: index}
+		match := preamblePattern.FindStringSubmatch(line)
+		if match == nil {
+			issues = append(issues, TranscriptionIssue{Part: part.index, Kind: Unreadable,
+				Message: fmt.Sprintf(`line %d should be a preamble, got "%s"`, i+1, line)})
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+			}
+			continue
+		}
+		part.count, _ = strconv.Atoi(match[4])
+		part.indicator = match[3] + " " + match[5] + " " + match[6]
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			i++
+			part.groups = append(part.groups, strings.Fields(strings.ToUpper(lines[i]))...)
+		}
+		parts = append(parts, part)
+		issues = append(issues, part.verify()...)
+	}
+	for i, part := range parts {
+		for _, earlier := range parts[:i] {
+			if earlier.indicator == part.indicator {
+				issues = append(issues, compareCopies(earlier, part)...)
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// verify checks the groups of a part and its letter count.
+func (p transcribedPart) verify() []TranscriptionIssue {
+	var issues []TranscriptionIssue
+	issue := func(group int, kind IssueKind, fix, format string, args ...interface{}) {
+		issues = append(issues, TranscriptionIssue{p.index, group, kind, fmt.Sprintf(format, args...), fix})
+	}
+	letters, slips, duplicates := 0, 0, 0
+	for i := 0; i < len(p.groups); i++ {
+		group := p.groups[i]
+		letters += len(group)
+		if _, err := ciphertextLetters(group); err != nil {
+			issue(i+1, Unreadable, "", `"%s" has characters other than letters`, group)
+		}
+		if i > 0 && group == p.groups[i-1] {
+			duplicates++
+			issue(i+1, DuplicatedGroup, "drop it if it was written down twice",
+				`"%s" is the same as the group before`, group)
+		}
+		last := i == len(p.groups)-1
+		if len(group) == 5 || (last && len(group) < 5) {
+			continue
+		}
+		if !last && len(group)+len(p.groups[i+1]) == 10 && (len(group) == 4 || len(group) == 6) {
+			next := p.groups[i+1]
+			joined := group + next
+			issue(i+1, BoundarySlip, fmt.Sprintf(`read "%s %s"`, joined[:5], joined[5:]),
+				`"%s %s" are %d and %d letters long`, group, next, len(group), len(next))
+			slips++
+			letters += len(next)
+			i++
+			continue
+		}
+		fix := "a letter seems to be missing"
+		if len(group) > 5 {
+			fix = "there seems to be a letter too many"
+		}
+		issue(i+1, WrongGroupLength, fix, `"%s" is %d letters long`, group, len(group))
+	}
+	if letters != p.count {
+		fix := ""
+		switch diff := letters - p.count; {
+		case diff == 5*duplicates:
+			fix = "dropping the duplicated groups makes up for it"
+		case diff < 0 && diff%5 == 0:
+			fix = fmt.Sprintf("%d groups of five seem to be missing", -diff/5)
+			if diff == -5 {
+				fix = "a group of five seems to be missing"
+			}
+			issue(0, MissingGroup, fix, "%d letters short", -diff)
+			return issues
+		}
+		issue(0, CountMismatch, fix, "the preamble says %d letters, got %d", p.count, letters)
+	}
+	return issues
+}
+
+// compareCopies reports where a copy of a part reads differently from
+// the earlier one, group by group.
+func compareCopies(first, copy transcribedPart) []TranscriptionIssue {
+	var issues []TranscriptionIssue
+	issue := func(group int, kind IssueKind, fix, format string, args ...interface{}) {
+		issues = append(issues, TranscriptionIssue{copy.index, group, kind, fmt.Sprintf(format, args...), fix})
+	}
+	a, b := first.groups, copy.groups
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i, j = i+1, j+1
+		case i+1 < len(a) && a[i+1] == b[j]:
+			issue(j+1, MissingGroup, fmt.Sprintf(`insert "%s"`, a[i]),
+				`group %d of part %d, "%s", isn't in this copy`, i+1, first.index, a[i])
+			i++
+		case j+1 < len(b) && b[j+1] == a[i]:
+			kind := CopiesDiffer
+			if j > 0 && b[j] == b[j-1] {
+				kind = DuplicatedGroup
+			}
+			issue(j+1, kind, "drop it if it doesn't belong",
+				`"%s" isn't in part %d`, b[j], first.index)
+			j++
+		default:
+			issue(j+1, CopiesDiffer, copyFix(a[i], b[j]),
+				`"%s" reads "%s" in part %d`, b[j], a[i], first.index)
+			i, j = i+1, j+1
+		}
+	}
+	for ; i < len(a); i++ {
+		issue(len(b)+1, MissingGroup, fmt.Sprintf(`append "%s"`, a[i]),
+			`group %d of part %d, "%s", isn't in this copy`, i+1, first.index, a[i])
+	}
+	for ; j < len(b); j++ {
+		issue(j+1, CopiesDiffer, "drop it if it doesn't belong", `"%s" isn't in part %d`, b[j], first.index)
+	}
+	return issues
+}
+
+// copyFix suggests a fix for a group that reads differently in two
+// copies.
+func copyFix(a, b string) string {
+	if len(a) != len(b) {
+		return ""
+	}
+	differ := 0
+	for i := range a {
+		if a[i] != b[i] {
+			differ++
+		}
+	}
+	if differ == 1 {
+		return "a letter was misheard in one of the copies"
+	}
+	return ""
+}
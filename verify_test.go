@@ -0,0 +1,148 @@
+package enigma
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// issueAt is where an issue was found, and of what kind.
+type issueAt struct {
+	part, group int
+	kind        IssueKind
+}
+
+// transcribed is a one-part transmission of 60 letters for the tests
+// to break: its preamble, and its twelve groups. The message key is
+// picked at random, so it's a new one every time.
+func transcribed(t *testing.T) (string, []string) {
+	t.Helper()
+	transmission, err := BuildTransmission(classicConfig(), receiveText[:60], TransmissionOptions{Time: "1920"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(transmission, "\n", 2)
+	return lines[0], strings.Fields(lines[1])
+}
+
+// copyOf writes down a part again with the groups.
+func copyOf(preamble string, groups []string) string {
+	return preamble + "\n" + strings.Join(groups, " ")
+}
+
+// misheardLetter returns the group with its first letter misheard as
+// the next one of the alphabet.
+func misheardLetter(group string) string {
+	return string('A'+(group[0]-'A'+1)%26) + group[1:]
+}
+
+// issuesAt returns where the issues of the transcription are.
+func issuesAt(transcription string) []issueAt {
+	var at []issueAt
+	for _, issue := range VerifyTranscription(transcription) {
+		at = append(at, issueAt{issue.Part, issue.Group, issue.Kind})
+	}
+	return at
+}
+
+// A transmission as it was sent, once or twice, looks fine, and every
+// kind of error written into it is reported at its group.
+func TestVerifyTranscription(t *testing.T) {
+	preamble, groups := transcribed(t)
+	if issues := VerifyTranscription(copyOf(preamble, groups)); len(issues) != 0 {
+		t.Errorf("the transmission as sent has issues: %v", issues)
+	}
+	if issues := VerifyTranscription(copyOf(preamble, groups) + "\n\n" + copyOf(preamble, groups)); len(issues) != 0 {
+		t.Errorf("the transmission sent twice has issues: %v", issues)
+	}
+	tests := []struct {
+		name    string
+		corrupt func(g []string) []string
+		want    []issueAt
+	}{
+		{"letter lost", func(g []string) []string {
+			g[2] = g[2][:4]
+			return g
+		}, []issueAt{{1, 3, WrongGroupLength}, {1, 0, CountMismatch}}},
+		{"letter too many", func(g []string) []string {
+			g[6] += "Q"
+			return g
+		}, []issueAt{{1, 7, WrongGroupLength}, {1, 0, CountMismatch}}},
+		{"boundary slip", func(g []string) []string {
+			g[1], g[2] = g[1][:4], g[1][4:]+g[2]
+			return g
+		}, []issueAt{{1, 2, BoundarySlip}}},
+		{"group twice", func(g []string) []string {
+			return append(g[:4], append([]string{g[3]}, g[4:]...)...)
+		}, []issueAt{{1, 5, DuplicatedGroup}, {1, 0, CountMismatch}}},
+		{"group missing", func(g []string) []string {
+			return append(g[:4], g[5:]...)
+		}, []issueAt{{1, 0, MissingGroup}}},
+		{"unreadable group", func(g []string) []string {
+			g[8] = "QW3RT"
+			return g
+		}, []issueAt{{1, 9, Unreadable}}},
+	}
+	for _, tt := range tests {
+		got := issuesAt(copyOf(preamble, tt.corrupt(append([]string(nil), groups...))))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: the issues are %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+	if got, want := issuesAt("1920 = 6O = FPK IMI =\nQWERT"), []issueAt{{1, 0, Unreadable}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the unreadable preamble is reported as %v, expected %v", got, want)
+	}
+}
+
+// A part taken down twice is compared group by group, and each group
+// that went missing, got in twice, or was misheard in the second copy is
+// reported at its group of that copy.
+func TestVerifyTranscriptionCopies(t *testing.T) {
+	preamble, groups := transcribed(t)
+	tests := []struct {
+		name    string
+		corrupt func(g []string) []string
+		want    []issueAt
+	}{
+		{"group missing", func(g []string) []string {
+			return append(g[:4], g[5:]...)
+		}, []issueAt{{2, 0, MissingGroup}, {2, 5, MissingGroup}}},
+		{"last group missing", func(g []string) []string {
+			return g[:len(g)-1]
+		}, []issueAt{{2, 0, MissingGroup}, {2, 12, MissingGroup}}},
+		{"group twice", func(g []string) []string {
+			return append(g[:4], append([]string{g[3]}, g[4:]...)...)
+		}, []issueAt{{2, 5, DuplicatedGroup}, {2, 0, CountMismatch}, {2, 5, DuplicatedGroup}}},
+		{"letter misheard", func(g []string) []string {
+			g[5] = misheardLetter(g[5])
+			return g
+		}, []issueAt{{2, 6, CopiesDiffer}}},
+	}
+	for _, tt := range tests {
+		second := tt.corrupt(append([]string(nil), groups...))
+		got := issuesAt(copyOf(preamble, groups) + "\n\n" + copyOf(preamble, second))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: the issues are %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The issues say what's wrong and how to fix it, where they can.
+func TestTranscriptionIssueFix(t *testing.T) {
+	preamble, groups := transcribed(t)
+	slipped := append([]string(nil), groups...)
+	slipped[1], slipped[2] = slipped[1][:4], slipped[1][4:]+slipped[2]
+	issues := VerifyTranscription(copyOf(preamble, slipped))
+	if len(issues) != 1 || issues[0].Fix != `read "`+groups[1]+" "+groups[2]+`"` {
+		t.Fatalf("the boundary slip is reported as %v", issues)
+	}
+	if got, want := issues[0].String(), `part 1, group 2: boundary slip: "`+slipped[1]+" "+slipped[2]+`" are 4 and 6 letters long (read "`+groups[1]+" "+groups[2]+`")`; got != want {
+		t.Errorf("the issue reads %s, expected %s", got, want)
+	}
+	misheard := append([]string(nil), groups...)
+	misheard[5] = misheardLetter(misheard[5])
+	issues = VerifyTranscription(copyOf(preamble, groups) + "\n\n" + copyOf(preamble, misheard))
+	if len(issues) != 1 || !strings.Contains(issues[0].Fix, "misheard") {
+		t.Errorf("the misheard letter is reported as %v", issues)
+	}
+}